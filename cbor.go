@@ -53,6 +53,14 @@ var (
 	ErrNotWellFormed        = errors.New("cbor: not well formed")
 	ErrOverflow             = errors.New("cbor: overflow")
 	ErrNestedIndefinite     = errors.New("cbor: nested indefinite")
+	ErrUnexpectedTag        = errors.New("cbor: unexpected tag")
+	ErrInvalidTagContent    = errors.New("cbor: invalid tag content")
+	ErrInvalidUTF8          = errors.New("cbor: invalid utf-8")
+	ErrMismatchedChunk      = errors.New("cbor: mismatched chunk major type")
+	ErrDuplicateMapKey      = errors.New("cbor: duplicate map key")
+	ErrTooLarge             = errors.New("cbor: too large")
+	ErrTrailingData         = errors.New("cbor: trailing data")
+	ErrMissingMember        = errors.New("cbor: missing member")
 )
 
 const (
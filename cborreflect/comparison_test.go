@@ -0,0 +1,150 @@
+//go:build cbor_reflect && cbor_comparison
+
+package cborreflect
+
+import (
+	"reflect"
+	"testing"
+
+	cbor "github.com/alex-richards/tiny-cbor"
+	fxcbor "github.com/fxamacker/cbor/v2"
+	"github.com/google/go-cmp/cmp"
+)
+
+// testBytesPoint uses the method signatures of github.com/fxamacker/cbor, so
+// both libraries use its own encoding.
+type testBytesPoint struct {
+	X, Y int64
+}
+
+func (p testBytesPoint) MarshalCBOR() ([]byte, error) {
+	return cbor.Marshal(testPoint(p))
+}
+
+func (p *testBytesPoint) UnmarshalCBOR(data []byte) error {
+	return cbor.Unmarshal(data, (*testPoint)(p))
+}
+
+type testComparison struct {
+	Name    string            `cbor:"name"`
+	Tags    []string          `cbor:"tags,omitempty"`
+	Counts  map[string]uint64 `cbor:"counts"`
+	Point   testBytesPoint    `cbor:"point"`
+	Pointer *testBytesPoint   `cbor:"pointer"`
+	Ratio   float64           `json:"ratio"`
+	Data    []byte            `cbor:"3,keyasint"`
+	Array   testArray         `cbor:"array"`
+	testInner
+}
+
+func Test_Marshal_Comparison(t *testing.T) {
+	n := -7
+
+	tests := []struct {
+		value any
+		exact bool // byte for byte, without struct fields to sort
+	}{
+		{value: true, exact: true},
+		{value: uint16(500), exact: true},
+		{value: &n, exact: true},
+		{value: 1.1, exact: true},
+		{value: float32(0.5), exact: true},
+		{value: "IETF", exact: true},
+		{value: []byte{1, 2}, exact: true},
+		{value: [3]byte{1, 2, 3}, exact: true},
+		{value: []string(nil), exact: true},
+		{value: []any{uint64(1), "a", []any{false}}, exact: true},
+		{value: map[string]int{"aa": 3, "b": 2, "a": 1}, exact: true},
+		{value: testArray{X: 1, Y: "y"}, exact: true},
+		{value: testBytesPoint{X: 1, Y: -1}, exact: true},
+		{value: testInner{C: "x"}, exact: true},
+		{
+			value: testComparison{
+				Name:      "n",
+				Counts:    map[string]uint64{"x": 1, "y": 2},
+				Point:     testBytesPoint{X: 3, Y: 4},
+				Ratio:     0.25,
+				Data:      []byte{5},
+				Array:     testArray{X: 6, Y: "z"},
+				testInner: testInner{C: "c"},
+			},
+		},
+		{
+			value: testComparison{
+				Tags:    []string{"a", "b"},
+				Pointer: &testBytesPoint{X: -1},
+			},
+		},
+	}
+
+	fx, err := fxcbor.EncOptions{
+		ShortestFloat: fxcbor.ShortestFloat16,
+		Sort:          fxcbor.SortBytewiseLexical,
+	}.EncMode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(reflect.TypeOf(tt.value).String(), func(t *testing.T) {
+			got, err := Marshal(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gotFx, err := fx.Marshal(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.exact {
+				if diff := cmp.Diff(gotFx, got); diff != "" {
+					t.Fatal(diff)
+				}
+			}
+
+			decoded := reflect.New(reflect.TypeOf(tt.value))
+			if err = Unmarshal(gotFx, decoded.Interface()); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.value, decoded.Elem().Interface(), cmp.AllowUnexported(testComparison{}, testArray{})); diff != "" {
+				t.Fatal(diff)
+			}
+
+			decodedFx := reflect.New(reflect.TypeOf(tt.value))
+			if err = fxcbor.Unmarshal(got, decodedFx.Interface()); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.value, decodedFx.Elem().Interface(), cmp.AllowUnexported(testComparison{}, testArray{})); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func Test_Unmarshal_Comparison(t *testing.T) {
+	tests := []string{
+		"a3616101616282f43901f3616340",
+		"9f01820203a1616101ff",
+		"bf6161f96400ff",
+		"7f6161ff",
+	}
+
+	for _, encoded := range tests {
+		t.Run(encoded, func(t *testing.T) {
+			data := decodeHex(t, encoded)
+
+			var got, gotFx any
+			if err := Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if err := fxcbor.Unmarshal(data, &gotFx); err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(gotFx, got); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,455 @@
+//go:build cbor_reflect
+
+package cborreflect
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+
+	cbor "github.com/alex-richards/tiny-cbor"
+)
+
+// maxPrealloc limits the number of items reserved for a slice or map from its
+// length alone, so a short input can't claim a large allocation.
+const maxPrealloc = 1024
+
+// Unmarshal decodes [data], which must hold exactly one object, into [v], as
+// [Decode].
+func Unmarshal(data []byte, v any) error {
+	in := bytes.NewReader(data)
+	if err := Decode(in, v); err != nil {
+		return err
+	}
+
+	if in.Len() != 0 {
+		return cbor.ErrTrailingData
+	}
+
+	return nil
+}
+
+// Decode reads the next object from [in] into [v], which must be a non-nil
+// pointer.
+//
+// Values implementing [cbor.Unmarshaler] or [cbor.BytesUnmarshaler] decode
+// themselves. Null leaves non-nillable values unchanged, and tags are skipped.
+// Struct fields are matched by key, falling back to a case insensitive match
+// of text keys, and unknown keys are ignored. Into an empty interface, items
+// decode as uint64, int64, float64, bool, nil, string, []byte, []any or
+// map[any]any.
+func Decode(in io.Reader, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return cbor.ErrUnsupportedValue
+	}
+
+	return decode(decoder(in), rv.Elem())
+}
+
+// decoder returns [in] as a [cbor.Decoder], wrapping it if needed.
+func decoder(in io.Reader) *cbor.Decoder {
+	if d, ok := in.(*cbor.Decoder); ok {
+		return d
+	}
+	return cbor.NewDecoder(in)
+}
+
+func isNull(majorType cbor.MajorType, arg cbor.Arg, value uint64) bool {
+	return majorType == cbor.MajorTypeSimpleFloat && arg < cbor.Arg8 &&
+		(value == uint64(cbor.SimpleNull) || value == uint64(cbor.SimpleUndefined))
+}
+
+func decode(d *cbor.Decoder, v reflect.Value) error {
+	majorType, arg, value, err := d.PeekType()
+	if err != nil {
+		return err
+	}
+
+	t := v.Type()
+
+	if isNull(majorType, arg, value) {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map:
+			v.SetZero()
+		}
+		return cbor.ReadOver(d)
+	}
+
+	if t.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(t.Elem()))
+		}
+		return decode(d, v.Elem())
+	}
+
+	pt := reflect.PointerTo(t)
+	if pt.Implements(unmarshalerType) {
+		return v.Addr().Interface().(cbor.Unmarshaler).UnmarshalCBOR(d)
+	}
+	if pt.Implements(bytesUnmarshalerType) {
+		return cbor.Decode(d, v.Addr().Interface())
+	}
+
+	for majorType == cbor.MajorTypeTagged {
+		if _, err = cbor.ReadTag(d); err != nil {
+			return err
+		}
+		if majorType, _, _, err = d.PeekType(); err != nil {
+			return err
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		b, err := cbor.ReadBool(d)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := cbor.ReadSigned[int64](d)
+		if err != nil {
+			return err
+		}
+		if v.OverflowInt(i) {
+			return cbor.ErrOverflow
+		}
+		v.SetInt(i)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := cbor.ReadUnsigned[uint64](d)
+		if err != nil {
+			return err
+		}
+		if v.OverflowUint(u) {
+			return cbor.ErrOverflow
+		}
+		v.SetUint(u)
+
+	case reflect.Float32:
+		f, err := cbor.ReadFloat[float32](d)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(float64(f))
+
+	case reflect.Float64:
+		f, err := cbor.ReadFloat[float64](d)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+
+	case reflect.String:
+		s, err := cbor.ReadString(d)
+		if err != nil {
+			return err
+		}
+		v.SetString(s)
+
+	case reflect.Interface:
+		if t.NumMethod() != 0 {
+			if v.IsNil() || v.Elem().Kind() != reflect.Pointer {
+				return cbor.ErrUnsupportedValue
+			}
+			return decode(d, v.Elem())
+		}
+
+		a, err := decodeAny(d)
+		if err != nil {
+			return err
+		}
+		if a == nil {
+			v.SetZero()
+		} else {
+			v.Set(reflect.ValueOf(a))
+		}
+
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 && majorType == cbor.MajorTypeBstr {
+			b, err := cbor.ReadByteString(d)
+			if err != nil {
+				return err
+			}
+			v.SetBytes(b)
+			return nil
+		}
+		return decodeSlice(d, v)
+
+	case reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 && majorType == cbor.MajorTypeBstr {
+			b, err := cbor.ReadByteString(d)
+			if err != nil {
+				return err
+			}
+			v.SetZero()
+			reflect.Copy(v, reflect.ValueOf(b))
+			return nil
+		}
+		return decodeArray(d, v)
+
+	case reflect.Map:
+		return decodeMap(d, v)
+
+	case reflect.Struct:
+		return decodeStruct(d, v, majorType)
+
+	default:
+		return cbor.ErrUnsupportedValue
+	}
+
+	return nil
+}
+
+func decodeSlice(d *cbor.Decoder, v reflect.Value) error {
+	return cbor.ReadArray(
+		d,
+		func(indefinite bool, length uint64) error {
+			v.Set(reflect.MakeSlice(v.Type(), 0, int(min(length, maxPrealloc))))
+			return nil
+		},
+		func(in io.Reader) error {
+			item := reflect.New(v.Type().Elem()).Elem()
+			if err := decode(decoder(in), item); err != nil {
+				return err
+			}
+			v.Set(reflect.Append(v, item))
+			return nil
+		},
+	)
+}
+
+func decodeArray(d *cbor.Decoder, v reflect.Value) error {
+	v.SetZero()
+
+	i := 0
+	return cbor.ReadArray(
+		d,
+		func(bool, uint64) error { return nil },
+		func(in io.Reader) error {
+			defer func() { i++ }()
+			if i >= v.Len() {
+				return cbor.ReadOver(in)
+			}
+			return decode(decoder(in), v.Index(i))
+		},
+	)
+}
+
+func decodeMap(d *cbor.Decoder, v reflect.Value) error {
+	t := v.Type()
+	return cbor.ReadMap(
+		d,
+		func(indefinite bool, length uint64) error {
+			if v.IsNil() {
+				v.Set(reflect.MakeMapWithSize(t, int(min(length, maxPrealloc))))
+			}
+			return nil
+		},
+		func(in io.Reader) error {
+			d := decoder(in)
+
+			key := reflect.New(t.Key()).Elem()
+			if err := decode(d, key); err != nil {
+				return err
+			}
+			if !key.Comparable() {
+				return cbor.ErrUnsupportedValue
+			}
+
+			value := reflect.New(t.Elem()).Elem()
+			if err := decode(d, value); err != nil {
+				return err
+			}
+
+			v.SetMapIndex(key, value)
+			return nil
+		},
+	)
+}
+
+func decodeStruct(d *cbor.Decoder, v reflect.Value, majorType cbor.MajorType) error {
+	info, err := getStructInfo(v.Type())
+	if err != nil {
+		return err
+	}
+
+	if info.toArray {
+		i := 0
+		return cbor.ReadArray(
+			d,
+			func(indefinite bool, length uint64) error {
+				if !indefinite && length != uint64(len(info.fields)) {
+					return cbor.ErrUnsupportedValue
+				}
+				return nil
+			},
+			func(in io.Reader) error {
+				defer func() { i++ }()
+				if i >= len(info.fields) {
+					return cbor.ErrUnsupportedValue
+				}
+				fv, err := fieldByIndexAlloc(v, info.fields[i].index)
+				if err != nil {
+					return err
+				}
+				return decode(decoder(in), fv)
+			},
+		)
+	}
+
+	key := bytes.NewBuffer(nil)
+	return cbor.ReadMap(
+		d,
+		func(bool, uint64) error { return nil },
+		func(in io.Reader) error {
+			key.Reset()
+			if err := cbor.ReadRaw(in, key); err != nil {
+				return err
+			}
+
+			i, ok := info.byKey[key.String()]
+			if !ok {
+				i, ok = info.foldKey(key.Bytes())
+			}
+			if !ok {
+				return cbor.ReadOver(in)
+			}
+
+			fv, err := fieldByIndexAlloc(v, info.fields[i].index)
+			if err != nil {
+				return err
+			}
+			return decode(decoder(in), fv)
+		},
+	)
+}
+
+// foldKey finds a field whose name matches the text string [key] case
+// insensitively.
+func (info *structInfo) foldKey(key []byte) (int, bool) {
+	name, err := cbor.ReadString(bytes.NewReader(key))
+	if err != nil {
+		return 0, false
+	}
+
+	for i, f := range info.fields {
+		if strings.EqualFold(f.name, name) {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
+
+// fieldByIndexAlloc returns the field of [v] at [index], allocating any nil
+// embedded pointers on the way.
+func fieldByIndexAlloc(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, cbor.ErrUnsupportedValue
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
+
+func decodeAny(d *cbor.Decoder) (any, error) {
+	majorType, arg, value, err := d.PeekType()
+	if err != nil {
+		return nil, err
+	}
+
+	switch majorType {
+	case cbor.MajorTypeUInt:
+		return cbor.ReadUnsigned[uint64](d)
+
+	case cbor.MajorTypeNInt:
+		return cbor.ReadSigned[int64](d)
+
+	case cbor.MajorTypeBstr:
+		return cbor.ReadByteString(d)
+
+	case cbor.MajorTypeTstr:
+		return cbor.ReadString(d)
+
+	case cbor.MajorTypeArray:
+		var a []any
+		err = cbor.ReadArray(
+			d,
+			func(indefinite bool, length uint64) error {
+				a = make([]any, 0, min(length, maxPrealloc))
+				return nil
+			},
+			func(in io.Reader) error {
+				item, err := decodeAny(decoder(in))
+				if err != nil {
+					return err
+				}
+				a = append(a, item)
+				return nil
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+		return a, nil
+
+	case cbor.MajorTypeMap:
+		var m map[any]any
+		err = cbor.ReadMap(
+			d,
+			func(indefinite bool, length uint64) error {
+				m = make(map[any]any, min(length, maxPrealloc))
+				return nil
+			},
+			func(in io.Reader) error {
+				d := decoder(in)
+				key, err := decodeAny(d)
+				if err != nil {
+					return err
+				}
+				if key != nil && !reflect.ValueOf(key).Comparable() {
+					return cbor.ErrUnsupportedValue
+				}
+				value, err := decodeAny(d)
+				if err != nil {
+					return err
+				}
+				m[key] = value
+				return nil
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+		return m, nil
+
+	case cbor.MajorTypeTagged:
+		if _, err = cbor.ReadTag(d); err != nil {
+			return nil, err
+		}
+		return decodeAny(d)
+
+	default: // cbor.MajorTypeSimpleFloat
+		switch {
+		case arg == cbor.SimpleFloat16 || arg == cbor.SimpleFloat32 || arg == cbor.SimpleFloat64:
+			return cbor.ReadFloat[float64](d)
+		case arg < cbor.Arg8 && (value == uint64(cbor.SimpleFalse) || value == uint64(cbor.SimpleTrue)):
+			return cbor.ReadBool(d)
+		case isNull(majorType, arg, value):
+			return nil, cbor.ReadOver(d)
+		default:
+			return nil, cbor.ErrUnsupportedValue
+		}
+	}
+}
@@ -0,0 +1,7 @@
+// Package cborreflect marshals and unmarshals arbitrary Go values by
+// reflection, using the struct tags of github.com/fxamacker/cbor, as a
+// fallback for types without hand written codecs.
+//
+// It is only built with the cbor_reflect build tag, so the core package stays
+// free of reflection.
+package cborreflect
@@ -0,0 +1,241 @@
+//go:build cbor_reflect
+
+package cborreflect
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"slices"
+
+	cbor "github.com/alex-richards/tiny-cbor"
+)
+
+var (
+	marshalerType        = reflect.TypeFor[cbor.Marshaler]()
+	bytesMarshalerType   = reflect.TypeFor[cbor.BytesMarshaler]()
+	unmarshalerType      = reflect.TypeFor[cbor.Unmarshaler]()
+	bytesUnmarshalerType = reflect.TypeFor[cbor.BytesUnmarshaler]()
+)
+
+// Marshal returns the encoding of [v], as [Encode].
+func Marshal(v any) ([]byte, error) {
+	out := bytes.NewBuffer(nil)
+	if err := Encode(out, v); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+// Encode writes [v] to [out].
+//
+// Values implementing [cbor.Marshaler] or [cbor.BytesMarshaler] encode
+// themselves. Nil pointers, interfaces, slices and maps are written as null,
+// []byte and [N]byte as byte strings, and structs as maps keyed by field name,
+// or as arrays with the toarray option. Map keys are sorted by the bytewise
+// order of their encodings.
+func Encode(out io.Writer, v any) error {
+	return encode(out, reflect.ValueOf(v))
+}
+
+func encode(out io.Writer, v reflect.Value) error {
+	if !v.IsValid() {
+		return cbor.Encode(out, nil)
+	}
+
+	t := v.Type()
+	if t.Kind() == reflect.Interface {
+		return encode(out, v.Elem())
+	}
+	if t.Kind() == reflect.Pointer && v.IsNil() {
+		return cbor.Encode(out, nil)
+	}
+
+	if t.Implements(marshalerType) {
+		return v.Interface().(cbor.Marshaler).MarshalCBOR(out)
+	}
+	if t.Implements(bytesMarshalerType) {
+		return cbor.Encode(out, v.Interface().(cbor.BytesMarshaler))
+	}
+	if v.CanAddr() {
+		pt := reflect.PointerTo(t)
+		if pt.Implements(marshalerType) || pt.Implements(bytesMarshalerType) {
+			return encode(out, v.Addr())
+		}
+	}
+
+	var err error
+	switch t.Kind() {
+	case reflect.Bool:
+		_, err = cbor.WriteBool(out, v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		_, err = cbor.WriteSigned(out, v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		_, err = cbor.WriteUnsigned(out, v.Uint())
+	case reflect.Float32:
+		_, err = cbor.WriteFloat(out, float32(v.Float()))
+	case reflect.Float64:
+		_, err = cbor.WriteFloat(out, v.Float())
+	case reflect.String:
+		_, err = cbor.WriteString(out, v.String())
+	case reflect.Pointer:
+		return encode(out, v.Elem())
+	case reflect.Slice:
+		if v.IsNil() {
+			return cbor.Encode(out, nil)
+		}
+		if t.Elem().Kind() == reflect.Uint8 {
+			_, err = cbor.WriteBytes(out, v.Bytes())
+			return err
+		}
+		return encodeArray(out, v)
+	case reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			_, err = cbor.WriteBytes(out, b)
+			return err
+		}
+		return encodeArray(out, v)
+	case reflect.Map:
+		if v.IsNil() {
+			return cbor.Encode(out, nil)
+		}
+		return encodeMap(out, v)
+	case reflect.Struct:
+		return encodeStruct(out, v)
+	default:
+		return cbor.ErrUnsupportedValue
+	}
+
+	return err
+}
+
+func encodeArray(out io.Writer, v reflect.Value) error {
+	if _, err := cbor.WriteArrayHeader(out, uint64(v.Len())); err != nil {
+		return err
+	}
+
+	for i := 0; i < v.Len(); i++ {
+		if err := encode(out, v.Index(i)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func encodeMap(out io.Writer, v reflect.Value) error {
+	type entry struct {
+		key   []byte
+		value reflect.Value
+	}
+
+	entries := make([]entry, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		b := bytes.NewBuffer(nil)
+		if err := encode(b, iter.Key()); err != nil {
+			return err
+		}
+		entries = append(entries, entry{key: b.Bytes(), value: iter.Value()})
+	}
+
+	slices.SortFunc(entries, func(a, b entry) int {
+		return bytes.Compare(a.key, b.key)
+	})
+
+	if _, err := cbor.WriteMapHeader(out, uint64(len(entries))); err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if _, err := out.Write(e.key); err != nil {
+			return err
+		}
+		if err := encode(out, e.value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func encodeStruct(out io.Writer, v reflect.Value) error {
+	info, err := getStructInfo(v.Type())
+	if err != nil {
+		return err
+	}
+
+	if info.toArray {
+		if _, err = cbor.WriteArrayHeader(out, uint64(len(info.fields))); err != nil {
+			return err
+		}
+
+		for _, f := range info.fields {
+			fv, _ := fieldByIndex(v, f.index)
+			if err = encode(out, fv); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	fields := make([]reflect.Value, len(info.fields))
+	var length uint64
+	for i, f := range info.fields {
+		fv, ok := fieldByIndex(v, f.index)
+		if !ok || (f.omitEmpty && isEmpty(fv)) {
+			continue
+		}
+		fields[i] = fv
+		length++
+	}
+
+	if _, err = cbor.WriteMapHeader(out, length); err != nil {
+		return err
+	}
+
+	for i, f := range info.fields {
+		if !fields[i].IsValid() {
+			continue
+		}
+		if _, err = out.Write(f.key); err != nil {
+			return err
+		}
+		if err = encode(out, fields[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fieldByIndex returns the field of [v] at [index], or false if it is within a
+// nil embedded pointer.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// isEmpty reports whether [v] is omitted by the omitempty option.
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		return v.Len() == 0
+	case reflect.Struct:
+		return false
+	default:
+		return v.IsZero()
+	}
+}
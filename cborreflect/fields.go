@@ -0,0 +1,177 @@
+//go:build cbor_reflect
+
+package cborreflect
+
+import (
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	cbor "github.com/alex-richards/tiny-cbor"
+)
+
+// field is an encoded struct field, possibly promoted from an embedded struct.
+type field struct {
+	name      string
+	key       []byte // encoded map key
+	index     []int
+	omitEmpty bool
+	tagged    bool
+}
+
+type structInfo struct {
+	fields  []field
+	byKey   map[string]int // encoded key to index in fields
+	toArray bool
+}
+
+var structInfos sync.Map // reflect.Type to *structInfo
+
+func getStructInfo(t reflect.Type) (*structInfo, error) {
+	if info, ok := structInfos.Load(t); ok {
+		return info.(*structInfo), nil
+	}
+
+	info := &structInfo{byKey: make(map[string]int)}
+
+	var fields []field
+	err := collectFields(t, nil, info, &fields)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range dominantFields(fields) {
+		info.byKey[string(f.key)] = len(info.fields)
+		info.fields = append(info.fields, f)
+	}
+
+	structInfos.Store(t, info)
+	return info, nil
+}
+
+// collectFields appends the fields of [t], and of any untagged embedded
+// structs, to [fields], following the rules of encoding/json.
+func collectFields(t reflect.Type, index []int, info *structInfo, fields *[]field) error {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		tag, ok := f.Tag.Lookup("cbor")
+		if !ok {
+			tag = f.Tag.Get("json")
+		}
+		if tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if f.Name == "_" {
+			if index == nil && hasOption(opts, "toarray") {
+				info.toArray = true
+			}
+			continue
+		}
+
+		fieldIndex := append(index[:len(index):len(index)], i)
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if err := collectFields(ft, fieldIndex, info, fields); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		tagged := name != ""
+		if !tagged {
+			name = f.Name
+		}
+
+		var key []byte
+		var err error
+		if hasOption(opts, "keyasint") {
+			n, perr := strconv.ParseInt(name, 10, 64)
+			if perr != nil {
+				return cbor.ErrUnsupportedValue
+			}
+			key, err = cbor.Marshal(n)
+		} else {
+			key, err = cbor.Marshal(name)
+		}
+		if err != nil {
+			return err
+		}
+
+		*fields = append(*fields, field{
+			name:      name,
+			key:       key,
+			index:     fieldIndex,
+			omitEmpty: hasOption(opts, "omitempty"),
+			tagged:    tagged,
+		})
+	}
+
+	return nil
+}
+
+// dominantFields drops fields hidden by a shallower field with the same key,
+// and fields that conflict at the same depth, unless exactly one is tagged.
+func dominantFields(fields []field) []field {
+	byKey := make(map[string][]field)
+	for _, f := range fields {
+		byKey[string(f.key)] = append(byKey[string(f.key)], f)
+	}
+
+	out := make([]field, 0, len(fields))
+	for _, f := range fields {
+		candidates := byKey[string(f.key)]
+		depth := len(candidates[0].index)
+		for _, c := range candidates {
+			depth = min(depth, len(c.index))
+		}
+
+		var dominant []field
+		for _, c := range candidates {
+			if len(c.index) == depth {
+				dominant = append(dominant, c)
+			}
+		}
+
+		if len(dominant) > 1 {
+			var tagged []field
+			for _, c := range dominant {
+				if c.tagged {
+					tagged = append(tagged, c)
+				}
+			}
+			dominant = tagged
+		}
+
+		if len(dominant) == 1 && slices.Equal(dominant[0].index, f.index) {
+			out = append(out, f)
+		}
+	}
+
+	return out
+}
+
+func hasOption(opts string, option string) bool {
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
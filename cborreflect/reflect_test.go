@@ -0,0 +1,178 @@
+//go:build cbor_reflect
+
+package cborreflect
+
+import (
+	"io"
+	"reflect"
+	"testing"
+
+	cbor "github.com/alex-richards/tiny-cbor"
+	"github.com/google/go-cmp/cmp"
+)
+
+type testInner struct {
+	C string `cbor:"c"`
+}
+
+type testStruct struct {
+	A int    `cbor:"a"`
+	B []byte `cbor:"b,omitempty"`
+	testInner
+	N          *int   `cbor:"n,omitempty"`
+	K          uint8  `cbor:"1,keyasint"`
+	Skip       string `cbor:"-"`
+	unexported int
+}
+
+type testArray struct {
+	_ struct{} `cbor:",toarray"`
+	X int
+	Y string
+}
+
+type testPoint struct {
+	X, Y int64
+}
+
+func (p testPoint) MarshalCBOR(out io.Writer) error {
+	_, err := cbor.WriteSlice(out, []int64{p.X, p.Y}, cbor.WriteSigned[int64])
+	return err
+}
+
+func (p *testPoint) UnmarshalCBOR(in io.Reader) error {
+	s, err := cbor.ReadSlice(in, cbor.ReadSigned[int64])
+	if err != nil {
+		return err
+	}
+	if len(s) != 2 {
+		return cbor.ErrUnsupportedValue
+	}
+	p.X, p.Y = s[0], s[1]
+	return nil
+}
+
+type testEnvelope struct {
+	T uint8           `cbor:"t"`
+	P cbor.RawMessage `cbor:"p"`
+}
+
+type testHolder struct {
+	P  testPoint  `cbor:"p"`
+	PP *testPoint `cbor:"pp"`
+}
+
+func Test_Marshal(t *testing.T) {
+	n := 5
+
+	tests := []struct {
+		encoded string
+		value   any
+	}{
+		{encoded: "f6", value: (*int)(nil)},
+		{encoded: "05", value: &n},
+		{encoded: "3863", value: int16(-100)},
+		{encoded: "f93e00", value: 1.5},
+		{encoded: "6449455446", value: "IETF"},
+		{encoded: "4401020304", value: []byte{1, 2, 3, 4}},
+		{encoded: "4401020304", value: [4]byte{1, 2, 3, 4}},
+		{encoded: "f6", value: []string(nil)},
+		{encoded: "826161f5", value: []any{"a", true}},
+		{encoded: "a361610161620262616103", value: map[string]int{"aa": 3, "b": 2, "a": 1}},
+		{encoded: "a3616101616361780102", value: testStruct{A: 1, testInner: testInner{C: "x"}, K: 2, Skip: "skip", unexported: 3}},
+		{encoded: "a56161016162410161636178616e050102", value: testStruct{A: 1, B: []byte{1}, testInner: testInner{C: "x"}, N: &n, K: 2}},
+		{encoded: "82016179", value: testArray{X: 1, Y: "y"}},
+		{encoded: "a26174016170820102", value: testEnvelope{T: 1, P: cbor.RawMessage{0x82, 0x01, 0x02}}},
+		{encoded: "a26170820120627070820203", value: testHolder{P: testPoint{X: 1, Y: -1}, PP: &testPoint{X: 2, Y: 3}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			want := decodeHex(t, tt.encoded)
+
+			got, err := Marshal(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Fatal(diff)
+			}
+
+			decoded := reflect.New(reflect.TypeOf(tt.value))
+			if err = Unmarshal(want, decoded.Interface()); err != nil {
+				t.Fatal(err)
+			}
+
+			wantDecoded := tt.value
+			if s, ok := tt.value.(testStruct); ok {
+				s.Skip, s.unexported = "", 0
+				wantDecoded = s
+			}
+			if diff := cmp.Diff(wantDecoded, decoded.Elem().Interface(), cmp.AllowUnexported(testStruct{}, testArray{})); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func Test_Unmarshal(t *testing.T) {
+	tests := []struct {
+		encoded string
+		into    any
+		want    any
+	}{
+		{encoded: "bf616101616361780102ff", into: &testStruct{}, want: &testStruct{A: 1, testInner: testInner{C: "x"}, K: 2}},
+		{encoded: "a2614101617a01", into: &testStruct{}, want: &testStruct{A: 1}},
+		{encoded: "9f016179ff", into: &testArray{}, want: &testArray{X: 1, Y: "y"}},
+		{encoded: "f6", into: &testArray{X: 1}, want: &testArray{X: 1}},
+		{encoded: "c11a514b67b0", into: new(int), want: ptr(1363896240)},
+		{encoded: "83010203", into: new([2]int), want: &[2]int{1, 2}},
+		{encoded: "4101", into: new([2]byte), want: &[2]byte{1, 0}},
+		{
+			encoded: "a3616101616282f43901f3616340",
+			into:    new(any),
+			want:    ptr[any](map[any]any{"a": uint64(1), "b": []any{false, int64(-500)}, "c": []byte{}}),
+		},
+		{encoded: "a201020304", into: new(map[uint8]uint16), want: &map[uint8]uint16{1: 2, 3: 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			if err := Unmarshal(decodeHex(t, tt.encoded), tt.into); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, tt.into, cmp.AllowUnexported(testStruct{}, testArray{})); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func Test_Unmarshal_Errors(t *testing.T) {
+	tests := []struct {
+		encoded string
+		into    any
+		wantErr error
+	}{
+		{encoded: "01", into: 1, wantErr: cbor.ErrUnsupportedValue},
+		{encoded: "0102", into: new(int), wantErr: cbor.ErrTrailingData},
+		{encoded: "190100", into: new(uint8), wantErr: cbor.ErrOverflow},
+		{encoded: "6161", into: new(int), wantErr: cbor.ErrUnsupportedMajorType},
+		{encoded: "83016179f5", into: &testArray{}, wantErr: cbor.ErrUnsupportedValue},
+		{encoded: "a1410102", into: new(any), wantErr: cbor.ErrUnsupportedValue},
+		{encoded: "01", into: new(chan int), wantErr: cbor.ErrUnsupportedValue},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			err := Unmarshal(decodeHex(t, tt.encoded), tt.into)
+			if err != tt.wantErr {
+				t.Fatalf("want %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,309 @@
+package cddl
+
+import (
+	"encoding/hex"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Rule is a named type or group. A type rule is held as a group with a single
+// entry, see [Rule.Type].
+type Rule struct {
+	Name  string
+	Group *Group
+
+	prelude bool
+}
+
+// Type returns the type of a rule that is a single entry without a key or
+// occurrence indicator, or nil if the rule is a group.
+func (r *Rule) Type() *Type {
+	return groupType(r.Group)
+}
+
+func groupType(g *Group) *Type {
+	for g != nil && len(g.Choices) == 1 && len(g.Choices[0]) == 1 {
+		e := g.Choices[0][0]
+		if e.Key != nil || e.Occur != (Occurrence{1, 1}) {
+			return nil
+		}
+		if e.Type != nil {
+			return e.Type
+		}
+		g = e.Group
+	}
+	return nil
+}
+
+func (r *Rule) String() string {
+	if t := r.Type(); t != nil {
+		return r.Name + " = " + t.String()
+	}
+	return r.Name + " = (" + innerGroup(r.Group).String() + ")"
+}
+
+// Group is a choice between sequences of entries, separated by "//".
+type Group struct {
+	Choices [][]*Entry
+}
+
+func (g *Group) String() string {
+	var b strings.Builder
+	for i, choice := range g.Choices {
+		if i > 0 {
+			b.WriteString(" // ")
+		}
+		for j, e := range choice {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(e.String())
+		}
+	}
+	return b.String()
+}
+
+// Entry is a single member of a group, either a type with an optional key or
+// a nested group. Exactly one of Type and Group is set.
+type Entry struct {
+	Occur Occurrence
+	Key   *MemberKey
+	Type  *Type
+	Group *Group
+}
+
+func (e *Entry) String() string {
+	s := e.Occur.String()
+	if s != "" {
+		s += " "
+	}
+	if e.Key != nil {
+		s += e.Key.String()
+	}
+	if e.Group != nil {
+		return s + "(" + e.Group.String() + ")"
+	}
+	return s + e.Type.String()
+}
+
+// Occurrence is the number of times an entry may appear. Max is
+// [math.MaxUint64] when unbounded.
+type Occurrence struct {
+	Min, Max uint64
+}
+
+func (o Occurrence) String() string {
+	switch o {
+	case Occurrence{1, 1}:
+		return ""
+	case Occurrence{0, 1}:
+		return "?"
+	case Occurrence{0, math.MaxUint64}:
+		return "*"
+	case Occurrence{1, math.MaxUint64}:
+		return "+"
+	}
+
+	s := ""
+	if o.Min > 0 {
+		s = strconv.FormatUint(o.Min, 10)
+	}
+	s += "*"
+	if o.Max != math.MaxUint64 {
+		s += strconv.FormatUint(o.Max, 10)
+	}
+	return s
+}
+
+// MemberKey is the key of a map entry. Bareword keys are text values written
+// without quotes. Keys written with ":", and "^ =>", are cuts: once the key
+// matches, the value must match too.
+type MemberKey struct {
+	Type     *Type
+	Cut      bool
+	Bareword bool
+}
+
+func (k *MemberKey) String() string {
+	switch {
+	case k.Bareword:
+		return k.Type.Choices[0].Type2.Value.Text + ": "
+	case k.Cut && k.Type.Choices[0].Type2.Kind == Type2Value:
+		return k.Type.String() + ": "
+	case k.Cut:
+		return k.Type.String() + " ^ => "
+	default:
+		return k.Type.String() + " => "
+	}
+}
+
+// Type is a choice between types, separated by "/".
+type Type struct {
+	Choices []*Type1
+}
+
+func (t *Type) String() string {
+	var b strings.Builder
+	for i, c := range t.Choices {
+		if i > 0 {
+			b.WriteString(" / ")
+		}
+		b.WriteString(c.String())
+	}
+	return b.String()
+}
+
+// Type1 is a type, optionally constrained by a range ("..", "...") or control
+// operator (".size" etc.) with Arg as the other operand.
+type Type1 struct {
+	Type2 *Type2
+	Op    string
+	Arg   *Type2
+
+	re *regexp.Regexp // compiled .regexp argument
+}
+
+func (t *Type1) String() string {
+	switch t.Op {
+	case "":
+		return t.Type2.String()
+	case RangeInclusive, RangeExclusive:
+		return t.Type2.String() + t.Op + t.Arg.String()
+	default:
+		return t.Type2.String() + " " + t.Op + " " + t.Arg.String()
+	}
+}
+
+// Range operators.
+const (
+	RangeInclusive = ".."
+	RangeExclusive = "..."
+)
+
+// Control operators.
+const (
+	ControlSize    = ".size"
+	ControlBits    = ".bits"
+	ControlRegexp  = ".regexp"
+	ControlCBOR    = ".cbor"
+	ControlCBORSeq = ".cborseq"
+	ControlLT      = ".lt"
+	ControlLE      = ".le"
+	ControlGT      = ".gt"
+	ControlGE      = ".ge"
+	ControlEQ      = ".eq"
+	ControlNE      = ".ne"
+	ControlDefault = ".default"
+)
+
+type Type2Kind byte
+
+const (
+	Type2Value  Type2Kind = iota // a literal value
+	Type2Name                    // a reference to a rule
+	Type2Paren                   // a type in parentheses
+	Type2Map                     // {group}
+	Type2Array                   // [group]
+	Type2Unwrap                  // ~name
+	Type2Enum                    // &(group) or &name
+	Type2Tag                     // #6.tag(type)
+	Type2Major                   // #major.arg
+	Type2Any                     // #
+)
+
+// Type2 is a single type. The fields used depend on Kind.
+type Type2 struct {
+	Kind Type2Kind
+
+	Value Value  // Type2Value
+	Name  string // Type2Name, Type2Unwrap, and Type2Enum without a group
+	Type  *Type  // Type2Paren, Type2Tag
+	Group *Group // Type2Map, Type2Array, and Type2Enum without a name
+
+	Major uint8   // Type2Major
+	Arg   *uint64 // Type2Major argument, or Type2Tag number, if any
+}
+
+func (t *Type2) String() string {
+	switch t.Kind {
+	case Type2Value:
+		return t.Value.String()
+	case Type2Name:
+		return t.Name
+	case Type2Paren:
+		return "(" + t.Type.String() + ")"
+	case Type2Map:
+		return "{" + t.Group.String() + "}"
+	case Type2Array:
+		return "[" + t.Group.String() + "]"
+	case Type2Unwrap:
+		return "~" + t.Name
+	case Type2Enum:
+		if t.Group != nil {
+			return "&(" + t.Group.String() + ")"
+		}
+		return "&" + t.Name
+	case Type2Tag:
+		s := "#6"
+		if t.Arg != nil {
+			s += "." + strconv.FormatUint(*t.Arg, 10)
+		}
+		return s + "(" + t.Type.String() + ")"
+	case Type2Major:
+		s := "#" + strconv.Itoa(int(t.Major))
+		if t.Arg != nil {
+			s += "." + strconv.FormatUint(*t.Arg, 10)
+		}
+		return s
+	default:
+		return "#"
+	}
+}
+
+type ValueKind byte
+
+const (
+	ValueUint ValueKind = iota
+	ValueNint
+	ValueFloat
+	ValueText
+	ValueBytes
+)
+
+// Value is a literal. Integers are held as in CBOR, a ValueNint is -1 - Int.
+type Value struct {
+	Kind  ValueKind
+	Int   uint64
+	Float float64
+	Text  string
+	Bytes []byte
+}
+
+func (v Value) String() string {
+	switch v.Kind {
+	case ValueUint:
+		return strconv.FormatUint(v.Int, 10)
+	case ValueNint:
+		return formatNint(v.Int)
+	case ValueFloat:
+		s := strconv.FormatFloat(v.Float, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".eEIN") {
+			s += ".0"
+		}
+		return s
+	case ValueText:
+		return strconv.Quote(v.Text)
+	default:
+		return "h'" + hex.EncodeToString(v.Bytes) + "'"
+	}
+}
+
+// formatNint formats -1 - value, which may not fit an int64.
+func formatNint(value uint64) string {
+	if value == math.MaxUint64 {
+		return "-18446744073709551616"
+	}
+	return "-" + strconv.FormatUint(value+1, 10)
+}
@@ -0,0 +1,293 @@
+// Package cddl parses Concise Data Definition Language schemas, as described
+// in RFC 8610, and validates encoded items against them.
+//
+// Generic rules are not supported.
+package cddl
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"sync"
+)
+
+var (
+	ErrSyntax  = errors.New("cddl: syntax error")
+	ErrInvalid = errors.New("cddl: invalid item")
+)
+
+// SyntaxError is returned by [Parse] for a malformed or inconsistent schema.
+// Line and Column are zero when the error is not at a single position.
+type SyntaxError struct {
+	Line, Column int
+	Message      string
+}
+
+func (e *SyntaxError) Error() string {
+	if e.Line == 0 {
+		return "cddl: " + e.Message
+	}
+	return "cddl: " + strconv.Itoa(e.Line) + ":" + strconv.Itoa(e.Column) + ": " + e.Message
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return ErrSyntax
+}
+
+// ValidationError is returned when an item does not match a schema. Path
+// locates the failing item as in [cbor.ParsePath], and Rule is the innermost
+// rule being matched.
+type ValidationError struct {
+	Path    string
+	Rule    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "/"
+	}
+	return "cddl: " + path + ": " + e.Rule + ": " + e.Message
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalid
+}
+
+// Schema is a parsed CDDL document.
+type Schema struct {
+	// Rules holds the rules of the document in order. The first is the root.
+	Rules []*Rule
+
+	rules map[string]*Rule // including the prelude
+}
+
+// Rule returns the rule named [name], including rules from the prelude, or
+// nil if there is none.
+func (s *Schema) Rule(name string) *Rule {
+	return s.rules[name]
+}
+
+func (s *Schema) add(r *Rule) {
+	if old := s.rules[r.Name]; old == nil || old.prelude {
+		s.Rules = append(s.Rules, r)
+	}
+	s.rules[r.Name] = r
+}
+
+// Parse parses the CDDL document [src].
+func Parse(src string) (*Schema, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Schema{rules: make(map[string]*Rule)}
+	for name, r := range prelude() {
+		s.rules[name] = r
+	}
+
+	p := parser{tokens: tokens}
+	if err := p.parseRules(s); err != nil {
+		return nil, err
+	}
+	if len(s.Rules) == 0 {
+		return nil, &SyntaxError{Line: 1, Column: 1, Message: "no rules"}
+	}
+
+	for _, r := range s.Rules {
+		if err := s.resolveGroup(r, r.Group); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+const preludeSource = `
+any = #
+uint = #0
+nint = #1
+int = uint / nint
+bstr = #2
+bytes = bstr
+tstr = #3
+text = tstr
+tdate = #6.0(tstr)
+time = #6.1(number)
+number = int / float
+biguint = #6.2(bstr)
+bignint = #6.3(bstr)
+bigint = biguint / bignint
+integer = int / bigint
+unsigned = uint / biguint
+decfrac = #6.4([e10: int, m: integer])
+bigfloat = #6.5([e2: int, m: integer])
+eb64url = #6.21(any)
+eb64legacy = #6.22(any)
+eb16 = #6.23(any)
+encoded-cbor = #6.24(bstr)
+uri = #6.32(tstr)
+b64url = #6.33(tstr)
+b64legacy = #6.34(tstr)
+regexp = #6.35(tstr)
+mime-message = #6.36(tstr)
+cbor-any = #6.55799(any)
+float16 = #7.25
+float32 = #7.26
+float64 = #7.27
+float16-and-32 = float16 / float32
+float32-and-64 = float32 / float64
+float = float16-and-32 / float64
+false = #7.20
+true = #7.21
+bool = false / true
+nil = #7.22
+null = nil
+undefined = #7.23
+`
+
+var prelude = sync.OnceValue(func() map[string]*Rule {
+	tokens, err := lex(preludeSource)
+	if err != nil {
+		panic(err)
+	}
+
+	s := &Schema{rules: make(map[string]*Rule)}
+	p := parser{tokens: tokens}
+	if err := p.parseRules(s); err != nil {
+		panic(err)
+	}
+
+	for _, r := range s.rules {
+		r.prelude = true
+	}
+	return s.rules
+})
+
+// resolveGroup checks the names and operators used within [g].
+func (s *Schema) resolveGroup(r *Rule, g *Group) error {
+	for _, choice := range g.Choices {
+		for _, e := range choice {
+			if e.Key != nil {
+				if err := s.resolveType(r, e.Key.Type); err != nil {
+					return err
+				}
+			}
+			if e.Group != nil {
+				if err := s.resolveGroup(r, e.Group); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := s.resolveType(r, e.Type); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Schema) resolveType(r *Rule, t *Type) error {
+	for _, t1 := range t.Choices {
+		if err := s.resolveType2(r, t1.Type2); err != nil {
+			return err
+		}
+		if t1.Op == "" {
+			continue
+		}
+		if err := s.resolveType2(r, t1.Arg); err != nil {
+			return err
+		}
+
+		switch t1.Op {
+		case RangeInclusive, RangeExclusive:
+			lo, ok1 := s.value(t1.Type2)
+			hi, ok2 := s.value(t1.Arg)
+			if !ok1 || !ok2 || !isNumber(lo) || !isNumber(hi) || (lo.Kind == ValueFloat) != (hi.Kind == ValueFloat) {
+				return ruleError(r, "invalid range "+t1.String())
+			}
+
+		case ControlRegexp:
+			v, ok := s.value(t1.Arg)
+			if !ok || v.Kind != ValueText {
+				return ruleError(r, "invalid regexp "+t1.Arg.String())
+			}
+			re, err := regexp.Compile(`^(?:` + v.Text + `)$`)
+			if err != nil {
+				return ruleError(r, "invalid regexp "+t1.Arg.String())
+			}
+			t1.re = re
+
+		case ControlLT, ControlLE, ControlGT, ControlGE, ControlEQ, ControlNE:
+			if _, ok := s.value(t1.Arg); !ok {
+				return ruleError(r, "invalid argument to "+t1.Op)
+			}
+
+		case ControlSize, ControlBits, ControlCBOR, ControlCBORSeq, ControlDefault:
+
+		default:
+			return ruleError(r, "unsupported control operator "+t1.Op)
+		}
+	}
+	return nil
+}
+
+func (s *Schema) resolveType2(r *Rule, t *Type2) error {
+	switch t.Kind {
+	case Type2Name, Type2Unwrap:
+		if s.rules[t.Name] == nil {
+			return ruleError(r, "undefined rule "+t.Name)
+		}
+	case Type2Enum:
+		if t.Group != nil {
+			return s.resolveGroup(r, t.Group)
+		}
+		if s.rules[t.Name] == nil {
+			return ruleError(r, "undefined rule "+t.Name)
+		}
+	case Type2Paren, Type2Tag:
+		return s.resolveType(r, t.Type)
+	case Type2Map, Type2Array:
+		return s.resolveGroup(r, t.Group)
+	}
+	return nil
+}
+
+func ruleError(r *Rule, message string) error {
+	return &SyntaxError{Message: "rule " + r.Name + ": " + message}
+}
+
+// value returns the literal value of [t], following names to rules holding
+// a single value.
+func (s *Schema) value(t *Type2) (Value, bool) {
+	for range 64 {
+		switch t.Kind {
+		case Type2Value:
+			return t.Value, true
+		case Type2Paren:
+			if len(t.Type.Choices) != 1 || t.Type.Choices[0].Op != "" {
+				return Value{}, false
+			}
+			t = t.Type.Choices[0].Type2
+		case Type2Name:
+			r := s.rules[t.Name]
+			if r == nil {
+				return Value{}, false
+			}
+			rt := r.Type()
+			if rt == nil || len(rt.Choices) != 1 || rt.Choices[0].Op != "" {
+				return Value{}, false
+			}
+			t = rt.Choices[0].Type2
+		default:
+			return Value{}, false
+		}
+	}
+	return Value{}, false
+}
+
+func isNumber(v Value) bool {
+	return v.Kind == ValueUint || v.Kind == ValueNint || v.Kind == ValueFloat
+}
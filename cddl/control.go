@@ -0,0 +1,207 @@
+package cddl
+
+import (
+	"math/bits"
+
+	cbor "github.com/alex-richards/tiny-cbor"
+)
+
+func (v *validator) matchRange(t *Type1, it *item, path []string) bool {
+	lo, _ := v.schema.value(t.Type2)
+	hi, _ := v.schema.value(t.Arg)
+
+	var ok bool
+	if lo.Kind == ValueFloat {
+		ok = it.isFloat() && compareFloat(it.float(), lo.Float) >= 0
+		if t.Op == RangeInclusive {
+			ok = ok && compareFloat(it.float(), hi.Float) <= 0
+		} else {
+			ok = ok && compareFloat(it.float(), hi.Float) < 0
+		}
+	} else {
+		ok = it.isInt() && compareInt(it, lo) >= 0
+		if t.Op == RangeInclusive {
+			ok = ok && compareInt(it, hi) <= 0
+		} else {
+			ok = ok && compareInt(it, hi) < 0
+		}
+	}
+
+	if !ok {
+		v.failType(path, t.String(), it)
+	}
+	return ok
+}
+
+// compareInt compares an integer item with an integer value.
+func compareInt(it *item, value Value) int {
+	itNegative := it.majorType == cbor.MajorTypeNInt
+	valueNegative := value.Kind == ValueNint
+	switch {
+	case itNegative && !valueNegative:
+		return -1
+	case !itNegative && valueNegative:
+		return 1
+	case it.value == value.Int:
+		return 0
+	case (it.value < value.Int) != itNegative:
+		return -1
+	default:
+		return 1
+	}
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// compareNumber compares a number item with a number value, returning false
+// if either is not a number.
+func compareNumber(it *item, value Value) (int, bool) {
+	switch {
+	case it.isInt() && value.Kind != ValueFloat:
+		return compareInt(it, value), true
+	case it.isInt():
+		f := float64(it.value)
+		if it.majorType == cbor.MajorTypeNInt {
+			f = -1 - f
+		}
+		return compareFloat(f, value.Float), true
+	case it.isFloat() && value.Kind == ValueFloat:
+		return compareFloat(it.float(), value.Float), true
+	case it.isFloat() && value.Kind == ValueUint:
+		return compareFloat(it.float(), float64(value.Int)), true
+	case it.isFloat() && value.Kind == ValueNint:
+		return compareFloat(it.float(), -1-float64(value.Int)), true
+	}
+	return 0, false
+}
+
+// matchControl checks the control operator of [t] against an item already
+// matching its target type.
+func (v *validator) matchControl(t *Type1, it *item, path []string) bool {
+	var ok bool
+	switch t.Op {
+	case ControlSize:
+		ok = v.matchSize(t.Arg, it)
+
+	case ControlBits:
+		ok = v.matchBits(t.Arg, it)
+
+	case ControlRegexp:
+		ok = it.majorType == cbor.MajorTypeTstr && t.re.Match(it.bytes)
+
+	case ControlCBOR:
+		if it.majorType != cbor.MajorTypeBstr {
+			break
+		}
+		embedded, err := readItemData(it.bytes, v.maxDepth)
+		if err != nil {
+			v.fail(path, "invalid embedded item: "+err.Error())
+			return false
+		}
+		return v.matchType2(t.Arg, embedded, path)
+
+	case ControlCBORSeq:
+		if it.majorType != cbor.MajorTypeBstr {
+			break
+		}
+		embedded, err := readItemSequence(it.bytes, v.maxDepth)
+		if err != nil {
+			v.fail(path, "invalid embedded item: "+err.Error())
+			return false
+		}
+		return v.matchType2(t.Arg, &item{majorType: cbor.MajorTypeArray, items: embedded}, path)
+
+	case ControlLT, ControlLE, ControlGT, ControlGE:
+		value, _ := v.schema.value(t.Arg)
+		c, isNumber := compareNumber(it, value)
+		switch t.Op {
+		case ControlLT:
+			ok = isNumber && c < 0
+		case ControlLE:
+			ok = isNumber && c <= 0
+		case ControlGT:
+			ok = isNumber && c > 0
+		default:
+			ok = isNumber && c >= 0
+		}
+
+	case ControlEQ, ControlNE:
+		value, _ := v.schema.value(t.Arg)
+		equal := matchValue(value, it)
+		if c, isNumber := compareNumber(it, value); isNumber {
+			equal = c == 0
+		}
+		ok = equal == (t.Op == ControlEQ)
+
+	default: // ControlDefault
+		return true
+	}
+
+	if !ok {
+		v.failType(path, t.String(), it)
+	}
+	return ok
+}
+
+// matchSize checks the size of a string, or the number of bytes needed to
+// hold an unsigned integer, against [size].
+func (v *validator) matchSize(size *Type2, it *item) bool {
+	switch it.majorType {
+	case cbor.MajorTypeBstr, cbor.MajorTypeTstr:
+		return v.probe(func() bool {
+			return v.matchType2(size, uintItem(uint64(len(it.bytes))), nil)
+		})
+
+	case cbor.MajorTypeUInt:
+		// any size from the minimum up to 8 bytes can hold the value
+		for n := (bits.Len64(it.value) + 7) / 8; n <= 8; n++ {
+			if v.probe(func() bool { return v.matchType2(size, uintItem(uint64(n)), nil) }) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchBits checks that each bit set in an unsigned integer or byte string
+// is numbered by a value matching [allowed]. Bit n of a byte string is bit
+// n%8, from the least significant, of byte n/8.
+func (v *validator) matchBits(allowed *Type2, it *item) bool {
+	check := func(n uint64) bool {
+		return v.probe(func() bool { return v.matchType2(allowed, uintItem(n), nil) })
+	}
+
+	switch it.majorType {
+	case cbor.MajorTypeUInt:
+		for x := it.value; x != 0; x &= x - 1 {
+			if !check(uint64(bits.TrailingZeros64(x))) {
+				return false
+			}
+		}
+		return true
+
+	case cbor.MajorTypeBstr:
+		for i, b := range it.bytes {
+			for x := b; x != 0; x &= x - 1 {
+				if !check(uint64(i)*8 + uint64(bits.TrailingZeros8(x))) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func uintItem(value uint64) *item {
+	return &item{majorType: cbor.MajorTypeUInt, value: value}
+}
@@ -0,0 +1,1102 @@
+// Package gen generates Go types, and functions to read and write them with
+// tiny-cbor, from the rules of a CDDL schema.
+//
+// Each type rule becomes a Go type with Read and Write functions:
+//
+//   - maps with literal keys become structs, keyed by text or by integer
+//   - arrays of fixed entries become structs, read and written as records
+//   - arrays and maps with repeated entries become slices and maps
+//   - choices between literal values become enums
+//   - choices with null become pointers
+//   - anything else is kept encoded as a [cbor.RawMessage]
+//
+// Optional members are pointers, unless nil already means absent. Unknown map
+// keys are skipped when reading.
+package gen
+
+import (
+	"bytes"
+	"errors"
+	"go/format"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	cbor "github.com/alex-richards/tiny-cbor"
+	"github.com/alex-richards/tiny-cbor/cddl"
+)
+
+// Options configure [Generate].
+type Options struct {
+	// Package is the package name of the generated file.
+	Package string
+
+	// Source names the schema in the generated header, if set.
+	Source string
+}
+
+// goType is a Go type, with expressions for functions that read and write
+// it, func(io.Reader) (T, error) and func(io.Writer, T) (int, error).
+// Composite types also have templates for direct calls, taking the reader,
+// or the writer and value.
+type goType struct {
+	name      string
+	read      string
+	write     string
+	readCall  string
+	writeCall string
+	nillable  bool // nil is the zero value, and means absent
+}
+
+// callRead returns an expression reading [t] from [in].
+func (t *goType) callRead(in string) string {
+	if t.readCall != "" {
+		return strings.ReplaceAll(t.readCall, "$in", in)
+	}
+	return t.read + "(" + in + ")"
+}
+
+// callWrite returns an expression writing [v] as [t] to [out].
+func (t *goType) callWrite(out, v string) string {
+	if t.writeCall != "" {
+		return strings.NewReplacer("$out", out, "$v", v).Replace(t.writeCall)
+	}
+	return t.write + "(" + out + ", " + v + ")"
+}
+
+var (
+	uintType   = &goType{name: "uint64", read: "cbor.ReadUnsigned[uint64]", write: "cbor.WriteUnsigned[uint64]"}
+	intType    = &goType{name: "int64", read: "cbor.ReadSigned[int64]", write: "cbor.WriteSigned[int64]"}
+	floatType  = &goType{name: "float64", read: "cbor.ReadFloat[float64]", write: "cbor.WriteFloat[float64]"}
+	stringType = &goType{name: "string", read: "cbor.ReadString", write: "cbor.WriteString"}
+	bytesType  = &goType{name: "[]byte", read: "cbor.ReadByteString", write: "cbor.WriteBytes", nillable: true}
+	boolType   = &goType{name: "bool", read: "cbor.ReadBool", write: "cbor.WriteBool"}
+	rawType    = &goType{name: "cbor.RawMessage", read: "cbor.ReadRawMessage", write: "cbor.WriteRawMessage", nillable: true}
+)
+
+// preludeTypes maps the prelude types that have a Go equivalent.
+var preludeTypes = map[string]*goType{
+	"uint":           uintType,
+	"nint":           intType,
+	"int":            intType,
+	"tstr":           stringType,
+	"text":           stringType,
+	"bstr":           bytesType,
+	"bytes":          bytesType,
+	"bool":           boolType,
+	"float16":        floatType,
+	"float32":        floatType,
+	"float64":        floatType,
+	"float16-and-32": floatType,
+	"float32-and-64": floatType,
+	"float":          floatType,
+	"number":         floatType,
+}
+
+// unsupported is returned while generating a type that is kept encoded
+// instead.
+type unsupported struct {
+	reason string
+}
+
+func (e *unsupported) Error() string {
+	return "gen: " + e.reason
+}
+
+type generator struct {
+	schema *cddl.Schema
+
+	types  map[string]*goType // by rule name, nil while in progress
+	used   map[string]bool    // Go identifiers declared
+	chunks []*bytes.Buffer    // declarations, in order
+}
+
+// Generate returns Go source for the type rules of [s]. Group rules are
+// inlined where they are used.
+func Generate(s *cddl.Schema, opts Options) ([]byte, error) {
+	g := &generator{
+		schema: s,
+		types:  make(map[string]*goType),
+		used:   make(map[string]bool),
+	}
+
+	for _, r := range s.Rules {
+		if r.Type() == nil {
+			continue
+		}
+		if v, ok := g.constant(r); ok {
+			name := g.constIdent(exported(r.Name))
+			g.chunk().WriteString("const " + name + " = " + literal(v) + "\n")
+			continue
+		}
+		if _, err := g.ruleType(r); err != nil {
+			return nil, err
+		}
+	}
+
+	var b bytes.Buffer
+	b.WriteString("// Code generated by cddlgen")
+	if opts.Source != "" {
+		b.WriteString(" from " + opts.Source)
+	}
+	b.WriteString(". DO NOT EDIT.\n\n")
+	b.WriteString("package " + opts.Package + "\n\n")
+	b.WriteString("import (\n\t\"io\"\n\n\tcbor \"github.com/alex-richards/tiny-cbor\"\n)\n")
+	for _, c := range g.chunks {
+		b.WriteString("\n")
+		b.Write(c.Bytes())
+	}
+
+	return format.Source(b.Bytes())
+}
+
+// chunk reserves a place for a declaration.
+func (g *generator) chunk() *bytes.Buffer {
+	c := &bytes.Buffer{}
+	g.chunks = append(g.chunks, c)
+	return c
+}
+
+// ident returns an unused Go identifier based on [name], reserving it along
+// with its Read and Write functions.
+func (g *generator) ident(name string) string {
+	base := exported(name)
+	ident := base
+	for i := 2; g.used[ident] || g.used["Read"+ident] || g.used["Write"+ident]; i++ {
+		ident = base + strconv.Itoa(i)
+	}
+	g.used[ident] = true
+	g.used["Read"+ident] = true
+	g.used["Write"+ident] = true
+	return ident
+}
+
+// initialisms are written in upper case within identifiers.
+var initialisms = map[string]bool{
+	"cbor": true, "cose": true, "cwt": true, "http": true, "id": true, "ip": true,
+	"json": true, "uri": true, "url": true, "uuid": true,
+}
+
+// exported converts a CDDL name or text key to an exported Go identifier.
+func exported(name string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if initialisms[strings.ToLower(part)] {
+			b.WriteString(strings.ToUpper(part))
+			continue
+		}
+		runes := []rune(part)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+
+	s := b.String()
+	if s == "" || !unicode.IsLetter([]rune(s)[0]) {
+		s = "X" + s
+	}
+	return s
+}
+
+// ruleType returns the type for a rule, declaring it with Read and Write
+// functions the first time.
+func (g *generator) ruleType(r *cddl.Rule) (*goType, error) {
+	if t, ok := g.types[r.Name]; ok {
+		if t == nil {
+			return nil, &unsupported{reason: "rule " + r.Name + " refers to itself"}
+		}
+		return t, nil
+	}
+
+	t := r.Type()
+	if t == nil {
+		return nil, &unsupported{reason: "group " + r.Name + " used as a type"}
+	}
+
+	name := g.ident(r.Name)
+	c := g.chunk()
+
+	// placeholder for references from within the rule
+	gt := &goType{name: name, read: "Read" + name, write: "Write" + name, nillable: g.nillable(t)}
+	g.types[r.Name] = gt
+
+	inner, err := g.declare(c, name, t, r.Name)
+	var u *unsupported
+	if errors.As(err, &u) {
+		c.Reset()
+		inner = rawType
+		err = g.declareAlias(c, name, rawType, "is kept encoded, as "+u.reason)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	gt.nillable = inner.nillable
+	return gt, nil
+}
+
+// nillable guesses whether the type for [t] will be nillable, for references
+// made while it is generated.
+func (g *generator) nillable(t *cddl.Type) bool {
+	if _, ok := g.nullable(t); ok {
+		return true
+	}
+	if len(t.Choices) != 1 {
+		return false
+	}
+	switch t2 := t.Choices[0].Type2; t2.Kind {
+	case cddl.Type2Map:
+		return isTable(t2.Group)
+	case cddl.Type2Array:
+		return isList(t2.Group)
+	}
+	return false
+}
+
+// declare writes the declaration of the named type [name] for the rule
+// [rule] of type [t] to [c], returning the underlying type.
+func (g *generator) declare(c *bytes.Buffer, name string, t *cddl.Type, rule string) (*goType, error) {
+	if values, ok := g.enumValues(t); ok {
+		return g.declareEnum(c, name, values)
+	}
+
+	if len(t.Choices) == 1 && t.Choices[0].Op == "" {
+		switch t2 := t.Choices[0].Type2; t2.Kind {
+		case cddl.Type2Map:
+			if isTable(t2.Group) {
+				gt, err := g.tableType(t2.Group, name)
+				if err != nil {
+					return nil, err
+				}
+				return g.declareDefined(c, name, gt)
+			}
+			return g.declareMapStruct(c, name, t2.Group)
+		case cddl.Type2Array:
+			if isList(t2.Group) {
+				gt, err := g.listType(t2.Group, name)
+				if err != nil {
+					return nil, err
+				}
+				return g.declareDefined(c, name, gt)
+			}
+			return g.declareArrayStruct(c, name, t2.Group)
+		}
+	}
+
+	// an alias may not refer to itself
+	placeholder := g.types[rule]
+	g.types[rule] = nil
+	gt, err := g.typeOf(t, name)
+	g.types[rule] = placeholder
+	if err != nil {
+		return nil, err
+	}
+	return gt, g.declareAlias(c, name, gt, "")
+}
+
+// typeOf returns the type for [t], declaring any new named types based on
+// [hint].
+func (g *generator) typeOf(t *cddl.Type, hint string) (*goType, error) {
+	if inner, ok := g.nullable(t); ok {
+		it, err := g.typeOf(inner, hint)
+		if err != nil {
+			return nil, err
+		}
+		return &goType{
+			name:      "*" + it.name,
+			read:      "func(in io.Reader) (*" + it.name + ", error) { return cbor.ReadNullable(in, " + it.read + ") }",
+			write:     "func(out io.Writer, v *" + it.name + ") (int, error) { return cbor.WriteNullable(out, v, " + it.write + ") }",
+			readCall:  "cbor.ReadNullable($in, " + it.read + ")",
+			writeCall: "cbor.WriteNullable($out, $v, " + it.write + ")",
+			nillable:  true,
+		}, nil
+	}
+
+	if values, ok := g.enumValues(t); ok {
+		name := g.ident(hint)
+		gt := &goType{name: name, read: "Read" + name, write: "Write" + name}
+		if _, err := g.declareEnum(g.chunk(), name, values); err != nil {
+			return nil, err
+		}
+		return gt, nil
+	}
+
+	if len(t.Choices) != 1 {
+		return nil, &unsupported{reason: "choices between " + t.String() + " are not generated"}
+	}
+
+	t1 := t.Choices[0]
+	if t1.Op == cddl.RangeInclusive || t1.Op == cddl.RangeExclusive {
+		lo, _ := g.value(t1.Type2)
+		switch lo.Kind {
+		case cddl.ValueFloat:
+			return floatType, nil
+		case cddl.ValueNint:
+			return intType, nil
+		default:
+			return uintType, nil
+		}
+	}
+
+	// control operators constrain the type without changing it
+	t2 := t1.Type2
+	switch t2.Kind {
+	case cddl.Type2Value:
+		return valueType(t2.Value)
+
+	case cddl.Type2Name:
+		if v, ok := g.value(t2); ok {
+			return valueType(v)
+		}
+		r := g.schema.Rule(t2.Name)
+		if gt, ok := preludeTypes[t2.Name]; ok && g.isPrelude(r) {
+			return gt, nil
+		}
+		if g.isPrelude(r) {
+			return rawType, nil
+		}
+		return g.ruleType(r)
+
+	case cddl.Type2Paren:
+		return g.typeOf(t2.Type, hint)
+
+	case cddl.Type2Map:
+		if isTable(t2.Group) {
+			return g.tableType(t2.Group, hint)
+		}
+		return g.namedStruct(hint, t2.Group, g.declareMapStruct)
+
+	case cddl.Type2Array:
+		if isList(t2.Group) {
+			return g.listType(t2.Group, hint)
+		}
+		return g.namedStruct(hint, t2.Group, g.declareArrayStruct)
+	}
+
+	return rawType, nil
+}
+
+// constant returns the value of a rule that is a single integer or text
+// literal, which is declared as a constant.
+func (g *generator) constant(r *cddl.Rule) (cddl.Value, bool) {
+	t := r.Type()
+	if len(t.Choices) != 1 || t.Choices[0].Op != "" || t.Choices[0].Type2.Kind != cddl.Type2Value {
+		return cddl.Value{}, false
+	}
+	switch v := t.Choices[0].Type2.Value; v.Kind {
+	case cddl.ValueText:
+		return v, true
+	case cddl.ValueUint, cddl.ValueNint:
+		return v, v.Int <= math.MaxInt64
+	}
+	return cddl.Value{}, false
+}
+
+// isPrelude reports whether [r] is from the prelude rather than the schema.
+func (g *generator) isPrelude(r *cddl.Rule) bool {
+	return !slices.Contains(g.schema.Rules, r)
+}
+
+func (g *generator) namedStruct(
+	hint string,
+	group *cddl.Group,
+	declare func(*bytes.Buffer, string, *cddl.Group) (*goType, error),
+) (*goType, error) {
+	name := g.ident(hint)
+	c := g.chunk()
+	if _, err := declare(c, name, group); err != nil {
+		return nil, err
+	}
+	return &goType{name: name, read: "Read" + name, write: "Write" + name}, nil
+}
+
+func valueType(v cddl.Value) (*goType, error) {
+	switch v.Kind {
+	case cddl.ValueUint:
+		return uintType, nil
+	case cddl.ValueNint:
+		return intType, nil
+	case cddl.ValueFloat:
+		return floatType, nil
+	case cddl.ValueText:
+		return stringType, nil
+	default:
+		return bytesType, nil
+	}
+}
+
+// nullable returns [t] without null if it is a choice including null.
+func (g *generator) nullable(t *cddl.Type) (*cddl.Type, bool) {
+	var rest []*cddl.Type1
+	for _, t1 := range t.Choices {
+		if t1.Op == "" && t1.Type2.Kind == cddl.Type2Name && (t1.Type2.Name == "null" || t1.Type2.Name == "nil") {
+			continue
+		}
+		rest = append(rest, t1)
+	}
+	if len(rest) == len(t.Choices) || len(rest) == 0 {
+		return nil, false
+	}
+	return &cddl.Type{Choices: rest}, true
+}
+
+// value returns the literal value of [t], following rules holding a single
+// value.
+func (g *generator) value(t *cddl.Type2) (cddl.Value, bool) {
+	for range 64 {
+		switch t.Kind {
+		case cddl.Type2Value:
+			return t.Value, true
+		case cddl.Type2Name:
+			r := g.schema.Rule(t.Name)
+			rt := r.Type()
+			if rt == nil || len(rt.Choices) != 1 || rt.Choices[0].Op != "" {
+				return cddl.Value{}, false
+			}
+			t = rt.Choices[0].Type2
+		case cddl.Type2Paren:
+			if len(t.Type.Choices) != 1 || t.Type.Choices[0].Op != "" {
+				return cddl.Value{}, false
+			}
+			t = t.Type.Choices[0].Type2
+		default:
+			return cddl.Value{}, false
+		}
+	}
+	return cddl.Value{}, false
+}
+
+type enumValue struct {
+	name  string // CDDL name, if any
+	value cddl.Value
+}
+
+// enumValues returns the values of a choice between two or more literals of
+// the same kind, integer or text.
+func (g *generator) enumValues(t *cddl.Type) ([]enumValue, bool) {
+	var values []enumValue
+	for _, t1 := range t.Choices {
+		if t1.Op != "" {
+			return nil, false
+		}
+
+		t2 := t1.Type2
+		if t2.Kind == cddl.Type2Enum {
+			group := t2.Group
+			if group == nil {
+				group = g.schema.Rule(t2.Name).Group
+			}
+			vs, ok := g.groupValues(group)
+			if !ok {
+				return nil, false
+			}
+			values = append(values, vs...)
+			continue
+		}
+
+		v, ok := g.value(t2)
+		if !ok {
+			return nil, false
+		}
+		name := ""
+		if t2.Kind == cddl.Type2Name {
+			name = t2.Name
+		}
+		values = append(values, enumValue{name: name, value: v})
+	}
+
+	if len(values) < 2 {
+		return nil, false
+	}
+	text := values[0].value.Kind == cddl.ValueText
+	for _, v := range values {
+		switch v.value.Kind {
+		case cddl.ValueText:
+			if !text {
+				return nil, false
+			}
+		case cddl.ValueUint, cddl.ValueNint:
+			if text || (v.value.Kind == cddl.ValueUint && v.value.Int > math.MaxInt64) ||
+				(v.value.Kind == cddl.ValueNint && v.value.Int > math.MaxInt64) {
+				return nil, false
+			}
+		default:
+			return nil, false
+		}
+	}
+	return values, true
+}
+
+// groupValues returns the values of the entries of an enumerated group, named
+// by their keys.
+func (g *generator) groupValues(group *cddl.Group) ([]enumValue, bool) {
+	if len(group.Choices) == 1 && len(group.Choices[0]) == 1 && group.Choices[0][0].Group != nil {
+		group = group.Choices[0][0].Group
+	}
+	if len(group.Choices) != 1 {
+		return nil, false
+	}
+
+	var values []enumValue
+	for _, e := range group.Choices[0] {
+		if e.Type == nil || len(e.Type.Choices) != 1 || e.Type.Choices[0].Op != "" {
+			return nil, false
+		}
+		v, ok := g.value(e.Type.Choices[0].Type2)
+		if !ok {
+			return nil, false
+		}
+		name := ""
+		if e.Key != nil && e.Key.Bareword {
+			name = e.Key.Type.Choices[0].Type2.Value.Text
+		}
+		values = append(values, enumValue{name: name, value: v})
+	}
+	return values, true
+}
+
+func (g *generator) declareEnum(c *bytes.Buffer, name string, values []enumValue) (*goType, error) {
+	base := intType
+	if values[0].value.Kind == cddl.ValueText {
+		base = stringType
+	}
+
+	c.WriteString("type " + name + " " + base.name + "\n\nconst (\n")
+	var consts []string
+	for _, v := range values {
+		constName := v.name
+		switch {
+		case constName != "":
+		case v.value.Kind == cddl.ValueText:
+			constName = v.value.Text
+		case v.value.Kind == cddl.ValueNint:
+			constName = "minus-" + strconv.FormatUint(v.value.Int+1, 10)
+		default:
+			constName = strconv.FormatUint(v.value.Int, 10)
+		}
+		constName = g.constIdent(exported(name + "-" + constName))
+		consts = append(consts, constName)
+		c.WriteString("\t" + constName + " " + name + " = " + literal(v.value) + "\n")
+	}
+	c.WriteString(")\n\n")
+
+	c.WriteString("// Read" + name + " reads the next " + name + " from [in].\n")
+	c.WriteString("func Read" + name + "(in io.Reader) (" + name + ", error) {\n")
+	c.WriteString("\tv, err := " + base.read + "(in)\n")
+	c.WriteString("\tif err != nil {\n\t\treturn " + zero(base) + ", err\n\t}\n\n")
+	c.WriteString("\tswitch e := " + name + "(v); e {\n")
+	c.WriteString("\tcase " + strings.Join(consts, ", ") + ":\n\t\treturn e, nil\n\t}\n\n")
+	c.WriteString("\treturn " + zero(base) + ", cbor.ErrUnsupportedValue\n}\n\n")
+
+	c.WriteString("// Write" + name + " writes [v].\n")
+	c.WriteString("func Write" + name + "(out io.Writer, v " + name + ") (int, error) {\n")
+	c.WriteString("\treturn " + base.write + "(out, " + base.name + "(v))\n}\n")
+
+	return &goType{name: name, read: "Read" + name, write: "Write" + name}, nil
+}
+
+// constIdent returns an unused identifier for a constant.
+func (g *generator) constIdent(base string) string {
+	ident := base
+	for i := 2; g.used[ident]; i++ {
+		ident = base + strconv.Itoa(i)
+	}
+	g.used[ident] = true
+	return ident
+}
+
+func zero(t *goType) string {
+	if t == stringType {
+		return `""`
+	}
+	return "0"
+}
+
+// literal returns the Go literal for a value.
+func literal(v cddl.Value) string {
+	switch v.Kind {
+	case cddl.ValueUint:
+		return strconv.FormatUint(v.Int, 10)
+	case cddl.ValueNint:
+		return "-" + strconv.FormatUint(v.Int+1, 10)
+	default:
+		return strconv.Quote(v.Text)
+	}
+}
+
+// declareDefined declares [name] as a slice or map type [t], which may refer
+// to itself.
+func (g *generator) declareDefined(c *bytes.Buffer, name string, t *goType) (*goType, error) {
+	c.WriteString("type " + name + " " + t.name + "\n\n")
+
+	c.WriteString("// Read" + name + " reads the next " + name + " from [in].\n")
+	c.WriteString("func Read" + name + "(in io.Reader) (" + name + ", error) {\n")
+	c.WriteString("\treturn " + t.callRead("in") + "\n}\n\n")
+
+	c.WriteString("// Write" + name + " writes [v].\n")
+	c.WriteString("func Write" + name + "(out io.Writer, v " + name + ") (int, error) {\n")
+	c.WriteString("\treturn " + t.callWrite("out", "v") + "\n}\n")
+
+	return t, nil
+}
+
+func (g *generator) declareAlias(c *bytes.Buffer, name string, t *goType, doc string) error {
+	if doc != "" {
+		c.WriteString("// " + name + " " + doc + ".\n")
+	}
+	c.WriteString("type " + name + " = " + t.name + "\n\n")
+
+	c.WriteString("// Read" + name + " reads the next " + name + " from [in].\n")
+	c.WriteString("func Read" + name + "(in io.Reader) (" + name + ", error) {\n")
+	c.WriteString("\treturn " + t.callRead("in") + "\n}\n\n")
+
+	c.WriteString("// Write" + name + " writes [v].\n")
+	c.WriteString("func Write" + name + "(out io.Writer, v " + name + ") (int, error) {\n")
+	c.WriteString("\treturn " + t.callWrite("out", "v") + "\n}\n")
+	return nil
+}
+
+// isList reports whether an array group is a single repeated entry.
+func isList(group *cddl.Group) bool {
+	if len(group.Choices) != 1 || len(group.Choices[0]) != 1 {
+		return false
+	}
+	e := group.Choices[0][0]
+	return e.Type != nil && e.Occur.Max > 1
+}
+
+// isTable reports whether a map group is a single repeated entry with a key
+// type rather than a literal key.
+func isTable(group *cddl.Group) bool {
+	if len(group.Choices) != 1 || len(group.Choices[0]) != 1 {
+		return false
+	}
+	e := group.Choices[0][0]
+	return e.Key != nil && e.Type != nil && e.Occur.Max > 1
+}
+
+func (g *generator) listType(group *cddl.Group, hint string) (*goType, error) {
+	item, err := g.typeOf(group.Choices[0][0].Type, hint+"-item")
+	if err != nil {
+		return nil, err
+	}
+
+	name := "[]" + item.name
+	return &goType{
+		name:      name,
+		read:      "func(in io.Reader) (" + name + ", error) { return cbor.ReadSlice(in, " + item.read + ") }",
+		write:     "func(out io.Writer, v " + name + ") (int, error) { return cbor.WriteSlice(out, v, " + item.write + ") }",
+		readCall:  "cbor.ReadSlice($in, " + item.read + ")",
+		writeCall: "cbor.WriteSlice($out, $v, " + item.write + ")",
+		nillable:  true,
+	}, nil
+}
+
+func (g *generator) tableType(group *cddl.Group, hint string) (*goType, error) {
+	e := group.Choices[0][0]
+	key, err := g.typeOf(e.Key.Type, hint+"-key")
+	if err != nil {
+		return nil, err
+	}
+	if key != stringType && key != intType && key != uintType {
+		return nil, &unsupported{reason: "map keys of " + e.Key.Type.String() + " are not generated"}
+	}
+	value, err := g.typeOf(e.Type, hint+"-value")
+	if err != nil {
+		return nil, err
+	}
+
+	name := "map[" + key.name + "]" + value.name
+	return &goType{
+		name:      name,
+		read:      "func(in io.Reader) (" + name + ", error) { return cbor.ReadMapOf(in, " + key.read + ", " + value.read + ") }",
+		write:     "func(out io.Writer, v " + name + ") (int, error) { return cbor.WriteMapOfSorted(out, v, " + key.write + ", " + value.write + ") }",
+		readCall:  "cbor.ReadMapOf($in, " + key.read + ", " + value.read + ")",
+		writeCall: "cbor.WriteMapOfSorted($out, $v, " + key.write + ", " + value.write + ")",
+		nillable:  true,
+	}, nil
+}
+
+// member is a field of a generated struct.
+type member struct {
+	field    string
+	key      cddl.Value // map key, for map structs
+	typ      *goType
+	optional bool
+}
+
+// fieldType returns the Go type of the field, a pointer if it is optional and
+// nil does not already mean absent.
+func (m *member) fieldType() string {
+	if m.pointer() {
+		return "*" + m.typ.name
+	}
+	return m.typ.name
+}
+
+func (m *member) pointer() bool {
+	return m.optional && !m.typ.nillable
+}
+
+// flatEntry is an entry of a group, with nested groups inlined.
+type flatEntry struct {
+	entry    *cddl.Entry
+	optional bool
+}
+
+// flatten appends the entries of [group] to [out], inlining nested groups and
+// references to group rules that appear at most once.
+func (g *generator) flatten(group *cddl.Group, optional bool, depth int, out []flatEntry) ([]flatEntry, error) {
+	if depth > 64 {
+		return nil, &unsupported{reason: "groups nest too deeply"}
+	}
+	if len(group.Choices) != 1 {
+		return nil, &unsupported{reason: "group choices are not generated"}
+	}
+
+	for _, e := range group.Choices[0] {
+		inner := e.Group
+		if inner == nil && e.Key == nil && len(e.Type.Choices) == 1 && e.Type.Choices[0].Op == "" {
+			if t2 := e.Type.Choices[0].Type2; t2.Kind == cddl.Type2Name {
+				if r := g.schema.Rule(t2.Name); r != nil && r.Type() == nil {
+					inner = r.Group
+				}
+			}
+		}
+
+		if inner == nil {
+			out = append(out, flatEntry{entry: e, optional: optional || e.Occur.Min == 0})
+			continue
+		}
+
+		if e.Occur != (cddl.Occurrence{Min: 1, Max: 1}) && e.Occur != (cddl.Occurrence{Min: 0, Max: 1}) {
+			return nil, &unsupported{reason: "repeated groups are not generated"}
+		}
+		var err error
+		out, err = g.flatten(inner, optional || e.Occur.Min == 0, depth+1, out)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// fieldName returns an unused field name based on [name].
+func fieldName(used map[string]bool, name string) string {
+	base := exported(name)
+	field := base
+	for i := 2; used[field]; i++ {
+		field = base + strconv.Itoa(i)
+	}
+	used[field] = true
+	return field
+}
+
+// keyValue returns the literal key of a map entry.
+func (g *generator) keyValue(k *cddl.MemberKey) (cddl.Value, string, bool) {
+	if len(k.Type.Choices) != 1 || k.Type.Choices[0].Op != "" {
+		return cddl.Value{}, "", false
+	}
+	t2 := k.Type.Choices[0].Type2
+	v, ok := g.value(t2)
+	if !ok || (v.Kind != cddl.ValueText && v.Kind != cddl.ValueUint && v.Kind != cddl.ValueNint) {
+		return cddl.Value{}, "", false
+	}
+	if v.Kind != cddl.ValueText && v.Int > math.MaxInt64 {
+		return cddl.Value{}, "", false
+	}
+
+	switch {
+	case t2.Kind == cddl.Type2Name:
+		return v, t2.Name, true
+	case v.Kind == cddl.ValueText:
+		return v, v.Text, true
+	case v.Kind == cddl.ValueNint:
+		return v, "key-minus-" + strconv.FormatUint(v.Int+1, 10), true
+	default:
+		return v, "key-" + strconv.FormatUint(v.Int, 10), true
+	}
+}
+
+// encodedKey returns the encoding of a map key, to order keys when writing.
+func encodedKey(v cddl.Value) []byte {
+	var b bytes.Buffer
+	switch v.Kind {
+	case cddl.ValueText:
+		_, _ = cbor.WriteString(&b, v.Text)
+	case cddl.ValueNint:
+		_, _ = cbor.WriteSigned(&b, -1-int64(v.Int))
+	default:
+		_, _ = cbor.WriteUnsigned(&b, v.Int)
+	}
+	return b.Bytes()
+}
+
+func (g *generator) declareMapStruct(c *bytes.Buffer, name string, group *cddl.Group) (*goType, error) {
+	entries, err := g.flatten(group, false, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	used := make(map[string]bool)
+	var members []*member
+	text, ints := false, false
+	for _, fe := range entries {
+		e := fe.entry
+		if e.Key == nil {
+			return nil, &unsupported{reason: "map entries without keys are not generated"}
+		}
+
+		key, keyName, ok := g.keyValue(e.Key)
+		if !ok {
+			if fe.optional && e.Occur.Max > 1 {
+				continue // other keys are skipped
+			}
+			return nil, &unsupported{reason: "map keys of " + e.Key.Type.String() + " are not generated"}
+		}
+		if e.Occur.Max > 1 {
+			return nil, &unsupported{reason: "repeated map keys are not generated"}
+		}
+		if key.Kind == cddl.ValueText {
+			text = true
+		} else {
+			ints = true
+		}
+
+		field := fieldName(used, keyName)
+		typ, err := g.typeOf(e.Type, name+"-"+field)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, &member{field: field, key: key, typ: typ, optional: fe.optional})
+	}
+	if text && ints {
+		return nil, &unsupported{reason: "maps with both text and integer keys are not generated"}
+	}
+	if len(members) > 64 {
+		return nil, &unsupported{reason: "maps with more than 64 members are not generated"}
+	}
+
+	declareStruct(c, name, members)
+
+	c.WriteString("// Read" + name + " reads the next " + name + " from [in], skipping unknown keys.\n")
+	c.WriteString("func Read" + name + "(in io.Reader) (" + name + ", error) {\n")
+	c.WriteString("\tvar v " + name + "\n")
+	var required uint64
+	for i, m := range members {
+		if !m.optional {
+			required |= 1 << i
+		}
+	}
+	if required != 0 {
+		c.WriteString("\tvar seen uint64\n")
+	}
+	c.WriteString("\terr := cbor.ReadMap(\n\t\tin,\n")
+	c.WriteString("\t\tfunc(bool, uint64) error { return nil },\n")
+	c.WriteString("\t\tfunc(in io.Reader) error {\n")
+	c.WriteString("\t\t\td := cbor.NewDecoder(in)\n")
+	c.WriteString("\t\t\tmajorType, _, _, err := d.PeekType()\n")
+	c.WriteString("\t\t\tif err != nil {\n\t\t\t\treturn err\n\t\t\t}\n")
+	keyRead := "cbor.ReadString(d)"
+	if ints {
+		c.WriteString("\t\t\tif majorType != cbor.MajorTypeUInt && majorType != cbor.MajorTypeNInt {\n")
+		keyRead = "cbor.ReadSigned[int64](d)"
+	} else {
+		c.WriteString("\t\t\tif majorType != cbor.MajorTypeTstr {\n")
+	}
+	c.WriteString("\t\t\t\tif err := cbor.ReadOver(d); err != nil {\n\t\t\t\t\treturn err\n\t\t\t\t}\n")
+	c.WriteString("\t\t\t\treturn cbor.ReadOver(d)\n\t\t\t}\n\n")
+	c.WriteString("\t\t\tkey, err := " + keyRead + "\n")
+	c.WriteString("\t\t\tif err != nil {\n\t\t\t\treturn err\n\t\t\t}\n\n")
+	c.WriteString("\t\t\tswitch key {\n")
+	for i, m := range members {
+		c.WriteString("\t\t\tcase " + literal(m.key) + ":\n")
+		readField(c, "\t\t\t\t", m, "d")
+		if !m.optional {
+			c.WriteString("\t\t\t\tseen |= 1 << " + strconv.Itoa(i) + "\n")
+		}
+	}
+	c.WriteString("\t\t\tdefault:\n\t\t\t\terr = cbor.ReadOver(d)\n\t\t\t}\n")
+	c.WriteString("\t\t\treturn err\n\t\t},\n\t)\n")
+	c.WriteString("\tif err != nil {\n\t\treturn " + name + "{}, err\n\t}\n")
+	if required != 0 {
+		c.WriteString("\tif seen != " + "0x" + strconv.FormatUint(required, 16) + " {\n")
+		c.WriteString("\t\treturn " + name + "{}, cbor.ErrMissingMember\n\t}\n")
+	}
+	c.WriteString("\n\treturn v, nil\n}\n\n")
+
+	// members are written in the order of their encoded keys
+	ordered := slices.Clone(members)
+	slices.SortStableFunc(ordered, func(a, b *member) int {
+		return bytes.Compare(encodedKey(a.key), encodedKey(b.key))
+	})
+
+	c.WriteString("// Write" + name + " writes [v], omitting absent optional members.\n")
+	c.WriteString("func Write" + name + "(out io.Writer, v " + name + ") (int, error) {\n")
+	count := 0
+	for _, m := range members {
+		if !m.optional {
+			count++
+		}
+	}
+	c.WriteString("\tlength := uint64(" + strconv.Itoa(count) + ")\n")
+	for _, m := range members {
+		if m.optional {
+			c.WriteString("\tif v." + m.field + " != nil {\n\t\tlength++\n\t}\n")
+		}
+	}
+	c.WriteString("\n\tn, err := cbor.WriteMapHeader(out, length)\n")
+	c.WriteString("\tif err != nil {\n\t\treturn n, err\n\t}\n\n")
+	c.WriteString("\tvar nn int\n")
+	for _, m := range ordered {
+		indent := "\t"
+		if m.optional {
+			c.WriteString("\tif v." + m.field + " != nil {\n")
+			indent = "\t\t"
+		}
+		var keyWrite string
+		switch m.key.Kind {
+		case cddl.ValueText:
+			keyWrite = "cbor.WriteString(out, " + literal(m.key) + ")"
+		case cddl.ValueNint:
+			keyWrite = "cbor.WriteSigned(out, int64(" + literal(m.key) + "))"
+		default:
+			keyWrite = "cbor.WriteUnsigned(out, uint64(" + literal(m.key) + "))"
+		}
+		writeStep(c, indent, keyWrite)
+		writeField(c, indent, m)
+		if m.optional {
+			c.WriteString("\t}\n")
+		}
+	}
+	c.WriteString("\n\treturn n, nil\n}\n")
+
+	return &goType{name: name, read: "Read" + name, write: "Write" + name}, nil
+}
+
+func (g *generator) declareArrayStruct(c *bytes.Buffer, name string, group *cddl.Group) (*goType, error) {
+	entries, err := g.flatten(group, false, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	used := make(map[string]bool)
+	var members []*member
+	required := 0
+	for i, fe := range entries {
+		e := fe.entry
+		if e.Occur.Max > 1 {
+			return nil, &unsupported{reason: "repeated array entries are not generated"}
+		}
+		if fe.optional {
+			if i < len(entries)-1 && !entries[i+1].optional {
+				return nil, &unsupported{reason: "optional array entries are only generated at the end"}
+			}
+		} else {
+			required++
+		}
+
+		fieldBase := "field-" + strconv.Itoa(i)
+		if e.Key != nil {
+			if _, keyName, ok := g.keyValue(e.Key); ok {
+				fieldBase = keyName
+			}
+		}
+		field := fieldName(used, fieldBase)
+		typ, err := g.typeOf(e.Type, name+"-"+field)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, &member{field: field, typ: typ, optional: fe.optional})
+	}
+
+	declareStruct(c, name, members)
+
+	c.WriteString("// Read" + name + " reads the next " + name + " from [in].\n")
+	c.WriteString("func Read" + name + "(in io.Reader) (" + name + ", error) {\n")
+	c.WriteString("\tvar v " + name + "\n")
+	c.WriteString("\ti := 0\n")
+	c.WriteString("\terr := cbor.ReadArray(\n\t\tin,\n")
+	c.WriteString("\t\tfunc(bool, uint64) error { return nil },\n")
+	c.WriteString("\t\tfunc(in io.Reader) error {\n")
+	c.WriteString("\t\t\tvar err error\n")
+	c.WriteString("\t\t\tswitch i {\n")
+	for i, m := range members {
+		c.WriteString("\t\t\tcase " + strconv.Itoa(i) + ":\n")
+		readField(c, "\t\t\t\t", m, "in")
+	}
+	c.WriteString("\t\t\tdefault:\n\t\t\t\terr = cbor.ErrUnsupportedValue\n\t\t\t}\n")
+	c.WriteString("\t\t\ti++\n\t\t\treturn err\n\t\t},\n\t)\n")
+	c.WriteString("\tif err != nil {\n\t\treturn " + name + "{}, err\n\t}\n")
+	if required > 0 {
+		c.WriteString("\tif i < " + strconv.Itoa(required) + " {\n")
+		c.WriteString("\t\treturn " + name + "{}, cbor.ErrMissingMember\n\t}\n")
+	}
+	c.WriteString("\n\treturn v, nil\n}\n\n")
+
+	c.WriteString("// Write" + name + " writes [v], up to the last present optional member.\n")
+	c.WriteString("func Write" + name + "(out io.Writer, v " + name + ") (int, error) {\n")
+	c.WriteString("\tlength := uint64(" + strconv.Itoa(required) + ")\n")
+	for i, m := range members {
+		if m.optional {
+			c.WriteString("\tif v." + m.field + " != nil {\n\t\tlength = " + strconv.Itoa(i+1) + "\n\t}\n")
+		}
+	}
+	c.WriteString("\n\tn, err := cbor.WriteArrayHeader(out, length)\n")
+	c.WriteString("\tif err != nil {\n\t\treturn n, err\n\t}\n\n")
+	c.WriteString("\tvar nn int\n")
+	for i, m := range members {
+		indent := "\t"
+		if m.optional {
+			c.WriteString("\tif length > " + strconv.Itoa(i) + " {\n")
+			c.WriteString("\t\tif v." + m.field + " == nil {\n\t\t\treturn n, cbor.ErrMissingMember\n\t\t}\n")
+			indent = "\t\t"
+		}
+		writeField(c, indent, m)
+		if m.optional {
+			c.WriteString("\t}\n")
+		}
+	}
+	c.WriteString("\n\treturn n, nil\n}\n")
+
+	return &goType{name: name, read: "Read" + name, write: "Write" + name}, nil
+}
+
+func declareStruct(c *bytes.Buffer, name string, members []*member) {
+	c.WriteString("type " + name + " struct {\n")
+	for _, m := range members {
+		c.WriteString("\t" + m.field + " " + m.fieldType() + "\n")
+	}
+	c.WriteString("}\n\n")
+}
+
+// readField writes the statements reading a member from [in] into err.
+func readField(c *bytes.Buffer, indent string, m *member, in string) {
+	if !m.pointer() {
+		c.WriteString(indent + "v." + m.field + ", err = " + m.typ.callRead(in) + "\n")
+		return
+	}
+	c.WriteString(indent + "var x " + m.typ.name + "\n")
+	c.WriteString(indent + "x, err = " + m.typ.callRead(in) + "\n")
+	c.WriteString(indent + "v." + m.field + " = &x\n")
+}
+
+// writeField writes the statements writing a member.
+func writeField(c *bytes.Buffer, indent string, m *member) {
+	value := "v." + m.field
+	if m.pointer() {
+		value = "*" + value
+	}
+	writeStep(c, indent, m.typ.callWrite("out", value))
+}
+
+// writeStep writes the statements for a call returning (int, error), adding
+// to n.
+func writeStep(c *bytes.Buffer, indent, expr string) {
+	c.WriteString(indent + "nn, err = " + expr + "\n")
+	c.WriteString(indent + "n += nn\n")
+	c.WriteString(indent + "if err != nil {\n" + indent + "\treturn n, err\n" + indent + "}\n")
+}
@@ -0,0 +1,180 @@
+package gen
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/alex-richards/tiny-cbor/cddl"
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_Generate_Example(t *testing.T) {
+	src, err := os.ReadFile("internal/example/example.cddl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile("internal/example/example.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := cddl.Parse(string(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Generate(s, Options{Package: "example", Source: "example.cddl"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(string(want), string(got)); diff != "" {
+		t.Fatalf("run go generate in internal/example\n%s", diff)
+	}
+}
+
+func Test_Generate(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		want   []string
+	}{
+		{
+			name:   "Alias",
+			schema: "id = uint",
+			want:   []string{"type ID = uint64", "return cbor.ReadUnsigned[uint64](in)"},
+		},
+		{
+			name:   "Constant",
+			schema: `a = "x" b = -2`,
+			want:   []string{`const A = "x"`, "const B = -2"},
+		},
+		{
+			name:   "EnumNamedValues",
+			schema: "alg = es256 / eddsa es256 = -7 eddsa = -8",
+			want:   []string{"AlgEs256 Alg = -7", "AlgEddsa Alg = -8", "const Es256 = -7"},
+		},
+		{
+			name:   "EnumUnnamedValues",
+			schema: "code = 1 / -1",
+			want:   []string{"Code1      Code = 1", "CodeMinus1 Code = -1"},
+		},
+		{
+			name:   "Nullable",
+			schema: "a = tstr / null",
+			want:   []string{"type A = *string", "cbor.ReadNullable(in, cbor.ReadString)"},
+		},
+		{
+			name:   "List",
+			schema: "a = [* a]",
+			want:   []string{"type A []A", "cbor.ReadSlice(in, ReadA)"},
+		},
+		{
+			name:   "Table",
+			schema: "a = { * int => bool }",
+			want:   []string{"type A map[int64]bool", "cbor.WriteMapOfSorted(out, v, cbor.WriteSigned[int64], cbor.WriteBool)"},
+		},
+		{
+			name:   "Control",
+			schema: "a = bstr .size 16",
+			want:   []string{"type A = []byte"},
+		},
+		{
+			name:   "Range",
+			schema: "a = -10..10",
+			want:   []string{"type A = int64"},
+		},
+		{
+			name:   "Any",
+			schema: "a = any",
+			want:   []string{"type A = cbor.RawMessage"},
+		},
+		{
+			name:   "Choice",
+			schema: "a = int / tstr",
+			want:   []string{"// A is kept encoded, as choices between int / tstr are not generated.", "type A = cbor.RawMessage"},
+		},
+		{
+			name:   "GroupChoice",
+			schema: "a = { b: int // c: int }",
+			want:   []string{"// A is kept encoded, as group choices are not generated."},
+		},
+		{
+			name:   "MixedKeys",
+			schema: `a = { b: int, 1: int }`,
+			want:   []string{"// A is kept encoded, as maps with both text and integer keys are not generated."},
+		},
+		{
+			name:   "RequiredKeyType",
+			schema: "a = { tstr => int }",
+			want:   []string{"// A is kept encoded, as map keys of tstr are not generated."},
+		},
+		{
+			name:   "OptionalInside",
+			schema: "a = [? b: int, c: int]",
+			want:   []string{"// A is kept encoded, as optional array entries are only generated at the end."},
+		},
+		{
+			name:   "SelfAlias",
+			schema: "a = b / null b = a",
+			want:   []string{"type A = *B", "// B is kept encoded, as rule a refers to itself."},
+		},
+		{
+			name:   "IntKeyNames",
+			schema: `a = { 1: int, -2: int }`,
+			want:   []string{"Key1      int64", "KeyMinus2 int64", "case -2:", "cbor.WriteSigned(out, int64(-2))"},
+		},
+		{
+			name:   "DuplicateFields",
+			schema: `a = { "user-id": uint, "user_id": uint }`,
+			want:   []string{"UserID  uint64", "UserID2 uint64"},
+		},
+		{
+			name:   "NestedNames",
+			schema: `a = { b: { c: int }, d: [x: int, y: tstr], e: "x" / "y" }`,
+			want:   []string{"B AB", "type AB struct", "D AD", "type AD struct", "E AE", "type AE string"},
+		},
+		{
+			name:   "Flatten",
+			schema: "a = { g, ? (c: int) } g = (b: int)",
+			want:   []string{"B int64", "C *int64", "if seen != 0x1"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := cddl.Parse(test.schema)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, err := Generate(s, Options{Package: "p"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, want := range test.want {
+				if !strings.Contains(string(out), want) {
+					t.Errorf("missing %q in\n%s", want, out)
+				}
+			}
+		})
+	}
+}
+
+func Test_exported(t *testing.T) {
+	tests := map[string]string{
+		"message":      "Message",
+		"msg-id":       "MsgID",
+		"cose_key":     "COSEKey",
+		"$$ext":        "Ext",
+		"1st":          "X1st",
+		"":             "X",
+		"redirect.url": "RedirectURL",
+	}
+
+	for in, want := range tests {
+		if got := exported(in); got != want {
+			t.Errorf("exported(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
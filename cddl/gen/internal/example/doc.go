@@ -0,0 +1,5 @@
+// Package example is generated from example.cddl, to test the generator
+// against the schema it was generated from.
+package example
+
+//go:generate go run ../../../../cmd/cddlgen -package example -o example.go example.cddl
@@ -0,0 +1,784 @@
+// Code generated by cddlgen from example.cddl. DO NOT EDIT.
+
+package example
+
+import (
+	"io"
+
+	cbor "github.com/alex-richards/tiny-cbor"
+)
+
+type Message struct {
+	MsgID       uint64
+	Sender      *string
+	Body        *[]byte
+	MsgPriority *Priority
+	Tags        []string
+	Headers     map[string]string
+}
+
+// ReadMessage reads the next Message from [in], skipping unknown keys.
+func ReadMessage(in io.Reader) (Message, error) {
+	var v Message
+	var seen uint64
+	err := cbor.ReadMap(
+		in,
+		func(bool, uint64) error { return nil },
+		func(in io.Reader) error {
+			d := cbor.NewDecoder(in)
+			majorType, _, _, err := d.PeekType()
+			if err != nil {
+				return err
+			}
+			if majorType != cbor.MajorTypeUInt && majorType != cbor.MajorTypeNInt {
+				if err := cbor.ReadOver(d); err != nil {
+					return err
+				}
+				return cbor.ReadOver(d)
+			}
+
+			key, err := cbor.ReadSigned[int64](d)
+			if err != nil {
+				return err
+			}
+
+			switch key {
+			case 1:
+				v.MsgID, err = cbor.ReadUnsigned[uint64](d)
+				seen |= 1 << 0
+			case 2:
+				var x string
+				x, err = cbor.ReadString(d)
+				v.Sender = &x
+			case 3:
+				v.Body, err = cbor.ReadNullable(d, cbor.ReadByteString)
+				seen |= 1 << 2
+			case -1:
+				var x Priority
+				x, err = ReadPriority(d)
+				v.MsgPriority = &x
+			case 4:
+				v.Tags, err = cbor.ReadSlice(d, cbor.ReadString)
+			case 5:
+				v.Headers, err = cbor.ReadMapOf(d, cbor.ReadString, cbor.ReadString)
+			default:
+				err = cbor.ReadOver(d)
+			}
+			return err
+		},
+	)
+	if err != nil {
+		return Message{}, err
+	}
+	if seen != 0x5 {
+		return Message{}, cbor.ErrMissingMember
+	}
+
+	return v, nil
+}
+
+// WriteMessage writes [v], omitting absent optional members.
+func WriteMessage(out io.Writer, v Message) (int, error) {
+	length := uint64(2)
+	if v.Sender != nil {
+		length++
+	}
+	if v.MsgPriority != nil {
+		length++
+	}
+	if v.Tags != nil {
+		length++
+	}
+	if v.Headers != nil {
+		length++
+	}
+
+	n, err := cbor.WriteMapHeader(out, length)
+	if err != nil {
+		return n, err
+	}
+
+	var nn int
+	nn, err = cbor.WriteUnsigned(out, uint64(1))
+	n += nn
+	if err != nil {
+		return n, err
+	}
+	nn, err = cbor.WriteUnsigned[uint64](out, v.MsgID)
+	n += nn
+	if err != nil {
+		return n, err
+	}
+	if v.Sender != nil {
+		nn, err = cbor.WriteUnsigned(out, uint64(2))
+		n += nn
+		if err != nil {
+			return n, err
+		}
+		nn, err = cbor.WriteString(out, *v.Sender)
+		n += nn
+		if err != nil {
+			return n, err
+		}
+	}
+	nn, err = cbor.WriteUnsigned(out, uint64(3))
+	n += nn
+	if err != nil {
+		return n, err
+	}
+	nn, err = cbor.WriteNullable(out, v.Body, cbor.WriteBytes)
+	n += nn
+	if err != nil {
+		return n, err
+	}
+	if v.Tags != nil {
+		nn, err = cbor.WriteUnsigned(out, uint64(4))
+		n += nn
+		if err != nil {
+			return n, err
+		}
+		nn, err = cbor.WriteSlice(out, v.Tags, cbor.WriteString)
+		n += nn
+		if err != nil {
+			return n, err
+		}
+	}
+	if v.Headers != nil {
+		nn, err = cbor.WriteUnsigned(out, uint64(5))
+		n += nn
+		if err != nil {
+			return n, err
+		}
+		nn, err = cbor.WriteMapOfSorted(out, v.Headers, cbor.WriteString, cbor.WriteString)
+		n += nn
+		if err != nil {
+			return n, err
+		}
+	}
+	if v.MsgPriority != nil {
+		nn, err = cbor.WriteSigned(out, int64(-1))
+		n += nn
+		if err != nil {
+			return n, err
+		}
+		nn, err = WritePriority(out, *v.MsgPriority)
+		n += nn
+		if err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+type Priority int64
+
+const (
+	PriorityLow    Priority = 0
+	PriorityNormal Priority = 1
+	PriorityHigh   Priority = 2
+)
+
+// ReadPriority reads the next Priority from [in].
+func ReadPriority(in io.Reader) (Priority, error) {
+	v, err := cbor.ReadSigned[int64](in)
+	if err != nil {
+		return 0, err
+	}
+
+	switch e := Priority(v); e {
+	case PriorityLow, PriorityNormal, PriorityHigh:
+		return e, nil
+	}
+
+	return 0, cbor.ErrUnsupportedValue
+}
+
+// WritePriority writes [v].
+func WritePriority(out io.Writer, v Priority) (int, error) {
+	return cbor.WriteSigned[int64](out, int64(v))
+}
+
+const MsgID = 1
+
+const Sender = 2
+
+const Body = 3
+
+const MsgPriority = -1
+
+const Tags = 4
+
+const Headers = 5
+
+type Status string
+
+const (
+	StatusOk    Status = "ok"
+	StatusError Status = "error"
+	StatusRetry Status = "retry"
+)
+
+// ReadStatus reads the next Status from [in].
+func ReadStatus(in io.Reader) (Status, error) {
+	v, err := cbor.ReadString(in)
+	if err != nil {
+		return "", err
+	}
+
+	switch e := Status(v); e {
+	case StatusOk, StatusError, StatusRetry:
+		return e, nil
+	}
+
+	return "", cbor.ErrUnsupportedValue
+}
+
+// WriteStatus writes [v].
+func WriteStatus(out io.Writer, v Status) (int, error) {
+	return cbor.WriteString(out, string(v))
+}
+
+type Colour int64
+
+const (
+	ColourRed   Colour = 1
+	ColourGreen Colour = 2
+	ColourBlue  Colour = 3
+)
+
+// ReadColour reads the next Colour from [in].
+func ReadColour(in io.Reader) (Colour, error) {
+	v, err := cbor.ReadSigned[int64](in)
+	if err != nil {
+		return 0, err
+	}
+
+	switch e := Colour(v); e {
+	case ColourRed, ColourGreen, ColourBlue:
+		return e, nil
+	}
+
+	return 0, cbor.ErrUnsupportedValue
+}
+
+// WriteColour writes [v].
+func WriteColour(out io.Writer, v Colour) (int, error) {
+	return cbor.WriteSigned[int64](out, int64(v))
+}
+
+type Point struct {
+	X int64
+	Y int64
+	Z *int64
+}
+
+// ReadPoint reads the next Point from [in].
+func ReadPoint(in io.Reader) (Point, error) {
+	var v Point
+	i := 0
+	err := cbor.ReadArray(
+		in,
+		func(bool, uint64) error { return nil },
+		func(in io.Reader) error {
+			var err error
+			switch i {
+			case 0:
+				v.X, err = cbor.ReadSigned[int64](in)
+			case 1:
+				v.Y, err = cbor.ReadSigned[int64](in)
+			case 2:
+				var x int64
+				x, err = cbor.ReadSigned[int64](in)
+				v.Z = &x
+			default:
+				err = cbor.ErrUnsupportedValue
+			}
+			i++
+			return err
+		},
+	)
+	if err != nil {
+		return Point{}, err
+	}
+	if i < 2 {
+		return Point{}, cbor.ErrMissingMember
+	}
+
+	return v, nil
+}
+
+// WritePoint writes [v], up to the last present optional member.
+func WritePoint(out io.Writer, v Point) (int, error) {
+	length := uint64(2)
+	if v.Z != nil {
+		length = 3
+	}
+
+	n, err := cbor.WriteArrayHeader(out, length)
+	if err != nil {
+		return n, err
+	}
+
+	var nn int
+	nn, err = cbor.WriteSigned[int64](out, v.X)
+	n += nn
+	if err != nil {
+		return n, err
+	}
+	nn, err = cbor.WriteSigned[int64](out, v.Y)
+	n += nn
+	if err != nil {
+		return n, err
+	}
+	if length > 2 {
+		if v.Z == nil {
+			return n, cbor.ErrMissingMember
+		}
+		nn, err = cbor.WriteSigned[int64](out, *v.Z)
+		n += nn
+		if err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+type Shape struct {
+	Created *uint64
+	Status  *Status
+	Name    string
+	Colour  *Colour
+	Points  []Point
+	Origin  *Point
+	Label   *string
+	Style   ShapeStyle
+	Meta    Metadata
+}
+
+// ReadShape reads the next Shape from [in], skipping unknown keys.
+func ReadShape(in io.Reader) (Shape, error) {
+	var v Shape
+	var seen uint64
+	err := cbor.ReadMap(
+		in,
+		func(bool, uint64) error { return nil },
+		func(in io.Reader) error {
+			d := cbor.NewDecoder(in)
+			majorType, _, _, err := d.PeekType()
+			if err != nil {
+				return err
+			}
+			if majorType != cbor.MajorTypeTstr {
+				if err := cbor.ReadOver(d); err != nil {
+					return err
+				}
+				return cbor.ReadOver(d)
+			}
+
+			key, err := cbor.ReadString(d)
+			if err != nil {
+				return err
+			}
+
+			switch key {
+			case "created":
+				var x uint64
+				x, err = cbor.ReadUnsigned[uint64](d)
+				v.Created = &x
+			case "status":
+				var x Status
+				x, err = ReadStatus(d)
+				v.Status = &x
+			case "name":
+				v.Name, err = cbor.ReadString(d)
+				seen |= 1 << 2
+			case "colour":
+				var x Colour
+				x, err = ReadColour(d)
+				v.Colour = &x
+			case "points":
+				v.Points, err = cbor.ReadSlice(d, ReadPoint)
+				seen |= 1 << 4
+			case "origin":
+				var x Point
+				x, err = ReadPoint(d)
+				v.Origin = &x
+			case "label":
+				v.Label, err = cbor.ReadNullable(d, cbor.ReadString)
+			case "style":
+				v.Style, err = ReadShapeStyle(d)
+				seen |= 1 << 7
+			case "meta":
+				v.Meta, err = ReadMetadata(d)
+			default:
+				err = cbor.ReadOver(d)
+			}
+			return err
+		},
+	)
+	if err != nil {
+		return Shape{}, err
+	}
+	if seen != 0x94 {
+		return Shape{}, cbor.ErrMissingMember
+	}
+
+	return v, nil
+}
+
+// WriteShape writes [v], omitting absent optional members.
+func WriteShape(out io.Writer, v Shape) (int, error) {
+	length := uint64(3)
+	if v.Created != nil {
+		length++
+	}
+	if v.Status != nil {
+		length++
+	}
+	if v.Colour != nil {
+		length++
+	}
+	if v.Origin != nil {
+		length++
+	}
+	if v.Label != nil {
+		length++
+	}
+	if v.Meta != nil {
+		length++
+	}
+
+	n, err := cbor.WriteMapHeader(out, length)
+	if err != nil {
+		return n, err
+	}
+
+	var nn int
+	if v.Meta != nil {
+		nn, err = cbor.WriteString(out, "meta")
+		n += nn
+		if err != nil {
+			return n, err
+		}
+		nn, err = WriteMetadata(out, v.Meta)
+		n += nn
+		if err != nil {
+			return n, err
+		}
+	}
+	nn, err = cbor.WriteString(out, "name")
+	n += nn
+	if err != nil {
+		return n, err
+	}
+	nn, err = cbor.WriteString(out, v.Name)
+	n += nn
+	if err != nil {
+		return n, err
+	}
+	if v.Label != nil {
+		nn, err = cbor.WriteString(out, "label")
+		n += nn
+		if err != nil {
+			return n, err
+		}
+		nn, err = cbor.WriteNullable(out, v.Label, cbor.WriteString)
+		n += nn
+		if err != nil {
+			return n, err
+		}
+	}
+	nn, err = cbor.WriteString(out, "style")
+	n += nn
+	if err != nil {
+		return n, err
+	}
+	nn, err = WriteShapeStyle(out, v.Style)
+	n += nn
+	if err != nil {
+		return n, err
+	}
+	if v.Colour != nil {
+		nn, err = cbor.WriteString(out, "colour")
+		n += nn
+		if err != nil {
+			return n, err
+		}
+		nn, err = WriteColour(out, *v.Colour)
+		n += nn
+		if err != nil {
+			return n, err
+		}
+	}
+	if v.Origin != nil {
+		nn, err = cbor.WriteString(out, "origin")
+		n += nn
+		if err != nil {
+			return n, err
+		}
+		nn, err = WritePoint(out, *v.Origin)
+		n += nn
+		if err != nil {
+			return n, err
+		}
+	}
+	nn, err = cbor.WriteString(out, "points")
+	n += nn
+	if err != nil {
+		return n, err
+	}
+	nn, err = cbor.WriteSlice(out, v.Points, WritePoint)
+	n += nn
+	if err != nil {
+		return n, err
+	}
+	if v.Status != nil {
+		nn, err = cbor.WriteString(out, "status")
+		n += nn
+		if err != nil {
+			return n, err
+		}
+		nn, err = WriteStatus(out, *v.Status)
+		n += nn
+		if err != nil {
+			return n, err
+		}
+	}
+	if v.Created != nil {
+		nn, err = cbor.WriteString(out, "created")
+		n += nn
+		if err != nil {
+			return n, err
+		}
+		nn, err = cbor.WriteUnsigned[uint64](out, *v.Created)
+		n += nn
+		if err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+type ShapeStyle struct {
+	Width  float64
+	Dashed *bool
+}
+
+// ReadShapeStyle reads the next ShapeStyle from [in], skipping unknown keys.
+func ReadShapeStyle(in io.Reader) (ShapeStyle, error) {
+	var v ShapeStyle
+	var seen uint64
+	err := cbor.ReadMap(
+		in,
+		func(bool, uint64) error { return nil },
+		func(in io.Reader) error {
+			d := cbor.NewDecoder(in)
+			majorType, _, _, err := d.PeekType()
+			if err != nil {
+				return err
+			}
+			if majorType != cbor.MajorTypeTstr {
+				if err := cbor.ReadOver(d); err != nil {
+					return err
+				}
+				return cbor.ReadOver(d)
+			}
+
+			key, err := cbor.ReadString(d)
+			if err != nil {
+				return err
+			}
+
+			switch key {
+			case "width":
+				v.Width, err = cbor.ReadFloat[float64](d)
+				seen |= 1 << 0
+			case "dashed":
+				var x bool
+				x, err = cbor.ReadBool(d)
+				v.Dashed = &x
+			default:
+				err = cbor.ReadOver(d)
+			}
+			return err
+		},
+	)
+	if err != nil {
+		return ShapeStyle{}, err
+	}
+	if seen != 0x1 {
+		return ShapeStyle{}, cbor.ErrMissingMember
+	}
+
+	return v, nil
+}
+
+// WriteShapeStyle writes [v], omitting absent optional members.
+func WriteShapeStyle(out io.Writer, v ShapeStyle) (int, error) {
+	length := uint64(1)
+	if v.Dashed != nil {
+		length++
+	}
+
+	n, err := cbor.WriteMapHeader(out, length)
+	if err != nil {
+		return n, err
+	}
+
+	var nn int
+	nn, err = cbor.WriteString(out, "width")
+	n += nn
+	if err != nil {
+		return n, err
+	}
+	nn, err = cbor.WriteFloat[float64](out, v.Width)
+	n += nn
+	if err != nil {
+		return n, err
+	}
+	if v.Dashed != nil {
+		nn, err = cbor.WriteString(out, "dashed")
+		n += nn
+		if err != nil {
+			return n, err
+		}
+		nn, err = cbor.WriteBool(out, *v.Dashed)
+		n += nn
+		if err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+type Metadata map[string]cbor.RawMessage
+
+// ReadMetadata reads the next Metadata from [in].
+func ReadMetadata(in io.Reader) (Metadata, error) {
+	return cbor.ReadMapOf(in, cbor.ReadString, cbor.ReadRawMessage)
+}
+
+// WriteMetadata writes [v].
+func WriteMetadata(out io.Writer, v Metadata) (int, error) {
+	return cbor.WriteMapOfSorted(out, v, cbor.WriteString, cbor.WriteRawMessage)
+}
+
+type Node struct {
+	Value    int64
+	Children []Node
+}
+
+// ReadNode reads the next Node from [in], skipping unknown keys.
+func ReadNode(in io.Reader) (Node, error) {
+	var v Node
+	var seen uint64
+	err := cbor.ReadMap(
+		in,
+		func(bool, uint64) error { return nil },
+		func(in io.Reader) error {
+			d := cbor.NewDecoder(in)
+			majorType, _, _, err := d.PeekType()
+			if err != nil {
+				return err
+			}
+			if majorType != cbor.MajorTypeTstr {
+				if err := cbor.ReadOver(d); err != nil {
+					return err
+				}
+				return cbor.ReadOver(d)
+			}
+
+			key, err := cbor.ReadString(d)
+			if err != nil {
+				return err
+			}
+
+			switch key {
+			case "value":
+				v.Value, err = cbor.ReadSigned[int64](d)
+				seen |= 1 << 0
+			case "children":
+				v.Children, err = cbor.ReadSlice(d, ReadNode)
+			default:
+				err = cbor.ReadOver(d)
+			}
+			return err
+		},
+	)
+	if err != nil {
+		return Node{}, err
+	}
+	if seen != 0x1 {
+		return Node{}, cbor.ErrMissingMember
+	}
+
+	return v, nil
+}
+
+// WriteNode writes [v], omitting absent optional members.
+func WriteNode(out io.Writer, v Node) (int, error) {
+	length := uint64(1)
+	if v.Children != nil {
+		length++
+	}
+
+	n, err := cbor.WriteMapHeader(out, length)
+	if err != nil {
+		return n, err
+	}
+
+	var nn int
+	nn, err = cbor.WriteString(out, "value")
+	n += nn
+	if err != nil {
+		return n, err
+	}
+	nn, err = cbor.WriteSigned[int64](out, v.Value)
+	n += nn
+	if err != nil {
+		return n, err
+	}
+	if v.Children != nil {
+		nn, err = cbor.WriteString(out, "children")
+		n += nn
+		if err != nil {
+			return n, err
+		}
+		nn, err = cbor.WriteSlice(out, v.Children, WriteNode)
+		n += nn
+		if err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+type Port = uint64
+
+// ReadPort reads the next Port from [in].
+func ReadPort(in io.Reader) (Port, error) {
+	return cbor.ReadUnsigned[uint64](in)
+}
+
+// WritePort writes [v].
+func WritePort(out io.Writer, v Port) (int, error) {
+	return cbor.WriteUnsigned[uint64](out, v)
+}
+
+// Either is kept encoded, as choices between tstr / int are not generated.
+type Either = cbor.RawMessage
+
+// ReadEither reads the next Either from [in].
+func ReadEither(in io.Reader) (Either, error) {
+	return cbor.ReadRawMessage(in)
+}
+
+// WriteEither writes [v].
+func WriteEither(out io.Writer, v Either) (int, error) {
+	return cbor.WriteRawMessage(out, v)
+}
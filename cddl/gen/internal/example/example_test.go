@@ -0,0 +1,215 @@
+package example
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	cbor "github.com/alex-richards/tiny-cbor"
+	"github.com/alex-richards/tiny-cbor/cddl"
+	"github.com/google/go-cmp/cmp"
+)
+
+func decodeHex(tb testing.TB, encoded string) []byte {
+	tb.Helper()
+
+	decoded, err := hex.DecodeString(encoded)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	return decoded
+}
+
+func schema(tb testing.TB) *cddl.Schema {
+	tb.Helper()
+
+	src, err := os.ReadFile("example.cddl")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	s, err := cddl.Parse(string(src))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return s
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// roundTrip reads [encoded], compares it with [want], and checks writing it
+// gives the same bytes, valid against [rule].
+func roundTrip[T any](
+	t *testing.T,
+	rule string,
+	encoded string,
+	want T,
+	read func(io.Reader) (T, error),
+	write func(io.Writer, T) (int, error),
+) {
+	t.Helper()
+
+	data := decodeHex(t, encoded)
+	got, err := read(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatal(diff)
+	}
+
+	var out bytes.Buffer
+	n, err := write(&out, got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != out.Len() {
+		t.Fatalf("wrote %d, counted %d", out.Len(), n)
+	}
+	if diff := cmp.Diff(data, out.Bytes()); diff != "" {
+		t.Fatal(diff)
+	}
+
+	if err := schema(t).ValidateRule(bytes.NewReader(out.Bytes()), rule); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_Message(t *testing.T) {
+	t.Run("Minimal", func(t *testing.T) {
+		roundTrip(t, "message", "a2010703f6", Message{MsgID: 7}, ReadMessage, WriteMessage)
+	})
+
+	t.Run("Full", func(t *testing.T) {
+		roundTrip(t, "message", "a601070265616c6963650342686904826161616205a1616b61762002", Message{
+			MsgID:       7,
+			Sender:      ptr("alice"),
+			Body:        ptr([]byte("hi")),
+			MsgPriority: ptr(PriorityHigh),
+			Tags:        []string{"a", "b"},
+			Headers:     map[string]string{"k": "v"},
+		}, ReadMessage, WriteMessage)
+	})
+
+	t.Run("UnknownKeys", func(t *testing.T) {
+		got, err := ReadMessage(bytes.NewReader(decodeHex(t, "a46178010107098201020340")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(Message{MsgID: 7, Body: ptr([]byte{})}, got); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+}
+
+func Test_Point(t *testing.T) {
+	roundTrip(t, "point", "820121", Point{X: 1, Y: -2}, ReadPoint, WritePoint)
+	roundTrip(t, "point", "83012103", Point{X: 1, Y: -2, Z: ptr[int64](3)}, ReadPoint, WritePoint)
+}
+
+func Test_Node(t *testing.T) {
+	roundTrip(
+		t,
+		"node",
+		"a26576616c756501686368696c6472656e82a16576616c756502a26576616c756503686368696c6472656e80",
+		Node{Value: 1, Children: []Node{{Value: 2}, {Value: 3, Children: []Node{}}}},
+		ReadNode,
+		WriteNode,
+	)
+}
+
+func Test_Shape(t *testing.T) {
+	shape := Shape{
+		Created: ptr[uint64](1700000000),
+		Status:  ptr(StatusOk),
+		Name:    "triangle",
+		Colour:  ptr(ColourGreen),
+		Points:  []Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 0, Y: 1, Z: ptr[int64](2)}},
+		Origin:  &Point{X: -1, Y: -1},
+		Label:   ptr("a"),
+		Style:   ShapeStyle{Width: 1.5, Dashed: ptr(true)},
+		Meta:    Metadata{"version": cbor.RawMessage{0x02}},
+	}
+
+	var out bytes.Buffer
+	if _, err := WriteShape(&out, shape); err != nil {
+		t.Fatal(err)
+	}
+	if err := schema(t).ValidateRule(bytes.NewReader(out.Bytes()), "shape"); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ReadShape(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(shape, got); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func Test_Read_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		read    func(io.Reader) error
+		encoded string
+		want    error
+	}{
+		{
+			name:    "Message/MissingMember",
+			read:    func(in io.Reader) error { _, err := ReadMessage(in); return err },
+			encoded: "a10107",
+			want:    cbor.ErrMissingMember,
+		},
+		{
+			name:    "Message/UnknownEnumValue",
+			read:    func(in io.Reader) error { _, err := ReadMessage(in); return err },
+			encoded: "a3010703f62005",
+			want:    cbor.ErrUnsupportedValue,
+		},
+		{
+			name:    "Message/NotMap",
+			read:    func(in io.Reader) error { _, err := ReadMessage(in); return err },
+			encoded: "80",
+			want:    cbor.ErrUnsupportedMajorType,
+		},
+		{
+			name:    "Message/Truncated",
+			read:    func(in io.Reader) error { _, err := ReadMessage(in); return err },
+			encoded: "a20107",
+			want:    io.EOF,
+		},
+		{
+			name:    "Point/Short",
+			read:    func(in io.Reader) error { _, err := ReadPoint(in); return err },
+			encoded: "8101",
+			want:    cbor.ErrMissingMember,
+		},
+		{
+			name:    "Point/Long",
+			read:    func(in io.Reader) error { _, err := ReadPoint(in); return err },
+			encoded: "8401020304",
+			want:    cbor.ErrUnsupportedValue,
+		},
+		{
+			name:    "Status/Unknown",
+			read:    func(in io.Reader) error { _, err := ReadStatus(in); return err },
+			encoded: "64646f6e65",
+			want:    cbor.ErrUnsupportedValue,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.read(bytes.NewReader(decodeHex(t, test.encoded)))
+			if !errors.Is(err, test.want) {
+				t.Fatalf("got %v, want %v", err, test.want)
+			}
+		})
+	}
+}
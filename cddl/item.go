@@ -0,0 +1,270 @@
+package cddl
+
+import (
+	"bytes"
+	"io"
+	"math"
+
+	cbor "github.com/alex-richards/tiny-cbor"
+	"github.com/x448/float16"
+)
+
+const (
+	defaultMaxDepth = 1024
+	maxPrealloc     = 1024
+)
+
+// item is a decoded data item. The header is kept as read, so that floats
+// keep their width.
+type item struct {
+	majorType cbor.MajorType
+	arg       cbor.Arg
+	value     uint64 // integer, length, tag number, simple value, or float bits
+
+	bytes []byte  // string contents, joined if indefinite length
+	items []*item // array items, map keys and values interleaved, tag content
+}
+
+// readItem reads the next item from [in], nested [depth] items deep, failing
+// if it is nested more than [maxDepth] deep.
+func readItem(in *cbor.Decoder, depth, maxDepth int) (*item, error) {
+	if depth > maxDepth {
+		return nil, cbor.ErrTooLarge
+	}
+
+	majorType, arg, value, err := in.PeekType()
+	if err == io.EOF && depth > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	it := &item{majorType: majorType, arg: arg, value: value}
+
+	switch majorType {
+	case cbor.MajorTypeBstr, cbor.MajorTypeTstr:
+		it.bytes, err = cbor.ReadBytesInto(in, nil)
+		return it, err
+
+	case cbor.MajorTypeSimpleFloat:
+		if arg == cbor.ArgIndefinite {
+			return nil, cbor.ErrNotWellFormed
+		}
+	}
+
+	if err := skipHeader(in, arg); err != nil {
+		return nil, err
+	}
+
+	switch majorType {
+	case cbor.MajorTypeArray, cbor.MajorTypeMap:
+		n := value
+		if majorType == cbor.MajorTypeMap {
+			if n > math.MaxUint64/2 {
+				return nil, cbor.ErrOverflow
+			}
+			n *= 2
+		}
+
+		if arg != cbor.ArgIndefinite {
+			it.items = make([]*item, 0, min(n, maxPrealloc))
+			for range n {
+				child, err := readItem(in, depth+1, maxDepth)
+				if err != nil {
+					return nil, err
+				}
+				it.items = append(it.items, child)
+			}
+			return it, nil
+		}
+
+		for {
+			end, err := readBreak(in)
+			if err != nil {
+				return nil, err
+			}
+			if end {
+				break
+			}
+			child, err := readItem(in, depth+1, maxDepth)
+			if err != nil {
+				return nil, err
+			}
+			it.items = append(it.items, child)
+		}
+		if majorType == cbor.MajorTypeMap && len(it.items)%2 != 0 {
+			return nil, cbor.ErrNotWellFormed
+		}
+		return it, nil
+
+	case cbor.MajorTypeTagged:
+		content, err := readItem(in, depth+1, maxDepth)
+		if err != nil {
+			return nil, err
+		}
+		it.items = []*item{content}
+		return it, nil
+
+	default:
+		if arg == cbor.ArgIndefinite {
+			return nil, cbor.ErrNotWellFormed
+		}
+		return it, nil
+	}
+}
+
+// skipItem reads over the next item from [in], as [readItem], without
+// keeping it.
+func skipItem(in *cbor.Decoder, depth, maxDepth int) error {
+	if depth > maxDepth {
+		return cbor.ErrTooLarge
+	}
+
+	majorType, arg, _, err := peek(in, depth)
+	if err != nil {
+		return err
+	}
+
+	switch majorType {
+	case cbor.MajorTypeBstr, cbor.MajorTypeTstr:
+		r, err := cbor.ReadBytesStream(in)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(io.Discard, r)
+		return err
+
+	case cbor.MajorTypeArray, cbor.MajorTypeMap:
+		items, err := readContainer(in)
+		if err != nil {
+			return err
+		}
+		return items.skip(depth+1, maxDepth)
+
+	case cbor.MajorTypeTagged:
+		if err := skipHeader(in, arg); err != nil {
+			return err
+		}
+		return skipItem(in, depth+1, maxDepth)
+
+	default:
+		if arg == cbor.ArgIndefinite {
+			return cbor.ErrNotWellFormed
+		}
+		return skipHeader(in, arg)
+	}
+}
+
+// skipHeader reads over a header already decoded by [cbor.Decoder.PeekType].
+func skipHeader(in io.Reader, arg cbor.Arg) error {
+	l := 1
+	switch arg {
+	case cbor.Arg8:
+		l = 2
+	case cbor.Arg16:
+		l = 3
+	case cbor.Arg32:
+		l = 5
+	case cbor.Arg64:
+		l = 9
+	}
+
+	var b [9]byte
+	_, err := io.ReadFull(in, b[:l])
+	return err
+}
+
+// limitReader reads from [r], failing with cbor.ErrTooLarge once more than
+// [n] bytes have been read.
+type limitReader struct {
+	r io.Reader
+	n uint64
+}
+
+func (l *limitReader) Read(out []byte) (int, error) {
+	if l.n == 0 {
+		return 0, cbor.ErrTooLarge
+	}
+	if uint64(len(out)) > l.n {
+		out = out[:l.n]
+	}
+
+	n, err := l.r.Read(out)
+	l.n -= uint64(n)
+	return n, err
+}
+
+// readBreak reads the break ending an indefinite length container, if it is
+// next.
+func readBreak(in *cbor.Decoder) (bool, error) {
+	majorType, arg, _, err := in.PeekType()
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil || majorType != cbor.MajorTypeSimpleFloat || arg != cbor.ArgIndefinite {
+		return false, err
+	}
+	return true, skipHeader(in, arg)
+}
+
+// readItemData reads a single item from [data], with nothing following it,
+// nested at most [maxDepth] deep.
+func readItemData(data []byte, maxDepth int) (*item, error) {
+	in := cbor.NewDecoder(bytes.NewReader(data))
+	it, err := readItem(in, 0, maxDepth)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, _, err := in.PeekType(); err != io.EOF {
+		return nil, cbor.ErrTrailingData
+	}
+	return it, nil
+}
+
+// readItemSequence reads every item from [data], each nested at most
+// [maxDepth] deep.
+func readItemSequence(data []byte, maxDepth int) ([]*item, error) {
+	in := cbor.NewDecoder(bytes.NewReader(data))
+
+	var items []*item
+	for {
+		if _, _, _, err := in.PeekType(); err == io.EOF {
+			return items, nil
+		}
+		it, err := readItem(in, 0, maxDepth)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, it)
+	}
+}
+
+func (it *item) isInt() bool {
+	return it.majorType == cbor.MajorTypeUInt || it.majorType == cbor.MajorTypeNInt
+}
+
+func (it *item) isFloat() bool {
+	return it.majorType == cbor.MajorTypeSimpleFloat &&
+		(it.arg == cbor.SimpleFloat16 || it.arg == cbor.SimpleFloat32 || it.arg == cbor.SimpleFloat64)
+}
+
+// float returns the value of a float item.
+func (it *item) float() float64 {
+	switch it.arg {
+	case cbor.SimpleFloat16:
+		return float64(float16.Frombits(uint16(it.value)).Float32())
+	case cbor.SimpleFloat32:
+		return float64(math.Float32frombits(uint32(it.value)))
+	default:
+		return math.Float64frombits(it.value)
+	}
+}
+
+// simple returns the simple value of an item, which is false if it is not a
+// simple value.
+func (it *item) simple() (uint64, bool) {
+	if it.majorType != cbor.MajorTypeSimpleFloat || it.isFloat() {
+		return 0, false
+	}
+	return it.value, true
+}
@@ -0,0 +1,422 @@
+package cddl
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+type tokenKind byte
+
+const (
+	tokenEOF     tokenKind = iota
+	tokenName              // an identifier
+	tokenNumber            // a number, parsed into value
+	tokenText              // a text string, parsed into value
+	tokenBytes             // a byte string, parsed into value
+	tokenControl           // a control operator, such as .size
+	tokenHash              // #, #6 or #6.32, the digits are in text
+	tokenPunct             // punctuation
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value Value
+
+	line, column int
+	space        bool // preceded by whitespace or a comment
+}
+
+type lexer struct {
+	src          string
+	pos          int
+	line, column int
+}
+
+// lex splits the source into tokens, ending with a tokenEOF.
+func lex(src string) ([]token, error) {
+	l := lexer{src: src, line: 1, column: 1}
+
+	var tokens []token
+	for {
+		space := l.skipSpace()
+		t, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		t.space = space
+		tokens = append(tokens, t)
+		if t.kind == tokenEOF {
+			return tokens, nil
+		}
+	}
+}
+
+func (l *lexer) errorf(message string) error {
+	return &SyntaxError{Line: l.line, Column: l.column, Message: message}
+}
+
+func (l *lexer) peek(offset int) byte {
+	if l.pos+offset < len(l.src) {
+		return l.src[l.pos+offset]
+	}
+	return 0
+}
+
+func (l *lexer) advance(n int) {
+	for range n {
+		if l.src[l.pos] == '\n' {
+			l.line++
+			l.column = 1
+		} else {
+			l.column++
+		}
+		l.pos++
+	}
+}
+
+// skipSpace skips whitespace and comments, returning true if there were any.
+func (l *lexer) skipSpace() bool {
+	start := l.pos
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			l.advance(1)
+		case c == ';':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.advance(1)
+			}
+		default:
+			return l.pos > start
+		}
+	}
+	return l.pos > start
+}
+
+func (l *lexer) next() (token, error) {
+	t := token{line: l.line, column: l.column}
+	if l.pos >= len(l.src) {
+		t.kind = tokenEOF
+		return t, nil
+	}
+
+	c := l.src[l.pos]
+	switch {
+	case c == '"':
+		s, err := l.quoted('"')
+		if err != nil {
+			return t, err
+		}
+		t.kind = tokenText
+		t.value = Value{Kind: ValueText, Text: s}
+
+	case c == '\'':
+		s, err := l.quoted('\'')
+		if err != nil {
+			return t, err
+		}
+		t.kind = tokenBytes
+		t.value = Value{Kind: ValueBytes, Bytes: []byte(s)}
+
+	case (c == 'h' && l.peek(1) == '\'') ||
+		(c == 'b' && l.peek(1) == '6' && l.peek(2) == '4' && l.peek(3) == '\''):
+		b, err := l.encodedBytes()
+		if err != nil {
+			return t, err
+		}
+		t.kind = tokenBytes
+		t.value = Value{Kind: ValueBytes, Bytes: b}
+
+	case isIDStart(c):
+		t.kind = tokenName
+		t.text = l.name()
+
+	case isDigit(c) || (c == '-' && isDigit(l.peek(1))):
+		v, err := l.number()
+		if err != nil {
+			return t, err
+		}
+		t.kind = tokenNumber
+		t.value = v
+
+	case c == '.':
+		switch {
+		case l.peek(1) == '.' && l.peek(2) == '.':
+			t.kind, t.text = tokenPunct, RangeExclusive
+			l.advance(3)
+		case l.peek(1) == '.':
+			t.kind, t.text = tokenPunct, RangeInclusive
+			l.advance(2)
+		case isIDStart(l.peek(1)):
+			l.advance(1)
+			t.kind, t.text = tokenControl, "."+l.name()
+		default:
+			return t, l.errorf("unexpected '.'")
+		}
+
+	case c == '#':
+		l.advance(1)
+		t.kind = tokenHash
+		start := l.pos
+		if isDigit(l.peek(0)) {
+			l.advance(1)
+			if l.peek(0) == '.' && isDigit(l.peek(1)) {
+				l.advance(1)
+				for isDigit(l.peek(0)) {
+					l.advance(1)
+				}
+			}
+		}
+		t.text = l.src[start:l.pos]
+
+	default:
+		for _, p := range [...]string{"//=", "/=", "//", "=>", "=", "/", "(", ")", "{", "}", "[", "]", ",", ":", "^", "?", "*", "+", "~", "&", "<", ">"} {
+			if strings.HasPrefix(l.src[l.pos:], p) {
+				t.kind, t.text = tokenPunct, p
+				l.advance(len(p))
+				return t, nil
+			}
+		}
+		return t, l.errorf("unexpected " + strconv.QuoteRune(rune(c)))
+	}
+
+	return t, nil
+}
+
+func isIDStart(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '@' || c == '_' || c == '$'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// name reads an identifier, which may contain "-" and "." only when followed
+// by a letter or digit.
+func (l *lexer) name() string {
+	start := l.pos
+	l.advance(1)
+	for {
+		n := 0
+		for l.peek(n) == '-' || l.peek(n) == '.' {
+			n++
+		}
+		if c := l.peek(n); !isIDStart(c) && !isDigit(c) {
+			return l.src[start:l.pos]
+		}
+		l.advance(n + 1)
+	}
+}
+
+func (l *lexer) number() (Value, error) {
+	start := l.pos
+	if l.peek(0) == '-' {
+		l.advance(1)
+	}
+
+	if l.peek(0) == '0' && (l.peek(1) == 'x' || l.peek(1) == 'b') {
+		base := 16
+		if l.peek(1) == 'b' {
+			base = 2
+		}
+		l.advance(2)
+		digits := l.pos
+		for isDigit(l.peek(0)) || (base == 16 && strings.IndexByte("abcdefABCDEF", l.peek(0)) >= 0) {
+			l.advance(1)
+		}
+		u, err := strconv.ParseUint(l.src[digits:l.pos], base, 64)
+		if err != nil {
+			return Value{}, l.errorf("invalid number " + l.src[start:l.pos])
+		}
+		return intValue(l.src[start] == '-', u)
+	}
+
+	float := false
+	for isDigit(l.peek(0)) {
+		l.advance(1)
+	}
+	if l.peek(0) == '.' && isDigit(l.peek(1)) {
+		float = true
+		l.advance(1)
+		for isDigit(l.peek(0)) {
+			l.advance(1)
+		}
+	}
+	if c := l.peek(0); c == 'e' || c == 'E' {
+		n := 1
+		if c := l.peek(1); c == '+' || c == '-' {
+			n++
+		}
+		if isDigit(l.peek(n)) {
+			float = true
+			l.advance(n)
+			for isDigit(l.peek(0)) {
+				l.advance(1)
+			}
+		}
+	}
+
+	s := l.src[start:l.pos]
+	if float {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return Value{}, l.errorf("invalid number " + s)
+		}
+		return Value{Kind: ValueFloat, Float: f}, nil
+	}
+
+	u, err := strconv.ParseUint(strings.TrimPrefix(s, "-"), 10, 64)
+	if err != nil {
+		return Value{}, l.errorf("invalid number " + s)
+	}
+	return intValue(s[0] == '-', u)
+}
+
+// intValue returns the integer value of -u if negative is set, otherwise u.
+func intValue(negative bool, u uint64) (Value, error) {
+	if !negative {
+		return Value{Kind: ValueUint, Int: u}, nil
+	}
+	if u == 0 {
+		return Value{Kind: ValueUint}, nil
+	}
+	return Value{Kind: ValueNint, Int: u - 1}, nil
+}
+
+// quoted reads a string delimited by [quote], with JSON style escapes.
+func (l *lexer) quoted(quote byte) (string, error) {
+	l.advance(1)
+
+	var b strings.Builder
+	for {
+		if l.pos >= len(l.src) {
+			return "", l.errorf("unterminated string")
+		}
+
+		c := l.src[l.pos]
+		switch {
+		case c == quote:
+			l.advance(1)
+			return b.String(), nil
+
+		case c == '\\':
+			r, err := l.escape()
+			if err != nil {
+				return "", err
+			}
+			b.WriteRune(r)
+
+		case c == '\n' && quote == '"':
+			return "", l.errorf("unterminated string")
+
+		default:
+			r, n := utf8.DecodeRuneInString(l.src[l.pos:])
+			if r == utf8.RuneError && n == 1 {
+				return "", l.errorf("invalid utf-8")
+			}
+			b.WriteString(l.src[l.pos : l.pos+n])
+			l.advance(n)
+		}
+	}
+}
+
+func (l *lexer) escape() (rune, error) {
+	c := l.peek(1)
+	l.advance(2)
+	switch c {
+	case '"', '\'', '\\', '/':
+		return rune(c), nil
+	case 'b':
+		return '\b', nil
+	case 'f':
+		return '\f', nil
+	case 'n':
+		return '\n', nil
+	case 'r':
+		return '\r', nil
+	case 't':
+		return '\t', nil
+	case 'u':
+		r, err := l.hex4()
+		if err != nil {
+			return 0, err
+		}
+		if utf16.IsSurrogate(r) {
+			if l.peek(0) != '\\' || l.peek(1) != 'u' {
+				return 0, l.errorf("invalid escape")
+			}
+			l.advance(2)
+			r2, err := l.hex4()
+			if err != nil {
+				return 0, err
+			}
+			r = utf16.DecodeRune(r, r2)
+			if r == utf8.RuneError {
+				return 0, l.errorf("invalid escape")
+			}
+		}
+		return r, nil
+	default:
+		return 0, l.errorf("invalid escape")
+	}
+}
+
+func (l *lexer) hex4() (rune, error) {
+	if l.pos+4 > len(l.src) {
+		return 0, l.errorf("invalid escape")
+	}
+	u, err := strconv.ParseUint(l.src[l.pos:l.pos+4], 16, 16)
+	if err != nil {
+		return 0, l.errorf("invalid escape")
+	}
+	l.advance(4)
+	return rune(u), nil
+}
+
+// encodedBytes reads a h'...' or b64'...' byte string, which may contain
+// whitespace and comments.
+func (l *lexer) encodedBytes() ([]byte, error) {
+	base64Encoded := l.peek(0) == 'b'
+	if base64Encoded {
+		l.advance(4)
+	} else {
+		l.advance(2)
+	}
+
+	var s strings.Builder
+	for {
+		l.skipSpace()
+		if l.pos >= len(l.src) {
+			return nil, l.errorf("unterminated string")
+		}
+		c := l.src[l.pos]
+		l.advance(1)
+		if c == '\'' {
+			break
+		}
+		s.WriteByte(c)
+	}
+
+	if !base64Encoded {
+		b, err := hex.DecodeString(s.String())
+		if err != nil {
+			return nil, l.errorf("invalid hex string")
+		}
+		return b, nil
+	}
+
+	encoded := strings.TrimRight(s.String(), "=")
+	encoding := base64.RawURLEncoding
+	if strings.ContainsAny(encoded, "+/") {
+		encoding = base64.RawStdEncoding
+	}
+	b, err := encoding.DecodeString(encoded)
+	if err != nil {
+		return nil, l.errorf("invalid base64 string")
+	}
+	return b, nil
+}
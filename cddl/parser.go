@@ -0,0 +1,432 @@
+package cddl
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek(offset int) token {
+	if i := p.pos + offset; i < len(p.tokens) {
+		return p.tokens[i]
+	}
+	return p.tokens[len(p.tokens)-1]
+}
+
+func (p *parser) next() token {
+	t := p.peek(0)
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// is reports whether the next token is the punctuation [punct].
+func (p *parser) is(punct string) bool {
+	t := p.peek(0)
+	return t.kind == tokenPunct && t.text == punct
+}
+
+func (p *parser) accept(punct string) bool {
+	if p.is(punct) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(punct string) error {
+	if !p.accept(punct) {
+		return p.errorf("expected " + strconv.Quote(punct))
+	}
+	return nil
+}
+
+func (p *parser) errorf(message string) error {
+	return errorAt(p.peek(0), message)
+}
+
+func errorAt(t token, message string) error {
+	return &SyntaxError{Line: t.line, Column: t.column, Message: message}
+}
+
+// startsRule reports whether the next tokens begin a new rule.
+func (p *parser) startsRule() bool {
+	if p.peek(0).kind != tokenName {
+		return false
+	}
+	t := p.peek(1)
+	return t.kind == tokenPunct && (t.text == "=" || t.text == "/=" || t.text == "//=")
+}
+
+func (p *parser) parseRules(s *Schema) error {
+	for p.peek(0).kind != tokenEOF {
+		if p.peek(0).kind == tokenName && p.peek(1).kind == tokenPunct && p.peek(1).text == "<" {
+			p.pos++
+			return p.errorf("generic parameters are not supported")
+		}
+		if !p.startsRule() {
+			return p.errorf("expected rule")
+		}
+		nameToken := p.next()
+		name := nameToken.text
+
+		rule := s.rules[name]
+		if rule != nil && rule.prelude {
+			rule = nil
+		}
+
+		switch op := p.next().text; op {
+		case "=":
+			if rule != nil {
+				return errorAt(nameToken, "rule "+name+" is already defined")
+			}
+			g, err := p.parseGroup("")
+			if err != nil {
+				return err
+			}
+			s.add(&Rule{Name: name, Group: g})
+
+		case "/=":
+			t, err := p.parseType()
+			if err != nil {
+				return err
+			}
+			if rule == nil {
+				s.add(&Rule{Name: name, Group: typeGroup(t)})
+				continue
+			}
+			existing := rule.Type()
+			if existing == nil {
+				return errorAt(nameToken, "rule "+name+" is not a type")
+			}
+			existing.Choices = append(existing.Choices, t.Choices...)
+
+		default: // "//="
+			g, err := p.parseGroup("")
+			if err != nil {
+				return err
+			}
+			if rule == nil {
+				s.add(&Rule{Name: name, Group: g})
+				continue
+			}
+			existing := innerGroup(rule.Group)
+			existing.Choices = append(existing.Choices, innerGroup(g).Choices...)
+		}
+	}
+
+	return nil
+}
+
+// innerGroup returns the group within parentheses if [g] is only that.
+func innerGroup(g *Group) *Group {
+	if len(g.Choices) == 1 && len(g.Choices[0]) == 1 {
+		if e := g.Choices[0][0]; e.Group != nil && e.Key == nil && e.Occur == (Occurrence{1, 1}) {
+			return e.Group
+		}
+	}
+	return g
+}
+
+// typeGroup returns a group holding only [t].
+func typeGroup(t *Type) *Group {
+	return &Group{Choices: [][]*Entry{{{Occur: Occurrence{1, 1}, Type: t}}}}
+}
+
+// parseGroup reads a group up to the punctuation [end], or up to the start
+// of the next rule if [end] is empty.
+func (p *parser) parseGroup(end string) (*Group, error) {
+	g := &Group{Choices: [][]*Entry{nil}}
+	for {
+		if p.peek(0).kind == tokenEOF || (end == "" && p.startsRule()) || (end != "" && p.is(end)) {
+			break
+		}
+		if p.accept("//") {
+			g.Choices = append(g.Choices, nil)
+			continue
+		}
+
+		e, err := p.parseEntry()
+		if err != nil {
+			return nil, err
+		}
+		last := len(g.Choices) - 1
+		g.Choices[last] = append(g.Choices[last], e)
+
+		if !p.accept(",") && !p.is("//") && !p.is(end) && !(end == "" && p.startsRule()) && p.peek(0).kind != tokenEOF {
+			// entries may be separated by whitespace alone
+			if !p.peek(0).space {
+				return nil, p.errorf("expected \",\"")
+			}
+		}
+	}
+
+	if end == "" && len(g.Choices) == 1 && len(g.Choices[0]) == 0 {
+		return nil, p.errorf("expected type or group")
+	}
+	return g, nil
+}
+
+func (p *parser) parseEntry() (*Entry, error) {
+	e := &Entry{Occur: p.parseOccurrence()}
+
+	t, next := p.peek(0), p.peek(1)
+	if next.kind == tokenPunct && next.text == ":" {
+		switch t.kind {
+		case tokenName:
+			p.pos += 2
+			e.Key = &MemberKey{
+				Type:     valueType(Value{Kind: ValueText, Text: t.text}),
+				Cut:      true,
+				Bareword: true,
+			}
+		case tokenNumber, tokenText, tokenBytes:
+			p.pos += 2
+			e.Key = &MemberKey{Type: valueType(t.value), Cut: true}
+		}
+		if e.Key != nil {
+			var err error
+			e.Type, err = p.parseType()
+			return e, err
+		}
+	}
+
+	var t2 *Type2
+	if p.accept("(") {
+		g, err := p.parseGroup(")")
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+
+		if !p.continuesType() {
+			e.Group = g
+			return e, nil
+		}
+
+		t := groupType(g)
+		if t == nil {
+			return nil, p.errorf("expected type")
+		}
+		t2 = &Type2{Kind: Type2Paren, Type: t}
+	} else {
+		var err error
+		if t2, err = p.parseType2(); err != nil {
+			return nil, err
+		}
+	}
+
+	t1, err := p.parseType1From(t2)
+	if err != nil {
+		return nil, err
+	}
+
+	cut := p.accept("^")
+	if p.accept("=>") {
+		e.Key = &MemberKey{Type: &Type{Choices: []*Type1{t1}}, Cut: cut}
+		e.Type, err = p.parseType()
+		return e, err
+	}
+	if cut {
+		return nil, p.errorf("expected \"=>\"")
+	}
+
+	e.Type, err = p.parseTypeFrom(t1)
+	return e, err
+}
+
+// continuesType reports whether the next token continues a type, so that a
+// preceding parenthesised group must have been a type.
+func (p *parser) continuesType() bool {
+	t := p.peek(0)
+	if t.kind == tokenControl {
+		return true
+	}
+	if t.kind != tokenPunct {
+		return false
+	}
+	switch t.text {
+	case "/", "=>", "^", RangeInclusive, RangeExclusive:
+		return true
+	}
+	return false
+}
+
+func valueType(v Value) *Type {
+	return &Type{Choices: []*Type1{{Type2: &Type2{Kind: Type2Value, Value: v}}}}
+}
+
+// parseOccurrence reads an optional occurrence indicator, returning exactly
+// once if there is none.
+func (p *parser) parseOccurrence() Occurrence {
+	switch {
+	case p.accept("?"):
+		return Occurrence{0, 1}
+	case p.accept("+"):
+		return Occurrence{1, math.MaxUint64}
+	}
+
+	o := Occurrence{0, math.MaxUint64}
+	t, next := p.peek(0), p.peek(1)
+	if t.kind == tokenNumber && t.value.Kind == ValueUint &&
+		next.kind == tokenPunct && next.text == "*" && !next.space {
+		o.Min = t.value.Int
+		p.pos++
+	} else if !p.is("*") {
+		return Occurrence{1, 1}
+	}
+	p.pos++
+
+	if t := p.peek(0); t.kind == tokenNumber && t.value.Kind == ValueUint && !t.space {
+		o.Max = t.value.Int
+		p.pos++
+	}
+	return o
+}
+
+func (p *parser) parseType() (*Type, error) {
+	t1, err := p.parseType1()
+	if err != nil {
+		return nil, err
+	}
+	return p.parseTypeFrom(t1)
+}
+
+func (p *parser) parseTypeFrom(t1 *Type1) (*Type, error) {
+	t := &Type{Choices: []*Type1{t1}}
+	for p.accept("/") {
+		t1, err := p.parseType1()
+		if err != nil {
+			return nil, err
+		}
+		t.Choices = append(t.Choices, t1)
+	}
+	return t, nil
+}
+
+func (p *parser) parseType1() (*Type1, error) {
+	t2, err := p.parseType2()
+	if err != nil {
+		return nil, err
+	}
+	return p.parseType1From(t2)
+}
+
+func (p *parser) parseType1From(t2 *Type2) (*Type1, error) {
+	t1 := &Type1{Type2: t2}
+
+	t := p.peek(0)
+	if t.kind == tokenControl || p.is(RangeInclusive) || p.is(RangeExclusive) {
+		p.pos++
+		t1.Op = t.text
+
+		var err error
+		if t1.Arg, err = p.parseType2(); err != nil {
+			return nil, err
+		}
+	}
+
+	return t1, nil
+}
+
+func (p *parser) parseType2() (*Type2, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber, tokenText, tokenBytes:
+		return &Type2{Kind: Type2Value, Value: t.value}, nil
+
+	case tokenName:
+		if p.is("<") {
+			return nil, p.errorf("generic arguments are not supported")
+		}
+		return &Type2{Kind: Type2Name, Name: t.text}, nil
+
+	case tokenHash:
+		return p.parseHash(t.text)
+
+	case tokenPunct:
+		switch t.text {
+		case "(":
+			inner, err := p.parseType()
+			if err != nil {
+				return nil, err
+			}
+			return &Type2{Kind: Type2Paren, Type: inner}, p.expect(")")
+
+		case "{", "[":
+			end, kind := "}", Type2Map
+			if t.text == "[" {
+				end, kind = "]", Type2Array
+			}
+			g, err := p.parseGroup(end)
+			if err != nil {
+				return nil, err
+			}
+			return &Type2{Kind: kind, Group: g}, p.expect(end)
+
+		case "~":
+			name := p.next()
+			if name.kind != tokenName {
+				p.pos--
+				return nil, p.errorf("expected name")
+			}
+			return &Type2{Kind: Type2Unwrap, Name: name.text}, nil
+
+		case "&":
+			if p.accept("(") {
+				g, err := p.parseGroup(")")
+				if err != nil {
+					return nil, err
+				}
+				return &Type2{Kind: Type2Enum, Group: g}, p.expect(")")
+			}
+			name := p.next()
+			if name.kind != tokenName {
+				p.pos--
+				return nil, p.errorf("expected name or group")
+			}
+			return &Type2{Kind: Type2Enum, Name: name.text}, nil
+		}
+	}
+
+	p.pos--
+	return nil, p.errorf("expected type")
+}
+
+// parseHash reads the rest of a #, #major.arg or #6.tag(type) type, [digits]
+// holds what followed the #.
+func (p *parser) parseHash(digits string) (*Type2, error) {
+	if digits == "" {
+		return &Type2{Kind: Type2Any}, nil
+	}
+
+	t := &Type2{Kind: Type2Major, Major: digits[0] - '0'}
+	if t.Major > 7 {
+		return nil, p.errorf("invalid major type")
+	}
+	if _, arg, ok := strings.Cut(digits, "."); ok {
+		a, err := strconv.ParseUint(arg, 10, 64)
+		if err != nil {
+			return nil, p.errorf("invalid argument")
+		}
+		t.Arg = &a
+	}
+
+	if t.Major == 6 && p.accept("(") {
+		inner, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		return &Type2{Kind: Type2Tag, Arg: t.Arg, Type: inner}, p.expect(")")
+	}
+
+	return t, nil
+}
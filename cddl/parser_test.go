@@ -0,0 +1,144 @@
+package cddl
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_Parse(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{
+			src:  "a = uint / -1 / 1.5 / \"x\\n\" / h'01 02' / b64'AQI' / 'ab'",
+			want: []string{`a = uint / -1 / 1.5 / "x\n" / h'0102' / h'0102' / h'6162'`},
+		},
+		{
+			src:  "a = {? b: int, * tstr => any, 1: bstr, tstr ^ => uint, 2*3 \"d\": 1..10}",
+			want: []string{`a = {? b: int, * tstr => any, 1: bstr, tstr ^ => uint, 2*3 "d": 1..10}`},
+		},
+		{
+			src:  "a = [+ b // * (c, d)] ; comment\nb = #6.32(tstr) c = #7.25 d = # e = #6.1",
+			want: []string{"a = [+ b // * (c, d)]", "b = #6.32(tstr)", "c = #7.25", "d = #", "e = #6.1"},
+		},
+		{
+			src:  "a = b b = (c: int, d: int) e = ~a / &b / &(x: 1, y: 2) / bstr .size (1..4)",
+			want: []string{"a = b", "b = (c: int, d: int)", `e = ~a / &b / &(x: 1, y: 2) / bstr .size (1..4)`},
+		},
+		{
+			src:  "a = 1 a /= 2 b = (c: 1) b //= (d: 2)",
+			want: []string{"a = 1 / 2", "b = (c: 1 // d: 2)"},
+		},
+		{
+			src:  "a = (int / tstr) b = ((int)) c = ((int) => tstr)",
+			want: []string{"a = int / tstr", "b = int", "c = ((int) => tstr)"},
+		},
+		{
+			src:  "uint = tstr",
+			want: []string{"uint = tstr"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			s, err := Parse(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, r := range s.Rules {
+				got = append(got, r.String())
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func Test_Parse_AST(t *testing.T) {
+	s, err := Parse("a = {? b: int, 2* int => [* tstr]}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := s.Rule("a")
+	if r == nil || r.Type() == nil || s.Rule("tstr") == nil {
+		t.Fatal("missing rules")
+	}
+
+	m := r.Type().Choices[0].Type2
+	if m.Kind != Type2Map {
+		t.Fatalf("want map, got %v", m)
+	}
+	entries := m.Group.Choices[0]
+	if len(entries) != 2 {
+		t.Fatalf("want 2 entries, got %d", len(entries))
+	}
+
+	b, c := entries[0], entries[1]
+	if b.Occur != (Occurrence{0, 1}) || !b.Key.Bareword || !b.Key.Cut || b.Key.Type.Choices[0].Type2.Value.Text != "b" {
+		t.Fatalf("unexpected entry %v", b)
+	}
+	if c.Occur != (Occurrence{2, math.MaxUint64}) || c.Key.Cut || c.Key.Type.Choices[0].Type2.Name != "int" {
+		t.Fatalf("unexpected entry %v", c)
+	}
+	if c.Type.Choices[0].Type2.Kind != Type2Array {
+		t.Fatalf("unexpected entry %v", c)
+	}
+}
+
+func Test_Parse_Errors(t *testing.T) {
+	tests := []struct {
+		src     string
+		wantErr string
+	}{
+		{src: "", wantErr: "cddl: 1:1: no rules"},
+		{src: "a = ", wantErr: "cddl: 1:5: expected type or group"},
+		{src: "a = [int", wantErr: `cddl: 1:9: expected "]"`},
+		{src: "a = int\nb = ", wantErr: "cddl: 2:5: expected type or group"},
+		{src: "a = b", wantErr: "cddl: rule a: undefined rule b"},
+		{src: "a = 1 a = 2", wantErr: "cddl: 1:7: rule a is already defined"},
+		{src: "a<t> = t", wantErr: "cddl: 1:2: generic parameters are not supported"},
+		{src: "a = b<int> b = 1", wantErr: "cddl: 1:6: generic arguments are not supported"},
+		{src: "a = tstr .regexp 1", wantErr: "cddl: rule a: invalid regexp 1"},
+		{src: "a = tstr .regexp \"(\"", wantErr: `cddl: rule a: invalid regexp "("`},
+		{src: "a = tstr .foo 1", wantErr: "cddl: rule a: unsupported control operator .foo"},
+		{src: "a = 1..tstr", wantErr: "cddl: rule a: invalid range 1..tstr"},
+		{src: "a = 1..2.5", wantErr: "cddl: rule a: invalid range 1..2.5"},
+		{src: "a = \"x", wantErr: "cddl: 1:7: unterminated string"},
+		{src: "a = h'0'", wantErr: "cddl: 1:9: invalid hex string"},
+		{src: "a = \"\\q\"", wantErr: "cddl: 1:8: invalid escape"},
+		{src: "a = #8", wantErr: "cddl: 1:7: invalid major type"},
+		{src: "a = (b: int) => int", wantErr: "cddl: 1:14: expected type"},
+		{src: "a = 1 % 2", wantErr: "cddl: 1:7: unexpected '%'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := Parse(tt.src)
+			if !errors.Is(err, ErrSyntax) {
+				t.Fatalf("want %v, got %v", ErrSyntax, err)
+			}
+			if err.Error() != tt.wantErr {
+				t.Fatalf("want %q, got %q", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func Test_Parse_Prelude(t *testing.T) {
+	s, err := Parse(preludeSource + "\nroot = any")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(s.Rules[0].String(), "any = #") {
+		t.Fatalf("unexpected first rule %v", s.Rules[0])
+	}
+}
@@ -0,0 +1,367 @@
+package cddl
+
+import (
+	"io"
+	"math"
+	"slices"
+	"strconv"
+
+	cbor "github.com/alex-richards/tiny-cbor"
+)
+
+// The stream functions match an item as it is read, without holding it in
+// memory, where that needs no backtracking: items matched by their header
+// alone, such as bstr or any, which are read over; tags; arrays whose group
+// has no choices and a fixed number of each entry but the last; and maps
+// whose group has no choices and only literal keys. Anything else is read
+// into memory, and matched as before.
+
+// streamRule matches [r] against the next item from [in], nested [depth]
+// items deep.
+func (v *validator) streamRule(r *Rule, in *cbor.Decoder, path []string, depth int) (bool, error) {
+	rt := r.Type()
+	if r.prelude && rt != nil {
+		if ok, err := v.skip(rt, in, depth); ok || err != nil {
+			return ok, err
+		}
+	}
+	if rt == nil || r.prelude || v.ruleDepth >= maxRuleDepth {
+		it, err := readItem(in, depth, v.maxDepth)
+		if err != nil {
+			return false, err
+		}
+		return v.matchRule(r, it, path), nil
+	}
+
+	rule := v.rule
+	v.rule = r.Name
+	v.ruleDepth++
+	defer func() {
+		v.rule = rule
+		v.ruleDepth--
+	}()
+
+	return v.streamType(rt, in, path, depth)
+}
+
+// streamType matches [t] against the next item from [in], nested [depth]
+// items deep.
+func (v *validator) streamType(t *Type, in *cbor.Decoder, path []string, depth int) (bool, error) {
+	if depth > v.maxDepth {
+		return false, cbor.ErrTooLarge
+	}
+	if ok, err := v.skip(t, in, depth); ok || err != nil {
+		return ok, err
+	}
+	if len(t.Choices) != 1 || t.Choices[0].Op != "" {
+		return v.readType(t, in, path, depth)
+	}
+
+	t2 := t.Choices[0].Type2
+	switch t2.Kind {
+	case Type2Name:
+		return v.streamRule(v.schema.rules[t2.Name], in, path, depth)
+
+	case Type2Paren:
+		return v.streamType(t2.Type, in, path, depth)
+	}
+
+	majorType, arg, value, err := peek(in, depth)
+	if err != nil {
+		return false, err
+	}
+
+	switch {
+	case t2.Kind == Type2Tag && majorType == cbor.MajorTypeTagged && (t2.Arg == nil || value == *t2.Arg):
+		if err = skipHeader(in, arg); err != nil {
+			return false, err
+		}
+		return v.streamType(t2.Type, in, path, depth+1)
+
+	case t2.Kind == Type2Array && majorType == cbor.MajorTypeArray && v.streamsArray(t2.Group):
+		return v.streamArray(t2.Group, in, path, depth)
+
+	case t2.Kind == Type2Map && majorType == cbor.MajorTypeMap && streamsMap(t2.Group):
+		return v.streamMap(t2.Group, in, path, depth)
+
+	default:
+		return v.readType(t, in, path, depth)
+	}
+}
+
+// readType reads the next item from [in] into memory, and matches [t]
+// against it.
+func (v *validator) readType(t *Type, in *cbor.Decoder, path []string, depth int) (bool, error) {
+	it, err := readItem(in, depth, v.maxDepth)
+	if err != nil {
+		return false, err
+	}
+	return v.matchType(t, it, path), nil
+}
+
+// skip reads over the next item from [in] if its header alone matches [t],
+// returning false, having read nothing, otherwise.
+func (v *validator) skip(t *Type, in *cbor.Decoder, depth int) (bool, error) {
+	if len(t.Choices) != 1 || t.Choices[0].Op != "" {
+		return false, nil
+	}
+
+	t2 := t.Choices[0].Type2
+	switch t2.Kind {
+	case Type2Any:
+	case Type2Major:
+		majorType, _, _, err := peek(in, depth)
+		if err != nil {
+			return false, err
+		}
+		if t2.Arg != nil || majorType != cbor.MajorType(t2.Major<<5) {
+			return false, nil
+		}
+	default:
+		return false, nil
+	}
+
+	return true, skipItem(in, depth, v.maxDepth)
+}
+
+// streamsArray reports whether an array matching [g] can be streamed: [g]
+// has no choices, or nested groups, and a fixed number of each entry but the
+// last.
+func (v *validator) streamsArray(g *Group) bool {
+	if len(g.Choices) != 1 {
+		return false
+	}
+
+	entries := g.Choices[0]
+	for i, e := range entries {
+		if inner, _ := v.entryGroup(e); inner != nil {
+			return false
+		}
+		if i < len(entries)-1 && e.Occur.Min != e.Occur.Max {
+			return false
+		}
+	}
+	return true
+}
+
+// streamArray matches [g], as checked by streamsArray, against the array
+// next in [in].
+func (v *validator) streamArray(g *Group, in *cbor.Decoder, path []string, depth int) (bool, error) {
+	items, err := readContainer(in)
+	if err != nil {
+		return false, err
+	}
+
+	var pos uint64
+	for _, e := range g.Choices[0] {
+		for count := uint64(0); count < e.Occur.Max; count++ {
+			more, err := items.next()
+			if err != nil {
+				return false, err
+			}
+			if !more {
+				if count < e.Occur.Min {
+					v.fail(append(path, strconv.FormatUint(pos, 10)), "missing "+e.Type.String())
+					return false, nil
+				}
+				break
+			}
+
+			ok, err := v.streamType(e.Type, in, append(path, strconv.FormatUint(pos, 10)), depth+1)
+			if err != nil {
+				return false, err
+			}
+			if !ok {
+				return false, items.skip(depth+1, v.maxDepth)
+			}
+			pos++
+		}
+	}
+
+	more, err := items.next()
+	if err != nil || !more {
+		return err == nil, err
+	}
+	it, err := readItem(in, depth+1, v.maxDepth)
+	if err != nil {
+		return false, err
+	}
+	v.fail(append(path, strconv.FormatUint(pos, 10)), "unexpected "+describe(it))
+	return false, items.skip(depth+1, v.maxDepth)
+}
+
+// streamsMap reports whether a map matching [g] can be streamed: [g] has no
+// choices, and each entry a different literal key.
+func streamsMap(g *Group) bool {
+	if len(g.Choices) != 1 {
+		return false
+	}
+
+	keys := make(map[string]bool)
+	for _, e := range g.Choices[0] {
+		if e.Key == nil || len(e.Key.Type.Choices) != 1 {
+			return false
+		}
+		key := e.Key.Type.Choices[0]
+		if key.Op != "" || key.Type2.Kind != Type2Value || keys[key.Type2.Value.String()] {
+			return false
+		}
+		keys[key.Type2.Value.String()] = true
+	}
+	return true
+}
+
+// streamMap matches [g], as checked by streamsMap, against the map next in
+// [in]. As when matching in memory, a missing entry is reported in place of
+// an unexpected key.
+func (v *validator) streamMap(g *Group, in *cbor.Decoder, path []string, depth int) (bool, error) {
+	items, err := readContainer(in)
+	if err != nil {
+		return false, err
+	}
+
+	entries := g.Choices[0]
+	counts := make([]uint64, len(entries))
+	var unexpected []string
+	for {
+		more, err := items.next()
+		if err != nil {
+			return false, err
+		}
+		if !more {
+			break
+		}
+
+		key, err := readItem(in, depth+1, v.maxDepth)
+		if err != nil {
+			return false, err
+		}
+		// a map ending after a key fails as not well formed
+		if _, err = items.next(); err != nil {
+			return false, err
+		}
+
+		keyPath := append(slices.Clip(path), keySegment(key))
+		i := slices.IndexFunc(entries, func(e *Entry) bool {
+			return matchValue(e.Key.Type.Choices[0].Type2.Value, key)
+		})
+		if i < 0 || counts[i] == entries[i].Occur.Max {
+			if unexpected == nil {
+				unexpected = keyPath
+			}
+			if err = skipItem(in, depth+1, v.maxDepth); err != nil {
+				return false, err
+			}
+			continue
+		}
+		counts[i]++
+
+		ok, err := v.streamType(entries[i].Type, in, keyPath, depth+1)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return false, items.skip(depth+1, v.maxDepth)
+		}
+	}
+
+	for i, e := range entries {
+		if counts[i] < e.Occur.Min {
+			v.fail(path, "missing "+e.String())
+			return false, nil
+		}
+	}
+	if unexpected != nil {
+		v.fail(unexpected, "unexpected key")
+		return false, nil
+	}
+	return true, nil
+}
+
+// containerItems reads the items of an array, or the keys and values of a
+// map, in turn.
+type containerItems struct {
+	in         *cbor.Decoder
+	indefinite bool
+	left       uint64 // items left, if not indefinite
+	pairs      bool   // the items are the keys and values of a map
+	read       uint64
+	done       bool
+}
+
+// readContainer reads the header of the array or map next in [in].
+func readContainer(in *cbor.Decoder) (*containerItems, error) {
+	majorType, arg, value, err := in.PeekType()
+	if err != nil {
+		return nil, err
+	}
+
+	items := &containerItems{
+		in:         in,
+		indefinite: arg == cbor.ArgIndefinite,
+		left:       value,
+		pairs:      majorType == cbor.MajorTypeMap,
+	}
+	if items.pairs && !items.indefinite {
+		if value > math.MaxUint64/2 {
+			return nil, cbor.ErrOverflow
+		}
+		items.left *= 2
+	}
+	return items, skipHeader(in, arg)
+}
+
+// next reports whether there is another item, reading the break after the
+// last of an indefinite length container.
+func (c *containerItems) next() (bool, error) {
+	if c.done {
+		return false, nil
+	}
+
+	if !c.indefinite {
+		if c.left == 0 {
+			c.done = true
+			return false, nil
+		}
+		c.left--
+		c.read++
+		return true, nil
+	}
+
+	end, err := readBreak(c.in)
+	if err != nil {
+		return false, err
+	}
+	if end {
+		c.done = true
+		if c.pairs && c.read%2 != 0 {
+			return false, cbor.ErrNotWellFormed
+		}
+		return false, nil
+	}
+	c.read++
+	return true, nil
+}
+
+// skip reads over the items left, nested [depth] items deep.
+func (c *containerItems) skip(depth, maxDepth int) error {
+	for {
+		more, err := c.next()
+		if err != nil || !more {
+			return err
+		}
+		if err = skipItem(c.in, depth, maxDepth); err != nil {
+			return err
+		}
+	}
+}
+
+// peek returns the header of the next item from [in], nested [depth] items
+// deep, without reading it.
+func peek(in *cbor.Decoder, depth int) (cbor.MajorType, cbor.Arg, uint64, error) {
+	majorType, arg, value, err := in.PeekType()
+	if err == io.EOF && depth > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return majorType, arg, value, err
+}
@@ -0,0 +1,653 @@
+package cddl
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	cbor "github.com/alex-richards/tiny-cbor"
+)
+
+// maxRuleDepth bounds the nesting of rule references while matching, so a
+// rule that refers to itself without consuming anything can't recurse
+// forever.
+const maxRuleDepth = 256
+
+// ValidateOptions limits the items read by [ValidateOptions.Validate]. The
+// zero value matches the behaviour of [Schema.Validate].
+type ValidateOptions struct {
+	// MaxSize is the largest item, in encoded bytes, that is read; larger
+	// items fail with cbor.ErrTooLarge. Zero is no limit. This bounds the
+	// memory held for the parts of an item that are not streamed.
+	MaxSize uint64
+
+	// MaxDepth is the deepest nesting of arrays, maps and tags that is read;
+	// deeper items fail with cbor.ErrTooLarge. Zero is 1024.
+	MaxDepth int
+}
+
+// Validate reads the next item from [in] and checks it against the first
+// rule of the schema, returning a [*ValidationError] if it does not match.
+//
+// Parts of the item are matched as they are read, without being held in
+// memory, where the schema allows that without backtracking: strings and
+// other items matched by their type alone, such as bstr or any; tags; arrays
+// of a fixed sequence of types, the last of which may repeat, such as [* T];
+// and maps whose keys are all literals. Any other part, such as one matched
+// against a choice, is read into memory before it is matched, as the choice
+// may need to revisit it. Use [ValidateOptions.Validate] to limit the size
+// of the items read from untrusted input.
+func (s *Schema) Validate(in io.Reader) error {
+	return ValidateOptions{}.Validate(s, in)
+}
+
+// ValidateRule reads the next item from [in] as [Schema.Validate], checking
+// it against the rule [name].
+func (s *Schema) ValidateRule(in io.Reader, name string) error {
+	return ValidateOptions{}.ValidateRule(s, in, name)
+}
+
+// Validate reads the next item from [in], within the limits of [o], and
+// checks it against the first rule of [s], as [Schema.Validate].
+func (o ValidateOptions) Validate(s *Schema, in io.Reader) error {
+	return o.ValidateRule(s, in, s.Rules[0].Name)
+}
+
+// ValidateRule reads the next item from [in], within the limits of [o], and
+// checks it against the rule [name] of [s], as [Schema.ValidateRule].
+func (o ValidateOptions) ValidateRule(s *Schema, in io.Reader, name string) error {
+	r := s.rules[name]
+	if r == nil {
+		return &ValidationError{Rule: name, Message: "undefined rule"}
+	}
+
+	if o.MaxSize > 0 {
+		in = &limitReader{r: in, n: o.MaxSize}
+	}
+	d, ok := in.(*cbor.Decoder)
+	if !ok {
+		d = cbor.NewDecoder(in)
+	}
+
+	v := validator{schema: s, maxDepth: o.MaxDepth}
+	if v.maxDepth == 0 {
+		v.maxDepth = defaultMaxDepth
+	}
+	ok, err := v.streamRule(r, d, nil, 0)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return v.err
+	}
+	return nil
+}
+
+type validator struct {
+	schema   *Schema
+	maxDepth int // of the items read, including embedded items
+
+	rule      string // innermost rule being matched
+	ruleDepth int
+	quiet     int // failures are not recorded while probing
+
+	err      *ValidationError // the most relevant failure so far
+	errPath  []string
+	errScore int
+	errType  bool // err is a type mismatch
+}
+
+// fail records a failure at [path] if it is deeper than any so far.
+func (v *validator) fail(path []string, message string) {
+	v.record(path, message, 2*len(path)+1, false)
+}
+
+// failType records a failure of [it] to match the type described by
+// [expected] at [path]. It replaces a type mismatch at the same depth, so a
+// choice is reported as a whole rather than by its last alternative.
+func (v *validator) failType(path []string, expected string, it *item) {
+	v.record(path, "expected "+expected+", got "+describe(it), 2*len(path)+1, true)
+}
+
+// failLiteral records a failure of [it] to match a literal value. This ranks
+// below failures of the containing item, as literals usually tell choices
+// apart, and the failure within the choice that applies is more useful.
+func (v *validator) failLiteral(path []string, value Value, it *item) {
+	v.record(path, "expected "+value.String()+", got "+describe(it), 2*len(path)-2, true)
+}
+
+// record keeps the failure with the highest score, or on a tie the one
+// further along the same array, or a type mismatch replacing another.
+func (v *validator) record(path []string, message string, score int, typeMismatch bool) {
+	if v.quiet > 0 {
+		return
+	}
+	if v.err != nil {
+		if score < v.errScore {
+			return
+		}
+		if score == v.errScore && !further(path, v.errPath) && !(typeMismatch && v.errType && !further(v.errPath, path)) {
+			return
+		}
+	}
+
+	v.err = &ValidationError{Path: formatPath(path), Rule: v.rule, Message: message}
+	v.errPath = append(v.errPath[:0], path...)
+	v.errScore = score
+	v.errType = typeMismatch
+}
+
+// further reports whether [a] is a later item than [b] in the same array.
+func further(a, b []string) bool {
+	if len(a) != len(b) || len(a) == 0 {
+		return false
+	}
+	for i := range len(a) - 1 {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	i, err1 := strconv.ParseUint(a[len(a)-1], 10, 64)
+	j, err2 := strconv.ParseUint(b[len(b)-1], 10, 64)
+	return err1 == nil && err2 == nil && i > j
+}
+
+// probe matches without recording failures.
+func (v *validator) probe(f func() bool) bool {
+	v.quiet++
+	defer func() { v.quiet-- }()
+	return f()
+}
+
+func (v *validator) matchRule(r *Rule, it *item, path []string) bool {
+	if v.ruleDepth >= maxRuleDepth {
+		v.fail(path, "rule nesting too deep")
+		return false
+	}
+
+	rule := v.rule
+	if !r.prelude {
+		v.rule = r.Name
+	}
+	v.ruleDepth++
+	defer func() {
+		v.rule = rule
+		v.ruleDepth--
+	}()
+
+	t := r.Type()
+	if t == nil {
+		v.fail(path, "group "+r.Name+" used as a type")
+		return false
+	}
+	if !v.matchType(t, it, path) {
+		if r.prelude {
+			v.failType(path, r.Name, it)
+		}
+		return false
+	}
+	return true
+}
+
+func (v *validator) matchType(t *Type, it *item, path []string) bool {
+	for _, t1 := range t.Choices {
+		if v.matchType1(t1, it, path) {
+			return true
+		}
+	}
+
+	if len(t.Choices) > 1 {
+		v.failType(path, t.String(), it)
+	}
+	return false
+}
+
+func (v *validator) matchType1(t *Type1, it *item, path []string) bool {
+	switch t.Op {
+	case "":
+		return v.matchType2(t.Type2, it, path)
+	case RangeInclusive, RangeExclusive:
+		return v.matchRange(t, it, path)
+	default:
+		return v.matchType2(t.Type2, it, path) && v.matchControl(t, it, path)
+	}
+}
+
+func (v *validator) matchType2(t *Type2, it *item, path []string) bool {
+	switch t.Kind {
+	case Type2Value:
+		if !matchValue(t.Value, it) {
+			v.failLiteral(path, t.Value, it)
+			return false
+		}
+		return true
+
+	case Type2Name:
+		return v.matchRule(v.schema.rules[t.Name], it, path)
+
+	case Type2Paren:
+		return v.matchType(t.Type, it, path)
+
+	case Type2Map:
+		if it.majorType != cbor.MajorTypeMap {
+			v.failType(path, "map", it)
+			return false
+		}
+		return v.matchMap(t.Group, it, path)
+
+	case Type2Array:
+		if it.majorType != cbor.MajorTypeArray {
+			v.failType(path, "array", it)
+			return false
+		}
+		return v.matchArray(t.Group, it, path)
+
+	case Type2Unwrap:
+		r := v.schema.rules[t.Name]
+		rt := r.Type()
+		if rt != nil && len(rt.Choices) == 1 && rt.Choices[0].Op == "" && rt.Choices[0].Type2.Kind == Type2Tag {
+			return v.matchType(rt.Choices[0].Type2.Type, it, path)
+		}
+		v.fail(path, "~"+t.Name+" used as a type")
+		return false
+
+	case Type2Enum:
+		g := t.Group
+		if g == nil {
+			g = v.schema.rules[t.Name].Group
+		}
+		for _, value := range v.enumValues(g, nil, 0) {
+			if v.probe(func() bool { return v.matchType(value, it, path) }) {
+				return true
+			}
+		}
+		v.failType(path, t.String(), it)
+		return false
+
+	case Type2Tag:
+		if it.majorType != cbor.MajorTypeTagged || (t.Arg != nil && it.value != *t.Arg) {
+			v.failType(path, t.String(), it)
+			return false
+		}
+		return v.matchType(t.Type, it.items[0], path)
+
+	case Type2Major:
+		if !matchMajor(t, it) {
+			v.failType(path, t.String(), it)
+			return false
+		}
+		return true
+
+	default: // Type2Any
+		return true
+	}
+}
+
+// enumValues returns the types of the entries in [g], following nested and
+// named groups.
+func (v *validator) enumValues(g *Group, values []*Type, depth int) []*Type {
+	if depth > maxRuleDepth {
+		return values
+	}
+	for _, choice := range g.Choices {
+		for _, e := range choice {
+			if inner, _ := v.entryGroup(e); inner != nil {
+				values = v.enumValues(inner, values, depth+1)
+			} else {
+				values = append(values, e.Type)
+			}
+		}
+	}
+	return values
+}
+
+func matchValue(value Value, it *item) bool {
+	switch value.Kind {
+	case ValueUint:
+		return it.majorType == cbor.MajorTypeUInt && it.value == value.Int
+	case ValueNint:
+		return it.majorType == cbor.MajorTypeNInt && it.value == value.Int
+	case ValueFloat:
+		return it.isFloat() && it.float() == value.Float
+	case ValueText:
+		return it.majorType == cbor.MajorTypeTstr && string(it.bytes) == value.Text
+	default:
+		return it.majorType == cbor.MajorTypeBstr && bytes.Equal(it.bytes, value.Bytes)
+	}
+}
+
+func matchMajor(t *Type2, it *item) bool {
+	if it.majorType != cbor.MajorType(t.Major<<5) {
+		return false
+	}
+	if t.Arg == nil {
+		return true
+	}
+
+	switch cbor.MajorType(t.Major << 5) {
+	case cbor.MajorTypeSimpleFloat:
+		switch *t.Arg {
+		case uint64(cbor.SimpleFloat16), uint64(cbor.SimpleFloat32), uint64(cbor.SimpleFloat64):
+			return uint64(it.arg) == *t.Arg
+		}
+		simple, ok := it.simple()
+		return ok && simple == *t.Arg
+	case cbor.MajorTypeBstr, cbor.MajorTypeTstr:
+		return uint64(len(it.bytes)) == *t.Arg
+	case cbor.MajorTypeArray:
+		return uint64(len(it.items)) == *t.Arg
+	case cbor.MajorTypeMap:
+		return uint64(len(it.items)/2) == *t.Arg
+	default:
+		return it.value == *t.Arg
+	}
+}
+
+// entryGroup returns the group an entry stands for, if it is a nested group,
+// a reference to a group rule, or an unwrapped map or array, along with the
+// name of any rule.
+func (v *validator) entryGroup(e *Entry) (*Group, *Rule) {
+	if e.Group != nil {
+		return e.Group, nil
+	}
+	if e.Key != nil || len(e.Type.Choices) != 1 || e.Type.Choices[0].Op != "" {
+		return nil, nil
+	}
+
+	t := e.Type.Choices[0].Type2
+	switch t.Kind {
+	case Type2Name:
+		r := v.schema.rules[t.Name]
+		if r.Type() == nil {
+			return r.Group, r
+		}
+	case Type2Unwrap:
+		r := v.schema.rules[t.Name]
+		rt := r.Type()
+		if rt != nil && len(rt.Choices) == 1 && rt.Choices[0].Op == "" {
+			if t2 := rt.Choices[0].Type2; t2.Kind == Type2Map || t2.Kind == Type2Array {
+				return t2.Group, r
+			}
+		}
+	}
+	return nil, nil
+}
+
+// withRule calls [match] with [r], if any, as the rule being matched, and
+// the rule from before around calls to the continuation [k].
+func withRule[T any](v *validator, r *Rule, path []string, k func(T) bool, match func(func(T) bool) bool) bool {
+	if r == nil {
+		return match(k)
+	}
+	if v.ruleDepth >= maxRuleDepth {
+		v.fail(path, "rule nesting too deep")
+		return false
+	}
+
+	outer, outerDepth := v.rule, v.ruleDepth
+	inner := outer
+	if !r.prelude {
+		inner = r.Name
+	}
+
+	v.rule, v.ruleDepth = inner, outerDepth+1
+	defer func() { v.rule, v.ruleDepth = outer, outerDepth }()
+
+	return match(func(next T) bool {
+		v.rule, v.ruleDepth = outer, outerDepth
+		defer func() { v.rule, v.ruleDepth = inner, outerDepth+1 }()
+		return k(next)
+	})
+}
+
+func (v *validator) matchArray(g *Group, it *item, path []string) bool {
+	items := it.items
+	return v.matchSequence(g, items, 0, path, func(pos int) bool {
+		if pos < len(items) {
+			v.fail(append(path, strconv.Itoa(pos)), "unexpected "+describe(items[pos]))
+			return false
+		}
+		return true
+	})
+}
+
+// matchSequence matches [g] against [items] from [pos], calling [k] with the
+// position after each way of matching until it returns true.
+func (v *validator) matchSequence(g *Group, items []*item, pos int, path []string, k func(int) bool) bool {
+	for _, choice := range g.Choices {
+		if v.matchEntries(choice, items, pos, path, k) {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *validator) matchEntries(entries []*Entry, items []*item, pos int, path []string, k func(int) bool) bool {
+	if len(entries) == 0 {
+		return k(pos)
+	}
+	return v.matchOccurrences(entries[0], 0, items, pos, path, func(next int) bool {
+		return v.matchEntries(entries[1:], items, next, path, k)
+	})
+}
+
+// matchOccurrences matches further occurrences of [e] after [count], as many
+// as possible first.
+func (v *validator) matchOccurrences(e *Entry, count uint64, items []*item, pos int, path []string, k func(int) bool) bool {
+	if count < e.Occur.Max {
+		matched := v.matchEntry(e, items, pos, path, count < e.Occur.Min, func(next int) bool {
+			// an occurrence matching nothing can only be needed to reach Min
+			if next == pos && count >= e.Occur.Min {
+				return false
+			}
+			return v.matchOccurrences(e, count+1, items, next, path, k)
+		})
+		if matched {
+			return true
+		}
+	}
+
+	return count >= e.Occur.Min && k(pos)
+}
+
+// matchEntry matches [e] against the items from [pos], reporting the item as
+// missing if [required] and there are no more.
+func (v *validator) matchEntry(e *Entry, items []*item, pos int, path []string, required bool, k func(int) bool) bool {
+	if g, r := v.entryGroup(e); g != nil {
+		return withRule(v, r, path, k, func(k func(int) bool) bool {
+			return v.matchSequence(g, items, pos, path, k)
+		})
+	}
+
+	if pos >= len(items) {
+		if required {
+			v.fail(append(path, strconv.Itoa(pos)), "missing "+e.Type.String())
+		}
+		return false
+	}
+	if !v.matchType(e.Type, items[pos], append(path, strconv.Itoa(pos))) {
+		return false
+	}
+	return k(pos + 1)
+}
+
+func (v *validator) matchMap(g *Group, it *item, path []string) bool {
+	items := it.items
+	used := make([]bool, len(items)/2)
+	return v.matchMapGroup(g, items, used, path, func(used []bool) bool {
+		for i, u := range used {
+			if !u {
+				v.fail(append(path, keySegment(items[2*i])), "unexpected key")
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// matchMapGroup matches [g] against the entries of a map not yet [used],
+// calling [k] with the entries used after each way of matching until it
+// returns true.
+func (v *validator) matchMapGroup(g *Group, items []*item, used []bool, path []string, k func([]bool) bool) bool {
+	for _, choice := range g.Choices {
+		if v.matchMapEntries(choice, items, used, path, k) {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *validator) matchMapEntries(entries []*Entry, items []*item, used []bool, path []string, k func([]bool) bool) bool {
+	if len(entries) == 0 {
+		return k(used)
+	}
+	return v.matchMapOccurrences(entries[0], 0, items, used, path, func(next []bool) bool {
+		return v.matchMapEntries(entries[1:], items, next, path, k)
+	})
+}
+
+func (v *validator) matchMapOccurrences(e *Entry, count uint64, items []*item, used []bool, path []string, k func([]bool) bool) bool {
+	if count < e.Occur.Max {
+		matched := v.matchMapEntry(e, items, used, path, count < e.Occur.Min, func(next []bool) bool {
+			if count >= e.Occur.Min && countUsed(next) == countUsed(used) {
+				return false
+			}
+			return v.matchMapOccurrences(e, count+1, items, next, path, k)
+		})
+		if matched {
+			return true
+		}
+	}
+
+	return count >= e.Occur.Min && k(used)
+}
+
+// matchMapEntry matches [e] against one entry of a map not yet [used],
+// reporting the entry as missing if [required] and no key matches.
+func (v *validator) matchMapEntry(e *Entry, items []*item, used []bool, path []string, required bool, k func([]bool) bool) bool {
+	if g, r := v.entryGroup(e); g != nil {
+		return withRule(v, r, path, k, func(k func([]bool) bool) bool {
+			return v.matchMapGroup(g, items, used, path, k)
+		})
+	}
+
+	if e.Key == nil {
+		v.fail(path, "map entry without a key: "+e.String())
+		return false
+	}
+
+	found := false
+	for i, u := range used {
+		if u {
+			continue
+		}
+
+		key, value := items[2*i], items[2*i+1]
+		if !v.probe(func() bool { return v.matchType(e.Key.Type, key, path) }) {
+			continue
+		}
+		found = true
+		if !v.matchType(e.Type, value, append(path, keySegment(key))) {
+			if e.Key.Cut {
+				return false
+			}
+			continue
+		}
+
+		next := append([]bool(nil), used...)
+		next[i] = true
+		return k(next)
+	}
+
+	if required && !found {
+		v.fail(path, "missing "+e.String())
+	}
+	return false
+}
+
+func countUsed(used []bool) int {
+	n := 0
+	for _, u := range used {
+		if u {
+			n++
+		}
+	}
+	return n
+}
+
+// formatPath joins path segments, escaping them as in [cbor.ParsePath].
+func formatPath(path []string) string {
+	var b strings.Builder
+	for _, segment := range path {
+		b.WriteByte('/')
+		b.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(segment))
+	}
+	return b.String()
+}
+
+// keySegment returns the path segment for a map key.
+func keySegment(key *item) string {
+	switch key.majorType {
+	case cbor.MajorTypeUInt:
+		return strconv.FormatUint(key.value, 10)
+	case cbor.MajorTypeNInt:
+		return formatNint(key.value)
+	case cbor.MajorTypeTstr:
+		return string(key.bytes)
+	case cbor.MajorTypeBstr:
+		return "h'" + hex.EncodeToString(key.bytes) + "'"
+	default:
+		return describe(key)
+	}
+}
+
+// describe returns a short description of an item for error messages.
+func describe(it *item) string {
+	switch it.majorType {
+	case cbor.MajorTypeUInt:
+		return strconv.FormatUint(it.value, 10)
+	case cbor.MajorTypeNInt:
+		return formatNint(it.value)
+	case cbor.MajorTypeBstr:
+		if len(it.bytes) > 16 {
+			return "bstr of " + count(len(it.bytes), "byte", "bytes")
+		}
+		return "h'" + hex.EncodeToString(it.bytes) + "'"
+	case cbor.MajorTypeTstr:
+		if len(it.bytes) > 32 || !utf8.Valid(it.bytes) {
+			return "tstr of " + count(len(it.bytes), "byte", "bytes")
+		}
+		return strconv.Quote(string(it.bytes))
+	case cbor.MajorTypeArray:
+		return "array of " + count(len(it.items), "item", "items")
+	case cbor.MajorTypeMap:
+		return "map of " + count(len(it.items)/2, "entry", "entries")
+	case cbor.MajorTypeTagged:
+		return "tag " + strconv.FormatUint(it.value, 10)
+	}
+
+	if it.isFloat() {
+		return Value{Kind: ValueFloat, Float: it.float()}.String()
+	}
+	switch it.value {
+	case uint64(cbor.SimpleFalse):
+		return "false"
+	case uint64(cbor.SimpleTrue):
+		return "true"
+	case uint64(cbor.SimpleNull):
+		return "null"
+	case uint64(cbor.SimpleUndefined):
+		return "undefined"
+	}
+	return "simple(" + strconv.FormatUint(it.value, 10) + ")"
+}
+
+func count(n int, singular, plural string) string {
+	if n == 1 {
+		return "1 " + singular
+	}
+	return strconv.Itoa(n) + " " + plural
+}
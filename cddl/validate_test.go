@@ -0,0 +1,348 @@
+package cddl
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+
+	cbor "github.com/alex-richards/tiny-cbor"
+)
+
+const messageSchema = `
+message = {
+  type: "ping" / "pong",
+  id: uint .size 4,
+  ? payload: bstr .cbor point,
+  ? tags: [* label],
+  * int => any,
+}
+point = [x: int, y: int]
+label = tstr .regexp "[a-z]+"
+`
+
+const listSchema = `
+list = [2*3 uint, ? tstr, + bool]
+`
+
+const shapeSchema = `
+shape = circle / rect
+circle = { kind: "circle", common, r: uint }
+rect = { kind: "rect", common, (w: uint, h: uint // side: uint) }
+common = ( ? name: tstr )
+`
+
+const recordSchema = `
+record = {
+  name: tstr,
+  data: bstr,
+  ? parts: [* part],
+  ? sig: #6.18(bstr),
+}
+part = [uint, bstr]
+`
+
+const controlSchema = `
+control = [
+  flags: uint .bits permission,
+  mask: bstr .bits (0..9),
+  small: -10..max-small,
+  ratio: 0.0...1.0,
+  port: uint .lt 65536,
+  level: (int .ge -1) .default 0,
+  colour: &colours,
+  ~pair,
+  url: ~uri,
+  when: tdate / time,
+  half: float16,
+  seq: bstr .cborseq [* uint],
+]
+permission = &(read: 0, write: 1, execute: 2)
+colours = (red: 1, green: 2, blue: 3)
+pair = [tstr, tstr]
+max-small = 10
+`
+
+func Test_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   string
+		encoded  string
+		wantPath string
+		wantRule string
+	}{
+		{name: "ping", schema: messageSchema, encoded: "a264747970656470696e6762696401"},
+		{name: "full", schema: messageSchema, encoded: "a5647479706564706f6e6762696401677061796c6f616443820121647461677382616162626305f6"},
+		{name: "choice", schema: messageSchema, encoded: "a264747970656470756e6762696401", wantPath: "/type", wantRule: "message"},
+		{name: "size", schema: messageSchema, encoded: "a264747970656470696e676269641b0000000100000000", wantPath: "/id", wantRule: "message"},
+		{name: "missing", schema: messageSchema, encoded: "a164747970656470696e67", wantPath: "/", wantRule: "message"},
+		{name: "unexpected key", schema: messageSchema, encoded: "a364747970656470696e6762696401617801", wantPath: "/x", wantRule: "message"},
+		{name: "embedded", schema: messageSchema, encoded: "a364747970656470696e6762696401677061796c6f61644482016161", wantPath: "/payload/1", wantRule: "point"},
+		{name: "embedded truncated", schema: messageSchema, encoded: "a364747970656470696e6762696401677061796c6f6164428201", wantPath: "/payload", wantRule: "message"},
+		{name: "regexp", schema: messageSchema, encoded: "a364747970656470696e676269640164746167738261616142", wantPath: "/tags/1", wantRule: "label"},
+
+		{name: "min", schema: listSchema, encoded: "830102f5"},
+		{name: "max", schema: listSchema, encoded: "860102036161f4f5"},
+		{name: "too few", schema: listSchema, encoded: "8201f5", wantPath: "/1", wantRule: "list"},
+		{name: "too many", schema: listSchema, encoded: "8501020304f5", wantPath: "/3", wantRule: "list"},
+		{name: "missing item", schema: listSchema, encoded: "820102", wantPath: "/2", wantRule: "list"},
+		{name: "not an array", schema: listSchema, encoded: "a0", wantPath: "/", wantRule: "list"},
+
+		{name: "circle", schema: shapeSchema, encoded: "a2646b696e6466636972636c65617201"},
+		{name: "side", schema: shapeSchema, encoded: "a3646b696e646472656374646e616d656161647369646502"},
+		{name: "rect", schema: shapeSchema, encoded: "a3646b696e646472656374617701616802"},
+		{name: "group choice", schema: shapeSchema, encoded: "a2646b696e646472656374617701", wantPath: "/", wantRule: "rect"},
+		{name: "group value", schema: shapeSchema, encoded: "a3646b696e64647265637461770161686178", wantPath: "/h", wantRule: "rect"},
+		{name: "discriminator", schema: shapeSchema, encoded: "a1646b696e6463747269", wantPath: "/", wantRule: "shape"},
+		{name: "circle value", schema: shapeSchema, encoded: "a2646b696e6466636972636c6561726178", wantPath: "/r", wantRule: "circle"},
+
+		{name: "record", schema: recordSchema, encoded: "a2646e616d65616164646174614101"},
+		{name: "parts", schema: recordSchema, encoded: "a4646e616d656161646461746140657061727473828201410282024063736967d24103"},
+		{name: "part item", schema: recordSchema, encoded: "a3646e616d6561616464617461406570617274738182016178", wantPath: "/parts/0/1", wantRule: "part"},
+		{name: "part missing", schema: recordSchema, encoded: "a3646e616d656161646461746140657061727473818101", wantPath: "/parts/0/1", wantRule: "part"},
+		{name: "part extra", schema: recordSchema, encoded: "a3646e616d6561616464617461406570617274738183014003", wantPath: "/parts/0/2", wantRule: "part"},
+		{name: "record missing", schema: recordSchema, encoded: "a2646e616d656161617801", wantPath: "/", wantRule: "record"},
+		{name: "record unexpected", schema: recordSchema, encoded: "a3646e616d656161646461746140617801", wantPath: "/x", wantRule: "record"},
+		{name: "record value", schema: recordSchema, encoded: "a2646e616d65616164646174616178", wantPath: "/data", wantRule: "record"},
+		{name: "tag content", schema: recordSchema, encoded: "a3646e616d65616164646174614063736967d201", wantPath: "/sig", wantRule: "record"},
+		{name: "tag number", schema: recordSchema, encoded: "a3646e616d65616164646174614063736967d140", wantPath: "/sig", wantRule: "record"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.schema)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = s.Validate(bytes.NewReader(decodeHex(t, tt.encoded)))
+			if tt.wantPath == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			var verr *ValidationError
+			if !errors.As(err, &verr) || !errors.Is(err, ErrInvalid) {
+				t.Fatalf("want validation error, got %v", err)
+			}
+			path := verr.Path
+			if path == "" {
+				path = "/"
+			}
+			if path != tt.wantPath || verr.Rule != tt.wantRule {
+				t.Fatalf("want %s in %s, got %v", tt.wantPath, tt.wantRule, err)
+			}
+		})
+	}
+}
+
+func Test_Validate_Controls(t *testing.T) {
+	s, err := Parse(controlSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items := map[string]string{
+		"flags":  "05",
+		"mask":   "420102",
+		"small":  "29",
+		"ratio":  "f93800",
+		"port":   "191950",
+		"level":  "20",
+		"colour": "02",
+		"pair":   "61616162",
+		"url":    "68687474703a2f2f78",
+		"when":   "c10a",
+		"half":   "f93e00",
+		"seq":    "4301020a",
+	}
+	order := []string{"flags", "mask", "small", "ratio", "port", "level", "colour", "pair", "url", "when", "half", "seq"}
+	encode := func(replace, with string) []byte {
+		var b bytes.Buffer
+		b.Write([]byte{0x8d})
+		for _, name := range order {
+			item := items[name]
+			if name == replace {
+				item = with
+			}
+			b.Write(decodeHex(t, item))
+		}
+		return b.Bytes()
+	}
+
+	if err := s.Validate(bytes.NewReader(encode("", ""))); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		with     string
+		wantPath string
+	}{
+		{name: "flags", with: "08", wantPath: "/0"},
+		{name: "mask", with: "420004", wantPath: "/1"},
+		{name: "small", with: "0b", wantPath: "/2"},
+		{name: "small", with: "f93c00", wantPath: "/2"},
+		{name: "ratio", with: "f93c00", wantPath: "/3"},
+		{name: "ratio", with: "00", wantPath: "/3"},
+		{name: "port", with: "1a00010000", wantPath: "/4"},
+		{name: "level", with: "21", wantPath: "/5"},
+		{name: "colour", with: "04", wantPath: "/6"},
+		{name: "pair", with: "61610a", wantPath: "/8"},
+		{name: "url", with: "d8216161", wantPath: "/9"},
+		{name: "when", with: "c16161", wantPath: "/10"},
+		{name: "half", with: "fa3fc00000", wantPath: "/11"},
+		{name: "seq", with: "43016161", wantPath: "/12/1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+"/"+tt.with, func(t *testing.T) {
+			err := s.Validate(bytes.NewReader(encode(tt.name, tt.with)))
+
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("want validation error, got %v", err)
+			}
+			if verr.Path != tt.wantPath {
+				t.Fatalf("want %s, got %v", tt.wantPath, err)
+			}
+		})
+	}
+}
+
+func Test_ValidateRule(t *testing.T) {
+	s, err := Parse(messageSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := cbor.NewDecoder(bytes.NewReader(decodeHex(t, "8201028261616162")))
+	if err := s.ValidateRule(in, "point"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ValidateRule(in, "point"); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("want %v, got %v", ErrInvalid, err)
+	}
+	if err := s.ValidateRule(in, "point"); err != io.EOF {
+		t.Fatalf("want %v, got %v", io.EOF, err)
+	}
+}
+
+func Test_Validate_NotWellFormed(t *testing.T) {
+	s, err := Parse("any-item = any")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		encoded string
+		wantErr error
+	}{
+		{encoded: "8201", wantErr: io.ErrUnexpectedEOF},
+		{encoded: "9f01", wantErr: io.ErrUnexpectedEOF},
+		{encoded: "ff", wantErr: cbor.ErrNotWellFormed},
+		{encoded: "bf01ff", wantErr: cbor.ErrNotWellFormed},
+		{encoded: "62c328", wantErr: cbor.ErrInvalidUTF8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			err := s.Validate(bytes.NewReader(decodeHex(t, tt.encoded)))
+			if err != tt.wantErr {
+				t.Fatalf("want %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func Test_ValidateOptions(t *testing.T) {
+	tests := []struct {
+		schema  string
+		encoded string
+		options ValidateOptions
+		wantErr error
+	}{
+		{encoded: "83010203", options: ValidateOptions{MaxSize: 4}},
+		{encoded: "83010203", options: ValidateOptions{MaxSize: 3}, wantErr: cbor.ErrTooLarge},
+		{encoded: "5820" + strings.Repeat("00", 32), options: ValidateOptions{MaxSize: 16}, wantErr: cbor.ErrTooLarge},
+		{encoded: "5f5001" + strings.Repeat("00", 15) + "ff", options: ValidateOptions{MaxSize: 16}, wantErr: cbor.ErrTooLarge},
+		{encoded: "5f4f" + strings.Repeat("00", 15) + "ff", options: ValidateOptions{MaxSize: 18}},
+		{encoded: "81818101", options: ValidateOptions{MaxDepth: 3}},
+		{encoded: "81818101", options: ValidateOptions{MaxDepth: 2}, wantErr: cbor.ErrTooLarge},
+		{encoded: "c1c1c101", options: ValidateOptions{MaxDepth: 2}, wantErr: cbor.ErrTooLarge},
+		{schema: "list = [* [* int]]", encoded: "81818101", options: ValidateOptions{MaxDepth: 2}, wantErr: cbor.ErrTooLarge},
+		{schema: "embedded = bstr .cbor any", encoded: "44818181 01", options: ValidateOptions{MaxDepth: 3}},
+		{schema: "embedded = bstr .cbor any", encoded: "44818181 01", options: ValidateOptions{MaxDepth: 2}, wantErr: ErrInvalid},
+		{schema: "embedded = bstr .cborseq any", encoded: "4401818101", options: ValidateOptions{MaxDepth: 1}, wantErr: ErrInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			if tt.schema == "" {
+				tt.schema = "any-item = any"
+			}
+			s, err := Parse(tt.schema)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = tt.options.Validate(s, bytes.NewReader(decodeHex(t, strings.ReplaceAll(tt.encoded, " ", ""))))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("want %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func Test_Validate_Streamed(t *testing.T) {
+	s, err := Parse(recordSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a failed item is read over, so the next can be validated
+	in := cbor.NewDecoder(bytes.NewReader(decodeHex(t, "a3646e616d6561616464617461406570617274738182016178"+"a2646e616d65616164646174614101")))
+	if err := s.Validate(in); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("want %v, got %v", ErrInvalid, err)
+	}
+	if err := s.Validate(in); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Validate(in); err != io.EOF {
+		t.Fatalf("want %v, got %v", io.EOF, err)
+	}
+}
+
+func Test_Validate_StreamedAllocations(t *testing.T) {
+	// 64 parts of 64 KiB each
+	const parts, size = 64, 64 << 10
+	encoded := bytes.NewBuffer(decodeHex(t, "a3646e616d65616164646174614065706172747398"))
+	encoded.WriteByte(parts)
+	for range parts {
+		encoded.Write([]byte{0x82, 0x01, 0x5a, 0, size >> 16, 0, 0})
+		encoded.Write(make([]byte, size))
+	}
+	data := encoded.Bytes()
+
+	s, err := Parse(recordSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	if err = s.Validate(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	runtime.ReadMemStats(&after)
+
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > size {
+		t.Fatalf("allocated %d bytes validating %d", alloc, len(data))
+	}
+}
@@ -0,0 +1,115 @@
+// Command cbor works with CBOR files.
+//
+//	cbor diff [-hex] a.cbor b.cbor
+//
+// diff prints the differences between the objects in two files, one per line
+// in diagnostic notation, and exits with status 1 if there are any, as
+// diff(1). With -hex the files hold the encoding in hex, and "-" reads
+// standard input.
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	cbor "github.com/alex-richards/tiny-cbor"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the command with [args], returning its exit status.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("cbor", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: cbor diff [-hex] a.cbor b.cbor")
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() < 1 || flags.Arg(0) != "diff" {
+		flags.Usage()
+		return 2
+	}
+
+	differs, err := diff(flags.Args()[1:], stdin, stdout)
+	if err != nil {
+		fmt.Fprintln(stderr, "cbor diff:", err)
+		return 2
+	}
+	if differs {
+		return 1
+	}
+	return 0
+}
+
+func diff(args []string, stdin io.Reader, out io.Writer) (bool, error) {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	hexInput := flags.Bool("hex", false, "files hold the encoding in hex")
+	if err := flags.Parse(args); err != nil {
+		return false, err
+	}
+	if flags.NArg() != 2 {
+		return false, errors.New("expected two files")
+	}
+	if flags.Arg(0) == "-" && flags.Arg(1) == "-" {
+		return false, errors.New("only one file can be standard input")
+	}
+
+	a, err := readValue(flags.Arg(0), *hexInput, stdin)
+	if err != nil {
+		return false, err
+	}
+	b, err := readValue(flags.Arg(1), *hexInput, stdin)
+	if err != nil {
+		return false, err
+	}
+
+	diffs := cbor.DiffValues(a, b)
+	for _, d := range diffs {
+		if _, err := fmt.Fprintln(out, d); err != nil {
+			return false, err
+		}
+	}
+	return len(diffs) > 0, nil
+}
+
+func readValue(name string, hexInput bool, stdin io.Reader) (cbor.Value, error) {
+	var data []byte
+	var err error
+	if name == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return cbor.Value{}, err
+	}
+
+	if hexInput {
+		data, err = hex.DecodeString(strings.Join(strings.Fields(string(data)), ""))
+		if err != nil {
+			return cbor.Value{}, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	in := bytes.NewReader(data)
+	v, err := cbor.ReadValue(in)
+	if err != nil {
+		return cbor.Value{}, fmt.Errorf("%s: %w", name, err)
+	}
+	if in.Len() != 0 {
+		return cbor.Value{}, fmt.Errorf("%s: trailing data after object", name)
+	}
+	return v, nil
+}
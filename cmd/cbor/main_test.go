@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_Run(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		stdin      string // a file in testdata
+		wantStatus int
+		wantOut    string
+		wantErr    string
+	}{
+		{name: "same", args: []string{"diff", "testdata/a.cbor", "testdata/a.cbor"}},
+		{name: "differs", args: []string{"diff", "testdata/a.cbor", "testdata/b.cbor"}, wantStatus: 1, wantOut: "/b/1: changed 3 to 4\n"},
+		{name: "hex", args: []string{"diff", "-hex", "testdata/a.hex", "testdata/b.hex"}, wantStatus: 1, wantOut: "/b/1: changed 3 to 4\n"},
+		{name: "hex same", args: []string{"diff", "-hex", "testdata/a.hex", "testdata/a.hex"}},
+		{name: "stdin", args: []string{"diff", "testdata/a.cbor", "-"}, stdin: "b.cbor", wantStatus: 1, wantOut: "/b/1: changed 3 to 4\n"},
+		{name: "hex stdin", args: []string{"diff", "-hex", "-", "testdata/a.hex"}, stdin: "a.hex"},
+
+		{name: "no command", args: nil, wantStatus: 2, wantErr: "usage: cbor diff"},
+		{name: "unknown command", args: []string{"patch"}, wantStatus: 2, wantErr: "usage: cbor diff"},
+		{name: "unknown flag", args: []string{"diff", "-x", "testdata/a.cbor", "testdata/b.cbor"}, wantStatus: 2, wantErr: "cbor diff: flag provided but not defined: -x"},
+		{name: "one file", args: []string{"diff", "testdata/a.cbor"}, wantStatus: 2, wantErr: "cbor diff: expected two files"},
+		{name: "both stdin", args: []string{"diff", "-", "-"}, wantStatus: 2, wantErr: "cbor diff: only one file can be standard input"},
+		{name: "missing file", args: []string{"diff", "testdata/a.cbor", "testdata/missing.cbor"}, wantStatus: 2, wantErr: "cbor diff: open testdata/missing.cbor"},
+		{name: "invalid hex", args: []string{"diff", "-hex", "testdata/a.hex", "testdata/bad.hex"}, wantStatus: 2, wantErr: "cbor diff: testdata/bad.hex: encoding/hex"},
+		{name: "binary as hex", args: []string{"diff", "-hex", "testdata/a.hex", "testdata/b.cbor"}, wantStatus: 2, wantErr: "cbor diff: testdata/b.cbor: encoding/hex"},
+		{name: "trailing data", args: []string{"diff", "testdata/a.cbor", "testdata/trailing.cbor"}, wantStatus: 2, wantErr: "cbor diff: testdata/trailing.cbor: trailing data"},
+		{name: "truncated", args: []string{"diff", "testdata/a.cbor", "testdata/a.hex"}, wantStatus: 2, wantErr: "cbor diff: testdata/a.hex:"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdin := bytes.NewReader(nil)
+			if tt.stdin != "" {
+				data, err := os.ReadFile("testdata/" + tt.stdin)
+				if err != nil {
+					t.Fatal(err)
+				}
+				stdin = bytes.NewReader(data)
+			}
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+			status := run(tt.args, stdin, stdout, stderr)
+			if status != tt.wantStatus {
+				t.Fatalf("got status %d, want %d: %s", status, tt.wantStatus, stderr)
+			}
+			if stdout.String() != tt.wantOut {
+				t.Fatalf("got output %q, want %q", stdout, tt.wantOut)
+			}
+			if !strings.HasPrefix(stderr.String(), tt.wantErr) {
+				t.Fatalf("got error %q, want %q", stderr, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+// Command cddlgen generates Go types, and functions to read and write them
+// with tiny-cbor, from a CDDL schema.
+//
+//	cddlgen -package name [-o file.go] schema.cddl
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/alex-richards/tiny-cbor/cddl"
+	"github.com/alex-richards/tiny-cbor/cddl/gen"
+)
+
+func main() {
+	pkg := flag.String("package", "", "package name of the generated file")
+	output := flag.String("o", "", "file to write, instead of standard output")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: cddlgen -package name [-o file.go] schema.cddl")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *pkg == "" || flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(flag.Arg(0), *pkg, *output); err != nil {
+		fmt.Fprintln(os.Stderr, "cddlgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(input, pkg, output string) error {
+	src, err := os.ReadFile(input)
+	if err != nil {
+		return err
+	}
+
+	s, err := cddl.Parse(string(src))
+	if err != nil {
+		return fmt.Errorf("%s: %w", input, err)
+	}
+
+	code, err := gen.Generate(s, gen.Options{Package: pkg, Source: filepath.Base(input)})
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		_, err = os.Stdout.Write(code)
+		return err
+	}
+	return os.WriteFile(output, code, 0o644)
+}
@@ -0,0 +1,490 @@
+package cbor
+
+import (
+	"bytes"
+	"io"
+	"iter"
+	"math"
+	"slices"
+)
+
+// maxPrealloc limits the number of items reserved for an array or map from
+// its length alone, so a short input can't claim a large allocation.
+const maxPrealloc = 1024
+
+// ReadSlice reads the next object from [in] as an array, reading each item
+// with [readItem].
+func ReadSlice[T any](
+	in io.Reader,
+	readItem func(in io.Reader) (T, error),
+) ([]T, error) {
+	var s []T
+	err := ReadArray(
+		in,
+		func(indefinite bool, length uint64) error {
+			s = make([]T, 0, min(length, maxPrealloc))
+			return nil
+		},
+		func(in io.Reader) error {
+			v, err := readItem(in)
+			if err != nil {
+				return err
+			}
+			s = append(s, v)
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// ReadMapOf reads the next object from [in] as a map, reading each key with
+// [readKey] and each value with [readValue]. The last value of a duplicate key
+// is kept; use [ReadMap] with [MapKeys] for other policies.
+func ReadMapOf[K comparable, V any](
+	in io.Reader,
+	readKey func(in io.Reader) (K, error),
+	readValue func(in io.Reader) (V, error),
+) (map[K]V, error) {
+	var m map[K]V
+	err := ReadMap(
+		in,
+		func(indefinite bool, length uint64) error {
+			m = make(map[K]V, min(length, maxPrealloc))
+			return nil
+		},
+		func(in io.Reader) error {
+			k, err := readKey(in)
+			if err != nil {
+				return err
+			}
+			v, err := readValue(in)
+			if err != nil {
+				return err
+			}
+			m[k] = v
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// WriteSlice writes [value] as a definite length array, writing each item with
+// [writeItem].
+func WriteSlice[T any](
+	out io.Writer,
+	value []T,
+	writeItem func(out io.Writer, item T) (int, error),
+) (int, error) {
+	n, err := WriteArrayHeader(out, uint64(len(value)))
+	if err != nil {
+		return n, err
+	}
+
+	for _, item := range value {
+		nn, err := writeItem(out, item)
+		n += nn
+		if err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+// WriteMapOf writes [value] as a definite length map, writing each key with
+// [writeKey] and each value with [writeValue], in Go's map iteration order.
+func WriteMapOf[K comparable, V any](
+	out io.Writer,
+	value map[K]V,
+	writeKey func(out io.Writer, key K) (int, error),
+	writeValue func(out io.Writer, value V) (int, error),
+) (int, error) {
+	n, err := WriteMapHeader(out, uint64(len(value)))
+	if err != nil {
+		return n, err
+	}
+
+	for k, v := range value {
+		nn, err := writeKey(out, k)
+		n += nn
+		if err != nil {
+			return n, err
+		}
+
+		nn, err = writeValue(out, v)
+		n += nn
+		if err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+// WriteMapOfSorted writes [value] as [WriteMapOf], with the entries sorted by
+// the bytewise order of their encoded keys, so the output is deterministic as
+// described in RFC 8949 section 4.2.1.
+func WriteMapOfSorted[K comparable, V any](
+	out io.Writer,
+	value map[K]V,
+	writeKey func(out io.Writer, key K) (int, error),
+	writeValue func(out io.Writer, value V) (int, error),
+) (int, error) {
+	type entry struct {
+		key   []byte
+		value V
+	}
+
+	entries := make([]entry, 0, len(value))
+	for k, v := range value {
+		b := bytes.NewBuffer(nil)
+		if _, err := writeKey(b, k); err != nil {
+			return 0, err
+		}
+		entries = append(entries, entry{key: b.Bytes(), value: v})
+	}
+
+	slices.SortFunc(entries, func(a, b entry) int {
+		return bytes.Compare(a.key, b.key)
+	})
+
+	n, err := WriteMapHeader(out, uint64(len(entries)))
+	if err != nil {
+		return n, err
+	}
+
+	for _, e := range entries {
+		nn, err := out.Write(e.key)
+		n += nn
+		if err != nil {
+			return n, err
+		}
+
+		nn, err = writeValue(out, e.value)
+		n += nn
+		if err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+// ReadNullable reads the next object from [in] with [readValue], or returns
+// nil if it is null.
+func ReadNullable[T any](
+	in io.Reader,
+	readValue func(in io.Reader) (T, error),
+) (*T, error) {
+	d, ok := in.(*Decoder)
+	if !ok {
+		d = NewDecoder(in)
+	}
+
+	majorType, arg, value, err := d.PeekType()
+	if err != nil {
+		return nil, err
+	}
+
+	if majorType == MajorTypeSimpleFloat && arg == 0 && value == uint64(SimpleNull) {
+		_, err = d.Read(sharedBuffer[:1])
+		return nil, err
+	}
+
+	v, err := readValue(d)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v, nil
+}
+
+// WriteNullable writes [value] with [writeValue], or null if it is nil.
+func WriteNullable[T any](
+	out io.Writer,
+	value *T,
+	writeValue func(out io.Writer, value T) (int, error),
+) (int, error) {
+	if value == nil {
+		return writeMajorType(out, MajorTypeSimpleFloat, uint64(SimpleNull))
+	}
+
+	return writeValue(out, *value)
+}
+
+// Items returns an iterator over the items of the next object in [in], which
+// must be an array, of definite or indefinite length. The loop body reads each
+// item from the reader it is given, which returns io.EOF at the end of the
+// item and is only valid until the next iteration. Any part of an item the
+// body leaves unread is skipped, as are the remaining items if the loop ends
+// early, so [in] is left after the array.
+//
+// A failure is yielded with a nil reader, and ends the iteration.
+func Items(in io.Reader) iter.Seq2[io.Reader, error] {
+	return func(yield func(io.Reader, error) bool) {
+		majorType, arg, value, err := readMajorType(in)
+		if err == nil && majorType != MajorTypeArray {
+			err = ErrUnsupportedMajorType
+		}
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		iterate(in, arg, value, false, yield)
+	}
+}
+
+// Pairs returns an iterator over the entries of the next object in [in],
+// which must be a map, as [Items]. The loop body reads the key, and then the
+// value, of each entry from the reader it is given. Any part of a value the
+// body leaves unread is skipped.
+func Pairs(in io.Reader) iter.Seq2[io.Reader, error] {
+	return func(yield func(io.Reader, error) bool) {
+		majorType, arg, value, err := readMajorType(in)
+		if err == nil && majorType != MajorTypeMap {
+			err = ErrUnsupportedMajorType
+		}
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		iterate(in, arg, value, true, yield)
+	}
+}
+
+// iterate yields each item of an array, or entry of a map, whose header has
+// been read from [in]. Keys are read ahead, to tell whether the value was
+// read. Each item, or value, is read through an itemReader, which ends at the
+// end of the item, so the rest of an item the body only partly read can be
+// skipped.
+func iterate(in io.Reader, arg Arg, value uint64, pairs bool, yield func(io.Reader, error) bool) {
+	var pin *peekReader
+	if arg == ArgIndefinite {
+		pin = &peekReader{r: in}
+		in = pin
+	}
+
+	key := bytes.NewBuffer(nil)
+	done := false
+	for i := uint64(0); ; i++ {
+		if pin != nil {
+			b, err := pin.PeekByte()
+			if err != nil {
+				if !done {
+					yield(nil, unexpectedEOF(err))
+				}
+				return
+			}
+			if b == valueBreak {
+				return
+			}
+		} else if i == value {
+			return
+		}
+
+		ir := &itemReader{r: in}
+		var item io.Reader = ir
+		if pairs {
+			key.Reset()
+			if err := ReadRaw(in, key); err != nil {
+				if !done {
+					yield(nil, unexpectedEOF(err))
+				}
+				return
+			}
+			item = io.MultiReader(bytes.NewReader(key.Bytes()), ir)
+		}
+
+		if !done && !yield(item, nil) {
+			done = true
+		}
+
+		var err error
+		if ir.started {
+			err = ir.skip()
+		} else {
+			err = readOverNext(in, false)
+		}
+		if err != nil {
+			if !done {
+				yield(nil, unexpectedEOF(err))
+			}
+			return
+		}
+	}
+}
+
+// itemReader reads a single item from [r], following its structure as it is
+// read so that it never reads past the end of the item, and returns io.EOF
+// there.
+type itemReader struct {
+	r       io.Reader
+	started bool
+
+	header  []byte                // the header read so far
+	content uint64                // bytes left of a string chunk
+	open    []itemReaderContainer // containers, tags and indefinite strings not yet ended
+	done    bool
+	err     error
+}
+
+type itemReaderContainer struct {
+	left       uint64 // items left, unless indefinite
+	indefinite bool
+}
+
+func (r *itemReader) Read(out []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	if r.done {
+		return 0, io.EOF
+	}
+	if len(out) == 0 {
+		return 0, nil
+	}
+
+	r.started = true
+	if need := r.need(); uint64(len(out)) > need {
+		out = out[:need]
+	}
+
+	n, err := r.r.Read(out)
+	if terr := r.track(out[:n]); terr != nil {
+		r.err = terr
+		return n, terr
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		r.err = err
+	}
+
+	return n, err
+}
+
+// skip reads over the rest of the item.
+func (r *itemReader) skip() error {
+	var buf [512]byte
+	for {
+		n, err := r.Read(buf[:])
+		if err == io.EOF && n == 0 {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// need returns how many more bytes can be read without reading past the end
+// of the item, at least 1.
+func (r *itemReader) need() uint64 {
+	switch {
+	case r.content > 0:
+		return r.content
+	case len(r.header) > 0:
+		_, l, _, _ := decodeArg(Arg(r.header[0] & argMask))
+		return uint64(1 + int(l) - len(r.header))
+	default:
+		return 1
+	}
+}
+
+// track follows the structure of the item through [b], which must not go past
+// its end.
+func (r *itemReader) track(b []byte) error {
+	for len(b) > 0 {
+		if r.content > 0 {
+			n := min(r.content, uint64(len(b)))
+			r.content -= n
+			b = b[n:]
+			if r.content == 0 {
+				r.end()
+			}
+			continue
+		}
+
+		r.header = append(r.header, b[0])
+		b = b[1:]
+
+		majorType, arg := decodePrefix(r.header[0])
+		arg, l, value, err := decodeArg(arg)
+		if err != nil {
+			return err
+		}
+		if len(r.header) < 1+int(l) {
+			continue
+		}
+		if l > 0 {
+			value = shiftBytesInto[uint64](r.header[1:])
+		}
+		r.header = r.header[:0]
+
+		if arg == ArgIndefinite {
+			switch majorType {
+			case MajorTypeBstr, MajorTypeTstr, MajorTypeArray, MajorTypeMap:
+				r.open = append(r.open, itemReaderContainer{indefinite: true})
+			case MajorTypeSimpleFloat:
+				// break
+				if len(r.open) == 0 || !r.open[len(r.open)-1].indefinite {
+					return ErrNotWellFormed
+				}
+				r.open = r.open[:len(r.open)-1]
+				r.end()
+			default:
+				return ErrNotWellFormed
+			}
+			continue
+		}
+
+		switch majorType {
+		case MajorTypeBstr, MajorTypeTstr:
+			r.content = value
+			if value == 0 {
+				r.end()
+			}
+		case MajorTypeArray, MajorTypeMap:
+			if majorType == MajorTypeMap {
+				value = min(value, math.MaxUint64/2) * 2
+			}
+			if value == 0 {
+				r.end()
+			} else {
+				r.open = append(r.open, itemReaderContainer{left: value})
+			}
+		case MajorTypeTagged:
+			r.open = append(r.open, itemReaderContainer{left: 1})
+		default:
+			r.end()
+		}
+	}
+
+	return nil
+}
+
+// end records the end of an item, and so of any containers it completes.
+func (r *itemReader) end() {
+	for len(r.open) > 0 {
+		top := &r.open[len(r.open)-1]
+		if top.indefinite {
+			return
+		}
+		if top.left--; top.left > 0 {
+			return
+		}
+		r.open = r.open[:len(r.open)-1]
+	}
+	r.done = true
+}
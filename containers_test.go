@@ -0,0 +1,499 @@
+package cbor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_ReadSlice(t *testing.T) {
+	tests := []struct {
+		encoded string
+		want    []uint64
+		wantErr bool
+	}{
+		{encoded: "80", want: []uint64{}},
+		{encoded: "83010203", want: []uint64{1, 2, 3}},
+		{encoded: "9f010203ff", want: []uint64{1, 2, 3}},
+		{encoded: "9b7fffffffffffffff", wantErr: true},
+		{encoded: "a0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			got, err := ReadSlice(bytes.NewReader(decodeHex(t, tt.encoded)), ReadUnsigned[uint64])
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func Test_ReadMapOf(t *testing.T) {
+	tests := []struct {
+		encoded string
+		want    map[string]uint64
+		wantErr bool
+	}{
+		{encoded: "a0", want: map[string]uint64{}},
+		{encoded: "a2616101616202", want: map[string]uint64{"a": 1, "b": 2}},
+		{encoded: "bf616101616202ff", want: map[string]uint64{"a": 1, "b": 2}},
+		{encoded: "bb7fffffffffffffff", wantErr: true},
+		{encoded: "a1010102", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			got, err := ReadMapOf(bytes.NewReader(decodeHex(t, tt.encoded)), ReadString, ReadUnsigned[uint64])
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func Test_WriteSlice(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	n, err := WriteSlice(out, []uint64{1, 2, 3}, WriteUnsigned[uint64])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := decodeHex(t, "83010203")
+	if n != len(want) {
+		t.Fatalf("want %d, got %d", len(want), n)
+	}
+	if diff := cmp.Diff(want, out.Bytes()); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func Test_WriteMapOf(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	n, err := WriteMapOf(out, map[string]uint64{"a": 1}, WriteString, WriteUnsigned[uint64])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := decodeHex(t, "a1616101")
+	if n != len(want) {
+		t.Fatalf("want %d, got %d", len(want), n)
+	}
+	if diff := cmp.Diff(want, out.Bytes()); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func Test_WriteMapOfSorted(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	n, err := WriteMapOfSorted(
+		out,
+		map[string]uint64{"aa": 3, "b": 2, "a": 1},
+		WriteString,
+		WriteUnsigned[uint64],
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := decodeHex(t, "a361610161620262616103")
+	if n != len(want) {
+		t.Fatalf("want %d, got %d", len(want), n)
+	}
+	if diff := cmp.Diff(want, out.Bytes()); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func Test_ReadNullable(t *testing.T) {
+	in := bytes.NewReader(decodeHex(t, "f6820102"))
+
+	got, err := ReadNullable(in, ReadString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != nil {
+		t.Fatalf("want nil, got %v", *got)
+	}
+
+	s, err := ReadNullable(in, func(in io.Reader) ([]uint64, error) {
+		return ReadSlice(in, ReadUnsigned[uint64])
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&[]uint64{1, 2}, s); diff != "" {
+		t.Fatal(diff)
+	}
+	if in.Len() != 0 {
+		t.Fatalf("want all read, got %d left", in.Len())
+	}
+
+	if _, err := ReadNullable(in, ReadString); err != io.EOF {
+		t.Fatalf("want %v, got %v", io.EOF, err)
+	}
+}
+
+func Test_WriteNullable(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	value := "a"
+
+	for _, v := range []*string{nil, &value} {
+		if _, err := WriteNullable(out, v, WriteString); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if diff := cmp.Diff(decodeHex(t, "f66161"), out.Bytes()); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func Test_Items(t *testing.T) {
+	tests := []struct {
+		encoded string
+		read    int // items to read before breaking, -1 for all
+		want    []uint64
+	}{
+		{encoded: "80", read: -1},
+		{encoded: "83010203", read: -1, want: []uint64{1, 2, 3}},
+		{encoded: "9f010203ff", read: -1, want: []uint64{1, 2, 3}},
+		{encoded: "83010203", read: 1, want: []uint64{1}},
+		{encoded: "9f010203ff", read: 1, want: []uint64{1}},
+		{encoded: "83018102a10304", read: 1, want: []uint64{1}},
+		{encoded: "9f01810280ff", read: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			// followed by true, to check the array is passed over
+			in := bytes.NewReader(append(decodeHex(t, tt.encoded), 0xf5))
+
+			var got []uint64
+			for item, err := range Items(in) {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(got) == tt.read {
+					break
+				}
+				v, err := ReadUnsigned[uint64](item)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, v)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatal(diff)
+			}
+
+			if b, err := ReadBool(in); err != nil || !b {
+				t.Fatalf("got %v, %v after array", b, err)
+			}
+		})
+	}
+}
+
+func Test_Items_Unread(t *testing.T) {
+	// items left unread are skipped
+	for _, encoded := range []string{"8301820203a1616104", "9f01820203a1616104ff"} {
+		t.Run(encoded, func(t *testing.T) {
+			in := bytes.NewReader(append(decodeHex(t, encoded), 0xf5))
+
+			n := 0
+			for _, err := range Items(in) {
+				if err != nil {
+					t.Fatal(err)
+				}
+				n++
+			}
+			if n != 3 {
+				t.Fatalf("got %d items", n)
+			}
+
+			if b, err := ReadBool(in); err != nil || !b {
+				t.Fatalf("got %v, %v after array", b, err)
+			}
+		})
+	}
+}
+
+func Test_Items_PartlyRead(t *testing.T) {
+	// the rest of an item the body fails to read is skipped
+	for _, encoded := range []string{
+		"826361626305",
+		"9f6361626305ff",
+		"83820102c1186405",
+		"827f6161ff05",
+		"82bf6161f4ff05",
+	} {
+		t.Run(encoded, func(t *testing.T) {
+			in := bytes.NewReader(append(decodeHex(t, encoded), 0x07))
+
+			var got []uint64
+			for item, err := range Items(in) {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if v, err := ReadUnsigned[uint64](item); err == nil {
+					got = append(got, v)
+				}
+			}
+			if diff := cmp.Diff([]uint64{5}, got); diff != "" {
+				t.Fatal(diff)
+			}
+
+			if v, err := ReadUnsigned[uint64](in); err != nil || v != 7 {
+				t.Fatalf("got %v, %v after array", v, err)
+			}
+		})
+	}
+}
+
+func Test_Items_Bounded(t *testing.T) {
+	// an item can't be read past its end
+	in := bytes.NewReader(decodeHex(t, "83636162639f01ff05"))
+
+	var got []string
+	for item, err := range Items(in) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, hex.EncodeToString(b))
+	}
+
+	if diff := cmp.Diff([]string{"63616263", "9f01ff", "05"}, got); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func Test_Items_Nested(t *testing.T) {
+	in := bytes.NewReader(decodeHex(t, "829f0102ff820304"))
+
+	var got [][]uint64
+	for item, err := range Items(in) {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var s []uint64
+		for item, err := range Items(item) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			v, err := ReadUnsigned[uint64](item)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s = append(s, v)
+		}
+		got = append(got, s)
+	}
+
+	if diff := cmp.Diff([][]uint64{{1, 2}, {3, 4}}, got); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func Test_Items_Errors(t *testing.T) {
+	tests := []struct {
+		encoded string
+		err     error
+	}{
+		{encoded: "", err: io.EOF},
+		{encoded: "a0", err: ErrUnsupportedMajorType},
+		{encoded: "8301", err: io.ErrUnexpectedEOF},
+		{encoded: "9f01", err: io.ErrUnexpectedEOF},
+		{encoded: "8182", err: io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			var got error
+			for item, err := range Items(bytes.NewReader(decodeHex(t, tt.encoded))) {
+				if got != nil {
+					t.Fatal("yielded after error")
+				}
+				if err != nil && item != nil {
+					t.Fatal("reader with error")
+				}
+				got = err
+			}
+			if !errors.Is(got, tt.err) {
+				t.Fatalf("got %v, want %v", got, tt.err)
+			}
+		})
+	}
+}
+
+func Test_Pairs(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoded string
+		read    func(t *testing.T, entry io.Reader) string
+		want    []string
+	}{
+		{
+			name:    "key and value",
+			encoded: "a2616101616202",
+			read: func(t *testing.T, entry io.Reader) string {
+				k, err := ReadString(entry)
+				if err != nil {
+					t.Fatal(err)
+				}
+				v, err := ReadUnsigned[uint64](entry)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return k + string(rune('0'+v))
+			},
+			want: []string{"a1", "b2"},
+		},
+		{
+			name:    "indefinite",
+			encoded: "bf616101616202ff",
+			read: func(t *testing.T, entry io.Reader) string {
+				k, err := ReadString(entry)
+				if err != nil {
+					t.Fatal(err)
+				}
+				v, err := ReadUnsigned[uint64](entry)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return k + string(rune('0'+v))
+			},
+			want: []string{"a1", "b2"},
+		},
+		{
+			name:    "key only",
+			encoded: "bf616182010261628102ff",
+			read: func(t *testing.T, entry io.Reader) string {
+				k, err := ReadString(entry)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return k
+			},
+			want: []string{"a", "b"},
+		},
+		{
+			name:    "unread",
+			encoded: "a2616182010261628102",
+			read: func(t *testing.T, entry io.Reader) string {
+				return ""
+			},
+			want: []string{"", ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := bytes.NewReader(append(decodeHex(t, tt.encoded), 0xf5))
+
+			var got []string
+			for entry, err := range Pairs(in) {
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, tt.read(t, entry))
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatal(diff)
+			}
+
+			if b, err := ReadBool(in); err != nil || !b {
+				t.Fatalf("got %v, %v after map", b, err)
+			}
+		})
+	}
+}
+
+func Test_Pairs_PartlyRead(t *testing.T) {
+	// {"a": "xyz", "b": 5}
+	in := bytes.NewReader(decodeHex(t, "a261616378797a616205f5"))
+
+	got := map[string]uint64{}
+	for entry, err := range Pairs(in) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		k, err := ReadString(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v, err := ReadUnsigned[uint64](entry); err == nil {
+			got[k] = v
+		}
+	}
+	if diff := cmp.Diff(map[string]uint64{"b": 5}, got); diff != "" {
+		t.Fatal(diff)
+	}
+
+	if b, err := ReadBool(in); err != nil || !b {
+		t.Fatalf("got %v, %v after map", b, err)
+	}
+}
+
+func Test_Pairs_Break(t *testing.T) {
+	for _, encoded := range []string{"a3616101616202616303", "bf616101616202616303ff"} {
+		t.Run(encoded, func(t *testing.T) {
+			in := bytes.NewReader(append(decodeHex(t, encoded), 0xf5))
+
+			for entry, err := range Pairs(in) {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if k, err := ReadString(entry); err != nil || k != "a" {
+					t.Fatal(k, err)
+				}
+				break
+			}
+
+			if b, err := ReadBool(in); err != nil || !b {
+				t.Fatalf("got %v, %v after map", b, err)
+			}
+		})
+	}
+}
+
+func Test_Pairs_Errors(t *testing.T) {
+	tests := []struct {
+		encoded string
+		err     error
+	}{
+		{encoded: "", err: io.EOF},
+		{encoded: "80", err: ErrUnsupportedMajorType},
+		{encoded: "a161", err: io.ErrUnexpectedEOF},
+		{encoded: "a16161", err: io.ErrUnexpectedEOF},
+		{encoded: "bf616101", err: io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			var got error
+			for _, err := range Pairs(bytes.NewReader(decodeHex(t, tt.encoded))) {
+				if err != nil {
+					got = err
+				}
+			}
+			if !errors.Is(got, tt.err) {
+				t.Fatalf("got %v, want %v", got, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package cose
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"math/big"
+)
+
+// Algorithm is a COSE algorithm identifier, from the IANA COSE Algorithms
+// registry.
+type Algorithm int64
+
+const (
+	AlgES256   Algorithm = -7
+	AlgEdDSA   Algorithm = -8
+	AlgES384   Algorithm = -35
+	AlgHMAC256 Algorithm = 5 // HMAC 256/256
+)
+
+// Signer creates signatures for [Sign1Message.Sign].
+type Signer interface {
+	Algorithm() Algorithm
+	Sign(toBeSigned []byte) ([]byte, error)
+}
+
+// Verifier checks signatures for [Sign1Message.Verify].
+type Verifier interface {
+	Algorithm() Algorithm
+	Verify(toBeSigned []byte, signature []byte) error
+}
+
+// NewSigner returns a [Signer] for [alg], which must match [key], an
+// *ecdsa.PrivateKey on the matching curve or an ed25519.PrivateKey.
+func NewSigner(alg Algorithm, key crypto.PrivateKey) (Signer, error) {
+	switch alg {
+	case AlgES256, AlgES384:
+		k, ok := key.(*ecdsa.PrivateKey)
+		if !ok || k.Curve != ecdsaCurve(alg) {
+			return nil, ErrUnsupportedAlgorithm
+		}
+		return &ecdsaSigner{alg: alg, key: k}, nil
+
+	case AlgEdDSA:
+		k, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, ErrUnsupportedAlgorithm
+		}
+		return ed25519Signer(k), nil
+
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+}
+
+// NewVerifier returns a [Verifier] for [alg], which must match [key], an
+// *ecdsa.PublicKey on the matching curve or an ed25519.PublicKey.
+func NewVerifier(alg Algorithm, key crypto.PublicKey) (Verifier, error) {
+	switch alg {
+	case AlgES256, AlgES384:
+		k, ok := key.(*ecdsa.PublicKey)
+		if !ok || k.Curve != ecdsaCurve(alg) {
+			return nil, ErrUnsupportedAlgorithm
+		}
+		return &ecdsaVerifier{alg: alg, key: k}, nil
+
+	case AlgEdDSA:
+		k, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, ErrUnsupportedAlgorithm
+		}
+		return ed25519Verifier(k), nil
+
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+}
+
+func ecdsaCurve(alg Algorithm) elliptic.Curve {
+	if alg == AlgES384 {
+		return elliptic.P384()
+	}
+	return elliptic.P256()
+}
+
+func ecdsaDigest(alg Algorithm, b []byte) []byte {
+	if alg == AlgES384 {
+		d := sha512.Sum384(b)
+		return d[:]
+	}
+	d := sha256.Sum256(b)
+	return d[:]
+}
+
+type ecdsaSigner struct {
+	alg Algorithm
+	key *ecdsa.PrivateKey
+}
+
+func (s *ecdsaSigner) Algorithm() Algorithm {
+	return s.alg
+}
+
+// Sign returns the signature as r and s, each padded to the size of the
+// curve, rather than ASN.1.
+func (s *ecdsaSigner) Sign(toBeSigned []byte) ([]byte, error) {
+	r, ss, err := ecdsa.Sign(rand.Reader, s.key, ecdsaDigest(s.alg, toBeSigned))
+	if err != nil {
+		return nil, err
+	}
+
+	size := (s.key.Curve.Params().BitSize + 7) / 8
+	signature := make([]byte, 2*size)
+	r.FillBytes(signature[:size])
+	ss.FillBytes(signature[size:])
+	return signature, nil
+}
+
+type ecdsaVerifier struct {
+	alg Algorithm
+	key *ecdsa.PublicKey
+}
+
+func (v *ecdsaVerifier) Algorithm() Algorithm {
+	return v.alg
+}
+
+func (v *ecdsaVerifier) Verify(toBeSigned []byte, signature []byte) error {
+	size := (v.key.Curve.Params().BitSize + 7) / 8
+	if len(signature) != 2*size {
+		return ErrVerification
+	}
+
+	r := new(big.Int).SetBytes(signature[:size])
+	s := new(big.Int).SetBytes(signature[size:])
+	if !ecdsa.Verify(v.key, ecdsaDigest(v.alg, toBeSigned), r, s) {
+		return ErrVerification
+	}
+
+	return nil
+}
+
+type ed25519Signer ed25519.PrivateKey
+
+func (ed25519Signer) Algorithm() Algorithm {
+	return AlgEdDSA
+}
+
+func (s ed25519Signer) Sign(toBeSigned []byte) ([]byte, error) {
+	return ed25519.Sign(ed25519.PrivateKey(s), toBeSigned), nil
+}
+
+type ed25519Verifier ed25519.PublicKey
+
+func (ed25519Verifier) Algorithm() Algorithm {
+	return AlgEdDSA
+}
+
+func (v ed25519Verifier) Verify(toBeSigned []byte, signature []byte) error {
+	if !ed25519.Verify(ed25519.PublicKey(v), toBeSigned, signature) {
+		return ErrVerification
+	}
+	return nil
+}
+
+// hmac256 returns the HMAC 256/256 tag of [b] under [key].
+func hmac256(key []byte, b []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write(b)
+	return h.Sum(nil)
+}
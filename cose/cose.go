@@ -0,0 +1,21 @@
+// Package cose creates and verifies CBOR Object Signing and Encryption
+// messages, as described in RFC 9052, using tiny-cbor and the standard library
+// crypto packages.
+package cose
+
+import "errors"
+
+var (
+	ErrInvalidMessage       = errors.New("cose: invalid message")
+	ErrUnsupportedAlgorithm = errors.New("cose: unsupported algorithm")
+	ErrUnsupportedCritical  = errors.New("cose: unsupported critical header")
+	ErrVerification         = errors.New("cose: verification failed")
+	ErrDetachedPayload      = errors.New("cose: missing detached payload")
+	ErrInvalidKey           = errors.New("cose: invalid key")
+	ErrUnsupportedKey       = errors.New("cose: unsupported key")
+)
+
+const (
+	TagSign1 uint64 = 18
+	TagMac0  uint64 = 17
+)
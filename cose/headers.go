@@ -0,0 +1,256 @@
+package cose
+
+import (
+	"bytes"
+	"io"
+
+	cbor "github.com/alex-richards/tiny-cbor"
+)
+
+// Header labels, from the IANA COSE Header Parameters registry.
+const (
+	HeaderAlgorithm   int64 = 1
+	HeaderCritical    int64 = 2
+	HeaderContentType int64 = 3
+	HeaderKeyID       int64 = 4
+	HeaderIV          int64 = 5
+	HeaderPartialIV   int64 = 6
+)
+
+// Headers is a protected or unprotected header map.
+type Headers struct {
+	Algorithm Algorithm
+
+	// Critical lists the labels, int64, int or string, that must be
+	// understood. It is only allowed in protected headers.
+	Critical []any
+
+	// ContentType is a uint64 content format or a string media type.
+	ContentType any
+
+	KeyID     []byte
+	IV        []byte
+	PartialIV []byte
+
+	// Other holds any other headers, keyed by int64 or string label.
+	Other map[any]cbor.RawMessage
+}
+
+// encodeProtected returns the contents of the protected header bucket, which
+// is empty rather than an empty map when there are no headers.
+func (h *Headers) encodeProtected() ([]byte, error) {
+	out := bytes.NewBuffer(nil)
+	if err := h.write(out); err != nil {
+		return nil, err
+	}
+
+	if out.Len() == 1 {
+		return []byte{}, nil
+	}
+
+	return out.Bytes(), nil
+}
+
+// write writes [h] as a map, sorted as described in RFC 8949 section 4.2.1.
+func (h *Headers) write(out io.Writer) error {
+	entries := make(map[any]cbor.RawMessage, len(h.Other)+6)
+	for k, v := range h.Other {
+		entries[NormalizeLabel(k)] = v
+	}
+
+	var err error
+	add := func(label int64, v any) {
+		if err != nil {
+			return
+		}
+		var b []byte
+		if b, err = cbor.Marshal(v); err == nil {
+			entries[label] = b
+		}
+	}
+
+	if h.Algorithm != 0 {
+		add(HeaderAlgorithm, int64(h.Algorithm))
+	}
+	if len(h.Critical) > 0 {
+		b := bytes.NewBuffer(nil)
+		if _, err = cbor.WriteSlice(b, h.Critical, WriteLabel); err != nil {
+			return err
+		}
+		entries[HeaderCritical] = b.Bytes()
+	}
+	if h.ContentType != nil {
+		add(HeaderContentType, h.ContentType)
+	}
+	if h.KeyID != nil {
+		add(HeaderKeyID, h.KeyID)
+	}
+	if h.IV != nil {
+		add(HeaderIV, h.IV)
+	}
+	if h.PartialIV != nil {
+		add(HeaderPartialIV, h.PartialIV)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = cbor.WriteMapOfSorted(out, entries, WriteLabel, cbor.WriteRawMessage)
+	return err
+}
+
+// readHeaders reads a header map from [in], adding its labels to [labels] and
+// failing if any were already there. The critical header is only allowed in
+// the [protected] bucket (RFC 9052 section 3.1).
+func readHeaders(in io.Reader, labels map[any]struct{}, protected bool) (Headers, error) {
+	var h Headers
+	keys := cbor.NewMapKeys(cbor.DupMapKeyError)
+
+	err := cbor.ReadMap(
+		in,
+		func(bool, uint64) error { return nil },
+		func(in io.Reader) error {
+			key, _, err := keys.Next(in)
+			if err != nil {
+				return err
+			}
+
+			label, err := ReadLabel(key)
+			if err != nil {
+				return err
+			}
+
+			if _, ok := labels[label]; ok {
+				return ErrInvalidMessage
+			}
+			labels[label] = struct{}{}
+
+			switch label {
+			case HeaderAlgorithm:
+				var alg int64
+				if alg, err = cbor.ReadSigned[int64](in); err != nil {
+					return ErrUnsupportedAlgorithm
+				}
+				h.Algorithm = Algorithm(alg)
+
+			case HeaderCritical:
+				if !protected {
+					return ErrInvalidMessage
+				}
+				h.Critical, err = cbor.ReadSlice(in, ReadLabel)
+				if err == nil && len(h.Critical) == 0 {
+					err = ErrInvalidMessage
+				}
+
+			case HeaderContentType:
+				d := cbor.NewDecoder(in)
+				var majorType cbor.MajorType
+				if majorType, _, _, err = d.PeekType(); err != nil {
+					return err
+				}
+				if majorType == cbor.MajorTypeTstr {
+					h.ContentType, err = cbor.ReadString(d)
+				} else {
+					h.ContentType, err = cbor.ReadUnsigned[uint64](d)
+				}
+
+			case HeaderKeyID:
+				h.KeyID, err = cbor.ReadByteString(in)
+
+			case HeaderIV:
+				h.IV, err = cbor.ReadByteString(in)
+
+			case HeaderPartialIV:
+				h.PartialIV, err = cbor.ReadByteString(in)
+
+			default:
+				if h.Other == nil {
+					h.Other = make(map[any]cbor.RawMessage)
+				}
+				h.Other[label], err = cbor.ReadRawMessage(in)
+			}
+
+			return err
+		},
+	)
+	if err == cbor.ErrDuplicateMapKey || err == cbor.ErrUnsupportedMajorType {
+		err = ErrInvalidMessage
+	}
+
+	return h, err
+}
+
+// readProtected parses the contents of a protected header bucket.
+func readProtected(b []byte, labels map[any]struct{}) (Headers, error) {
+	if len(b) == 0 {
+		return Headers{}, nil
+	}
+
+	in := bytes.NewReader(b)
+	h, err := readHeaders(in, labels, true)
+	if err != nil {
+		return Headers{}, err
+	}
+	if in.Len() != 0 {
+		return Headers{}, ErrInvalidMessage
+	}
+
+	return h, nil
+}
+
+// checkCritical fails if any critical label is one this package does not
+// process.
+func (h *Headers) checkCritical() error {
+	for _, label := range h.Critical {
+		switch NormalizeLabel(label) {
+		case HeaderAlgorithm, HeaderContentType, HeaderKeyID, HeaderIV, HeaderPartialIV:
+		default:
+			return ErrUnsupportedCritical
+		}
+	}
+	return nil
+}
+
+// ReadLabel reads a label, an integer or a text string, as used for headers,
+// key parameters and CWT claims. It returns an integer as int64, and fails
+// with ErrInvalidMessage for any other type.
+func ReadLabel(in io.Reader) (any, error) {
+	d := cbor.NewDecoder(in)
+	majorType, _, _, err := d.PeekType()
+	if err != nil {
+		return nil, err
+	}
+
+	switch majorType {
+	case cbor.MajorTypeUInt, cbor.MajorTypeNInt:
+		return cbor.ReadSigned[int64](d)
+	case cbor.MajorTypeTstr:
+		return cbor.ReadString(d)
+	default:
+		return nil, ErrInvalidMessage
+	}
+}
+
+// NormalizeLabel returns an int label as int64, as read by ReadLabel, so
+// labels can be compared.
+func NormalizeLabel(label any) any {
+	if l, ok := label.(int); ok {
+		return int64(l)
+	}
+	return label
+}
+
+// WriteLabel writes an int64, int or string label, failing with
+// ErrInvalidMessage for any other type.
+func WriteLabel(out io.Writer, label any) (int, error) {
+	switch l := label.(type) {
+	case int64:
+		return cbor.WriteSigned(out, l)
+	case int:
+		return cbor.WriteSigned(out, int64(l))
+	case string:
+		return cbor.WriteString(out, l)
+	default:
+		return 0, ErrInvalidMessage
+	}
+}
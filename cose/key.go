@@ -0,0 +1,551 @@
+package cose
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"io"
+	"math/big"
+
+	cbor "github.com/alex-richards/tiny-cbor"
+)
+
+// KeyType is a COSE key type, from the IANA COSE Key Types registry.
+type KeyType int64
+
+const (
+	KeyTypeOKP       KeyType = 1
+	KeyTypeEC2       KeyType = 2
+	KeyTypeSymmetric KeyType = 4
+)
+
+// Curve is a COSE elliptic curve, from the IANA COSE Elliptic Curves
+// registry.
+type Curve int64
+
+const (
+	CurveP256    Curve = 1
+	CurveP384    Curve = 2
+	CurveP521    Curve = 3
+	CurveX25519  Curve = 4
+	CurveX448    Curve = 5
+	CurveEd25519 Curve = 6
+	CurveEd448   Curve = 7
+)
+
+// Key labels, common to all key types and then by key type.
+const (
+	KeyLabelType      int64 = 1
+	KeyLabelID        int64 = 2
+	KeyLabelAlgorithm int64 = 3
+	KeyLabelOps       int64 = 4
+	KeyLabelBaseIV    int64 = 5
+
+	KeyLabelCurve int64 = -1 // EC2 and OKP
+	KeyLabelX     int64 = -2 // EC2 and OKP
+	KeyLabelY     int64 = -3 // EC2
+	KeyLabelD     int64 = -4 // EC2 and OKP
+	KeyLabelK     int64 = -1 // Symmetric
+)
+
+// Key is a COSE_Key. Only the fields for its Type are used.
+type Key struct {
+	Type      KeyType
+	ID        []byte
+	Algorithm Algorithm
+
+	// Ops lists the permitted operations, int64 or string.
+	Ops    []any
+	BaseIV []byte
+
+	// Curve, X, Y and D are the parameters of EC2 and OKP keys. Public keys
+	// have X, and Y for EC2, and private keys D. Compressed EC2 points are
+	// not supported.
+	Curve Curve
+	X     []byte
+	Y     []byte
+	D     []byte
+
+	// K is a Symmetric key.
+	K []byte
+
+	// Other holds any other parameters, keyed by int64, int or string label.
+	// WriteKey fails if it holds the label of a parameter set above.
+	Other map[any]cbor.RawMessage
+}
+
+// KeySet is a COSE_KeySet.
+type KeySet []Key
+
+// NewKey returns the Key for [key], which may be an *ecdsa.PublicKey,
+// *ecdsa.PrivateKey, ed25519.PublicKey, ed25519.PrivateKey, an X25519
+// *ecdh.PublicKey or *ecdh.PrivateKey, or a []byte symmetric key.
+func NewKey(key any) (*Key, error) {
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		crv, size, err := ec2Curve(k.Curve)
+		if err != nil {
+			return nil, err
+		}
+		return &Key{
+			Type:  KeyTypeEC2,
+			Curve: crv,
+			X:     k.X.FillBytes(make([]byte, size)),
+			Y:     k.Y.FillBytes(make([]byte, size)),
+		}, nil
+
+	case *ecdsa.PrivateKey:
+		pub, err := NewKey(&k.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		pub.D = k.D.FillBytes(make([]byte, len(pub.X)))
+		return pub, nil
+
+	case ed25519.PublicKey:
+		return &Key{Type: KeyTypeOKP, Curve: CurveEd25519, X: bytes.Clone(k)}, nil
+
+	case ed25519.PrivateKey:
+		return &Key{
+			Type:  KeyTypeOKP,
+			Curve: CurveEd25519,
+			X:     bytes.Clone(k.Public().(ed25519.PublicKey)),
+			D:     bytes.Clone(k.Seed()),
+		}, nil
+
+	case *ecdh.PublicKey:
+		if k.Curve() != ecdh.X25519() {
+			return nil, ErrUnsupportedKey
+		}
+		return &Key{Type: KeyTypeOKP, Curve: CurveX25519, X: k.Bytes()}, nil
+
+	case *ecdh.PrivateKey:
+		if k.Curve() != ecdh.X25519() {
+			return nil, ErrUnsupportedKey
+		}
+		return &Key{Type: KeyTypeOKP, Curve: CurveX25519, X: k.PublicKey().Bytes(), D: k.Bytes()}, nil
+
+	case []byte:
+		return &Key{Type: KeyTypeSymmetric, K: bytes.Clone(k)}, nil
+
+	default:
+		return nil, ErrUnsupportedKey
+	}
+}
+
+// PublicKey returns the public key of an EC2 or OKP key, as an
+// *ecdsa.PublicKey, ed25519.PublicKey or *ecdh.PublicKey. The public key is
+// derived from D if X is missing.
+func (k *Key) PublicKey() (any, error) {
+	if k.X == nil && k.D != nil {
+		priv, err := k.PrivateKey()
+		if err != nil {
+			return nil, err
+		}
+		switch p := priv.(type) {
+		case *ecdsa.PrivateKey:
+			return &p.PublicKey, nil
+		case ed25519.PrivateKey:
+			return p.Public(), nil
+		case *ecdh.PrivateKey:
+			return p.PublicKey(), nil
+		}
+	}
+
+	switch k.Type {
+	case KeyTypeEC2:
+		curve, ecdhCurve, err := k.ec2Curve()
+		if err != nil {
+			return nil, err
+		}
+		point, err := k.ec2Point(curve)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = ecdhCurve.NewPublicKey(point); err != nil {
+			return nil, ErrInvalidKey
+		}
+		return &ecdsa.PublicKey{
+			Curve: curve,
+			X:     new(big.Int).SetBytes(k.X),
+			Y:     new(big.Int).SetBytes(k.Y),
+		}, nil
+
+	case KeyTypeOKP:
+		switch k.Curve {
+		case CurveEd25519:
+			if len(k.X) != ed25519.PublicKeySize {
+				return nil, ErrInvalidKey
+			}
+			return ed25519.PublicKey(bytes.Clone(k.X)), nil
+		case CurveX25519:
+			pub, err := ecdh.X25519().NewPublicKey(k.X)
+			if err != nil {
+				return nil, ErrInvalidKey
+			}
+			return pub, nil
+		default:
+			return nil, ErrUnsupportedKey
+		}
+
+	case KeyTypeSymmetric:
+		return nil, ErrInvalidKey
+
+	default:
+		return nil, ErrUnsupportedKey
+	}
+}
+
+// PrivateKey returns the private key of an EC2 or OKP key, as an
+// *ecdsa.PrivateKey, ed25519.PrivateKey or *ecdh.PrivateKey, or the []byte of
+// a Symmetric key. Any public key parameters must match D.
+func (k *Key) PrivateKey() (any, error) {
+	switch k.Type {
+	case KeyTypeEC2:
+		curve, ecdhCurve, err := k.ec2Curve()
+		if err != nil {
+			return nil, err
+		}
+		priv, err := ecdhCurve.NewPrivateKey(k.D)
+		if err != nil {
+			return nil, ErrInvalidKey
+		}
+
+		point := priv.PublicKey().Bytes()
+		size := (len(point) - 1) / 2
+		if k.X != nil {
+			want, err := k.ec2Point(curve)
+			if err != nil {
+				return nil, err
+			}
+			if !bytes.Equal(point, want) {
+				return nil, ErrInvalidKey
+			}
+		}
+
+		return &ecdsa.PrivateKey{
+			PublicKey: ecdsa.PublicKey{
+				Curve: curve,
+				X:     new(big.Int).SetBytes(point[1 : 1+size]),
+				Y:     new(big.Int).SetBytes(point[1+size:]),
+			},
+			D: new(big.Int).SetBytes(k.D),
+		}, nil
+
+	case KeyTypeOKP:
+		switch k.Curve {
+		case CurveEd25519:
+			if len(k.D) != ed25519.SeedSize {
+				return nil, ErrInvalidKey
+			}
+			priv := ed25519.NewKeyFromSeed(k.D)
+			if k.X != nil && !bytes.Equal(k.X, priv.Public().(ed25519.PublicKey)) {
+				return nil, ErrInvalidKey
+			}
+			return priv, nil
+		case CurveX25519:
+			priv, err := ecdh.X25519().NewPrivateKey(k.D)
+			if err != nil {
+				return nil, ErrInvalidKey
+			}
+			if k.X != nil && !bytes.Equal(k.X, priv.PublicKey().Bytes()) {
+				return nil, ErrInvalidKey
+			}
+			return priv, nil
+		default:
+			return nil, ErrUnsupportedKey
+		}
+
+	case KeyTypeSymmetric:
+		if len(k.K) == 0 {
+			return nil, ErrInvalidKey
+		}
+		return bytes.Clone(k.K), nil
+
+	default:
+		return nil, ErrUnsupportedKey
+	}
+}
+
+func (k *Key) ec2Curve() (elliptic.Curve, ecdh.Curve, error) {
+	switch k.Curve {
+	case CurveP256:
+		return elliptic.P256(), ecdh.P256(), nil
+	case CurveP384:
+		return elliptic.P384(), ecdh.P384(), nil
+	case CurveP521:
+		return elliptic.P521(), ecdh.P521(), nil
+	default:
+		return nil, nil, ErrUnsupportedKey
+	}
+}
+
+// ec2Point returns X and Y as an uncompressed point.
+func (k *Key) ec2Point(curve elliptic.Curve) ([]byte, error) {
+	size := (curve.Params().BitSize + 7) / 8
+	if len(k.X) != size || len(k.Y) != size {
+		return nil, ErrInvalidKey
+	}
+
+	point := make([]byte, 0, 1+2*size)
+	point = append(point, 4)
+	point = append(point, k.X...)
+	return append(point, k.Y...), nil
+}
+
+func ec2Curve(curve elliptic.Curve) (Curve, int, error) {
+	switch curve {
+	case elliptic.P256():
+		return CurveP256, 32, nil
+	case elliptic.P384():
+		return CurveP384, 48, nil
+	case elliptic.P521():
+		return CurveP521, 66, nil
+	default:
+		return 0, 0, ErrUnsupportedKey
+	}
+}
+
+// validate checks the labels required by the key type are present.
+func (k *Key) validate() error {
+	switch k.Type {
+	case 0:
+		return ErrInvalidKey
+	case KeyTypeEC2:
+		if k.Curve == 0 || (k.D == nil && (k.X == nil || k.Y == nil)) || (k.X == nil) != (k.Y == nil) {
+			return ErrInvalidKey
+		}
+	case KeyTypeOKP:
+		if k.Curve == 0 || (k.D == nil && k.X == nil) || k.Y != nil {
+			return ErrInvalidKey
+		}
+	case KeyTypeSymmetric:
+		if len(k.K) == 0 || k.Curve != 0 || k.X != nil || k.Y != nil || k.D != nil {
+			return ErrInvalidKey
+		}
+	default:
+		return ErrUnsupportedKey
+	}
+	return nil
+}
+
+// ReadKey reads a COSE_Key from [in], checking the labels required by its key
+// type are present.
+func ReadKey(in io.Reader) (*Key, error) {
+	var k Key
+	keys := cbor.NewMapKeys(cbor.DupMapKeyError)
+
+	err := cbor.ReadMap(
+		in,
+		func(bool, uint64) error { return nil },
+		func(in io.Reader) error {
+			key, _, err := keys.Next(in)
+			if err != nil {
+				return err
+			}
+
+			label, err := ReadLabel(key)
+			if err != nil {
+				return err
+			}
+
+			// Labels below zero depend on the key type, which may come after
+			// them, so are read generically and resolved below.
+			switch label {
+			case KeyLabelType:
+				var kty int64
+				kty, err = cbor.ReadSigned[int64](in)
+				k.Type = KeyType(kty)
+			case KeyLabelID:
+				k.ID, err = cbor.ReadByteString(in)
+			case KeyLabelAlgorithm:
+				var alg int64
+				alg, err = cbor.ReadSigned[int64](in)
+				k.Algorithm = Algorithm(alg)
+			case KeyLabelOps:
+				k.Ops, err = cbor.ReadSlice(in, ReadLabel)
+			case KeyLabelBaseIV:
+				k.BaseIV, err = cbor.ReadByteString(in)
+			default:
+				if k.Other == nil {
+					k.Other = make(map[any]cbor.RawMessage)
+				}
+				k.Other[label], err = cbor.ReadRawMessage(in)
+			}
+
+			return err
+		},
+	)
+	if err != nil {
+		if err == cbor.ErrDuplicateMapKey || err == cbor.ErrUnsupportedMajorType ||
+			err == cbor.ErrOverflow || err == ErrInvalidMessage {
+			err = ErrInvalidKey
+		}
+		return nil, err
+	}
+
+	if err = k.resolveParameters(); err != nil {
+		return nil, err
+	}
+	if err = k.validate(); err != nil {
+		return nil, err
+	}
+
+	return &k, nil
+}
+
+// resolveParameters moves the parameters of the key type out of Other.
+func (k *Key) resolveParameters() error {
+	take := func(label int64, read func(io.Reader) error) error {
+		raw, ok := k.Other[label]
+		if !ok {
+			return nil
+		}
+		delete(k.Other, label)
+
+		if err := read(raw.Reader()); err != nil {
+			return ErrInvalidKey
+		}
+		return nil
+	}
+
+	readBytes := func(dst *[]byte) func(io.Reader) error {
+		return func(in io.Reader) (err error) {
+			*dst, err = cbor.ReadByteString(in)
+			return err
+		}
+	}
+
+	var err error
+	switch k.Type {
+	case KeyTypeEC2, KeyTypeOKP:
+		err = take(KeyLabelCurve, func(in io.Reader) error {
+			crv, err := cbor.ReadSigned[int64](in)
+			k.Curve = Curve(crv)
+			return err
+		})
+		if err == nil {
+			err = take(KeyLabelX, readBytes(&k.X))
+		}
+		if err == nil && k.Type == KeyTypeEC2 {
+			err = take(KeyLabelY, readBytes(&k.Y))
+		}
+		if err == nil {
+			err = take(KeyLabelD, readBytes(&k.D))
+		}
+	case KeyTypeSymmetric:
+		err = take(KeyLabelK, readBytes(&k.K))
+	}
+
+	if len(k.Other) == 0 {
+		k.Other = nil
+	}
+
+	return err
+}
+
+// WriteKey writes [k] as a COSE_Key map, sorted as described in RFC 8949
+// section 4.2.1.
+func WriteKey(out io.Writer, k *Key) (int, error) {
+	if err := k.validate(); err != nil {
+		return 0, err
+	}
+
+	entries := make(map[any]cbor.RawMessage, len(k.Other)+10)
+	for label, v := range k.Other {
+		entries[NormalizeLabel(label)] = v
+	}
+
+	// Other must not hold a label written from a field.
+	var err error
+	set := func(label int64, b []byte) {
+		if _, ok := entries[label]; ok {
+			err = ErrInvalidKey
+			return
+		}
+		entries[label] = b
+	}
+	add := func(label int64, v any) {
+		if err != nil {
+			return
+		}
+		var b []byte
+		if b, err = cbor.Marshal(v); err == nil {
+			set(label, b)
+		}
+	}
+
+	add(KeyLabelType, int64(k.Type))
+	if k.ID != nil {
+		add(KeyLabelID, k.ID)
+	}
+	if k.Algorithm != 0 {
+		add(KeyLabelAlgorithm, int64(k.Algorithm))
+	}
+	if len(k.Ops) > 0 {
+		b := bytes.NewBuffer(nil)
+		if _, err = cbor.WriteSlice(b, k.Ops, WriteLabel); err != nil {
+			return 0, err
+		}
+		set(KeyLabelOps, b.Bytes())
+	}
+	if k.BaseIV != nil {
+		add(KeyLabelBaseIV, k.BaseIV)
+	}
+
+	switch k.Type {
+	case KeyTypeEC2, KeyTypeOKP:
+		add(KeyLabelCurve, int64(k.Curve))
+		if k.X != nil {
+			add(KeyLabelX, k.X)
+		}
+		if k.Y != nil {
+			add(KeyLabelY, k.Y)
+		}
+		if k.D != nil {
+			add(KeyLabelD, k.D)
+		}
+	case KeyTypeSymmetric:
+		add(KeyLabelK, k.K)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return cbor.WriteMapOfSorted(out, entries, WriteLabel, cbor.WriteRawMessage)
+}
+
+func (k *Key) MarshalCBOR(out io.Writer) error {
+	_, err := WriteKey(out, k)
+	return err
+}
+
+func (k *Key) UnmarshalCBOR(in io.Reader) error {
+	read, err := ReadKey(in)
+	if err != nil {
+		return err
+	}
+
+	*k = *read
+	return nil
+}
+
+// ReadKeySet reads a COSE_KeySet from [in].
+func ReadKeySet(in io.Reader) (KeySet, error) {
+	return cbor.ReadSlice(in, func(in io.Reader) (Key, error) {
+		k, err := ReadKey(in)
+		if err != nil {
+			return Key{}, err
+		}
+		return *k, nil
+	})
+}
+
+// WriteKeySet writes [s] as a COSE_KeySet.
+func WriteKeySet(out io.Writer, s KeySet) (int, error) {
+	return cbor.WriteSlice(out, s, func(out io.Writer, k Key) (int, error) {
+		return WriteKey(out, &k)
+	})
+}
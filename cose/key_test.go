@@ -0,0 +1,249 @@
+package cose
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	cbor "github.com/alex-richards/tiny-cbor"
+	"github.com/google/go-cmp/cmp"
+)
+
+// The P-256 key of meriadoc.brandybuck@buckland.example from RFC 9052
+// appendix C.7.
+const (
+	testKeyPublic  = "a501020258246d65726961646f632e6272616e64796275636b406275636b6c616e642e6578616d706c65200121582065eda5a12577c2bae829437fe338701a10aaa375e1bb5b5de108de439c08551d2258201e52ed75701163f7f9e40ddf9f341b3dc9ba860af7e0ca7ca7e9eecd0084d19c"
+	testKeyPrivate = "a601020258246d65726961646f632e6272616e64796275636b406275636b6c616e642e6578616d706c65200121582065eda5a12577c2bae829437fe338701a10aaa375e1bb5b5de108de439c08551d2258201e52ed75701163f7f9e40ddf9f341b3dc9ba860af7e0ca7ca7e9eecd0084d19c235820aff907c99f9ad3aae6c4cdf21122bce2bd68b5283e6907154ad911840fa208cf"
+)
+
+func Test_Key(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{name: "EC2 public", encoded: testKeyPublic},
+		{name: "EC2 private", encoded: testKeyPrivate},
+		{name: "Symmetric with unknown", encoded: "a301041863617820420102"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded := decodeHex(t, tt.encoded)
+
+			k, err := ReadKey(bytes.NewReader(encoded))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			out := bytes.NewBuffer(nil)
+			if _, err = WriteKey(out, k); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(encoded, out.Bytes()); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+
+	t.Run("EC2 keys", func(t *testing.T) {
+		k, err := ReadKey(bytes.NewReader(decodeHex(t, testKeyPrivate)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if k.Type != KeyTypeEC2 || k.Curve != CurveP256 || string(k.ID) != "meriadoc.brandybuck@buckland.example" {
+			t.Fatalf("unexpected key %+v", k)
+		}
+
+		priv, err := k.PrivateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		pub, err := k.PublicKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !priv.(*ecdsa.PrivateKey).PublicKey.Equal(pub) {
+			t.Fatal("public key does not match private key")
+		}
+
+		k.X[0] ^= 1
+		if _, err = k.PrivateKey(); err != ErrInvalidKey {
+			t.Fatalf("want %v, got %v", ErrInvalidKey, err)
+		}
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		k, err := ReadKey(bytes.NewReader(decodeHex(t, "a301041863617820420102")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := map[any]cbor.RawMessage{int64(99): {0x61, 0x78}}
+		if diff := cmp.Diff(want, k.Other); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+}
+
+func Test_WriteKey_Other(t *testing.T) {
+	tests := []struct {
+		name    string
+		other   map[any]cbor.RawMessage
+		want    string
+		wantErr error
+	}{
+		{name: "int", other: map[any]cbor.RawMessage{99: {0x02}}, want: "a30104186302204101"},
+		{name: "int K", other: map[any]cbor.RawMessage{-1: {0x41, 0x02}}, wantErr: ErrInvalidKey},
+		{name: "int64 K", other: map[any]cbor.RawMessage{int64(-1): {0x41, 0x02}}, wantErr: ErrInvalidKey},
+		{name: "type", other: map[any]cbor.RawMessage{int64(KeyLabelType): {0x04}}, wantErr: ErrInvalidKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := &Key{Type: KeyTypeSymmetric, K: []byte{0x01}, Other: tt.other}
+
+			out := bytes.NewBuffer(nil)
+			_, err := WriteKey(out, k)
+			if err != tt.wantErr {
+				t.Fatalf("want %v, got %v", tt.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(decodeHex(t, tt.want), out.Bytes()); diff != "" {
+				t.Fatal(diff)
+			}
+			if _, err = ReadKey(out); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func Test_NewKey(t *testing.T) {
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p521, _ := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	_, ed, _ := ed25519.GenerateKey(rand.Reader)
+	x25519, _ := ecdh.X25519().GenerateKey(rand.Reader)
+
+	type equaler interface {
+		Equal(crypto.PublicKey) bool
+	}
+
+	tests := []struct {
+		name string
+		priv crypto.PrivateKey
+		pub  crypto.PublicKey
+	}{
+		{name: "P-256", priv: p256, pub: p256.Public()},
+		{name: "P-521", priv: p521, pub: p521.Public()},
+		{name: "Ed25519", priv: ed, pub: ed.Public()},
+		{name: "X25519", priv: x25519, pub: x25519.PublicKey()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, key := range []any{tt.priv, tt.pub} {
+				k, err := NewKey(key)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				out := bytes.NewBuffer(nil)
+				if _, err = WriteKey(out, k); err != nil {
+					t.Fatal(err)
+				}
+				read, err := ReadKey(out)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				pub, err := read.PublicKey()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !tt.pub.(equaler).Equal(pub) {
+					t.Fatal("public key does not match")
+				}
+
+				if i == 0 {
+					priv, err := read.PrivateKey()
+					if err != nil {
+						t.Fatal(err)
+					}
+					if !tt.priv.(interface{ Equal(crypto.PrivateKey) bool }).Equal(priv) {
+						t.Fatal("private key does not match")
+					}
+				} else if _, err = read.PrivateKey(); err != ErrInvalidKey {
+					t.Fatalf("want %v, got %v", ErrInvalidKey, err)
+				}
+			}
+		})
+	}
+
+	t.Run("Symmetric", func(t *testing.T) {
+		k, err := NewKey([]byte{1, 2, 3})
+		if err != nil {
+			t.Fatal(err)
+		}
+		priv, err := k.PrivateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff([]byte{1, 2, 3}, priv); diff != "" {
+			t.Fatal(diff)
+		}
+		if _, err = k.PublicKey(); err != ErrInvalidKey {
+			t.Fatalf("want %v, got %v", ErrInvalidKey, err)
+		}
+	})
+}
+
+func Test_ReadKey_Errors(t *testing.T) {
+	tests := []struct {
+		encoded string
+		wantErr error
+	}{
+		{encoded: "a0", wantErr: ErrInvalidKey},
+		{encoded: "a10102", wantErr: ErrInvalidKey},
+		{encoded: "a10104", wantErr: ErrInvalidKey},
+		{encoded: "a10103", wantErr: ErrUnsupportedKey},
+		{encoded: "a301040104204101", wantErr: ErrInvalidKey},
+		{encoded: "a30101200621f5", wantErr: ErrInvalidKey},
+		{encoded: "a201022001", wantErr: ErrInvalidKey},
+		{encoded: "a14001", wantErr: ErrInvalidKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			_, err := ReadKey(bytes.NewReader(decodeHex(t, tt.encoded)))
+			if err != tt.wantErr {
+				t.Fatalf("want %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func Test_KeySet(t *testing.T) {
+	encoded := decodeHex(t, "82"+"a301041863617820420102"+testKeyPublic)
+
+	s, err := ReadKeySet(bytes.NewReader(encoded))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s) != 2 || s[0].Type != KeyTypeSymmetric || s[1].Type != KeyTypeEC2 {
+		t.Fatalf("unexpected key set %+v", s)
+	}
+
+	out := bytes.NewBuffer(nil)
+	if _, err = WriteKeySet(out, s); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(encoded, out.Bytes()); diff != "" {
+		t.Fatal(diff)
+	}
+}
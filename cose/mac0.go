@@ -0,0 +1,115 @@
+package cose
+
+import (
+	"crypto/hmac"
+	"io"
+)
+
+// Mac0Message is a COSE_Mac0 message, authenticated with a key shared with
+// the recipient. Only HMAC 256/256 is supported.
+type Mac0Message struct {
+	Protected   Headers
+	Unprotected Headers
+
+	// Payload is the authenticated content. When Detached it is not carried in
+	// the message, and must be set from elsewhere before calling Verify.
+	Payload  []byte
+	Detached bool
+
+	Tag []byte
+
+	// rawProtected is the protected header bucket as authenticated or read,
+	// which must be used as is to verify.
+	rawProtected []byte
+}
+
+// ReadMac0 reads a COSE_Mac0 message from [in], tagged with TagMac0 or
+// untagged.
+func ReadMac0(in io.Reader) (*Mac0Message, error) {
+	m, err := readMessage(in, TagMac0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Mac0Message{
+		Protected:    m.protected,
+		Unprotected:  m.unprotected,
+		Payload:      m.payload,
+		Detached:     m.detached,
+		Tag:          m.last,
+		rawProtected: m.rawProtected,
+	}, nil
+}
+
+// WriteMac0 writes [m], tagged with TagMac0. It must have been authenticated,
+// or read.
+func WriteMac0(out io.Writer, m *Mac0Message) (int, error) {
+	if m.rawProtected == nil || m.Tag == nil {
+		return 0, ErrInvalidMessage
+	}
+
+	return writeMessage(out, message{
+		rawProtected: m.rawProtected,
+		unprotected:  m.Unprotected,
+		payload:      m.Payload,
+		detached:     m.Detached,
+		last:         m.Tag,
+	}, TagMac0)
+}
+
+func (m *Mac0Message) MarshalCBOR(out io.Writer) error {
+	_, err := WriteMac0(out, m)
+	return err
+}
+
+func (m *Mac0Message) UnmarshalCBOR(in io.Reader) error {
+	read, err := ReadMac0(in)
+	if err != nil {
+		return err
+	}
+
+	*m = *read
+	return nil
+}
+
+// Authenticate computes the tag over the payload and protected headers with
+// [key], along with [external] additional authenticated data, which may be
+// nil. The algorithm header is set to AlgHMAC256 if it is unset.
+func (m *Mac0Message) Authenticate(key []byte, external []byte) error {
+	if m.Protected.Algorithm == 0 {
+		m.Protected.Algorithm = AlgHMAC256
+	}
+	if m.Protected.Algorithm != AlgHMAC256 {
+		return ErrUnsupportedAlgorithm
+	}
+
+	rawProtected, err := m.Protected.encodeProtected()
+	if err != nil {
+		return err
+	}
+
+	m.rawProtected = rawProtected
+	m.Tag = hmac256(key, toBeAuthenticated("MAC0", rawProtected, external, m.Payload))
+	return nil
+}
+
+// Verify checks the tag with [key], along with [external] additional
+// authenticated data, which may be nil.
+func (m *Mac0Message) Verify(key []byte, external []byte) error {
+	if m.rawProtected == nil {
+		return ErrInvalidMessage
+	}
+	if m.Detached && m.Payload == nil {
+		return ErrDetachedPayload
+	}
+	if err := checkAlgorithm(&m.Protected, AlgHMAC256); err != nil {
+		return err
+	}
+
+	tag := hmac256(key, toBeAuthenticated("MAC0", m.rawProtected, external, m.Payload))
+	if !hmac.Equal(tag, m.Tag) {
+		return ErrVerification
+	}
+
+	return nil
+}
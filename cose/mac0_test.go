@@ -0,0 +1,84 @@
+package cose
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const (
+	testMac0Key      = "849b57219dae48de646d07dbb533566e976686457c1491be3a76dcea6c427188"
+	testMac0External = "11aa22bb33cc44dd55006699"
+)
+
+func Test_Mac0(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoded  string
+		message  Mac0Message
+		external string
+	}{
+		{
+			name:    "attached",
+			encoded: "d18443a10105a1044a6f75722d73656372657454546869732069732074686520636f6e74656e742e5820a1a848d3471f9d61ee49018d244c824772f223ad4f935293f1789fc3a08d8c58",
+			message: Mac0Message{
+				Unprotected: Headers{KeyID: []byte("our-secret")},
+				Payload:     []byte("This is the content."),
+			},
+		},
+		{
+			name:    "detached",
+			encoded: "d18443a10105a0f65820bf0f822c20433c6d8365212f02a06cb9f1c56d73fc9d7bedeca32be81202c757",
+			message: Mac0Message{
+				Payload:  []byte("This is the content."),
+				Detached: true,
+			},
+			external: testMac0External,
+		},
+	}
+
+	key := decodeHex(t, testMac0Key)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded := decodeHex(t, tt.encoded)
+			external := decodeHex(t, tt.external)
+
+			m := tt.message
+			if err := m.Authenticate(key, external); err != nil {
+				t.Fatal(err)
+			}
+
+			out := bytes.NewBuffer(nil)
+			if _, err := WriteMac0(out, &m); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(encoded, out.Bytes()); diff != "" {
+				t.Fatal(diff)
+			}
+
+			read, err := ReadMac0(bytes.NewReader(encoded))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if read.Detached {
+				if err = read.Verify(key, external); err != ErrDetachedPayload {
+					t.Fatalf("want %v, got %v", ErrDetachedPayload, err)
+				}
+				read.Payload = tt.message.Payload
+			}
+			if err = read.Verify(key, external); err != nil {
+				t.Fatal(err)
+			}
+
+			if err = read.Verify(key, []byte("other")); err != ErrVerification {
+				t.Fatalf("want %v, got %v", ErrVerification, err)
+			}
+			read.Payload = []byte("Other content")
+			if err = read.Verify(key, external); err != ErrVerification {
+				t.Fatalf("want %v, got %v", ErrVerification, err)
+			}
+		})
+	}
+}
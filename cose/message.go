@@ -0,0 +1,144 @@
+package cose
+
+import (
+	"bytes"
+	"io"
+
+	cbor "github.com/alex-richards/tiny-cbor"
+)
+
+// message holds the parts shared by COSE_Sign1 and COSE_Mac0, a protected
+// header bucket, unprotected headers, payload, and signature or tag.
+type message struct {
+	rawProtected []byte
+	protected    Headers
+	unprotected  Headers
+	payload      []byte
+	detached     bool
+	last         []byte
+}
+
+// readMessage reads a message, which may be tagged with [tag].
+func readMessage(in io.Reader, tag uint64) (message, error) {
+	d := cbor.NewDecoder(in)
+
+	majorType, _, value, err := d.PeekType()
+	if err != nil {
+		return message{}, err
+	}
+	if majorType == cbor.MajorTypeTagged {
+		if value != tag {
+			return message{}, cbor.ErrUnexpectedTag
+		}
+		if _, err = cbor.ReadTag(d); err != nil {
+			return message{}, err
+		}
+	}
+
+	var m message
+	labels := make(map[any]struct{})
+	i := 0
+	err = cbor.ReadArray(
+		d,
+		func(indefinite bool, length uint64) error {
+			if !indefinite && length != 4 {
+				return ErrInvalidMessage
+			}
+			return nil
+		},
+		func(in io.Reader) error {
+			defer func() { i++ }()
+
+			var err error
+			switch i {
+			case 0:
+				if m.rawProtected, err = cbor.ReadByteString(in); err == nil {
+					m.protected, err = readProtected(m.rawProtected, labels)
+				}
+
+			case 1:
+				m.unprotected, err = readHeaders(in, labels, false)
+
+			case 2:
+				d := cbor.NewDecoder(in)
+				var majorType cbor.MajorType
+				var value uint64
+				if majorType, _, value, err = d.PeekType(); err != nil {
+					return err
+				}
+				if majorType == cbor.MajorTypeSimpleFloat && value == uint64(cbor.SimpleNull) {
+					m.detached = true
+					err = cbor.ReadOver(d)
+				} else {
+					m.payload, err = cbor.ReadByteString(d)
+				}
+
+			case 3:
+				m.last, err = cbor.ReadByteString(in)
+
+			default:
+				err = ErrInvalidMessage
+			}
+
+			if err == cbor.ErrUnsupportedMajorType {
+				err = ErrInvalidMessage
+			}
+			return err
+		},
+	)
+	if err != nil {
+		return message{}, err
+	}
+	if i != 4 {
+		return message{}, ErrInvalidMessage
+	}
+
+	return m, nil
+}
+
+// writeMessage writes [m], tagged with [tag].
+func writeMessage(out io.Writer, m message, tag uint64) (int, error) {
+	if len(m.unprotected.Critical) > 0 {
+		return 0, ErrInvalidMessage
+	}
+
+	b := bytes.NewBuffer(nil)
+
+	_, _ = cbor.WriteTag(b, tag)
+	_, _ = cbor.WriteArrayHeader(b, 4)
+	_, _ = cbor.WriteBytes(b, m.rawProtected)
+
+	if err := m.unprotected.write(b); err != nil {
+		return 0, err
+	}
+
+	if m.detached {
+		_ = cbor.Encode(b, nil)
+	} else {
+		_, _ = cbor.WriteBytes(b, m.payload)
+	}
+
+	_, _ = cbor.WriteBytes(b, m.last)
+
+	return out.Write(b.Bytes())
+}
+
+// toBeAuthenticated returns the Sig_structure or MAC_structure for
+// [context].
+func toBeAuthenticated(context string, rawProtected []byte, external []byte, payload []byte) []byte {
+	b := bytes.NewBuffer(nil)
+	_, _ = cbor.WriteArrayHeader(b, 4)
+	_, _ = cbor.WriteString(b, context)
+	_, _ = cbor.WriteBytes(b, rawProtected)
+	_, _ = cbor.WriteBytes(b, external)
+	_, _ = cbor.WriteBytes(b, payload)
+	return b.Bytes()
+}
+
+// checkAlgorithm fails if [h] names an algorithm other than [alg], or none.
+func checkAlgorithm(h *Headers, alg Algorithm) error {
+	if h.Algorithm != alg {
+		return ErrUnsupportedAlgorithm
+	}
+	return h.checkCritical()
+}
@@ -0,0 +1,115 @@
+package cose
+
+import (
+	"io"
+)
+
+// Sign1Message is a COSE_Sign1 message, signed by a single signer.
+type Sign1Message struct {
+	Protected   Headers
+	Unprotected Headers
+
+	// Payload is the signed content. When Detached it is not carried in the
+	// message, and must be set from elsewhere before calling Verify.
+	Payload  []byte
+	Detached bool
+
+	Signature []byte
+
+	// rawProtected is the protected header bucket as signed or read, which
+	// must be used as is to verify.
+	rawProtected []byte
+}
+
+// ReadSign1 reads a COSE_Sign1 message from [in], tagged with TagSign1 or
+// untagged.
+func ReadSign1(in io.Reader) (*Sign1Message, error) {
+	m, err := readMessage(in, TagSign1)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Sign1Message{
+		Protected:    m.protected,
+		Unprotected:  m.unprotected,
+		Payload:      m.payload,
+		Detached:     m.detached,
+		Signature:    m.last,
+		rawProtected: m.rawProtected,
+	}, nil
+}
+
+// WriteSign1 writes [m], tagged with TagSign1. It must have been signed, or
+// read.
+func WriteSign1(out io.Writer, m *Sign1Message) (int, error) {
+	if m.rawProtected == nil || m.Signature == nil {
+		return 0, ErrInvalidMessage
+	}
+
+	return writeMessage(out, message{
+		rawProtected: m.rawProtected,
+		unprotected:  m.Unprotected,
+		payload:      m.Payload,
+		detached:     m.Detached,
+		last:         m.Signature,
+	}, TagSign1)
+}
+
+func (m *Sign1Message) MarshalCBOR(out io.Writer) error {
+	_, err := WriteSign1(out, m)
+	return err
+}
+
+func (m *Sign1Message) UnmarshalCBOR(in io.Reader) error {
+	read, err := ReadSign1(in)
+	if err != nil {
+		return err
+	}
+
+	*m = *read
+	return nil
+}
+
+// Sign signs the payload and protected headers with [signer], along with
+// [external] additional authenticated data, which may be nil. The algorithm
+// header is set from [signer] if it is unset. Sign again after changing the
+// protected headers or payload.
+func (m *Sign1Message) Sign(signer Signer, external []byte) error {
+	if m.Protected.Algorithm == 0 {
+		m.Protected.Algorithm = signer.Algorithm()
+	}
+	if m.Protected.Algorithm != signer.Algorithm() {
+		return ErrUnsupportedAlgorithm
+	}
+
+	rawProtected, err := m.Protected.encodeProtected()
+	if err != nil {
+		return err
+	}
+
+	signature, err := signer.Sign(toBeAuthenticated("Signature1", rawProtected, external, m.Payload))
+	if err != nil {
+		return err
+	}
+
+	m.rawProtected = rawProtected
+	m.Signature = signature
+	return nil
+}
+
+// Verify checks the signature with [verifier], whose algorithm must match the
+// protected algorithm header, along with [external] additional authenticated
+// data, which may be nil.
+func (m *Sign1Message) Verify(verifier Verifier, external []byte) error {
+	if m.rawProtected == nil {
+		return ErrInvalidMessage
+	}
+	if m.Detached && m.Payload == nil {
+		return ErrDetachedPayload
+	}
+	if err := checkAlgorithm(&m.Protected, verifier.Algorithm()); err != nil {
+		return err
+	}
+
+	return verifier.Verify(toBeAuthenticated("Signature1", m.rawProtected, external, m.Payload), m.Signature)
+}
@@ -0,0 +1,176 @@
+package cose
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"io"
+	"testing"
+
+	cbor "github.com/alex-richards/tiny-cbor"
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_Sign1(t *testing.T) {
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	_, ed, _ := ed25519.GenerateKey(rand.Reader)
+
+	tests := []struct {
+		alg  Algorithm
+		key  crypto.Signer
+		size int
+	}{
+		{alg: AlgES256, key: p256, size: 64},
+		{alg: AlgES384, key: p384, size: 96},
+		{alg: AlgEdDSA, key: ed, size: 64},
+	}
+
+	for _, tt := range tests {
+		for _, detached := range []bool{false, true} {
+			signer, err := NewSigner(tt.alg, tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			verifier, err := NewVerifier(tt.alg, tt.key.Public())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			m := Sign1Message{
+				Protected:   Headers{ContentType: "text/plain"},
+				Unprotected: Headers{KeyID: []byte("11")},
+				Payload:     []byte("This is the content."),
+				Detached:    detached,
+			}
+			external := []byte("external")
+			if err = m.Sign(signer, external); err != nil {
+				t.Fatal(err)
+			}
+			if len(m.Signature) != tt.size {
+				t.Fatalf("want %d, got %d", tt.size, len(m.Signature))
+			}
+
+			out := bytes.NewBuffer(nil)
+			if _, err = WriteSign1(out, &m); err != nil {
+				t.Fatal(err)
+			}
+
+			read, err := ReadSign1(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if read.Detached != detached {
+				t.Fatalf("want detached %t", detached)
+			}
+			if detached {
+				read.Payload = m.Payload
+			}
+			if diff := cmp.Diff(m, *read, cmp.AllowUnexported(Sign1Message{})); diff != "" {
+				t.Fatal(diff)
+			}
+
+			if err = read.Verify(verifier, external); err != nil {
+				t.Fatal(err)
+			}
+			if err = read.Verify(verifier, nil); err != ErrVerification {
+				t.Fatalf("want %v, got %v", ErrVerification, err)
+			}
+
+			read.Signature[0] ^= 1
+			if err = read.Verify(verifier, external); err != ErrVerification {
+				t.Fatalf("want %v, got %v", ErrVerification, err)
+			}
+		}
+	}
+}
+
+func Test_Sign1_Algorithm(t *testing.T) {
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, ed, _ := ed25519.GenerateKey(rand.Reader)
+
+	if _, err := NewSigner(AlgES384, p256); err != ErrUnsupportedAlgorithm {
+		t.Fatalf("want %v, got %v", ErrUnsupportedAlgorithm, err)
+	}
+	if _, err := NewSigner(AlgHMAC256, p256); err != ErrUnsupportedAlgorithm {
+		t.Fatalf("want %v, got %v", ErrUnsupportedAlgorithm, err)
+	}
+
+	signer, _ := NewSigner(AlgES256, p256)
+	verifier, _ := NewVerifier(AlgEdDSA, ed.Public())
+
+	m := Sign1Message{Payload: []byte("x")}
+	if err := m.Sign(signer, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Verify(verifier, nil); err != ErrUnsupportedAlgorithm {
+		t.Fatalf("want %v, got %v", ErrUnsupportedAlgorithm, err)
+	}
+}
+
+func Test_ReadSign1(t *testing.T) {
+	tests := []struct {
+		encoded string
+		wantErr error
+	}{
+		// protected {1: -7}, unprotected {4: h'3131'}, payload h'01'
+		{encoded: "d28443a10126a10442313141014100"},
+		{encoded: "8443a10126a10442313141014100"},
+		{encoded: "d18443a10126a10442313141014100", wantErr: cbor.ErrUnexpectedTag},
+		{encoded: "d28343a10126a1044231314101", wantErr: ErrInvalidMessage},
+		// label 1 in both buckets
+		{encoded: "d28443a10126a1012641014100", wantErr: ErrInvalidMessage},
+		// duplicate label 1
+		{encoded: "d28445a201260126a041014100", wantErr: ErrInvalidMessage},
+		// unprotected is not a map
+		{encoded: "d28443a10126616141014100", wantErr: ErrInvalidMessage},
+		// protected {1: -7, 2: [99]}
+		{encoded: "d28447a2012602811863a041014100"},
+		// protected {1: -7}, unprotected {2: [1]}
+		{encoded: "d28443a10126a102810141014100", wantErr: ErrInvalidMessage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			_, err := ReadSign1(bytes.NewReader(decodeHex(t, tt.encoded)))
+			if err != tt.wantErr {
+				t.Fatalf("want %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	t.Run("CriticalInt", func(t *testing.T) {
+		p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		signer, _ := NewSigner(AlgES256, p256)
+		verifier, _ := NewVerifier(AlgES256, p256.Public())
+
+		m := Sign1Message{Protected: Headers{Critical: []any{1}}, Payload: []byte("x")}
+		if err := m.Sign(signer, nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.Verify(verifier, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		m.Unprotected.Critical = []any{1}
+		if _, err := WriteSign1(io.Discard, &m); err != ErrInvalidMessage {
+			t.Fatalf("want %v, got %v", ErrInvalidMessage, err)
+		}
+	})
+
+	t.Run("Critical", func(t *testing.T) {
+		m, err := ReadSign1(bytes.NewReader(decodeHex(t, "d28447a2012602811863a041014100")))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		verifier, _ := NewVerifier(AlgES256, p256.Public())
+		if err = m.Verify(verifier, nil); err != ErrUnsupportedCritical {
+			t.Fatalf("want %v, got %v", ErrUnsupportedCritical, err)
+		}
+	})
+}
@@ -0,0 +1,241 @@
+// Package cwt reads, writes and validates CBOR Web Tokens, as described in
+// RFC 8392.
+package cwt
+
+import (
+	"errors"
+	"io"
+	"math"
+	"time"
+
+	cbor "github.com/alex-richards/tiny-cbor"
+	"github.com/alex-richards/tiny-cbor/cose"
+)
+
+var (
+	ErrInvalidClaims   = errors.New("cwt: invalid claims")
+	ErrExpired         = errors.New("cwt: expired")
+	ErrNotYetValid     = errors.New("cwt: not yet valid")
+	ErrInvalidIssuer   = errors.New("cwt: invalid issuer")
+	ErrInvalidAudience = errors.New("cwt: invalid audience")
+)
+
+// TagCWT marks a CWT, wrapping a tagged COSE message.
+const TagCWT uint64 = 61
+
+// Claim keys, from the IANA CBOR Web Token Claims registry.
+const (
+	ClaimIssuer         int64 = 1
+	ClaimSubject        int64 = 2
+	ClaimAudience       int64 = 3
+	ClaimExpirationTime int64 = 4
+	ClaimNotBefore      int64 = 5
+	ClaimIssuedAt       int64 = 6
+	ClaimCWTID          int64 = 7
+	ClaimConfirmation   int64 = 8
+)
+
+// Claims is a CWT claims set. Empty fields are absent.
+type Claims struct {
+	Issuer   string
+	Subject  string
+	Audience string
+
+	ExpirationTime time.Time
+	NotBefore      time.Time
+	IssuedAt       time.Time
+
+	CWTID []byte
+
+	// Confirmation is the cnf claim map, as described in RFC 8747.
+	Confirmation cbor.RawMessage
+
+	// Other holds any other claims, keyed by int64, int or string.
+	// WriteClaims fails if it holds the key of a claim set above.
+	Other map[any]cbor.RawMessage
+}
+
+// ReadClaims reads a claims set from [in]. Unknown claims are kept in Other.
+func ReadClaims(in io.Reader) (*Claims, error) {
+	var c Claims
+	keys := cbor.NewMapKeys(cbor.DupMapKeyError)
+
+	err := cbor.ReadMap(
+		in,
+		func(bool, uint64) error { return nil },
+		func(in io.Reader) error {
+			key, _, err := keys.Next(in)
+			if err != nil {
+				return err
+			}
+
+			claim, err := cose.ReadLabel(key)
+			if err != nil {
+				return err
+			}
+
+			switch claim {
+			case ClaimIssuer:
+				c.Issuer, err = cbor.ReadString(in)
+			case ClaimSubject:
+				c.Subject, err = cbor.ReadString(in)
+			case ClaimAudience:
+				c.Audience, err = cbor.ReadString(in)
+			case ClaimExpirationTime:
+				c.ExpirationTime, err = readNumericDate(in)
+			case ClaimNotBefore:
+				c.NotBefore, err = readNumericDate(in)
+			case ClaimIssuedAt:
+				c.IssuedAt, err = readNumericDate(in)
+			case ClaimCWTID:
+				c.CWTID, err = cbor.ReadByteString(in)
+			case ClaimConfirmation:
+				c.Confirmation, err = cbor.ReadRawMessage(in)
+			default:
+				if c.Other == nil {
+					c.Other = make(map[any]cbor.RawMessage)
+				}
+				c.Other[claim], err = cbor.ReadRawMessage(in)
+			}
+
+			return err
+		},
+	)
+	if err != nil {
+		if err == cbor.ErrDuplicateMapKey || err == cbor.ErrUnsupportedMajorType ||
+			err == cbor.ErrOverflow || err == cbor.ErrUnsupportedValue ||
+			err == cose.ErrInvalidMessage {
+			err = ErrInvalidClaims
+		}
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// WriteClaims writes [c] as a claims set, sorted as described in RFC 8949
+// section 4.2.1.
+func WriteClaims(out io.Writer, c *Claims) (int, error) {
+	entries := make(map[any]cbor.RawMessage, len(c.Other)+8)
+	for k, v := range c.Other {
+		entries[cose.NormalizeLabel(k)] = v
+	}
+
+	// Other must not hold a key written from a field.
+	var err error
+	set := func(claim int64, b []byte) {
+		if _, ok := entries[claim]; ok {
+			err = ErrInvalidClaims
+			return
+		}
+		entries[claim] = b
+	}
+	add := func(claim int64, v any) {
+		if err != nil {
+			return
+		}
+		var b []byte
+		if b, err = cbor.Marshal(v); err == nil {
+			set(claim, b)
+		}
+	}
+
+	if c.Issuer != "" {
+		add(ClaimIssuer, c.Issuer)
+	}
+	if c.Subject != "" {
+		add(ClaimSubject, c.Subject)
+	}
+	if c.Audience != "" {
+		add(ClaimAudience, c.Audience)
+	}
+	if !c.ExpirationTime.IsZero() {
+		add(ClaimExpirationTime, numericDate(c.ExpirationTime))
+	}
+	if !c.NotBefore.IsZero() {
+		add(ClaimNotBefore, numericDate(c.NotBefore))
+	}
+	if !c.IssuedAt.IsZero() {
+		add(ClaimIssuedAt, numericDate(c.IssuedAt))
+	}
+	if c.CWTID != nil {
+		add(ClaimCWTID, c.CWTID)
+	}
+	if c.Confirmation != nil {
+		set(ClaimConfirmation, c.Confirmation)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := cbor.WriteMapOfSorted(out, entries, cose.WriteLabel, cbor.WriteRawMessage)
+	if err == cose.ErrInvalidMessage {
+		err = ErrInvalidClaims
+	}
+	return n, err
+}
+
+func (c *Claims) MarshalCBOR(out io.Writer) error {
+	_, err := WriteClaims(out, c)
+	return err
+}
+
+func (c *Claims) UnmarshalCBOR(in io.Reader) error {
+	read, err := ReadClaims(in)
+	if err != nil {
+		return err
+	}
+
+	*c = *read
+	return nil
+}
+
+// ConfirmationKey returns the COSE_Key of a cnf claim, as described in RFC
+// 8747 section 3.2.
+func (c *Claims) ConfirmationKey() (*cose.Key, error) {
+	if c.Confirmation == nil {
+		return nil, cbor.ErrNotFound
+	}
+
+	in, err := cbor.Lookup(c.Confirmation.Reader(), cbor.Key(1))
+	if err != nil {
+		return nil, err
+	}
+
+	return cose.ReadKey(in)
+}
+
+// numericDate returns [t] as whole seconds since the epoch, or as a float if
+// it has a fractional part.
+func numericDate(t time.Time) any {
+	if t.Nanosecond() == 0 {
+		return t.Unix()
+	}
+	return float64(t.UnixNano()) / float64(time.Second)
+}
+
+func readNumericDate(in io.Reader) (time.Time, error) {
+	d := cbor.NewDecoder(in)
+	majorType, _, _, err := d.PeekType()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if majorType == cbor.MajorTypeSimpleFloat {
+		f, err := cbor.ReadFloat[float64](d)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return time.Time{}, ErrInvalidClaims
+		}
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(frac*float64(time.Second))), nil
+	}
+
+	sec, err := cbor.ReadSigned[int64](d)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(sec, 0), nil
+}
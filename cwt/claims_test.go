@@ -0,0 +1,225 @@
+package cwt
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	cbor "github.com/alex-richards/tiny-cbor"
+	"github.com/alex-richards/tiny-cbor/cose"
+	"github.com/google/go-cmp/cmp"
+)
+
+// The claims set of RFC 8392 appendix A.1.
+const testClaims = "a70175636f61703a2f2f61732e6578616d706c652e636f6d02656572696b77037818636f61703a2f2f6c696768742e6578616d706c652e636f6d041a5612aeb0051a5610d9f0061a5610d9f007420b71"
+
+func decodeHex(tb testing.TB, encoded string) []byte {
+	tb.Helper()
+
+	decoded, err := hex.DecodeString(encoded)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	return decoded
+}
+
+func Test_Claims(t *testing.T) {
+	tests := []struct {
+		encoded string
+		want    Claims
+	}{
+		{
+			encoded: testClaims,
+			want: Claims{
+				Issuer:         "coap://as.example.com",
+				Subject:        "erikw",
+				Audience:       "coap://light.example.com",
+				ExpirationTime: time.Unix(1444064944, 0),
+				NotBefore:      time.Unix(1443944944, 0),
+				IssuedAt:       time.Unix(1443944944, 0),
+				CWTID:          []byte{0x0b, 0x71},
+			},
+		},
+		{
+			// {4: 1.5, 99: [1], "x": true}
+			encoded: "a304f93e00186381016178f5",
+			want: Claims{
+				ExpirationTime: time.Unix(1, 500000000),
+				Other: map[any]cbor.RawMessage{
+					int64(99): {0x81, 0x01},
+					"x":       {0xf5},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			encoded := decodeHex(t, tt.encoded)
+
+			got, err := ReadClaims(bytes.NewReader(encoded))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(&tt.want, got); diff != "" {
+				t.Fatal(diff)
+			}
+
+			out := bytes.NewBuffer(nil)
+			if _, err = WriteClaims(out, got); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(encoded, out.Bytes()); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func Test_ReadClaims_Errors(t *testing.T) {
+	tests := []string{
+		"a2016161016162",
+		"a10101",
+		"a104f97e00",
+		"a1f501",
+	}
+
+	for _, encoded := range tests {
+		t.Run(encoded, func(t *testing.T) {
+			_, err := ReadClaims(bytes.NewReader(decodeHex(t, encoded)))
+			if err != ErrInvalidClaims {
+				t.Fatalf("want %v, got %v", ErrInvalidClaims, err)
+			}
+		})
+	}
+}
+
+func Test_WriteClaims_Other(t *testing.T) {
+	tests := []struct {
+		name    string
+		claims  Claims
+		want    string
+		wantErr error
+	}{
+		{name: "int", claims: Claims{Issuer: "a", Other: map[any]cbor.RawMessage{9: {0x01}}}, want: "a20161610901"},
+		{name: "int issuer", claims: Claims{Issuer: "a", Other: map[any]cbor.RawMessage{1: {0x62, 0x61, 0x62}}}, wantErr: ErrInvalidClaims},
+		{name: "int64 issuer", claims: Claims{Issuer: "a", Other: map[any]cbor.RawMessage{ClaimIssuer: {0x62, 0x61, 0x62}}}, wantErr: ErrInvalidClaims},
+		{name: "confirmation", claims: Claims{Confirmation: cbor.RawMessage{0xa0}, Other: map[any]cbor.RawMessage{8: {0xa0}}}, wantErr: ErrInvalidClaims},
+		{name: "invalid key", claims: Claims{Other: map[any]cbor.RawMessage{1.5: {0x01}}}, wantErr: ErrInvalidClaims},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := bytes.NewBuffer(nil)
+			_, err := WriteClaims(out, &tt.claims)
+			if err != tt.wantErr {
+				t.Fatalf("want %v, got %v", tt.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(decodeHex(t, tt.want), out.Bytes()); diff != "" {
+				t.Fatal(diff)
+			}
+			if _, err = ReadClaims(out); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func Test_Validator(t *testing.T) {
+	claims := Claims{
+		Issuer:         "iss",
+		Audience:       "aud",
+		ExpirationTime: time.Unix(2000, 0),
+		NotBefore:      time.Unix(1000, 0),
+		IssuedAt:       time.Unix(1000, 0),
+	}
+
+	tests := []struct {
+		now       int64
+		validator Validator
+		wantErr   error
+	}{
+		{now: 1500},
+		{now: 2000, wantErr: ErrExpired},
+		{now: 2000, validator: Validator{Skew: time.Second}},
+		{now: 999, wantErr: ErrNotYetValid},
+		{now: 999, validator: Validator{Skew: time.Second}},
+		{now: 1500, validator: Validator{Issuer: "iss", Audience: "aud"}},
+		{now: 1500, validator: Validator{Issuer: "other"}, wantErr: ErrInvalidIssuer},
+		{now: 1500, validator: Validator{Audience: "other"}, wantErr: ErrInvalidAudience},
+	}
+
+	for _, tt := range tests {
+		v := tt.validator
+		v.Now = func() time.Time { return time.Unix(tt.now, 0) }
+		if err := v.Validate(&claims); err != tt.wantErr {
+			t.Fatalf("%d %+v: want %v, got %v", tt.now, tt.validator, tt.wantErr, err)
+		}
+	}
+}
+
+func Test_ConfirmationKey(t *testing.T) {
+	// {8: {1: {1: 4, -1: h'0102'}}}
+	c, err := ReadClaims(bytes.NewReader(decodeHex(t, "a108a101a2010420420102")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k, err := c.ConfirmationKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.Type != cose.KeyTypeSymmetric || !bytes.Equal(k.K, []byte{1, 2}) {
+		t.Fatalf("unexpected key %+v", k)
+	}
+}
+
+func Test_Sign1(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	signer, _ := cose.NewSigner(cose.AlgEdDSA, priv)
+	verifier, _ := cose.NewVerifier(cose.AlgEdDSA, pub)
+
+	claims := &Claims{Issuer: "iss", CWTID: []byte{1}}
+
+	m, err := Sign(claims, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Unprotected.KeyID = []byte("kid")
+
+	out := bytes.NewBuffer(nil)
+	if _, err = WriteSign1(out, m); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(out.Bytes(), []byte{0xd8, 0x3d, 0xd2}) {
+		t.Fatalf("unexpected tags % x", out.Bytes()[:3])
+	}
+
+	read, err := ReadSign1(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(read.Unprotected.KeyID) != "kid" {
+		t.Fatalf("unexpected key id %q", read.Unprotected.KeyID)
+	}
+
+	got, err := Verify(read, verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(claims, got); diff != "" {
+		t.Fatal(diff)
+	}
+
+	read.Payload[len(read.Payload)-1] ^= 1
+	if _, err = Verify(read, verifier); err != cose.ErrVerification {
+		t.Fatalf("want %v, got %v", cose.ErrVerification, err)
+	}
+}
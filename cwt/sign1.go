@@ -0,0 +1,74 @@
+package cwt
+
+import (
+	"bytes"
+	"io"
+
+	cbor "github.com/alex-richards/tiny-cbor"
+	"github.com/alex-richards/tiny-cbor/cose"
+)
+
+// Sign returns a COSE_Sign1 message carrying [c], signed with [signer].
+// Unprotected headers may be added before it is written.
+func Sign(c *Claims, signer cose.Signer) (*cose.Sign1Message, error) {
+	payload := bytes.NewBuffer(nil)
+	if _, err := WriteClaims(payload, c); err != nil {
+		return nil, err
+	}
+
+	m := &cose.Sign1Message{Payload: payload.Bytes()}
+	if err := m.Sign(signer, nil); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// WriteSign1 writes [m] as a CWT, tagged with TagCWT.
+func WriteSign1(out io.Writer, m *cose.Sign1Message) (int, error) {
+	n, err := cbor.WriteTag(out, TagCWT)
+	if err != nil {
+		return n, err
+	}
+
+	nn, err := cose.WriteSign1(out, m)
+	return n + nn, err
+}
+
+// ReadSign1 reads a CWT made of a COSE_Sign1 message from [in], which may be
+// tagged with TagCWT. Its headers, such as the key ID, can be used to choose
+// the verifier passed to [Verify].
+func ReadSign1(in io.Reader) (*cose.Sign1Message, error) {
+	d := cbor.NewDecoder(in)
+
+	majorType, _, value, err := d.PeekType()
+	if err != nil {
+		return nil, err
+	}
+	if majorType == cbor.MajorTypeTagged && value == TagCWT {
+		if _, err = cbor.ReadTag(d); err != nil {
+			return nil, err
+		}
+	}
+
+	return cose.ReadSign1(d)
+}
+
+// Verify checks the signature of [m] with [verifier] and returns the claims
+// it carries. The claims are not validated.
+func Verify(m *cose.Sign1Message, verifier cose.Verifier) (*Claims, error) {
+	if err := m.Verify(verifier, nil); err != nil {
+		return nil, err
+	}
+
+	in := bytes.NewReader(m.Payload)
+	c, err := ReadClaims(in)
+	if err != nil {
+		return nil, err
+	}
+	if in.Len() != 0 {
+		return nil, ErrInvalidClaims
+	}
+
+	return c, nil
+}
@@ -0,0 +1,45 @@
+package cwt
+
+import "time"
+
+// Validator checks the time based claims of a claims set, and optionally its
+// issuer and audience.
+type Validator struct {
+	// Now returns the current time, time.Now if nil.
+	Now func() time.Time
+
+	// Skew is the clock skew allowed either side of the time based claims.
+	Skew time.Duration
+
+	// Issuer and Audience must match the claims, if set.
+	Issuer   string
+	Audience string
+}
+
+// Validate fails if [c] has expired, is not yet valid, or was issued in the
+// future, allowing for Skew, or if its issuer or audience do not match.
+func (v Validator) Validate(c *Claims) error {
+	now := time.Now()
+	if v.Now != nil {
+		now = v.Now()
+	}
+
+	if !c.ExpirationTime.IsZero() && !now.Before(c.ExpirationTime.Add(v.Skew)) {
+		return ErrExpired
+	}
+	if !c.NotBefore.IsZero() && now.Before(c.NotBefore.Add(-v.Skew)) {
+		return ErrNotYetValid
+	}
+	if !c.IssuedAt.IsZero() && now.Before(c.IssuedAt.Add(-v.Skew)) {
+		return ErrNotYetValid
+	}
+
+	if v.Issuer != "" && c.Issuer != v.Issuer {
+		return ErrInvalidIssuer
+	}
+	if v.Audience != "" && c.Audience != v.Audience {
+		return ErrInvalidAudience
+	}
+
+	return nil
+}
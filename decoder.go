@@ -0,0 +1,21 @@
+package cbor
+
+import "io"
+
+// Decoder wraps a reader so that the header of the next object can be
+// inspected before it is read. A Decoder is itself a reader, and can be
+// passed to any of the Read functions.
+type Decoder struct {
+	peekReader
+}
+
+func NewDecoder(in io.Reader) *Decoder {
+	return &Decoder{peekReader: peekReader{r: in}}
+}
+
+// PeekType returns the major type, argument and value of the next object
+// without consuming it. The value is the integer, length, tag number, simple
+// value or float bits, as decoded by the header.
+func (d *Decoder) PeekType() (MajorType, Arg, uint64, error) {
+	return d.peekHeader()
+}
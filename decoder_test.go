@@ -0,0 +1,103 @@
+package cbor
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_Decoder_PeekType(t *testing.T) {
+	tests := []struct {
+		encoded       string
+		wantMajorType MajorType
+		wantArg       Arg
+		wantValue     uint64
+	}{
+		{encoded: "0a", wantMajorType: MajorTypeUInt, wantArg: 0, wantValue: 10},
+		{encoded: "1b000000e8d4a51000", wantMajorType: MajorTypeUInt, wantArg: Arg64, wantValue: 1000000000000},
+		{encoded: "3903e7", wantMajorType: MajorTypeNInt, wantArg: Arg16, wantValue: 999},
+		{encoded: "6449455446", wantMajorType: MajorTypeTstr, wantArg: 0, wantValue: 4},
+		{encoded: "5f42010243030405ff", wantMajorType: MajorTypeBstr, wantArg: ArgIndefinite, wantValue: 0},
+		{encoded: "98190102030405060708090a0b0c0d0e0f101112131415161718181819", wantMajorType: MajorTypeArray, wantArg: Arg8, wantValue: 25},
+		{encoded: "c11a514b67b0", wantMajorType: MajorTypeTagged, wantArg: 0, wantValue: 1},
+		{encoded: "f6", wantMajorType: MajorTypeSimpleFloat, wantArg: 0, wantValue: uint64(SimpleNull)},
+		{encoded: "fa47c35000", wantMajorType: MajorTypeSimpleFloat, wantArg: SimpleFloat32, wantValue: 0x47c35000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			encoded := decodeHex(t, tt.encoded)
+			decoder := NewDecoder(bytes.NewReader(encoded))
+
+			for range 2 {
+				majorType, arg, value, err := decoder.PeekType()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if majorType != tt.wantMajorType || arg != tt.wantArg || value != tt.wantValue {
+					t.Fatalf("want %d %d %d, got %d %d %d", tt.wantMajorType, tt.wantArg, tt.wantValue, majorType, arg, value)
+				}
+			}
+
+			out := bytes.NewBuffer(nil)
+			if err := ReadRaw(decoder, out); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(encoded, out.Bytes()); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func Test_Decoder_Union(t *testing.T) {
+	decoder := NewDecoder(bytes.NewReader(decodeHex(t, "0a6161f6")))
+
+	var got []any
+	for {
+		majorType, _, value, err := decoder.PeekType()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		switch {
+		case majorType == MajorTypeUInt:
+			v, err := ReadUnsigned[uint64](decoder)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, v)
+		case majorType == MajorTypeTstr:
+			s, err := ReadString(decoder)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, s)
+		case majorType == MajorTypeSimpleFloat && value == uint64(SimpleNull):
+			if err := ReadOver(decoder); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, nil)
+		default:
+			t.Fatalf("unexpected major type %d", majorType)
+		}
+	}
+
+	if diff := cmp.Diff([]any{uint64(10), "a", nil}, got); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func Test_Decoder_PeekType_Truncated(t *testing.T) {
+	decoder := NewDecoder(bytes.NewReader(decodeHex(t, "1a0001")))
+
+	_, _, _, err := decoder.PeekType()
+	if err != io.ErrUnexpectedEOF {
+		t.Fatalf("want %v, got %v", io.ErrUnexpectedEOF, err)
+	}
+}
@@ -0,0 +1,217 @@
+package cbor
+
+import (
+	"encoding/hex"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// String returns [v] in diagnostic notation (RFC 8949 section 8). Encoding
+// indicators are added only where the encoding differs from the preferred
+// one: "_" for indefinite lengths, and "_0" to "_3" for arguments, or floats,
+// wider than needed.
+func (v Value) String() string {
+	var b strings.Builder
+	v.appendDiagnostic(&b)
+	return b.String()
+}
+
+func (v Value) appendDiagnostic(b *strings.Builder) {
+	switch v.Kind {
+	case ValueUint:
+		b.WriteString(strconv.FormatUint(v.Uint, 10))
+		b.WriteString(v.indicator(v.Uint))
+
+	case ValueNegInt:
+		if v.Uint == math.MaxUint64 {
+			b.WriteString("-18446744073709551616")
+		} else {
+			b.WriteString("-" + strconv.FormatUint(v.Uint+1, 10))
+		}
+		b.WriteString(v.indicator(v.Uint))
+
+	case ValueBytes, ValueText:
+		if v.Arg != ArgIndefinite {
+			appendDiagnosticString(b, v.Kind, v.Bytes)
+			b.WriteString(v.indicator(uint64(len(v.Bytes))))
+			return
+		}
+		b.WriteString("(_ ")
+		for i, chunk := range v.Items {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			appendDiagnosticString(b, v.Kind, chunk.Bytes)
+			b.WriteString(chunk.indicator(uint64(len(chunk.Bytes))))
+		}
+		b.WriteString(")")
+
+	case ValueArray:
+		b.WriteString("[")
+		b.WriteString(v.containerIndicator(uint64(len(v.Items))))
+		for i, item := range v.Items {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			item.appendDiagnostic(b)
+		}
+		b.WriteString("]")
+
+	case ValueMap:
+		b.WriteString("{")
+		b.WriteString(v.containerIndicator(uint64(len(v.Pairs))))
+		for i, pair := range v.Pairs {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			pair.Key.appendDiagnostic(b)
+			b.WriteString(": ")
+			pair.Value.appendDiagnostic(b)
+		}
+		b.WriteString("}")
+
+	case ValueTag:
+		b.WriteString(strconv.FormatUint(v.Uint, 10))
+		b.WriteString(v.indicator(v.Uint))
+		b.WriteString("(")
+		for _, item := range v.Items {
+			item.appendDiagnostic(b)
+		}
+		b.WriteString(")")
+
+	case ValueSimple:
+		switch {
+		case v.Uint == uint64(SimpleFalse):
+			b.WriteString("false")
+		case v.Uint == SimpleTrue:
+			b.WriteString("true")
+		case v.Uint == SimpleNull:
+			b.WriteString("null")
+		case v.Uint == SimpleUndefined:
+			b.WriteString("undefined")
+		default:
+			b.WriteString("simple(" + strconv.FormatUint(v.Uint, 10) + ")")
+		}
+		if v.Arg == Arg8 && v.Uint < 32 {
+			b.WriteString("_0")
+		}
+
+	case ValueFloat:
+		f, err := v.AsFloat()
+		if err != nil {
+			b.WriteString("?")
+			return
+		}
+		b.WriteString(formatFloat(f))
+		if shortest := FloatValue(f); shortest.Arg != v.Arg || (math.IsNaN(f) && shortest.Uint != v.Uint) {
+			b.WriteString("_" + strconv.Itoa(int(v.Arg-Arg8)))
+		}
+
+	default:
+		b.WriteString("?")
+	}
+}
+
+// preferredArg returns the argument encoding of the shortest header for
+// [value].
+func preferredArg(value uint64) Arg {
+	switch {
+	case value < uint64(Arg8):
+		return 0
+	case value <= math.MaxUint8:
+		return Arg8
+	case value <= math.MaxUint16:
+		return Arg16
+	case value <= math.MaxUint32:
+		return Arg32
+	default:
+		return Arg64
+	}
+}
+
+// encodedArg returns the argument encoding used for [value], resolving the
+// shortest form.
+func (v Value) encodedArg(value uint64) Arg {
+	if v.Arg == 0 {
+		return preferredArg(value)
+	}
+	return v.Arg
+}
+
+// indicator returns the encoding indicator for a header argument wider than
+// needed for [value].
+func (v Value) indicator(value uint64) string {
+	arg := v.encodedArg(value)
+	if arg == preferredArg(value) || arg < Arg8 || arg > Arg64 {
+		return ""
+	}
+	return "_" + strconv.Itoa(int(arg-Arg8))
+}
+
+// containerIndicator returns the encoding indicator for an array or map,
+// followed by a space if there are items.
+func (v Value) containerIndicator(length uint64) string {
+	s := "_"
+	if v.Arg != ArgIndefinite {
+		s = v.indicator(length)
+	}
+	if s != "" && length > 0 {
+		s += " "
+	}
+	return s
+}
+
+func appendDiagnosticString(b *strings.Builder, kind ValueKind, s []byte) {
+	if kind == ValueBytes {
+		b.WriteString("h'" + hex.EncodeToString(s) + "'")
+		return
+	}
+
+	b.WriteByte('"')
+	for len(s) > 0 {
+		r, n := utf8.DecodeRune(s)
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == utf8.RuneError && n == 1:
+			b.WriteString(`\u` + strconv.FormatUint(uint64(s[0])|0x10000, 16)[1:])
+		case r < 0x20 || r == 0x7f:
+			b.WriteString(`\u` + strconv.FormatUint(uint64(r)|0x10000, 16)[1:])
+		default:
+			b.Write(s[:n])
+		}
+		s = s[n:]
+	}
+	b.WriteByte('"')
+}
+
+// formatFloat formats [f] as in diagnostic notation, always with a decimal
+// point or exponent.
+func formatFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if strings.Contains(s, ".") {
+		return s
+	}
+	if i := strings.IndexByte(s, 'e'); i >= 0 {
+		return s[:i] + ".0" + s[i:]
+	}
+	return s + ".0"
+}
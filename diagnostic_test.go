@@ -0,0 +1,70 @@
+package cbor
+
+import (
+	"bytes"
+	"testing"
+)
+
+func Test_Value_String(t *testing.T) {
+	tests := []struct {
+		encoded string
+		want    string
+	}{
+		{"00", "0"},
+		{"1819", "25"},
+		{"1800", "0_0"},
+		{"190017", "23_1"},
+		{"1b000000000000ffff", "65535_3"},
+		{"20", "-1"},
+		{"3bffffffffffffffff", "-18446744073709551616"},
+		{"4401020304", "h'01020304'"},
+		{"40", "h''"},
+		{"6449455446", `"IETF"`},
+		{"62225c", `"\"\\"`},
+		{"6161", `"a"`},
+		{"780161", `"a"_0`},
+		{"62c3bc", `"ü"`},
+		{"620a01", `"\n\u0001"`},
+		{"5f41614162ff", "(_ h'61', h'62')"},
+		{"7f6161780162ff", `(_ "a", "b"_0)`},
+		{"80", "[]"},
+		{"83010203", "[1, 2, 3]"},
+		{"9f01ff", "[_ 1]"},
+		{"9fff", "[_]"},
+		{"980101", "[_0 1]"},
+		{"a26161016162820203", `{"a": 1, "b": [2, 3]}`},
+		{"bf6161f4ff", `{_ "a": false}`},
+		{"c100", "1(0)"},
+		{"d818f6", "24(null)"},
+		{"d801f6", "1_0(null)"},
+		{"f4", "false"},
+		{"f5", "true"},
+		{"f6", "null"},
+		{"f7", "undefined"},
+		{"f0", "simple(16)"},
+		{"f8ff", "simple(255)"},
+		{"f93c00", "1.0"},
+		{"f93e00", "1.5"},
+		{"fa47c35000", "100000.0"},
+		{"fb3ff0000000000000", "1.0_3"},
+		{"fa3f800000", "1.0_2"},
+		{"fb3ff199999999999a", "1.1"},
+		{"fb7e37e43c8800759c", "1.0e+300"},
+		{"f97c00", "Infinity"},
+		{"f9fc00", "-Infinity"},
+		{"f97e00", "NaN"},
+		{"fb7ff8000000000000", "NaN_3"},
+		{"f97c01", "NaN_1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			v, err := ReadValue(bytes.NewReader(decodeHex(t, tt.encoded)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := v.String(); got != tt.want {
+				t.Fatalf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
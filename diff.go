@@ -0,0 +1,243 @@
+package cbor
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DiffKind is the kind of a [Difference].
+type DiffKind byte
+
+const (
+	// DiffChanged is a value replaced by a different one.
+	DiffChanged DiffKind = iota
+	// DiffAdded is a map entry or array item only in the second object.
+	DiffAdded
+	// DiffRemoved is a map entry or array item only in the first object.
+	DiffRemoved
+	// DiffLength is an array with a different number of items.
+	DiffLength
+	// DiffTag is a tag with a different number, its content is compared
+	// separately.
+	DiffTag
+	// DiffEncoding is the same data encoded differently: argument or float
+	// widths, indefinite lengths, string chunks, or the order of map entries.
+	DiffEncoding
+)
+
+// Difference is a single difference found by [Diff].
+type Difference struct {
+	Kind DiffKind
+
+	// Path is where the difference is, in the form accepted by [ParsePath].
+	// Map keys that are not text or integers are in diagnostic notation.
+	Path string
+
+	// A and B are the differing items, or map keys for a difference in the
+	// encoding of a key. A is unset for DiffAdded, B for DiffRemoved.
+	A, B Value
+}
+
+func (d Difference) String() string {
+	var s string
+	switch d.Kind {
+	case DiffChanged:
+		s = "changed " + d.A.String() + " to " + d.B.String()
+	case DiffAdded:
+		s = "added " + d.B.String()
+	case DiffRemoved:
+		s = "removed " + d.A.String()
+	case DiffLength:
+		s = "length " + strconv.Itoa(len(d.A.Items)) + " to " + strconv.Itoa(len(d.B.Items))
+	case DiffTag:
+		s = "tag " + strconv.FormatUint(d.A.Uint, 10) + " to " + strconv.FormatUint(d.B.Uint, 10)
+	default:
+		s = "encoding " + d.A.String() + " to " + d.B.String()
+	}
+
+	if d.Path == "" {
+		return s
+	}
+	return d.Path + ": " + s
+}
+
+// Diff reads the next object from each of [a] and [b] and returns their
+// differences, in the order they appear. Map entries are matched by key,
+// regardless of order.
+func Diff(a, b io.Reader) ([]Difference, error) {
+	va, err := ReadValue(a)
+	if err != nil {
+		return nil, err
+	}
+	vb, err := ReadValue(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return DiffValues(va, vb), nil
+}
+
+// DiffValues returns the differences between [a] and [b], as [Diff].
+func DiffValues(a, b Value) []Difference {
+	var d differ
+	d.diff(a, b, "")
+	return d.diffs
+}
+
+type differ struct {
+	diffs []Difference
+}
+
+func (d *differ) add(kind DiffKind, path string, a, b Value) {
+	d.diffs = append(d.diffs, Difference{Kind: kind, Path: path, A: a, B: b})
+}
+
+func (d *differ) diff(a, b Value, path string) {
+	if a.Kind != b.Kind {
+		d.add(DiffChanged, path, a, b)
+		return
+	}
+
+	switch a.Kind {
+	case ValueUint, ValueNegInt, ValueSimple:
+		switch {
+		case a.Uint != b.Uint:
+			d.add(DiffChanged, path, a, b)
+		case a.encodedArg(a.Uint) != b.encodedArg(b.Uint):
+			d.add(DiffEncoding, path, a, b)
+		}
+
+	case ValueFloat:
+		fa, _ := a.AsFloat()
+		fb, _ := b.AsFloat()
+		switch {
+		case fa != fb && !(math.IsNaN(fa) && math.IsNaN(fb)):
+			d.add(DiffChanged, path, a, b)
+		case a.Arg != b.Arg || a.Uint != b.Uint:
+			d.add(DiffEncoding, path, a, b)
+		}
+
+	case ValueBytes, ValueText:
+		switch {
+		case !bytes.Equal(a.content(), b.content()):
+			d.add(DiffChanged, path, a, b)
+		case !sameEncoding(a, b):
+			d.add(DiffEncoding, path, a, b)
+		}
+
+	case ValueArray:
+		if len(a.Items) != len(b.Items) {
+			d.add(DiffLength, path, a, b)
+		} else if a.encodedArg(uint64(len(a.Items))) != b.encodedArg(uint64(len(b.Items))) {
+			d.add(DiffEncoding, path, a, b)
+		}
+		for i := range max(len(a.Items), len(b.Items)) {
+			itemPath := path + "/" + strconv.Itoa(i)
+			switch {
+			case i >= len(b.Items):
+				d.add(DiffRemoved, itemPath, a.Items[i], Value{})
+			case i >= len(a.Items):
+				d.add(DiffAdded, itemPath, Value{}, b.Items[i])
+			default:
+				d.diff(a.Items[i], b.Items[i], itemPath)
+			}
+		}
+
+	case ValueMap:
+		d.diffMap(a, b, path)
+
+	case ValueTag:
+		if a.Uint != b.Uint {
+			d.add(DiffTag, path, a, b)
+		} else if a.encodedArg(a.Uint) != b.encodedArg(b.Uint) {
+			d.add(DiffEncoding, path, a, b)
+		}
+		if len(a.Items) == 1 && len(b.Items) == 1 {
+			d.diff(a.Items[0], b.Items[0], path)
+		}
+	}
+}
+
+func (d *differ) diffMap(a, b Value, path string) {
+	start := len(d.diffs)
+	if a.encodedArg(uint64(len(a.Pairs))) != b.encodedArg(uint64(len(b.Pairs))) {
+		d.add(DiffEncoding, path, a, b)
+	}
+
+	// match each entry of a with the first unmatched entry of b with an equal
+	// key
+	keys := make([][]byte, len(b.Pairs))
+	for i, pair := range b.Pairs {
+		keys[i] = appendDeterministic(nil, pair.Key)
+	}
+	matched := make([]bool, len(b.Pairs))
+	matches := make([]int, len(a.Pairs))
+	for i, pair := range a.Pairs {
+		key := appendDeterministic(nil, pair.Key)
+		matches[i] = -1
+		for j := range b.Pairs {
+			if !matched[j] && bytes.Equal(key, keys[j]) {
+				matched[j] = true
+				matches[i] = j
+				break
+			}
+		}
+	}
+
+	ordered := true
+	last := -1
+	for i, pair := range a.Pairs {
+		entryPath := path + "/" + pathSegment(pair.Key)
+		j := matches[i]
+		if j < 0 {
+			d.add(DiffRemoved, entryPath, pair.Value, Value{})
+			continue
+		}
+
+		if j < last {
+			ordered = false
+		}
+		last = j
+
+		if !sameEncoding(pair.Key, b.Pairs[j].Key) {
+			d.add(DiffEncoding, entryPath, pair.Key, b.Pairs[j].Key)
+		}
+		d.diff(pair.Value, b.Pairs[j].Value, entryPath)
+	}
+	for j, pair := range b.Pairs {
+		if !matched[j] {
+			d.add(DiffAdded, path+"/"+pathSegment(pair.Key), Value{}, pair.Value)
+		}
+	}
+
+	// the order only matters when nothing else differs
+	if !ordered && len(d.diffs) == start {
+		d.add(DiffEncoding, path, a, b)
+	}
+}
+
+// sameEncoding reports whether [a] and [b] encode to the same bytes.
+func sameEncoding(a, b Value) bool {
+	var ba, bb bytes.Buffer
+	_, errA := WriteValue(&ba, a)
+	_, errB := WriteValue(&bb, b)
+	return errA == nil && errB == nil && bytes.Equal(ba.Bytes(), bb.Bytes())
+}
+
+// pathSegment returns the path segment for a map key: text as is, integers
+// in decimal, and anything else in diagnostic notation.
+func pathSegment(key Value) string {
+	var s string
+	switch key.Kind {
+	case ValueText:
+		s = string(key.content())
+	case ValueUint, ValueNegInt:
+		s = Value{Kind: key.Kind, Uint: key.Uint}.String()
+	default:
+		s = key.String()
+	}
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
@@ -0,0 +1,107 @@
+package cbor
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_Diff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []string
+	}{
+		{name: "equal", a: "a3616101616282010261636178", b: "a3616101616282010261636178"},
+		{name: "uint", a: "01", b: "02", want: []string{"changed 1 to 2"}},
+		{name: "kind", a: "01", b: "6161", want: []string{`changed 1 to "a"`}},
+		{name: "uint width", a: "01", b: "1801", want: []string{"encoding 1 to 1_0"}},
+		{name: "float", a: "f93c00", b: "f93e00", want: []string{"changed 1.0 to 1.5"}},
+		{name: "float width", a: "f93c00", b: "fa3f800000", want: []string{"encoding 1.0 to 1.0_2"}},
+		{name: "nan", a: "f97e00", b: "f97c01", want: []string{"encoding NaN to NaN_1"}},
+		{name: "float int", a: "f93c00", b: "01", want: []string{"changed 1.0 to 1"}},
+		{name: "text", a: "6161", b: "6162", want: []string{`changed "a" to "b"`}},
+		{name: "text chunks", a: "626162", b: "7f61616162ff", want: []string{`encoding "ab" to (_ "a", "b")`}},
+		{name: "array length", a: "8101", b: "83010203", want: []string{
+			"length 1 to 3",
+			"/1: added 2",
+			"/2: added 3",
+		}},
+		{name: "array removed", a: "83010203", b: "820102", want: []string{
+			"length 3 to 2",
+			"/2: removed 3",
+		}},
+		{name: "array indefinite", a: "820102", b: "9f0102ff", want: []string{"encoding [1, 2] to [_ 1, 2]"}},
+		{name: "map", a: "a3616101616282010261636178", b: "a361610261628301020361646178", want: []string{
+			"/a: changed 1 to 2",
+			"/b: length 2 to 3",
+			"/b/2: added 3",
+			`/c: removed "x"`,
+			`/d: added "x"`,
+		}},
+		{name: "map order", a: "a3616101616282010261636178", b: "a3616282010261610161636178", want: []string{
+			`encoding {"a": 1, "b": [1, 2], "c": "x"} to {"b": [1, 2], "a": 1, "c": "x"}`,
+		}},
+		{name: "map key width", a: "a10101", b: "a1180101", want: []string{"/1: encoding 1 to 1_0"}},
+		{name: "map key escaped", a: "a163612f62a101f5", b: "a163612f62a101f4", want: []string{"/a~1b/1: changed true to false"}},
+		{name: "map key bytes", a: "a1416b01", b: "a1416b02", want: []string{"/h'6b': changed 1 to 2"}},
+		{name: "map negative key", a: "a12001", b: "a12002", want: []string{"/-1: changed 1 to 2"}},
+		{name: "tag", a: "c100", b: "c200", want: []string{"tag 1 to 2"}},
+		{name: "tag content", a: "c100", b: "c201", want: []string{"tag 1 to 2", "changed 0 to 1"}},
+		{name: "tag width", a: "c100", b: "d80100", want: []string{"encoding 1(0) to 1_0(0)"}},
+		{name: "simple", a: "f4", b: "f5", want: []string{"changed false to true"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diffs, err := Diff(bytes.NewReader(decodeHex(t, tt.a)), bytes.NewReader(decodeHex(t, tt.b)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, d := range diffs {
+				got = append(got, d.String())
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func Test_Diff_Kinds(t *testing.T) {
+	diffs := DiffValues(
+		MapValue(Pair{TextValue("a"), UintValue(1)}, Pair{TextValue("b"), ArrayValue()}),
+		MapValue(Pair{TextValue("b"), ArrayValue(NullValue())}, Pair{TextValue("c"), TagValue(1, UintValue(0))}),
+	)
+
+	want := []Difference{
+		{Kind: DiffRemoved, Path: "/a", A: UintValue(1)},
+		{Kind: DiffLength, Path: "/b", A: ArrayValue(), B: ArrayValue(NullValue())},
+		{Kind: DiffAdded, Path: "/b/0", B: NullValue()},
+		{Kind: DiffAdded, Path: "/c", B: TagValue(1, UintValue(0))},
+	}
+	if diff := cmp.Diff(want, diffs); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func Test_Diff_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+	}{
+		{name: "a empty", a: "", b: "00"},
+		{name: "b empty", a: "00", b: ""},
+		{name: "a truncated", a: "8201", b: "00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Diff(bytes.NewReader(decodeHex(t, tt.a)), bytes.NewReader(decodeHex(t, tt.b)))
+			if err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
@@ -1,6 +1,6 @@
 module github.com/alex-richards/tiny-cbor
 
-go 1.22.6
+go 1.23
 
 require github.com/x448/float16 v0.8.4
 
@@ -2,10 +2,13 @@ package cbor
 
 import "io"
 
+const lenHeader = 9
+
 type peekReader struct {
-	r  io.Reader // wrapped reader
-	p  byte      // peeked byte
-	pv bool      // peeded valid
+	r  io.Reader       // wrapped reader
+	p  [lenHeader]byte // peeked bytes
+	po int             // offset of the next peeked byte
+	pn int             // number of peeked bytes
 }
 
 func (r *peekReader) Read(out []byte) (int, error) {
@@ -14,33 +17,69 @@ func (r *peekReader) Read(out []byte) (int, error) {
 		return 0, nil
 	}
 
-	if r.pv {
-		out[0] = r.p
-		r.pv = false
-		if ol == 1 {
-			return 1, nil
+	if r.po < r.pn {
+		n := copy(out, r.p[r.po:r.pn])
+		r.po += n
+		if n == ol {
+			return n, nil
 		}
 
-		n, err := r.r.Read(out[1:ol])
-		return n + 1, err
+		m, err := r.r.Read(out[n:ol])
+		return n + m, err
 	}
 
 	return r.r.Read(out)
 }
 
 func (r *peekReader) PeekByte() (byte, error) {
-	if r.pv {
-		return r.p, nil
+	if err := r.fill(1); err != nil {
+		return 0, err
 	}
 
-	var pb = []byte{0}
-	n, err := r.r.Read(pb)
+	return r.p[r.po], nil
+}
 
-	if n != 1 {
-		return 0, err
+// peekHeader returns the major type and any header arguments of the next
+// object without consuming them.
+func (r *peekReader) peekHeader() (MajorType, Arg, uint64, error) {
+	p, err := r.PeekByte()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	majorType, arg := decodePrefix(p)
+	arg, l, v, err := decodeArg(arg)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	if l > 0 {
+		if err = r.fill(1 + int(l)); err != nil {
+			return 0, 0, 0, err
+		}
+		v = shiftBytesInto[uint64](r.p[r.po+1 : r.po+1+int(l)])
+	}
+
+	return majorType, arg, v, nil
+}
+
+// fill ensures at least [n] bytes are peeked.
+func (r *peekReader) fill(n int) error {
+	if r.pn-r.po >= n {
+		return nil
+	}
+
+	if r.po > 0 {
+		r.pn = copy(r.p[:], r.p[r.po:r.pn])
+		r.po = 0
+	}
+
+	// read exactly what is needed, anything more may belong to another reader
+	m, err := io.ReadFull(r.r, r.p[r.pn:n])
+	r.pn += m
+	if err == io.EOF && r.pn > 0 {
+		return io.ErrUnexpectedEOF
 	}
 
-	r.p = pb[0]
-	r.pv = true
-	return r.p, nil
+	return err
 }
@@ -0,0 +1,55 @@
+package cbor
+
+import (
+	"bytes"
+	"io"
+)
+
+// MapKeys tracks the keys of a map as it is read to apply a [DupMapKey]
+// policy, for use from the readKeyValue callback of [ReadMap].
+//
+// Keys are compared by their deterministic encodings (RFC 8949 section
+// 4.2.1), so keys of any type can be tracked, and equal keys with different
+// encodings, such as 1 and 1 with a one byte argument, are duplicates.
+type MapKeys struct {
+	policy DupMapKey
+	seen   map[string]struct{}
+	key    bytes.Buffer
+	reader bytes.Reader
+}
+
+func NewMapKeys(policy DupMapKey) *MapKeys {
+	return &MapKeys{
+		policy: policy,
+		seen:   make(map[string]struct{}),
+	}
+}
+
+// Next reads the next key from [in] and returns a reader over its encoded
+// bytes, valid until the next call. If the value that follows should be
+// discarded keep is false, and the caller should ReadOver it.
+//
+// With DupMapKeyLastWins keep is always true, and the caller should replace
+// any earlier value.
+func (m *MapKeys) Next(in io.Reader) (key io.Reader, keep bool, err error) {
+	m.key.Reset()
+	if err = ReadRaw(in, &m.key); err != nil {
+		return nil, false, err
+	}
+
+	keep = true
+	if m.policy != DupMapKeyLastWins {
+		seen := string(deterministicKey(m.key.Bytes()))
+		if _, ok := m.seen[seen]; ok {
+			if m.policy == DupMapKeyError {
+				return nil, false, ErrDuplicateMapKey
+			}
+			keep = false
+		} else {
+			m.seen[seen] = struct{}{}
+		}
+	}
+
+	m.reader.Reset(m.key.Bytes())
+	return &m.reader, keep, nil
+}
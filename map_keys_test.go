@@ -0,0 +1,66 @@
+package cbor
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_MapKeys(t *testing.T) {
+	in := bytes.NewReader(decodeHex(t, "a3616101616202616103"))
+
+	keys := NewMapKeys(DupMapKeyFirstWins)
+	got := map[string]uint64{}
+	err := ReadMap(
+		in,
+		func(bool, uint64) error { return nil },
+		func(in io.Reader) error {
+			key, keep, err := keys.Next(in)
+			if err != nil {
+				return err
+			}
+			if !keep {
+				return ReadOver(in)
+			}
+
+			k, err := ReadString(key)
+			if err != nil {
+				return err
+			}
+			v, err := ReadUnsigned[uint64](in)
+			if err != nil {
+				return err
+			}
+			got[k] = v
+			return nil
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(map[string]uint64{"a": 1, "b": 2}, got); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func Test_MapKeys_Encoding(t *testing.T) {
+	in := bytes.NewReader(decodeHex(t, "a26161017f6161ff02"))
+
+	keys := NewMapKeys(DupMapKeyError)
+	err := ReadMap(
+		in,
+		func(bool, uint64) error { return nil },
+		func(in io.Reader) error {
+			if _, _, err := keys.Next(in); err != nil {
+				return err
+			}
+			return ReadOver(in)
+		},
+	)
+	if err != ErrDuplicateMapKey {
+		t.Fatalf("want %v, got %v", ErrDuplicateMapKey, err)
+	}
+}
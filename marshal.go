@@ -0,0 +1,169 @@
+package cbor
+
+import (
+	"bytes"
+	"io"
+)
+
+// Marshaler is implemented by types that write themselves as a single object.
+type Marshaler interface {
+	MarshalCBOR(out io.Writer) error
+}
+
+// Unmarshaler is implemented by types that read themselves from a single
+// object, reading no further than its end.
+type Unmarshaler interface {
+	UnmarshalCBOR(in io.Reader) error
+}
+
+// BytesMarshaler is implemented by types that encode themselves as a slice,
+// with the same method signature as github.com/fxamacker/cbor, so a type can
+// work with both libraries.
+type BytesMarshaler interface {
+	MarshalCBOR() ([]byte, error)
+}
+
+// BytesUnmarshaler is implemented by types that decode themselves from a slice
+// holding a single object, with the same method signature as
+// github.com/fxamacker/cbor.
+type BytesUnmarshaler interface {
+	UnmarshalCBOR(data []byte) error
+}
+
+// Marshal returns the encoding of [v], as [Encode].
+func Marshal(v any) ([]byte, error) {
+	out := bytes.NewBuffer(nil)
+	if err := Encode(out, v); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+// Unmarshal decodes [data], which must hold exactly one object, into [v], as
+// [Decode].
+func Unmarshal(data []byte, v any) error {
+	in := bytes.NewReader(data)
+	if err := Decode(in, v); err != nil {
+		return err
+	}
+
+	if in.Len() != 0 {
+		return ErrTrailingData
+	}
+
+	return nil
+}
+
+// Encode writes [v] to [out]. [v] may be a [Marshaler], a [BytesMarshaler],
+// nil, or a bool, integer, float, string or []byte.
+func Encode(out io.Writer, v any) error {
+	var err error
+	switch v := v.(type) {
+	case Marshaler:
+		err = v.MarshalCBOR(out)
+	case BytesMarshaler:
+		var b []byte
+		if b, err = v.MarshalCBOR(); err == nil {
+			_, err = out.Write(b)
+		}
+	case nil:
+		_, err = writeMajorType(out, MajorTypeSimpleFloat, uint64(SimpleNull))
+	case bool:
+		_, err = WriteBool(out, v)
+	case int:
+		_, err = WriteSigned(out, int64(v))
+	case int8:
+		_, err = WriteSigned(out, v)
+	case int16:
+		_, err = WriteSigned(out, v)
+	case int32:
+		_, err = WriteSigned(out, v)
+	case int64:
+		_, err = WriteSigned(out, v)
+	case uint:
+		_, err = WriteUnsigned(out, uint64(v))
+	case uint8:
+		_, err = WriteUnsigned(out, v)
+	case uint16:
+		_, err = WriteUnsigned(out, v)
+	case uint32:
+		_, err = WriteUnsigned(out, v)
+	case uint64:
+		_, err = WriteUnsigned(out, v)
+	case float32:
+		_, err = WriteFloat(out, v)
+	case float64:
+		_, err = WriteFloat(out, v)
+	case string:
+		_, err = WriteString(out, v)
+	case []byte:
+		_, err = WriteBytes(out, v)
+	default:
+		return ErrUnsupportedValue
+	}
+
+	return err
+}
+
+// Decode reads the next object from [in] into [v], which must be an
+// [Unmarshaler], a [BytesUnmarshaler], or a pointer to a bool, integer, float,
+// string or []byte.
+func Decode(in io.Reader, v any) error {
+	var err error
+	switch v := v.(type) {
+	case Unmarshaler:
+		err = v.UnmarshalCBOR(in)
+	case BytesUnmarshaler:
+		b := bytes.NewBuffer(nil)
+		if err = ReadRaw(in, b); err == nil {
+			err = v.UnmarshalCBOR(b.Bytes())
+		}
+	case *bool:
+		*v, err = ReadBool(in)
+	case *int:
+		var i int64
+		if i, err = ReadSigned[int64](in); err == nil {
+			if int64(int(i)) != i {
+				return ErrOverflow
+			}
+			*v = int(i)
+		}
+	case *int8:
+		*v, err = ReadSigned[int8](in)
+	case *int16:
+		*v, err = ReadSigned[int16](in)
+	case *int32:
+		*v, err = ReadSigned[int32](in)
+	case *int64:
+		*v, err = ReadSigned[int64](in)
+	case *uint:
+		var u uint64
+		if u, err = ReadUnsigned[uint64](in); err == nil {
+			if uint64(uint(u)) != u {
+				return ErrOverflow
+			}
+			*v = uint(u)
+		}
+	case *uint8:
+		*v, err = ReadUnsigned[uint8](in)
+	case *uint16:
+		*v, err = ReadUnsigned[uint16](in)
+	case *uint32:
+		*v, err = ReadUnsigned[uint32](in)
+	case *uint64:
+		*v, err = ReadUnsigned[uint64](in)
+	case *float32:
+		*v, err = ReadFloat[float32](in)
+	case *float64:
+		*v, err = ReadFloat[float64](in)
+	case *string:
+		*v, err = ReadString(in)
+	case *[]byte:
+		*v, err = ReadByteString(in)
+	default:
+		return ErrUnsupportedValue
+	}
+
+	return err
+}
@@ -0,0 +1,157 @@
+package cbor
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type testPoint struct {
+	X, Y int64
+}
+
+func (p testPoint) MarshalCBOR(out io.Writer) error {
+	_, err := WriteSlice(out, []int64{p.X, p.Y}, WriteSigned[int64])
+	return err
+}
+
+func (p *testPoint) UnmarshalCBOR(in io.Reader) error {
+	s, err := ReadSlice(in, ReadSigned[int64])
+	if err != nil {
+		return err
+	}
+	if len(s) != 2 {
+		return ErrUnsupportedValue
+	}
+	p.X, p.Y = s[0], s[1]
+	return nil
+}
+
+// testBytesPoint uses the method signatures of github.com/fxamacker/cbor.
+type testBytesPoint struct {
+	X, Y int64
+}
+
+func (p testBytesPoint) MarshalCBOR() ([]byte, error) {
+	return Marshal(testPoint(p))
+}
+
+func (p *testBytesPoint) UnmarshalCBOR(data []byte) error {
+	return Unmarshal(data, (*testPoint)(p))
+}
+
+func Test_Marshal(t *testing.T) {
+	tests := []struct {
+		encoded string
+		value   any
+		decoded any
+	}{
+		{encoded: "f6", value: nil},
+		{encoded: "f5", value: true, decoded: new(bool)},
+		{encoded: "1864", value: 100, decoded: new(int)},
+		{encoded: "3863", value: int8(-100), decoded: new(int8)},
+		{encoded: "1a000f4240", value: uint(1000000), decoded: new(uint)},
+		{encoded: "fb3ff199999999999a", value: 1.1, decoded: new(float64)},
+		{encoded: "6449455446", value: "IETF", decoded: new(string)},
+		{encoded: "4401020304", value: []byte{1, 2, 3, 4}, decoded: new([]byte)},
+		{encoded: "820120", value: testPoint{X: 1, Y: -1}, decoded: new(testPoint)},
+		{encoded: "820120", value: testBytesPoint{X: 1, Y: -1}, decoded: new(testBytesPoint)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			want := decodeHex(t, tt.encoded)
+
+			got, err := Marshal(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Fatal(diff)
+			}
+
+			if tt.decoded == nil {
+				return
+			}
+			if err = Unmarshal(want, tt.decoded); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.value, derefTest(tt.decoded)); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func Test_Unmarshal_Errors(t *testing.T) {
+	tests := []struct {
+		encoded string
+		v       any
+		wantErr error
+	}{
+		{encoded: "0102", v: new(int), wantErr: ErrTrailingData},
+		{encoded: "6161", v: new(int), wantErr: ErrUnsupportedMajorType},
+		{encoded: "190100", v: new(uint8), wantErr: ErrOverflow},
+		{encoded: "01", v: new(struct{}), wantErr: ErrUnsupportedValue},
+		{encoded: "83010203", v: new(testPoint), wantErr: ErrUnsupportedValue},
+		{encoded: "820120ff", v: new(testBytesPoint), wantErr: ErrTrailingData},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			err := Unmarshal(decodeHex(t, tt.encoded), tt.v)
+			if err != tt.wantErr {
+				t.Fatalf("want %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	t.Run("Marshal", func(t *testing.T) {
+		if _, err := Marshal(struct{}{}); err != ErrUnsupportedValue {
+			t.Fatalf("want %v, got %v", ErrUnsupportedValue, err)
+		}
+	})
+}
+
+func Test_Decode(t *testing.T) {
+	in := bytes.NewReader(decodeHex(t, "8201206449455446"))
+
+	var p testPoint
+	if err := Decode(in, &p); err != nil {
+		t.Fatal(err)
+	}
+	var s string
+	if err := Decode(in, &s); err != nil {
+		t.Fatal(err)
+	}
+
+	if p != (testPoint{X: 1, Y: -1}) || s != "IETF" {
+		t.Fatalf("got %v %q", p, s)
+	}
+}
+
+func derefTest(v any) any {
+	switch v := v.(type) {
+	case *bool:
+		return *v
+	case *int:
+		return *v
+	case *int8:
+		return *v
+	case *uint:
+		return *v
+	case *float64:
+		return *v
+	case *string:
+		return *v
+	case *[]byte:
+		return *v
+	case *testPoint:
+		return *v
+	case *testBytesPoint:
+		return *v
+	}
+	return v
+}
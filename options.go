@@ -0,0 +1,50 @@
+package cbor
+
+// Validity controls which checks beyond well-formedness are made when decoding.
+type Validity byte
+
+const (
+	// ValidityDefault checks the objects returned by typed reads and ReadAny,
+	// but not objects passed over by ReadRaw or ReadOver.
+	ValidityDefault Validity = iota
+	// ValidityNone makes no checks beyond well-formedness.
+	ValidityNone
+	// ValidityStrict checks everything, including objects passed over by
+	// ReadRaw and ReadOver.
+	ValidityStrict
+)
+
+// DupMapKey controls how maps with duplicate keys are decoded.
+type DupMapKey byte
+
+const (
+	// DupMapKeyLastWins keeps the value of the last duplicate key.
+	DupMapKeyLastWins DupMapKey = iota
+	// DupMapKeyFirstWins keeps the value of the first duplicate key.
+	DupMapKeyFirstWins
+	// DupMapKeyError fails with ErrDuplicateMapKey.
+	DupMapKeyError
+)
+
+// DecodeOptions configures the optional behaviour of the decoding functions.
+// The zero value matches the behaviour of the package level functions.
+type DecodeOptions struct {
+	// DecodeTags converts the contents of well known tags into their Go types
+	// in ReadAny, rather than discarding the tag.
+	DecodeTags bool
+
+	// Validity controls checking that text strings are valid UTF-8, and that
+	// the contents of well known tags are valid.
+	Validity Validity
+
+	// DupMapKey controls how duplicate map keys are handled by ReadAny.
+	DupMapKey DupMapKey
+}
+
+func (o DecodeOptions) validateTyped() bool {
+	return o.Validity != ValidityNone
+}
+
+func (o DecodeOptions) validateRaw() bool {
+	return o.Validity == ValidityStrict
+}
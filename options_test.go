@@ -0,0 +1,92 @@
+package cbor
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func Test_Validity(t *testing.T) {
+	tests := []struct {
+		encoded       string
+		wantBytesErr  error
+		wantRawErr    error
+		wantStrictErr error
+	}{
+		{encoded: "62c3bc"},
+		{encoded: "7f62c3bcff"},
+		{encoded: "62c328", wantBytesErr: ErrInvalidUTF8, wantStrictErr: ErrInvalidUTF8},
+		{encoded: "7f61c361bcff", wantBytesErr: ErrInvalidUTF8, wantStrictErr: ErrInvalidUTF8},
+		{encoded: "5f41016161ff", wantBytesErr: ErrMismatchedChunk, wantRawErr: ErrMismatchedChunk, wantStrictErr: ErrMismatchedChunk},
+		{encoded: "7f61614101ff", wantBytesErr: ErrMismatchedChunk, wantRawErr: ErrMismatchedChunk, wantStrictErr: ErrMismatchedChunk},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			encoded := decodeHex(t, tt.encoded)
+
+			err := ReadBytes(bytes.NewReader(encoded),
+				func(indefinite bool, length uint64) error { return nil },
+				io.Discard,
+			)
+			if err != tt.wantBytesErr {
+				t.Fatalf("ReadBytes want %v, got %v", tt.wantBytesErr, err)
+			}
+
+			runTest_Validity(t, encoded, DecodeOptions{}, tt.wantRawErr)
+			runTest_Validity(t, encoded, DecodeOptions{Validity: ValidityStrict}, tt.wantStrictErr)
+		})
+	}
+}
+
+func Test_Validity_Tags(t *testing.T) {
+	tests := []struct {
+		encoded       string
+		wantStrictErr error
+	}{
+		{encoded: "c074323031332d30332d32315432303a30343a30305a"},
+		{encoded: "c06161", wantStrictErr: ErrInvalidTagContent},
+		{encoded: "c11a514b67b0"},
+		{encoded: "c16161", wantStrictErr: ErrInvalidTagContent},
+		{encoded: "c249010000000000000000"},
+		{encoded: "c201", wantStrictErr: ErrInvalidTagContent},
+		{encoded: "c48221196ab3"},
+		{encoded: "c5822003"},
+		{encoded: "c48220c24101"},
+		{encoded: "c48101", wantStrictErr: ErrInvalidTagContent},
+		{encoded: "c482206161", wantStrictErr: ErrInvalidTagContent},
+		{encoded: "d818456449455446"},
+		{encoded: "d8184182", wantStrictErr: ErrInvalidTagContent},
+		{encoded: "d818420101", wantStrictErr: ErrInvalidTagContent},
+		{encoded: "81d8254400010203", wantStrictErr: ErrInvalidTagContent},
+		{encoded: "a1c06161c06161", wantStrictErr: ErrInvalidTagContent},
+		{encoded: "d74401020304"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			encoded := decodeHex(t, tt.encoded)
+
+			runTest_Validity(t, encoded, DecodeOptions{}, nil)
+			runTest_Validity(t, encoded, DecodeOptions{Validity: ValidityStrict}, tt.wantStrictErr)
+		})
+	}
+}
+
+func runTest_Validity(t *testing.T, encoded []byte, o DecodeOptions, wantErr error) {
+	t.Helper()
+
+	out := bytes.NewBuffer(nil)
+	err := o.ReadRaw(bytes.NewReader(encoded), out)
+	if err != wantErr {
+		t.Fatalf("ReadRaw want %v, got %v", wantErr, err)
+	}
+	if err == nil && !bytes.Equal(encoded, out.Bytes()) {
+		t.Fatal("ReadRaw output differs")
+	}
+
+	err = o.ReadOver(bytes.NewReader(encoded))
+	if err != wantErr {
+		t.Fatalf("ReadOver want %v, got %v", wantErr, err)
+	}
+}
@@ -0,0 +1,321 @@
+package cbor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrNotFound    = errors.New("cbor: not found")
+	ErrInvalidPath = errors.New("cbor: invalid path")
+)
+
+// PathElem selects an item within an array or map for [Lookup].
+type PathElem struct {
+	index   uint64
+	isIndex bool
+	keys    [][]byte // encoded map keys that match
+	raw     bool     // keys match exactly, not by value
+	name    string
+	err     error
+}
+
+// Index selects the [i]th item of an array.
+func Index(i uint64) PathElem {
+	return PathElem{
+		index:   i,
+		isIndex: true,
+		name:    strconv.FormatUint(i, 10),
+	}
+}
+
+// Key selects the value of a map entry by its key, which may be anything
+// accepted by [ValueOf] or [Encode]. Any CBOR value, such as a tag or a
+// negative integer beyond int64, can be passed as a [Value]. Keys are matched
+// by value, as [Equal], so a key encoded with wider arguments, string chunks
+// or a different float width still matches.
+func Key(key any) PathElem {
+	b, err := encodeKey(key)
+	if err != nil {
+		return PathElem{err: err}
+	}
+
+	return PathElem{
+		keys: [][]byte{b},
+		name: "h'" + hex.EncodeToString(b) + "'",
+	}
+}
+
+// RawKey selects the value of a map entry whose key is encoded exactly as
+// [key].
+func RawKey(key []byte) PathElem {
+	return PathElem{
+		keys: [][]byte{key},
+		raw:  true,
+		name: "h'" + hex.EncodeToString(key) + "'",
+	}
+}
+
+func (e PathElem) String() string {
+	return e.name
+}
+
+// ParsePath parses a JSON Pointer like path, such as "/a/0", into its
+// elements. Each element matches a map key that is the text string of the
+// element, or, if the element is a decimal integer without leading zeros, an
+// integer map key or array index of that value. "~1" and "~0" escape "/" and
+// "~".
+func ParsePath(path string) ([]PathElem, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	if path[0] != '/' {
+		return nil, ErrInvalidPath
+	}
+
+	segments := strings.Split(path[1:], "/")
+	elems := make([]PathElem, 0, len(segments))
+	for _, segment := range segments {
+		segment, err := unescapePathSegment(segment)
+		if err != nil {
+			return nil, err
+		}
+
+		b, _ := encodeKey(segment)
+		elem := PathElem{keys: [][]byte{b}, name: segment}
+
+		if !isDecimal(segment) {
+			elems = append(elems, elem)
+			continue
+		}
+
+		if i, err := strconv.ParseInt(segment, 10, 64); err == nil {
+			b, _ = encodeKey(i)
+			elem.keys = append(elem.keys, b)
+			if i >= 0 {
+				elem.index = uint64(i)
+				elem.isIndex = true
+			}
+		} else if u, err := strconv.ParseUint(segment, 10, 64); err == nil {
+			b, _ = encodeKey(u)
+			elem.keys = append(elem.keys, b)
+			elem.index = u
+			elem.isIndex = true
+		}
+
+		elems = append(elems, elem)
+	}
+
+	return elems, nil
+}
+
+// Lookup finds the item at [path] within the next object in [in], skipping
+// over everything before it, and returns a reader positioned at the start of
+// the item. Tags on arrays and maps along the path are ignored.
+//
+// The returned reader must be used in place of [in] to read the item.
+func Lookup(in io.Reader, path ...PathElem) (io.Reader, error) {
+	key := bytes.NewBuffer(nil)
+
+	for _, elem := range path {
+		if elem.err != nil {
+			return nil, elem.err
+		}
+
+		majorType, arg, value, err := readMajorType(in)
+		for err == nil && majorType == MajorTypeTagged {
+			majorType, arg, value, err = readMajorType(in)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch majorType {
+		case MajorTypeArray:
+			if !elem.isIndex {
+				return nil, ErrNotFound
+			}
+
+			if arg == ArgIndefinite {
+				pin := &peekReader{r: in}
+				for i := uint64(0); ; i++ {
+					b, err := pin.PeekByte()
+					if err != nil {
+						return nil, err
+					}
+					if b == valueBreak {
+						return nil, ErrNotFound
+					}
+					if i == elem.index {
+						break
+					}
+					if err = ReadOver(pin); err != nil {
+						return nil, err
+					}
+				}
+				in = pin
+			} else {
+				if elem.index >= value {
+					return nil, ErrNotFound
+				}
+				for range elem.index {
+					if err = ReadOver(in); err != nil {
+						return nil, err
+					}
+				}
+			}
+
+		case MajorTypeMap:
+			var pin *peekReader
+			if arg == ArgIndefinite {
+				pin = &peekReader{r: in}
+				in = pin
+			}
+
+			for i := uint64(0); ; i++ {
+				if pin != nil {
+					b, err := pin.PeekByte()
+					if err != nil {
+						return nil, err
+					}
+					if b == valueBreak {
+						return nil, ErrNotFound
+					}
+				} else if i == value {
+					return nil, ErrNotFound
+				}
+
+				key.Reset()
+				if err = ReadRaw(in, key); err != nil {
+					return nil, err
+				}
+				if elem.matches(key.Bytes()) {
+					break
+				}
+				if err = ReadOver(in); err != nil {
+					return nil, err
+				}
+			}
+
+		default:
+			return nil, ErrUnsupportedMajorType
+		}
+	}
+
+	return in, nil
+}
+
+// LookupRaw finds the item at [path] within the next object in [in], as
+// [Lookup], and returns its encoded bytes.
+func LookupRaw(in io.Reader, path ...PathElem) ([]byte, error) {
+	in, err := Lookup(in, path...)
+	if err != nil {
+		return nil, err
+	}
+
+	out := bytes.NewBuffer(nil)
+	if err = ReadRaw(in, out); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+func unescapePathSegment(segment string) (string, error) {
+	if !strings.Contains(segment, "~") {
+		return segment, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(segment); i++ {
+		if segment[i] != '~' {
+			b.WriteByte(segment[i])
+			continue
+		}
+
+		i++
+		switch {
+		case i < len(segment) && segment[i] == '0':
+			b.WriteByte('~')
+		case i < len(segment) && segment[i] == '1':
+			b.WriteByte('/')
+		default:
+			return "", ErrInvalidPath
+		}
+	}
+
+	return b.String(), nil
+}
+
+// isDecimal reports whether [segment] is a decimal integer, optionally
+// negative, without leading zeros.
+func isDecimal(segment string) bool {
+	digits := strings.TrimPrefix(segment, "-")
+	if digits == "" || digits[0] == '0' && (len(digits) > 1 || len(segment) > 1) {
+		return false
+	}
+	return strings.Trim(digits, "0123456789") == ""
+}
+
+// matches reports whether the encoded map [key] is selected.
+func (e PathElem) matches(key []byte) bool {
+	if !e.raw {
+		key = deterministicKey(key)
+	}
+	for _, k := range e.keys {
+		if bytes.Equal(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeKey returns the deterministic encoding of a map [key], as
+// [deterministicKey].
+func encodeKey(key any) ([]byte, error) {
+	v, err := ValueOf(key)
+	if err == ErrUnsupportedValue {
+		var b []byte
+		if b, err = Marshal(key); err != nil {
+			return nil, err
+		}
+		v, err = readValueData(b)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return appendDeterministic(nil, v), nil
+}
+
+// deterministicKey returns the deterministic encoding (RFC 8949 section
+// 4.2.1) of the encoded map [key], so keys can be compared by value. Integers
+// and definite length strings with the shortest headers are returned as is.
+func deterministicKey(key []byte) []byte {
+	majorType, arg, value, n, err := viewHeader(key, 0)
+	if err != nil {
+		return key
+	}
+
+	switch majorType {
+	case MajorTypeUInt, MajorTypeNInt:
+		if arg == preferredArg(value) {
+			return key
+		}
+	case MajorTypeBstr, MajorTypeTstr:
+		if arg == preferredArg(value) && uint64(len(key)-n) == value {
+			return key
+		}
+	}
+
+	v, err := readValueData(key)
+	if err != nil {
+		return key
+	}
+	return appendDeterministic(nil, v)
+}
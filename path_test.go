@@ -0,0 +1,243 @@
+package cbor
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_Lookup(t *testing.T) {
+	tests := []struct {
+		encoded string
+		path    []PathElem
+		want    string
+		wantErr error
+	}{
+		{
+			encoded: "a26161016162820203",
+			path:    []PathElem{Key("b"), Index(1)},
+			want:    "03",
+		},
+		{
+			encoded: "a26161016162820203",
+			path:    []PathElem{Key("a")},
+			want:    "01",
+		},
+		{
+			encoded: "a26161016162820203",
+			path:    nil,
+			want:    "a26161016162820203",
+		},
+		{
+			encoded: "bf61610161629f0203ffff",
+			path:    []PathElem{Key("b"), Index(1)},
+			want:    "03",
+		},
+		{
+			encoded: "bf61610161629f0203ffff",
+			path:    []PathElem{Key("b"), Index(2)},
+			wantErr: ErrNotFound,
+		},
+		{
+			encoded: "bf61610161629f0203ffff",
+			path:    []PathElem{Key("c")},
+			wantErr: ErrNotFound,
+		},
+		{
+			encoded: "a26161016162820203",
+			path:    []PathElem{Key("b"), Index(2)},
+			wantErr: ErrNotFound,
+		},
+		{
+			encoded: "a26161016162820203",
+			path:    []PathElem{Key("a"), Index(0)},
+			wantErr: ErrUnsupportedMajorType,
+		},
+		{
+			encoded: "a201a1206161034401020304",
+			path:    []PathElem{Key(1), Key(-1)},
+			want:    "6161",
+		},
+		{
+			encoded: "a201a1206161034401020304",
+			path:    []PathElem{Key(3)},
+			want:    "4401020304",
+		},
+		{
+			encoded: "a1420102d82183c0f5f6f7",
+			path:    []PathElem{Key([]byte{1, 2}), Index(2)},
+			want:    "f7",
+		},
+		{
+			encoded: "a1420102d82183c0f5f6f7",
+			path:    []PathElem{RawKey([]byte{0x42, 1, 2}), Index(0)},
+			want:    "c0f5",
+		},
+		{
+			encoded: "a11801f5",
+			path:    []PathElem{Key(1)},
+			want:    "f5",
+		},
+		{
+			encoded: "a11801f5",
+			path:    []PathElem{RawKey([]byte{0x01})},
+			wantErr: ErrNotFound,
+		},
+		{
+			encoded: "a17f626162ff01",
+			path:    []PathElem{Key("ab")},
+			want:    "01",
+		},
+		{
+			encoded: "a1fb3ff800000000000001",
+			path:    []PathElem{Key(1.5)},
+			want:    "01",
+		},
+		{
+			encoded: "a1c1016161",
+			path:    []PathElem{Key(TagValue(1, UintValue(1)))},
+			want:    "6161",
+		},
+		{
+			encoded: "a1f5f4",
+			path:    []PathElem{Key(struct{}{})},
+			wantErr: ErrUnsupportedValue,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			got, err := LookupRaw(bytes.NewReader(decodeHex(t, tt.encoded)), tt.path...)
+			if err != tt.wantErr {
+				t.Fatalf("want %v, got %v", tt.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(decodeHex(t, tt.want), got); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func Test_Lookup_Reader(t *testing.T) {
+	in, err := Lookup(bytes.NewReader(decodeHex(t, "9f0102bf6161182aff03ff")), Index(2), Key("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ReadUnsigned[uint8](in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 42 {
+		t.Fatalf("want 42, got %d", got)
+	}
+}
+
+func Test_ParsePath_LeadingZero(t *testing.T) {
+	path, err := ParsePath("/01")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = LookupRaw(bytes.NewReader(decodeHex(t, "820102")), path...)
+	if err != ErrNotFound {
+		t.Fatalf("want %v, got %v", ErrNotFound, err)
+	}
+}
+
+func Test_ParsePath(t *testing.T) {
+	tests := []struct {
+		path     string
+		encoded  string
+		want     string
+		wantErr  error
+		wantPath []string
+	}{
+		{
+			path:     "/b/1",
+			encoded:  "a26161016162820203",
+			want:     "03",
+			wantPath: []string{"b", "1"},
+		},
+		{
+			path:     "/1/-1",
+			encoded:  "a201a1206161034401020304",
+			want:     "6161",
+			wantPath: []string{"1", "-1"},
+		},
+		{
+			path:     "/a~1b/~0",
+			encoded:  "a163612f62a1617e01",
+			want:     "01",
+			wantPath: []string{"a/b", "~"},
+		},
+		{
+			path:     "/18446744073709551615",
+			encoded:  "a11bffffffffffffffff01",
+			want:     "01",
+			wantPath: []string{"18446744073709551615"},
+		},
+		{
+			path:     "/01",
+			encoded:  "a2016161623031f5",
+			want:     "f5",
+			wantPath: []string{"01"},
+		},
+		{
+			path:     "/-0",
+			encoded:  "a2006161622d30f5",
+			want:     "f5",
+			wantPath: []string{"-0"},
+		},
+		{
+			path:     "",
+			encoded:  "01",
+			want:     "01",
+			wantPath: []string{},
+		},
+		{
+			path:    "a",
+			wantErr: ErrInvalidPath,
+		},
+		{
+			path:    "/~2",
+			wantErr: ErrInvalidPath,
+		},
+		{
+			path:    "/~",
+			wantErr: ErrInvalidPath,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			path, err := ParsePath(tt.path)
+			if err != tt.wantErr {
+				t.Fatalf("want %v, got %v", tt.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+
+			gotPath := []string{}
+			for _, elem := range path {
+				gotPath = append(gotPath, elem.String())
+			}
+			if diff := cmp.Diff(tt.wantPath, gotPath); diff != "" {
+				t.Fatal(diff)
+			}
+
+			got, err := LookupRaw(bytes.NewReader(decodeHex(t, tt.encoded)), path...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(decodeHex(t, tt.want), got); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
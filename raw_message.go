@@ -0,0 +1,65 @@
+package cbor
+
+import (
+	"bytes"
+	"io"
+)
+
+// RawMessage is a single encoded object, kept as is to be decoded later or
+// written back verbatim. A nil RawMessage is written as null.
+type RawMessage []byte
+
+// ReadRawMessage reads the next object from [in] without decoding it.
+func ReadRawMessage(in io.Reader) (RawMessage, error) {
+	var m RawMessage
+	if err := m.UnmarshalCBOR(in); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// WriteRawMessage writes [value] verbatim, after checking it holds exactly one
+// well formed object.
+func WriteRawMessage(out io.Writer, value RawMessage) (int, error) {
+	if value == nil {
+		return writeMajorType(out, MajorTypeSimpleFloat, uint64(SimpleNull))
+	}
+
+	in := bytes.NewReader(value)
+	if err := ReadOver(in); err != nil {
+		return 0, err
+	}
+	if in.Len() != 0 {
+		return 0, ErrTrailingData
+	}
+
+	return out.Write(value)
+}
+
+func (m RawMessage) MarshalCBOR(out io.Writer) error {
+	_, err := WriteRawMessage(out, m)
+	return err
+}
+
+// UnmarshalCBOR reads the next object from [in] into [m], reusing its
+// capacity.
+func (m *RawMessage) UnmarshalCBOR(in io.Reader) error {
+	b := bytes.NewBuffer((*m)[:0])
+	if err := ReadRaw(in, b); err != nil {
+		return err
+	}
+
+	*m = b.Bytes()
+	return nil
+}
+
+// Reader returns a reader over [m], to pass to any of the Read functions.
+func (m RawMessage) Reader() io.Reader {
+	return bytes.NewReader(m)
+}
+
+// Unmarshal decodes [m] into [v], as [Unmarshal].
+func (m RawMessage) Unmarshal(v any) error {
+	return Unmarshal(m, v)
+}
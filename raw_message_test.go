@@ -0,0 +1,119 @@
+package cbor
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_RawMessage(t *testing.T) {
+	encoded := decodeHex(t, "a26174016170bf6161820102ff")
+
+	var kind uint64
+	var payload RawMessage
+	err := ReadMap(
+		bytes.NewReader(encoded),
+		func(bool, uint64) error { return nil },
+		func(in io.Reader) error {
+			key, err := ReadString(in)
+			if err != nil {
+				return err
+			}
+			switch key {
+			case "t":
+				kind, err = ReadUnsigned[uint64](in)
+			case "p":
+				err = Decode(in, &payload)
+			default:
+				err = ReadOver(in)
+			}
+			return err
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if kind != 1 {
+		t.Fatalf("want 1, got %d", kind)
+	}
+	if diff := cmp.Diff(RawMessage(decodeHex(t, "bf6161820102ff")), payload); diff != "" {
+		t.Fatal(diff)
+	}
+
+	var a []uint64
+	err = ReadMap(
+		payload.Reader(),
+		func(bool, uint64) error { return nil },
+		func(in io.Reader) error {
+			if err := ReadOver(in); err != nil {
+				return err
+			}
+			a, err = ReadSlice(in, ReadUnsigned[uint64])
+			return err
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]uint64{1, 2}, a); diff != "" {
+		t.Fatal(diff)
+	}
+
+	out := bytes.NewBuffer(nil)
+	if err = Encode(out, payload); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]byte(payload), out.Bytes()); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func Test_WriteRawMessage(t *testing.T) {
+	tests := []struct {
+		value   RawMessage
+		want    string
+		wantErr error
+	}{
+		{value: nil, want: "f6"},
+		{value: RawMessage{0x82, 0x01, 0x02}, want: "820102"},
+		{value: RawMessage{}, wantErr: io.EOF},
+		{value: RawMessage{0x82, 0x01}, wantErr: io.EOF},
+		{value: RawMessage{0x01, 0x02}, wantErr: ErrTrailingData},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			out := bytes.NewBuffer(nil)
+			_, err := WriteRawMessage(out, tt.value)
+			if err != tt.wantErr {
+				t.Fatalf("want %v, got %v", tt.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(decodeHex(t, tt.want), out.Bytes()); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func Test_ReadRawMessage(t *testing.T) {
+	in := bytes.NewReader(decodeHex(t, "8201026449455446"))
+
+	m, err := ReadRawMessage(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(RawMessage{0x82, 0x01, 0x02}, m); diff != "" {
+		t.Fatal(diff)
+	}
+
+	var s string
+	if err = Decode(in, &s); err != nil || s != "IETF" {
+		t.Fatalf("got %q, %v", s, err)
+	}
+}
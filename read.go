@@ -4,6 +4,7 @@ import (
 	"github.com/x448/float16"
 	"io"
 	"math"
+	"slices"
 )
 
 // readMajorType reads the major type and any header arguments from [in].
@@ -243,14 +244,17 @@ func ReadBytes(
 		return err
 	}
 
-	return readBytes(in, majorType, arg, value, readLength, out)
+	return readBytes(in, majorType, arg, value, true, readLength, out)
 }
 
+// readBytes reads the contents of a string, starting from after the header,
+// into [out]. If [validate] is set text strings must be valid UTF-8.
 func readBytes(
 	in io.Reader,
 	majorType MajorType,
 	arg Arg,
 	value uint64,
+	validate bool,
 	readLength func(indefinite bool, length uint64) error,
 	out io.Writer,
 ) error {
@@ -265,19 +269,25 @@ func readBytes(
 		return err
 	}
 
+	var v *utf8Writer
+	if validate && majorType == MajorTypeTstr {
+		v = &utf8Writer{w: out}
+		out = v
+	}
+
 	if indefinite {
 		for {
-			majorType, arg, value, err := readMajorType(in)
+			chunkMajorType, arg, value, err := readMajorType(in)
 			if err != nil {
 				return err
 			}
 
-			if majorType == MajorTypeSimpleFloat && arg == SimpleBreak {
+			if chunkMajorType == MajorTypeSimpleFloat && arg == SimpleBreak {
 				break
 			}
 
-			if majorType != MajorTypeBstr && majorType != MajorTypeTstr {
-				return ErrUnsupportedMajorType
+			if chunkMajorType != majorType {
+				return ErrMismatchedChunk
 			}
 
 			if arg == ArgIndefinite {
@@ -288,17 +298,151 @@ func readBytes(
 			if err != nil {
 				return err
 			}
+
+			if v != nil {
+				if err = v.end(); err != nil {
+					return err
+				}
+			}
 		}
 	} else {
 		err = readByteChunks(in, value, out)
 		if err != nil {
 			return err
 		}
+
+		if v != nil {
+			if err = v.end(); err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
 }
 
+// ReadString reads the next object from [in] as a text string.
+func ReadString(in io.Reader) (string, error) {
+	return ReadStringMax(in, math.MaxUint64)
+}
+
+// ReadStringMax reads the next object from [in] as a text string, failing
+// with ErrTooLarge if it is longer than [maxLength] bytes.
+func ReadStringMax(in io.Reader, maxLength uint64) (string, error) {
+	b, err := readStringMax(in, MajorTypeTstr, nil, maxLength)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// ReadByteString reads the next object from [in] as a byte string.
+func ReadByteString(in io.Reader) ([]byte, error) {
+	return ReadByteStringMax(in, math.MaxUint64)
+}
+
+// ReadByteStringMax reads the next object from [in] as a byte string, failing
+// with ErrTooLarge if it is longer than [maxLength] bytes.
+func ReadByteStringMax(in io.Reader, maxLength uint64) ([]byte, error) {
+	return readStringMax(in, MajorTypeBstr, nil, maxLength)
+}
+
+// ReadBytesInto reads the next object from [in] as a byte or text string,
+// reusing [dst] to hold its contents if it has the capacity.
+func ReadBytesInto(in io.Reader, dst []byte) ([]byte, error) {
+	return ReadBytesIntoMax(in, dst, math.MaxUint64)
+}
+
+// ReadBytesIntoMax reads the next object from [in] as [ReadBytesInto], failing
+// with ErrTooLarge if it is longer than [maxLength] bytes.
+func ReadBytesIntoMax(in io.Reader, dst []byte, maxLength uint64) ([]byte, error) {
+	majorType, arg, value, err := readMajorType(in)
+	if err != nil {
+		return nil, err
+	}
+
+	return appendString(in, majorType, arg, value, dst[:0], maxLength, true)
+}
+
+// readString reads the next object from [in] as a string of [wantMajorType]
+// and returns its contents, checking text strings are valid UTF-8.
+func readString(in io.Reader, wantMajorType MajorType) ([]byte, error) {
+	return readStringMax(in, wantMajorType, nil, math.MaxUint64)
+}
+
+func readStringMax(in io.Reader, wantMajorType MajorType, dst []byte, maxLength uint64) ([]byte, error) {
+	majorType, arg, value, err := readMajorType(in)
+	if err != nil {
+		return nil, err
+	}
+
+	if majorType != wantMajorType {
+		return nil, ErrUnsupportedMajorType
+	}
+
+	return appendString(in, majorType, arg, value, dst, maxLength, true)
+}
+
+// appendString reads the contents of a string, starting from after the
+// header, appending them to [dst], and checking text strings are valid UTF-8
+// if [validate] is set.
+func appendString(
+	in io.Reader,
+	majorType MajorType,
+	arg Arg,
+	value uint64,
+	dst []byte,
+	maxLength uint64,
+	validate bool,
+) ([]byte, error) {
+	w := &sliceWriter{b: dst, max: maxLength}
+	err := readBytes(in, majorType, arg, value, validate,
+		func(indefinite bool, length uint64) error {
+			if indefinite {
+				return nil
+			}
+			if length > maxLength {
+				return ErrTooLarge
+			}
+			w.b = slices.Grow(w.b, int(min(length, maxPreallocBytes)))
+			return nil
+		},
+		w,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if w.b == nil {
+		w.b = []byte{}
+	}
+
+	return w.b, nil
+}
+
+// maxPreallocBytes limits the space reserved for a string from its length
+// alone, so a short input can't claim a large allocation.
+const maxPreallocBytes = 64 * 1024
+
+// sliceWriter appends to a slice, failing with ErrTooLarge once more than
+// [max] bytes have been written.
+type sliceWriter struct {
+	b   []byte
+	n   uint64
+	max uint64
+}
+
+func (w *sliceWriter) Write(p []byte) (int, error) {
+	if uint64(len(p)) > w.max-w.n {
+		return 0, ErrTooLarge
+	}
+
+	w.b = append(w.b, p...)
+	w.n += uint64(len(p))
+	return len(p), nil
+}
+
 func readByteChunks(
 	in io.Reader,
 	length uint64,
@@ -311,8 +455,11 @@ func readByteChunks(
 	for length > 0 {
 		l = int(min(lenSharedBuffer, length))
 		b = sharedBuffer[:l]
-		n, err = in.Read(b)
+		n, err = io.ReadFull(in, b)
 		if n != l {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
 			return err
 		}
 		n, err = out.Write(b[:n])
@@ -5,21 +5,34 @@ package cbor
 import (
 	"bytes"
 	"io"
+	"math"
 )
 
 // ReadAny returns the next object form [in] regardless of type.
 // Outputs can be any of int64, uint64, bool, []byte, string, []any,
 // map[any]any, float32, float64, nil.
+//
+// Text is checked to be valid UTF-8, and the contents of well known tags to be
+// valid, failing with ErrInvalidTagContent; use [DecodeOptions.ReadAny] with
+// ValidityNone to skip these checks.
 func ReadAny(in io.Reader) (any, error) {
+	return DecodeOptions{}.ReadAny(in)
+}
+
+// ReadAny returns the next object from [in] regardless of type, as
+// [ReadAny]. With DecodeTags set, well known tags are additionally converted
+// to *url.URL, []byte, *regexp.Regexp, string, [16]byte, netip.Addr or
+// netip.Prefix.
+func (o DecodeOptions) ReadAny(in io.Reader) (any, error) {
 	majorType, arg, value, err := readMajorType(in)
 	if err != nil {
 		return 0, err
 	}
 
-	return readAny(in, majorType, arg, value)
+	return o.readAny(in, majorType, arg, value)
 }
 
-func readAny(in io.Reader, majorType MajorType, arg Arg, value uint64) (any, error) {
+func (o DecodeOptions) readAny(in io.Reader, majorType MajorType, arg Arg, value uint64) (any, error) {
 	var err error
 	switch majorType {
 	case MajorTypeUInt:
@@ -51,35 +64,17 @@ func readAny(in io.Reader, majorType MajorType, arg Arg, value uint64) (any, err
 		}
 
 	case MajorTypeBstr:
-		b := bytes.NewBuffer(nil)
-		err = readBytes(in, majorType, arg, value,
-			func(indefinite bool, length uint64) error {
-				b.Grow(int(length))
-				return nil
-			},
-			b,
-		)
-		if err != nil {
-			return nil, err
-		}
-		return b.Bytes(), nil
+		return appendString(in, majorType, arg, value, nil, math.MaxUint64, o.validateTyped())
 
 	case MajorTypeTstr:
-		b := bytes.NewBuffer(nil)
-		err = readBytes(in, majorType, arg, value,
-			func(indefinite bool, length uint64) error {
-				b.Grow(int(length))
-				return nil
-			},
-			b,
-		)
+		b, err := appendString(in, majorType, arg, value, nil, math.MaxUint64, o.validateTyped())
 		if err != nil {
 			return nil, err
 		}
-		return string(b.Bytes()), nil
+		return string(b), nil
 
 	case MajorTypeArray:
-		a := make([]any, value)
+		a := make([]any, 0, min(value, maxPrealloc))
 		if arg == ArgIndefinite {
 			for {
 				majorType, arg, value, err := readMajorType(in)
@@ -91,7 +86,7 @@ func readAny(in io.Reader, majorType MajorType, arg Arg, value uint64) (any, err
 					break
 				}
 
-				v, err := readAny(in, majorType, arg, value)
+				v, err := o.readAny(in, majorType, arg, value)
 				if err != nil {
 					return nil, err
 				}
@@ -99,55 +94,87 @@ func readAny(in io.Reader, majorType MajorType, arg Arg, value uint64) (any, err
 			}
 		} else {
 			for i := uint64(0); i < value; i++ {
-				v, err := ReadAny(in)
+				v, err := o.ReadAny(in)
 				if err != nil {
 					return nil, err
 				}
-				a[i] = v
+				a = append(a, v)
 			}
 		}
 		return a, nil
 
 	case MajorTypeMap:
-		m := make(map[any]any, value)
-		if arg == ArgIndefinite {
-			for {
-				majorType, arg, value, err := readMajorType(in)
-				if err != nil {
-					return nil, err
+		m := make(map[any]any, min(value, maxPrealloc))
+
+		var keys *MapKeys
+		if o.DupMapKey != DupMapKeyLastWins {
+			keys = NewMapKeys(o.DupMapKey)
+		}
+
+		err := readMap(
+			in, majorType, arg, value,
+			func(bool, uint64) error { return nil },
+			func(in io.Reader) error {
+				key, keep := in, true
+				if keys != nil {
+					var err error
+					if key, keep, err = keys.Next(in); err != nil {
+						return err
+					}
 				}
 
-				if majorType == MajorTypeSimpleFloat && arg == SimpleBreak {
-					break
+				if !keep {
+					return o.ReadOver(in)
 				}
 
-				k, err := readAny(in, majorType, arg, value)
+				k, err := o.ReadAny(key)
 				if err != nil {
-					return nil, err
+					return err
 				}
-				v, err := ReadAny(in)
-				if err != nil {
-					return nil, err
+				if !hashable(k) {
+					return ErrUnsupportedValue
 				}
-				m[k] = v
-			}
-		} else {
-			for i := uint64(0); i < value; i++ {
-				k, err := ReadAny(in)
-				if err != nil {
-					return nil, err
+
+				// keys that differ only in tags, or integer width, decode
+				// to the same Go value
+				if _, ok := m[k]; ok && keys != nil {
+					if o.DupMapKey == DupMapKeyError {
+						return ErrDuplicateMapKey
+					}
+					return o.ReadOver(in)
 				}
-				v, err := ReadAny(in)
+
+				v, err := o.ReadAny(in)
 				if err != nil {
-					return nil, err
+					return err
 				}
+
 				m[k] = v
-			}
+				return nil
+			},
+		)
+		if err != nil {
+			return nil, err
 		}
 		return m, nil
 
 	case MajorTypeTagged:
-		return ReadAny(in)
+		if o.DecodeTags {
+			if v, ok, err := readTagContent(in, value); ok {
+				return v, err
+			}
+		}
+		if o.validateTyped() && isKnownTag(value) {
+			content := bytes.NewBuffer(nil)
+			if err = ReadRaw(in, content); err != nil {
+				return nil, err
+			}
+			if err = validateTag(value, content.Bytes()); err != nil {
+				return nil, err
+			}
+			return o.ReadAny(content)
+		}
+		return o.ReadAny(in)
 
 	default: // MajorTypeSimpleFloat:
 		switch {
@@ -170,3 +197,13 @@ func readAny(in io.Reader, majorType MajorType, arg Arg, value uint64) (any, err
 		}
 	}
 }
+
+// hashable reports whether [k] can be used as a Go map key.
+func hashable(k any) bool {
+	switch k.(type) {
+	case []byte, []any, map[any]any:
+		return false
+	default:
+		return true
+	}
+}
@@ -6,7 +6,11 @@ import (
 	"bytes"
 	"encoding/hex"
 	"io"
+	"net/netip"
+	"net/url"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 func Test_ReadAny(t *testing.T) {
@@ -31,6 +35,86 @@ func Test_ReadAny(t *testing.T) {
 	}
 }
 
+func Test_ReadAny_DecodeTags(t *testing.T) {
+	tests := []struct {
+		encoded string
+		want    any
+	}{
+		{
+			encoded: "d82076687474703a2f2f7777772e6578616d706c652e636f6d",
+			want:    &url.URL{Scheme: "http", Host: "www.example.com"},
+		},
+		{
+			encoded: "d8226461476b3d",
+			want:    []byte("hi"),
+		},
+		{
+			encoded: "d82550000102030405060708090a0b0c0d0e0f",
+			want:    [16]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+		},
+		{
+			encoded: "d83444c0000201",
+			want:    netip.MustParseAddr("192.0.2.1"),
+		},
+		{
+			encoded: "d83482181843c00002",
+			want:    netip.MustParsePrefix("192.0.2.0/24"),
+		},
+		{
+			encoded: "82d83444c0000201c11a514b67b0",
+			want:    []any{netip.MustParseAddr("192.0.2.1"), uint32(1363896240)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			in := bytes.NewReader(decodeHex(t, tt.encoded))
+
+			got, err := DecodeOptions{DecodeTags: true}.ReadAny(in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got, cmpNetip...); diff != "" {
+				t.Fatal(diff)
+			}
+			if in.Len() != 0 {
+				t.Fatal("trailing data")
+			}
+		})
+	}
+}
+
+func Test_ReadAny_Validity(t *testing.T) {
+	tests := []struct {
+		encoded     string
+		wantErr     error
+		wantNoneErr error
+	}{
+		{encoded: "62c3bc"},
+		{encoded: "62c328", wantErr: ErrInvalidUTF8},
+		{encoded: "7f61c361bcff", wantErr: ErrInvalidUTF8},
+		{encoded: "7f61614101ff", wantErr: ErrMismatchedChunk, wantNoneErr: ErrMismatchedChunk},
+		{encoded: "c06161", wantErr: ErrInvalidTagContent},
+		{encoded: "82c074323031332d30332d32315432303a30343a30305ad8184182", wantErr: ErrInvalidTagContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			encoded := decodeHex(t, tt.encoded)
+
+			_, err := ReadAny(bytes.NewReader(encoded))
+			if err != tt.wantErr {
+				t.Fatalf("want %v, got %v", tt.wantErr, err)
+			}
+
+			_, err = DecodeOptions{Validity: ValidityNone}.ReadAny(bytes.NewReader(encoded))
+			if err != tt.wantNoneErr {
+				t.Fatalf("want %v, got %v", tt.wantNoneErr, err)
+			}
+		})
+	}
+}
+
 func Benchmark_ReadAny(b *testing.B) {
 	for _, tt := range tests_ExampleEncoded {
 		encoded := decodeHex(b, tt.encoded)
@@ -48,3 +132,56 @@ func Benchmark_ReadAny(b *testing.B) {
 		})
 	}
 }
+
+func Test_ReadAny_DupMapKey(t *testing.T) {
+	tests := []struct {
+		encoded string
+		policy  DupMapKey
+		want    any
+		wantErr error
+	}{
+		{encoded: "a2016161016162", policy: DupMapKeyLastWins, want: map[any]any{uint8(1): "b"}},
+		{encoded: "a2016161016162", policy: DupMapKeyFirstWins, want: map[any]any{uint8(1): "a"}},
+		{encoded: "a2016161016162", policy: DupMapKeyError, wantErr: ErrDuplicateMapKey},
+		{encoded: "bf016161016162ff", policy: DupMapKeyFirstWins, want: map[any]any{uint8(1): "a"}},
+		{encoded: "bf016161016162ff", policy: DupMapKeyError, wantErr: ErrDuplicateMapKey},
+		{encoded: "a201616118016162", policy: DupMapKeyError, wantErr: ErrDuplicateMapKey},
+		{encoded: "a20501180502", policy: DupMapKeyLastWins, want: map[any]any{uint8(5): uint8(2)}},
+		{encoded: "a20501180502", policy: DupMapKeyFirstWins, want: map[any]any{uint8(5): uint8(1)}},
+		{encoded: "a20501180502", policy: DupMapKeyError, wantErr: ErrDuplicateMapKey},
+		{encoded: "a20501190005f5", policy: DupMapKeyError, wantErr: ErrDuplicateMapKey},
+		{encoded: "a2016161c1016162", policy: DupMapKeyFirstWins, want: map[any]any{uint8(1): "a"}},
+		{encoded: "a2016161c1016162", policy: DupMapKeyError, wantErr: ErrDuplicateMapKey},
+		{encoded: "a1410102", policy: DupMapKeyLastWins, wantErr: ErrUnsupportedValue},
+		{encoded: "a1410102", policy: DupMapKeyError, wantErr: ErrUnsupportedValue},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			got, err := DecodeOptions{DupMapKey: tt.policy}.ReadAny(bytes.NewReader(decodeHex(t, tt.encoded)))
+			if err != tt.wantErr {
+				t.Fatalf("want %v, got %v", tt.wantErr, err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func Test_ReadAny_LargeLength(t *testing.T) {
+	tests := []string{
+		"7bffffffffffffffff",
+		"5b7fffffffffffffff",
+		"5bffffffffffffffff00",
+	}
+
+	for _, encoded := range tests {
+		t.Run(encoded, func(t *testing.T) {
+			_, err := ReadAny(bytes.NewReader(decodeHex(t, encoded)))
+			if err != io.ErrUnexpectedEOF {
+				t.Fatalf("want %v, got %v", io.ErrUnexpectedEOF, err)
+			}
+		})
+	}
+}
@@ -1,6 +1,9 @@
 package cbor
 
-import "io"
+import (
+	"bytes"
+	"io"
+)
 
 // ReadOver skips the next object in [in].
 func ReadOver(in io.Reader) error {
@@ -9,11 +12,22 @@ func ReadOver(in io.Reader) error {
 		return err
 	}
 
-	return readOver(in, majorType, arg, value)
+	return readOver(in, majorType, arg, value, false)
+}
+
+// ReadOver skips the next object in [in], as [ReadOver], checking the object
+// is valid if Validity is ValidityStrict.
+func (o DecodeOptions) ReadOver(in io.Reader) error {
+	majorType, arg, value, err := readMajorType(in)
+	if err != nil {
+		return err
+	}
+
+	return readOver(in, majorType, arg, value, o.validateRaw())
 }
 
 // readOver skips the next object in [in], starting from after the header.
-func readOver(in io.Reader, majorType MajorType, arg Arg, value uint64) error {
+func readOver(in io.Reader, majorType MajorType, arg Arg, value uint64, validate bool) error {
 	switch majorType {
 	case MajorTypeUInt,
 		MajorTypeNInt,
@@ -22,17 +36,32 @@ func readOver(in io.Reader, majorType MajorType, arg Arg, value uint64) error {
 
 	case MajorTypeBstr,
 		MajorTypeTstr:
+		if validate && majorType == MajorTypeTstr {
+			return readBytes(in, majorType, arg, value, validate,
+				func(indefinite bool, length uint64) error { return nil },
+				io.Discard,
+			)
+		}
+
 		if arg == ArgIndefinite {
 			for {
-				majorType, arg, value, err := readMajorType(in)
+				chunkMajorType, arg, value, err := readMajorType(in)
 				if err != nil {
 					return err
 				}
 
-				if majorType == MajorTypeSimpleFloat && arg == SimpleBreak {
+				if chunkMajorType == MajorTypeSimpleFloat && arg == SimpleBreak {
 					break
 				}
 
+				if chunkMajorType != majorType {
+					return ErrMismatchedChunk
+				}
+
+				if arg == ArgIndefinite {
+					return ErrNestedIndefinite
+				}
+
 				for value > 0 {
 					l := int(min(lenSharedBuffer, value))
 					n, err := in.Read(sharedBuffer[:l])
@@ -67,14 +96,14 @@ func readOver(in io.Reader, majorType MajorType, arg Arg, value uint64) error {
 					break
 				}
 
-				if err = readOver(in, majorType, arg, value); err != nil {
+				if err = readOver(in, majorType, arg, value, validate); err != nil {
 					return err
 				}
 			}
 			return nil
 		} else {
 			for i := uint64(0); i < value; i++ {
-				if err := ReadOver(in); err != nil {
+				if err := readOverNext(in, validate); err != nil {
 					return err
 				}
 			}
@@ -93,20 +122,20 @@ func readOver(in io.Reader, majorType MajorType, arg Arg, value uint64) error {
 					break
 				}
 
-				if err = readOver(in, majorType, arg, value); err != nil {
+				if err = readOver(in, majorType, arg, value, validate); err != nil {
 					return err
 				}
-				if err = ReadOver(in); err != nil {
+				if err = readOverNext(in, validate); err != nil {
 					return err
 				}
 			}
 			return nil
 		} else {
 			for i := uint64(0); i < value; i++ {
-				if err := ReadOver(in); err != nil {
+				if err := readOverNext(in, validate); err != nil {
 					return err
 				}
-				if err := ReadOver(in); err != nil {
+				if err := readOverNext(in, validate); err != nil {
 					return err
 				}
 			}
@@ -114,9 +143,26 @@ func readOver(in io.Reader, majorType MajorType, arg Arg, value uint64) error {
 		}
 
 	case MajorTypeTagged:
-		return ReadOver(in)
+		if validate && isKnownTag(value) {
+			content := bytes.NewBuffer(nil)
+			if err := readRaw(in, content, validate); err != nil {
+				return err
+			}
+			return validateTag(value, content.Bytes())
+		}
+		return readOverNext(in, validate)
 
 	default:
 		return ErrUnsupportedMajorType
 	}
 }
+
+// readOverNext skips the next object in [in].
+func readOverNext(in io.Reader, validate bool) error {
+	majorType, arg, value, err := readMajorType(in)
+	if err != nil {
+		return err
+	}
+
+	return readOver(in, majorType, arg, value, validate)
+}
@@ -1,12 +1,31 @@
 package cbor
 
 import (
+	"bytes"
 	"io"
 )
 
+// ReadRaw copies the next object from [in] to [out] unchanged.
 func ReadRaw(
 	in io.Reader,
 	out io.Writer,
+) error {
+	return readRaw(in, out, false)
+}
+
+// ReadRaw copies the next object from [in] to [out] unchanged, as [ReadRaw],
+// checking the object is valid if Validity is ValidityStrict.
+func (o DecodeOptions) ReadRaw(
+	in io.Reader,
+	out io.Writer,
+) error {
+	return readRaw(in, out, o.validateRaw())
+}
+
+func readRaw(
+	in io.Reader,
+	out io.Writer,
+	validate bool,
 ) error {
 	n, err := in.Read(sharedBuffer[:1])
 	if n != 1 {
@@ -51,14 +70,22 @@ func ReadRaw(
 					break
 				}
 
-				err = ReadRaw(pin, out)
+				chunkMajorType, chunkArg := decodePrefix(b)
+				if chunkMajorType != majorType {
+					return ErrMismatchedChunk
+				}
+				if chunkArg == ArgIndefinite {
+					return ErrNestedIndefinite
+				}
+
+				err = readRaw(pin, out, validate)
 				if err != nil {
 					return err
 				}
 			}
 			return nil
 		} else {
-			return readBytes(in, majorType, arg, v,
+			return readBytes(in, majorType, arg, v, validate,
 				func(indefinite bool, length uint64) error { return nil },
 				out,
 			)
@@ -78,7 +105,7 @@ func ReadRaw(
 					break
 				}
 
-				err = ReadRaw(pin, out)
+				err = readRaw(pin, out, validate)
 				if err != nil {
 					return err
 				}
@@ -86,7 +113,7 @@ func ReadRaw(
 			return nil
 		} else {
 			for range v {
-				err = ReadRaw(in, out)
+				err = readRaw(in, out, validate)
 				if err != nil {
 					return err
 				}
@@ -109,7 +136,7 @@ func ReadRaw(
 				}
 
 				for range 2 {
-					err = ReadRaw(pin, out)
+					err = readRaw(pin, out, validate)
 					if err != nil {
 						return err
 					}
@@ -119,7 +146,7 @@ func ReadRaw(
 		} else {
 			for range v {
 				for range 2 {
-					err = ReadRaw(in, out)
+					err = readRaw(in, out, validate)
 					if err != nil {
 						return err
 					}
@@ -129,6 +156,17 @@ func ReadRaw(
 		}
 
 	default: // MajorTypeTagged
-		return ReadRaw(in, out)
+		if validate && isKnownTag(v) {
+			content := bytes.NewBuffer(nil)
+			if err = readRaw(in, content, validate); err != nil {
+				return err
+			}
+			if err = validateTag(v, content.Bytes()); err != nil {
+				return err
+			}
+			_, err = content.WriteTo(out)
+			return err
+		}
+		return readRaw(in, out, validate)
 	}
 }
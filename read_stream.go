@@ -0,0 +1,177 @@
+package cbor
+
+import (
+	"io"
+	"math"
+)
+
+// ReadBytesStream reads the header of the next object from [in], a byte or
+// text string of definite or indefinite length, and returns a reader of its
+// content. The content is read from [in] on demand, a chunk at a time, so a
+// large string is never held in memory. Text is checked to be valid UTF-8.
+//
+// [in] is positioned after the string once the reader returns io.EOF, or is
+// closed; Close skips any content left unread. [in] must not be read from in
+// the meantime.
+func ReadBytesStream(in io.Reader) (io.ReadCloser, error) {
+	return DecodeOptions{}.ReadBytesStream(in)
+}
+
+// ReadBytesStream returns a reader of the content of the next string in [in],
+// as [ReadBytesStream], checking text is valid UTF-8 unless Validity is
+// ValidityNone.
+func (o DecodeOptions) ReadBytesStream(in io.Reader) (io.ReadCloser, error) {
+	majorType, arg, value, err := readMajorType(in)
+	if err != nil {
+		return nil, err
+	}
+
+	if majorType != MajorTypeBstr && majorType != MajorTypeTstr {
+		return nil, ErrUnsupportedMajorType
+	}
+
+	s := &bytesStream{
+		in:         in,
+		majorType:  majorType,
+		indefinite: arg == ArgIndefinite,
+	}
+	if !s.indefinite {
+		s.remaining = value
+		s.last = true
+	}
+	if o.validateTyped() && majorType == MajorTypeTstr {
+		s.utf8 = &utf8Writer{w: io.Discard}
+	}
+
+	return s, nil
+}
+
+type bytesStream struct {
+	in         io.Reader
+	majorType  MajorType
+	indefinite bool
+	utf8       *utf8Writer // checks text, if set
+
+	remaining uint64 // bytes left in the current chunk
+	last      bool   // the current chunk is the last
+	err       error  // returned by every read once set
+}
+
+func (s *bytesStream) Read(out []byte) (int, error) {
+	if len(out) == 0 {
+		return 0, nil
+	}
+
+	if s.err != nil {
+		return 0, s.err
+	}
+
+	for s.remaining == 0 {
+		if s.err = s.next(); s.err != nil {
+			return 0, s.err
+		}
+	}
+
+	if uint64(len(out)) > s.remaining {
+		out = out[:s.remaining]
+	}
+
+	n, err := s.in.Read(out)
+	s.remaining -= uint64(n)
+
+	if s.utf8 != nil && n > 0 {
+		if verr := s.utf8.check(out[:n]); verr != nil {
+			s.err = verr
+			return 0, verr
+		}
+	}
+
+	if err == io.EOF {
+		if s.remaining > 0 {
+			err = io.ErrUnexpectedEOF
+			s.err = err
+		} else {
+			err = nil
+		}
+	} else if err != nil {
+		s.err = err
+	}
+
+	return n, err
+}
+
+// WriteTo writes the content left unread to [w], copying each chunk with
+// io.CopyN, so io.Copy uses it in place of Read. io.CopyN still allocates a
+// buffer for each chunk unless [w] implements io.ReaderFrom.
+func (s *bytesStream) WriteTo(w io.Writer) (int64, error) {
+	if s.utf8 != nil {
+		s.utf8.w = w
+		defer func() { s.utf8.w = io.Discard }()
+		w = s.utf8
+	}
+
+	var tn int64
+	for {
+		for s.remaining == 0 && s.err == nil {
+			s.err = s.next()
+		}
+		if s.err == io.EOF {
+			return tn, nil
+		}
+		if s.err != nil {
+			return tn, s.err
+		}
+
+		n, err := io.CopyN(w, s.in, int64(min(s.remaining, math.MaxInt64)))
+		tn += n
+		s.remaining -= uint64(n)
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			s.err = err
+			return tn, err
+		}
+	}
+}
+
+// next ends the current chunk, and reads the header of the next one, if any.
+// It returns io.EOF at the end of the string.
+func (s *bytesStream) next() error {
+	if s.utf8 != nil {
+		if err := s.utf8.end(); err != nil {
+			return err
+		}
+	}
+
+	if s.last {
+		return io.EOF
+	}
+
+	majorType, arg, value, err := readMajorType(s.in)
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+
+	if majorType == MajorTypeSimpleFloat && arg == SimpleBreak {
+		return io.EOF
+	}
+
+	if majorType != s.majorType {
+		return ErrMismatchedChunk
+	}
+
+	if arg == ArgIndefinite {
+		return ErrNestedIndefinite
+	}
+
+	s.remaining = value
+	return nil
+}
+
+// Close skips any content left unread, so the underlying reader is positioned
+// after the string.
+func (s *bytesStream) Close() error {
+	_, err := io.Copy(io.Discard, s)
+	return err
+}
@@ -0,0 +1,184 @@
+package cbor
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_ReadBytesStream(t *testing.T) {
+	tests := []struct {
+		encoded string
+		want    string
+	}{
+		{encoded: "40", want: ""},
+		{encoded: "4401020304", want: "01020304"},
+		{encoded: "5f4201024103ff", want: "010203"},
+		{encoded: "5f40ff", want: ""},
+		{encoded: "5fff", want: ""},
+		{encoded: "5f410140410240ff", want: "0102"},
+		{encoded: "6449455446", want: "49455446"},
+		{encoded: "62c3bc", want: "c3bc"},
+		{encoded: "7f62c3bc6161ff", want: "c3bc61"},
+		{encoded: "59000401020304", want: "01020304"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			want := decodeHex(t, tt.want)
+
+			for name, readAll := range map[string]func(io.Reader) ([]byte, error){
+				"read":     io.ReadAll,
+				"one byte": func(r io.Reader) ([]byte, error) { return io.ReadAll(iotest.OneByteReader(r)) },
+				"write to": func(r io.Reader) ([]byte, error) {
+					out := bytes.NewBuffer(nil)
+					_, err := r.(io.WriterTo).WriteTo(out)
+					return out.Bytes(), err
+				},
+			} {
+				t.Run(name, func(t *testing.T) {
+					// followed by true, to check the string is passed over
+					in := bytes.NewReader(append(decodeHex(t, tt.encoded), 0xf5))
+
+					r, err := ReadBytesStream(in)
+					if err != nil {
+						t.Fatal(err)
+					}
+					got, err := readAll(r)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if diff := cmp.Diff(want, got, cmp.Comparer(bytes.Equal)); diff != "" {
+						t.Fatal(diff)
+					}
+
+					if b, err := ReadBool(in); err != nil || !b {
+						t.Fatalf("got %v, %v after string", b, err)
+					}
+				})
+			}
+
+			r, err := ReadBytesStream(bytes.NewReader(decodeHex(t, tt.encoded)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = iotest.TestReader(r, want); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func Test_ReadBytesStream_Close(t *testing.T) {
+	for _, encoded := range []string{"4401020304", "5f42010242030440ff"} {
+		t.Run(encoded, func(t *testing.T) {
+			in := bytes.NewReader(append(decodeHex(t, encoded), 0xf5))
+
+			r, err := ReadBytesStream(in)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			b := make([]byte, 1)
+			if _, err = io.ReadFull(r, b); err != nil || b[0] != 1 {
+				t.Fatal(b, err)
+			}
+			if err = r.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if n, err := r.Read(b); n != 0 || err != io.EOF {
+				t.Fatalf("got %d, %v after close", n, err)
+			}
+
+			if b, err := ReadBool(in); err != nil || !b {
+				t.Fatalf("got %v, %v after string", b, err)
+			}
+		})
+	}
+}
+
+func Test_ReadBytesStream_Errors(t *testing.T) {
+	tests := []struct {
+		encoded   string
+		options   DecodeOptions
+		err       error // from ReadBytesStream
+		streamErr error // from reading the stream
+	}{
+		{encoded: "", err: io.EOF},
+		{encoded: "01", err: ErrUnsupportedMajorType},
+		{encoded: "59", err: io.EOF},
+		{encoded: "4301", streamErr: io.ErrUnexpectedEOF},
+		{encoded: "5f4101", streamErr: io.ErrUnexpectedEOF},
+		{encoded: "5f41", streamErr: io.ErrUnexpectedEOF},
+		{encoded: "5f6161ff", streamErr: ErrMismatchedChunk},
+		{encoded: "5f5fffff", streamErr: ErrNestedIndefinite},
+		{encoded: "61ff", streamErr: ErrInvalidUTF8},
+		{encoded: "61c3", streamErr: ErrInvalidUTF8},
+		{encoded: "7f61c361bcff", streamErr: ErrInvalidUTF8},
+		{encoded: "61ff", options: DecodeOptions{Validity: ValidityNone}},
+		{encoded: "41ff"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			r, err := tt.options.ReadBytesStream(bytes.NewReader(decodeHex(t, tt.encoded)))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+
+			_, err = io.ReadAll(r)
+			if !errors.Is(err, tt.streamErr) {
+				t.Fatalf("got %v, want %v", err, tt.streamErr)
+			}
+
+			r, _ = tt.options.ReadBytesStream(bytes.NewReader(decodeHex(t, tt.encoded)))
+			_, err = r.(io.WriterTo).WriteTo(io.Discard)
+			if !errors.Is(err, tt.streamErr) {
+				t.Fatalf("got %v from write to, want %v", err, tt.streamErr)
+			}
+			if err = r.Close(); !errors.Is(err, tt.streamErr) {
+				t.Fatalf("got %v from close, want %v", err, tt.streamErr)
+			}
+		})
+	}
+}
+
+func Test_ReadBytesStream_ErrorPersists(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoded string
+		wrap    func(io.Reader) io.Reader
+		err     error
+	}{
+		{name: "invalid utf8", encoded: "64ff414243", err: ErrInvalidUTF8},
+		// fails the read after the header, and succeeds after that
+		{name: "transport", encoded: "6441424344", wrap: iotest.TimeoutReader, err: iotest.ErrTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var in io.Reader = bytes.NewReader(decodeHex(t, tt.encoded))
+			if tt.wrap != nil {
+				in = tt.wrap(in)
+			}
+			r, err := ReadBytesStream(in)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			out := make([]byte, 1)
+			for i := 0; i < 5; i++ {
+				if n, err := r.Read(out); n != 0 || err != tt.err {
+					t.Fatalf("read %d got %d, %v, want %v", i, n, err, tt.err)
+				}
+			}
+		})
+	}
+}
@@ -772,3 +772,87 @@ func Test_ReadTag(t *testing.T) {
 		})
 	}
 }
+
+func Test_ReadString(t *testing.T) {
+	tests := []struct {
+		encoded   string
+		maxLength uint64
+		want      string
+		wantErr   error
+	}{
+		{encoded: "60", maxLength: math.MaxUint64, want: ""},
+		{encoded: "6449455446", maxLength: math.MaxUint64, want: "IETF"},
+		{encoded: "7f657374726561646d696e67ff", maxLength: math.MaxUint64, want: "streaming"},
+		{encoded: "6449455446", maxLength: 4, want: "IETF"},
+		{encoded: "6449455446", maxLength: 3, wantErr: ErrTooLarge},
+		{encoded: "7f657374726561646d696e67ff", maxLength: 8, wantErr: ErrTooLarge},
+		{encoded: "4449455446", maxLength: math.MaxUint64, wantErr: ErrUnsupportedMajorType},
+		{encoded: "62c328", maxLength: math.MaxUint64, wantErr: ErrInvalidUTF8},
+		{encoded: "7b7fffffffffffffff", maxLength: 16, wantErr: ErrTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			got, err := ReadStringMax(bytes.NewReader(decodeHex(t, tt.encoded)), tt.maxLength)
+			if err != tt.wantErr {
+				t.Fatalf("want %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Fatalf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_ReadByteString(t *testing.T) {
+	tests := []struct {
+		encoded   string
+		maxLength uint64
+		want      []byte
+		wantErr   error
+	}{
+		{encoded: "40", maxLength: math.MaxUint64, want: []byte{}},
+		{encoded: "4401020304", maxLength: math.MaxUint64, want: []byte{1, 2, 3, 4}},
+		{encoded: "5f42010243030405ff", maxLength: math.MaxUint64, want: []byte{1, 2, 3, 4, 5}},
+		{encoded: "5f42010243030405ff", maxLength: 4, wantErr: ErrTooLarge},
+		{encoded: "6449455446", maxLength: math.MaxUint64, wantErr: ErrUnsupportedMajorType},
+		{encoded: "5b7fffffffffffffff", maxLength: math.MaxUint64, wantErr: io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			got, err := ReadByteStringMax(bytes.NewReader(decodeHex(t, tt.encoded)), tt.maxLength)
+			if err != tt.wantErr {
+				t.Fatalf("want %v, got %v", tt.wantErr, err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func Test_ReadBytesInto(t *testing.T) {
+	dst := make([]byte, 0, 16)
+
+	in := bytes.NewReader(decodeHex(t, "44010203046449455446"))
+
+	got, err := ReadBytesInto(in, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]byte{1, 2, 3, 4}, got); diff != "" {
+		t.Fatal(diff)
+	}
+	if &got[0] != &dst[:1][0] {
+		t.Fatal("dst not reused")
+	}
+
+	got, err = ReadBytesInto(in, got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]byte("IETF"), got); diff != "" {
+		t.Fatal(diff)
+	}
+}
@@ -0,0 +1,518 @@
+package cbor
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"time"
+)
+
+const (
+	TagDateTime        uint64 = 0
+	TagEpochDateTime   uint64 = 1
+	TagPositiveBignum  uint64 = 2
+	TagNegativeBignum  uint64 = 3
+	TagDecimalFraction uint64 = 4
+	TagBigfloat        uint64 = 5
+	TagEncodedCBOR     uint64 = 24
+	TagURI             uint64 = 32
+	TagBase64URL       uint64 = 33
+	TagBase64          uint64 = 34
+	TagRegexp          uint64 = 35
+	TagMIME            uint64 = 36
+	TagUUID            uint64 = 37
+	TagIPv4            uint64 = 52 // RFC 9164
+	TagIPv6            uint64 = 54 // RFC 9164
+)
+
+// readTagNumber reads a tag from [in] and checks it is one of [want].
+func readTagNumber(in io.Reader, want ...uint64) (uint64, error) {
+	tag, err := ReadTag(in)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, w := range want {
+		if tag == w {
+			return tag, nil
+		}
+	}
+
+	return 0, ErrUnexpectedTag
+}
+
+// ReadURI reads a tag 32 URI from [in].
+func ReadURI(in io.Reader) (*url.URL, error) {
+	if _, err := readTagNumber(in, TagURI); err != nil {
+		return nil, err
+	}
+
+	return readURI(in)
+}
+
+func readURI(in io.Reader) (*url.URL, error) {
+	b, err := readString(in, MajorTypeTstr)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(string(b))
+	if err != nil || u.Scheme == "" {
+		return nil, ErrInvalidTagContent
+	}
+
+	return u, nil
+}
+
+func WriteURI(out io.Writer, value *url.URL) (int, error) {
+	return writeTagged(out, TagURI, func() (int, error) {
+		return WriteString(out, value.String())
+	})
+}
+
+// ReadBase64URL reads a tag 33 base64url text string from [in] and returns
+// the decoded bytes.
+func ReadBase64URL(in io.Reader) ([]byte, error) {
+	if _, err := readTagNumber(in, TagBase64URL); err != nil {
+		return nil, err
+	}
+
+	return readBase64(in, base64.RawURLEncoding.Strict())
+}
+
+// ReadBase64 reads a tag 34 base64 text string from [in] and returns the
+// decoded bytes.
+func ReadBase64(in io.Reader) ([]byte, error) {
+	if _, err := readTagNumber(in, TagBase64); err != nil {
+		return nil, err
+	}
+
+	return readBase64(in, base64.StdEncoding.Strict())
+}
+
+func readBase64(in io.Reader, encoding *base64.Encoding) ([]byte, error) {
+	b, err := readString(in, MajorTypeTstr)
+	if err != nil {
+		return nil, err
+	}
+
+	d := make([]byte, encoding.DecodedLen(len(b)))
+	n, err := encoding.Decode(d, b)
+	if err != nil {
+		return nil, ErrInvalidTagContent
+	}
+
+	return d[:n], nil
+}
+
+func WriteBase64URL(out io.Writer, value []byte) (int, error) {
+	return writeTagged(out, TagBase64URL, func() (int, error) {
+		return WriteString(out, base64.RawURLEncoding.EncodeToString(value))
+	})
+}
+
+func WriteBase64(out io.Writer, value []byte) (int, error) {
+	return writeTagged(out, TagBase64, func() (int, error) {
+		return WriteString(out, base64.StdEncoding.EncodeToString(value))
+	})
+}
+
+// ReadRegexp reads a tag 35 regular expression from [in]. Expressions are
+// compiled with the Go RE2 syntax.
+func ReadRegexp(in io.Reader) (*regexp.Regexp, error) {
+	if _, err := readTagNumber(in, TagRegexp); err != nil {
+		return nil, err
+	}
+
+	return readRegexp(in)
+}
+
+func readRegexp(in io.Reader) (*regexp.Regexp, error) {
+	b, err := readString(in, MajorTypeTstr)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := regexp.Compile(string(b))
+	if err != nil {
+		return nil, ErrInvalidTagContent
+	}
+
+	return r, nil
+}
+
+func WriteRegexp(out io.Writer, value *regexp.Regexp) (int, error) {
+	return writeTagged(out, TagRegexp, func() (int, error) {
+		return WriteString(out, value.String())
+	})
+}
+
+// ReadMIME reads a tag 36 MIME message, including headers, from [in].
+func ReadMIME(in io.Reader) (string, error) {
+	if _, err := readTagNumber(in, TagMIME); err != nil {
+		return "", err
+	}
+
+	return readMIME(in)
+}
+
+func readMIME(in io.Reader) (string, error) {
+	b, err := readString(in, MajorTypeTstr)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err = mail.ReadMessage(bytes.NewReader(b)); err != nil {
+		return "", ErrInvalidTagContent
+	}
+
+	return string(b), nil
+}
+
+func WriteMIME(out io.Writer, value string) (int, error) {
+	return writeTagged(out, TagMIME, func() (int, error) {
+		return WriteString(out, value)
+	})
+}
+
+// ReadUUID reads a tag 37 UUID from [in].
+func ReadUUID(in io.Reader) ([16]byte, error) {
+	if _, err := readTagNumber(in, TagUUID); err != nil {
+		return [16]byte{}, err
+	}
+
+	return readUUID(in)
+}
+
+func readUUID(in io.Reader) ([16]byte, error) {
+	var u [16]byte
+
+	b, err := readString(in, MajorTypeBstr)
+	if err != nil {
+		return u, err
+	}
+
+	if len(b) != len(u) {
+		return u, ErrInvalidTagContent
+	}
+
+	copy(u[:], b)
+	return u, nil
+}
+
+func WriteUUID(out io.Writer, value [16]byte) (int, error) {
+	return writeTagged(out, TagUUID, func() (int, error) {
+		return WriteBytes(out, value[:])
+	})
+}
+
+// ReadIPAddr reads a tag 52 or 54 address from [in].
+func ReadIPAddr(in io.Reader) (netip.Addr, error) {
+	tag, err := readTagNumber(in, TagIPv4, TagIPv6)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	return readIPAddr(in, tag)
+}
+
+func readIPAddr(in io.Reader, tag uint64) (netip.Addr, error) {
+	b, err := readString(in, MajorTypeBstr)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	if len(b) != ipAddrLen(tag) {
+		return netip.Addr{}, ErrInvalidTagContent
+	}
+
+	a, _ := netip.AddrFromSlice(b)
+	return a, nil
+}
+
+// ReadIPPrefix reads a tag 52 or 54 prefix from [in]. Both the prefix form,
+// [length, address], and the interface form, [address, length], are accepted;
+// only the interface form may have bits set beyond the prefix length.
+func ReadIPPrefix(in io.Reader) (netip.Prefix, error) {
+	tag, err := readTagNumber(in, TagIPv4, TagIPv6)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+
+	return readIPPrefix(in, tag)
+}
+
+func readIPPrefix(in io.Reader, tag uint64) (netip.Prefix, error) {
+	majorType, arg, value, err := readMajorType(in)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+
+	if majorType != MajorTypeArray {
+		return netip.Prefix{}, ErrUnsupportedMajorType
+	}
+
+	if arg == ArgIndefinite || value != 2 {
+		return netip.Prefix{}, ErrInvalidTagContent
+	}
+
+	// peek the first header to tell the prefix and interface forms apart
+	pin := &peekReader{r: in}
+	p, err := pin.PeekByte()
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+
+	l := ipAddrLen(tag)
+	if MajorType(p&majorTypeMask) == MajorTypeBstr {
+		a, err := readIPAddr(pin, tag)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		bits, err := ReadUnsigned[uint8](pin)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		if int(bits) > l*8 {
+			return netip.Prefix{}, ErrInvalidTagContent
+		}
+
+		return netip.PrefixFrom(a, int(bits)), nil
+	}
+
+	bits, err := ReadUnsigned[uint8](pin)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	b, err := readString(pin, MajorTypeBstr)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+
+	// trailing zero bytes must be omitted, and no bits set past the prefix
+	if int(bits) > l*8 ||
+		len(b) > l ||
+		(len(b) > 0 && b[len(b)-1] == 0) ||
+		len(b) > (int(bits)+7)/8 {
+		return netip.Prefix{}, ErrInvalidTagContent
+	}
+
+	full := make([]byte, l)
+	copy(full, b)
+	a, _ := netip.AddrFromSlice(full)
+	prefix := netip.PrefixFrom(a, int(bits))
+	if prefix.Masked() != prefix {
+		return netip.Prefix{}, ErrInvalidTagContent
+	}
+
+	return prefix, nil
+}
+
+func ipAddrLen(tag uint64) int {
+	if tag == TagIPv4 {
+		return 4
+	}
+	return 16
+}
+
+func WriteIPAddr(out io.Writer, value netip.Addr) (int, error) {
+	tag := TagIPv6
+	if value.Is4() {
+		tag = TagIPv4
+	}
+
+	return writeTagged(out, tag, func() (int, error) {
+		return WriteBytes(out, value.AsSlice())
+	})
+}
+
+// WriteIPPrefix writes [value] in the prefix form, or in the interface form if
+// it has bits set beyond the prefix length.
+func WriteIPPrefix(out io.Writer, value netip.Prefix) (int, error) {
+	tag := TagIPv6
+	if value.Addr().Is4() {
+		tag = TagIPv4
+	}
+
+	return writeTagged(out, tag, func() (int, error) {
+		tn := 0
+		n, err := WriteArrayHeader(out, 2)
+		tn += n
+		if err != nil {
+			return tn, err
+		}
+
+		if value.Masked() != value {
+			n, err = WriteBytes(out, value.Addr().AsSlice())
+			tn += n
+			if err != nil {
+				return tn, err
+			}
+			n, err = WriteUnsigned(out, uint8(value.Bits()))
+			tn += n
+			return tn, err
+		}
+
+		n, err = WriteUnsigned(out, uint8(value.Bits()))
+		tn += n
+		if err != nil {
+			return tn, err
+		}
+		n, err = WriteBytes(out, bytes.TrimRight(value.Addr().AsSlice(), "\x00"))
+		tn += n
+		return tn, err
+	})
+}
+
+// readTagContent reads the content of a well known [tag] from [in] as its Go
+// type, returning false if the tag is not known and nothing was read.
+func readTagContent(in io.Reader, tag uint64) (any, bool, error) {
+	var v any
+	var err error
+	switch tag {
+	case TagURI:
+		v, err = readURI(in)
+	case TagBase64URL:
+		v, err = readBase64(in, base64.RawURLEncoding.Strict())
+	case TagBase64:
+		v, err = readBase64(in, base64.StdEncoding.Strict())
+	case TagRegexp:
+		v, err = readRegexp(in)
+	case TagMIME:
+		v, err = readMIME(in)
+	case TagUUID:
+		v, err = readUUID(in)
+	case TagIPv4, TagIPv6:
+		pin := &peekReader{r: in}
+		var p byte
+		p, err = pin.PeekByte()
+		if err != nil {
+			break
+		}
+		if MajorType(p&majorTypeMask) == MajorTypeArray {
+			v, err = readIPPrefix(pin, tag)
+		} else {
+			v, err = readIPAddr(pin, tag)
+		}
+	default:
+		return nil, false, nil
+	}
+
+	if err != nil {
+		return nil, true, err
+	}
+	return v, true, nil
+}
+
+// isKnownTag returns true if the content of [tag] can be checked by
+// validateTag.
+func isKnownTag(tag uint64) bool {
+	switch tag {
+	case TagDateTime, TagEpochDateTime,
+		TagPositiveBignum, TagNegativeBignum,
+		TagDecimalFraction, TagBigfloat,
+		TagEncodedCBOR,
+		TagURI, TagBase64URL, TagBase64, TagRegexp, TagMIME, TagUUID,
+		TagIPv4, TagIPv6:
+		return true
+	default:
+		return false
+	}
+}
+
+// validateTag checks the encoded [content] of a well known [tag].
+func validateTag(tag uint64, content []byte) error {
+	in := bytes.NewReader(content)
+
+	var err error
+	switch tag {
+	case TagDateTime:
+		var b []byte
+		if b, err = readString(in, MajorTypeTstr); err == nil {
+			_, err = time.Parse(time.RFC3339Nano, string(b))
+		}
+
+	case TagEpochDateTime:
+		var majorType MajorType
+		var arg Arg
+		if majorType, arg, _, err = readMajorType(in); err == nil &&
+			majorType != MajorTypeUInt &&
+			majorType != MajorTypeNInt &&
+			(majorType != MajorTypeSimpleFloat || arg < SimpleFloat16 || arg > SimpleFloat64) {
+			err = ErrInvalidTagContent
+		}
+
+	case TagPositiveBignum, TagNegativeBignum:
+		_, err = readString(in, MajorTypeBstr)
+
+	case TagDecimalFraction, TagBigfloat:
+		err = validateExponentMantissa(in)
+
+	case TagEncodedCBOR:
+		var b []byte
+		if b, err = readString(in, MajorTypeBstr); err == nil {
+			encoded := bytes.NewReader(b)
+			if err = ReadOver(encoded); err == nil && encoded.Len() != 0 {
+				err = ErrInvalidTagContent
+			}
+		}
+
+	default:
+		_, _, err = readTagContent(in, tag)
+	}
+
+	if err != nil {
+		return ErrInvalidTagContent
+	}
+	return nil
+}
+
+// validateExponentMantissa checks a decimal fraction or bigfloat is an array
+// of an integer exponent and an integer or bignum mantissa.
+func validateExponentMantissa(in io.Reader) error {
+	majorType, arg, value, err := readMajorType(in)
+	if err != nil {
+		return err
+	}
+	if majorType != MajorTypeArray || arg == ArgIndefinite || value != 2 {
+		return ErrInvalidTagContent
+	}
+
+	if majorType, _, _, err = readMajorType(in); err != nil {
+		return err
+	}
+	if majorType != MajorTypeUInt && majorType != MajorTypeNInt {
+		return ErrInvalidTagContent
+	}
+
+	majorType, _, value, err = readMajorType(in)
+	if err != nil {
+		return err
+	}
+	switch {
+	case majorType == MajorTypeUInt, majorType == MajorTypeNInt:
+		return nil
+	case majorType == MajorTypeTagged && (value == TagPositiveBignum || value == TagNegativeBignum):
+		_, err = readString(in, MajorTypeBstr)
+		return err
+	default:
+		return ErrInvalidTagContent
+	}
+}
+
+// writeTagged writes [tag] followed by the content written by [writeContent].
+func writeTagged(out io.Writer, tag uint64, writeContent func() (int, error)) (int, error) {
+	tn := 0
+	n, err := WriteTag(out, tag)
+	tn += n
+	if err != nil {
+		return tn, err
+	}
+	n, err = writeContent()
+	tn += n
+	return tn, err
+}
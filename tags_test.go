@@ -0,0 +1,185 @@
+package cbor
+
+import (
+	"bytes"
+	"io"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var cmpNetip = []cmp.Option{
+	cmp.Comparer(func(a, b netip.Addr) bool { return a == b }),
+	cmp.Comparer(func(a, b netip.Prefix) bool { return a == b }),
+}
+
+func Test_Tags(t *testing.T) {
+	tests := []struct {
+		encoded string
+		read    func(in io.Reader) (any, error)
+		write   func(out io.Writer, v any) (int, error)
+		want    any
+		wantErr error
+	}{
+		{
+			encoded: "d82076687474703a2f2f7777772e6578616d706c652e636f6d",
+			read:    func(in io.Reader) (any, error) { return ReadURI(in) },
+			write:   func(out io.Writer, v any) (int, error) { return WriteURI(out, v.(*url.URL)) },
+			want:    &url.URL{Scheme: "http", Host: "www.example.com"},
+		},
+		{
+			encoded: "d820692f72656c6174697665",
+			read:    func(in io.Reader) (any, error) { return ReadURI(in) },
+			wantErr: ErrInvalidTagContent,
+		},
+		{
+			encoded: "d821626141",
+			read:    func(in io.Reader) (any, error) { return ReadBase64URL(in) },
+			write:   func(out io.Writer, v any) (int, error) { return WriteBase64URL(out, v.([]byte)) },
+			want:    []byte("h"),
+		},
+		{
+			encoded: "d82163614764",
+			read:    func(in io.Reader) (any, error) { return ReadBase64URL(in) },
+			wantErr: ErrInvalidTagContent,
+		},
+		{
+			encoded: "d8226461476b3d",
+			read:    func(in io.Reader) (any, error) { return ReadBase64(in) },
+			write:   func(out io.Writer, v any) (int, error) { return WriteBase64(out, v.([]byte)) },
+			want:    []byte("hi"),
+		},
+		{
+			encoded: "d823625b5d",
+			read:    func(in io.Reader) (any, error) { return ReadRegexp(in) },
+			wantErr: ErrInvalidTagContent,
+		},
+		{
+			encoded: "d8246d413a20620d0a0d0a68656c6c6f",
+			read:    func(in io.Reader) (any, error) { return ReadMIME(in) },
+			write:   func(out io.Writer, v any) (int, error) { return WriteMIME(out, v.(string)) },
+			want:    "A: b\r\n\r\nhello",
+		},
+		{
+			encoded: "d8246568656c6c6f",
+			read:    func(in io.Reader) (any, error) { return ReadMIME(in) },
+			wantErr: ErrInvalidTagContent,
+		},
+		{
+			encoded: "d8244568656c6c6f",
+			read:    func(in io.Reader) (any, error) { return ReadMIME(in) },
+			wantErr: ErrUnsupportedMajorType,
+		},
+		{
+			encoded: "d82550000102030405060708090a0b0c0d0e0f",
+			read:    func(in io.Reader) (any, error) { return ReadUUID(in) },
+			write:   func(out io.Writer, v any) (int, error) { return WriteUUID(out, v.([16]byte)) },
+			want:    [16]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+		},
+		{
+			encoded: "d8254400010203",
+			read:    func(in io.Reader) (any, error) { return ReadUUID(in) },
+			wantErr: ErrInvalidTagContent,
+		},
+		{
+			encoded: "d82550000102030405060708090a0b0c0d0e0f",
+			read:    func(in io.Reader) (any, error) { return ReadURI(in) },
+			wantErr: ErrUnexpectedTag,
+		},
+		{
+			encoded: "d83444c0000201",
+			read:    func(in io.Reader) (any, error) { return ReadIPAddr(in) },
+			write:   func(out io.Writer, v any) (int, error) { return WriteIPAddr(out, v.(netip.Addr)) },
+			want:    netip.MustParseAddr("192.0.2.1"),
+		},
+		{
+			encoded: "d8365020010db81234deedbeefcafefacefeed",
+			read:    func(in io.Reader) (any, error) { return ReadIPAddr(in) },
+			write:   func(out io.Writer, v any) (int, error) { return WriteIPAddr(out, v.(netip.Addr)) },
+			want:    netip.MustParseAddr("2001:db8:1234:deed:beef:cafe:face:feed"),
+		},
+		{
+			encoded: "d83443c00002",
+			read:    func(in io.Reader) (any, error) { return ReadIPAddr(in) },
+			wantErr: ErrInvalidTagContent,
+		},
+		{
+			encoded: "d83482181843c00002",
+			read:    func(in io.Reader) (any, error) { return ReadIPPrefix(in) },
+			write:   func(out io.Writer, v any) (int, error) { return WriteIPPrefix(out, v.(netip.Prefix)) },
+			want:    netip.MustParsePrefix("192.0.2.0/24"),
+		},
+		{
+			encoded: "d8368218304620010db81234",
+			read:    func(in io.Reader) (any, error) { return ReadIPPrefix(in) },
+			write:   func(out io.Writer, v any) (int, error) { return WriteIPPrefix(out, v.(netip.Prefix)) },
+			want:    netip.MustParsePrefix("2001:db8:1234::/48"),
+		},
+		{
+			encoded: "d8348244c00002011818",
+			read:    func(in io.Reader) (any, error) { return ReadIPPrefix(in) },
+			write:   func(out io.Writer, v any) (int, error) { return WriteIPPrefix(out, v.(netip.Prefix)) },
+			want:    netip.MustParsePrefix("192.0.2.1/24"),
+		},
+		{
+			encoded: "d83482181844c0000200",
+			read:    func(in io.Reader) (any, error) { return ReadIPPrefix(in) },
+			wantErr: ErrInvalidTagContent,
+		},
+		{
+			encoded: "d83482181043c00002",
+			read:    func(in io.Reader) (any, error) { return ReadIPPrefix(in) },
+			wantErr: ErrInvalidTagContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			encoded := decodeHex(t, tt.encoded)
+
+			got, err := tt.read(bytes.NewReader(encoded))
+			if err != tt.wantErr {
+				t.Fatalf("want %v, got %v", tt.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tt.want, got, cmpNetip...); diff != "" {
+				t.Fatal(diff)
+			}
+
+			if tt.write == nil {
+				return
+			}
+			out := bytes.NewBuffer(nil)
+			n, err := tt.write(out, tt.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != len(encoded) {
+				t.Fatalf("want %d, got %d", len(encoded), n)
+			}
+			if diff := cmp.Diff(encoded, out.Bytes()); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+
+	t.Run("Regexp", func(t *testing.T) {
+		out := bytes.NewBuffer(nil)
+		_, err := WriteRegexp(out, regexp.MustCompile("a+"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ReadRegexp(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != "a+" {
+			t.Fatalf("want a+, got %s", got)
+		}
+	})
+}
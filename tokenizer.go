@@ -0,0 +1,288 @@
+package cbor
+
+import (
+	"io"
+	"math"
+)
+
+type TokenKind byte
+
+const (
+	TokenUint TokenKind = iota
+	TokenNegInt
+	TokenBytes
+	TokenText
+	TokenArrayStart
+	TokenMapStart
+	TokenEnd
+	TokenTag
+	TokenSimple
+	TokenFloat
+)
+
+// Token is a single item, or part of an item, returned by [Tokenizer.Next].
+type Token struct {
+	Kind TokenKind
+
+	// Value is the integer, tag number, simple value, string length or
+	// container length of the token. Negative integers are -1 - Value.
+	Value uint64
+
+	// Float is the value of a TokenFloat.
+	Float float64
+
+	// Indefinite is set on the start and TokenEnd of indefinite length strings,
+	// arrays and maps.
+	Indefinite bool
+
+	// Chunk is the next part of a string, only valid until the next call to
+	// [Tokenizer.Next].
+	Chunk []byte
+
+	// More is set when further tokens belong to the same string. Indefinite
+	// length strings are closed by a TokenEnd.
+	More bool
+}
+
+const lenTokenizerBuffer = 512
+
+type tokenizerFrame struct {
+	majorType  MajorType
+	indefinite bool
+	remaining  uint64 // items left in a definite container, two per map entry
+}
+
+// Tokenizer reads items from a stream one token at a time, with memory bounded
+// by the nesting depth rather than the size of the items.
+//
+// Every array and map, definite or not, is closed by a TokenEnd. Strings are
+// returned as one or more chunks of at most 512 bytes.
+type Tokenizer struct {
+	in    io.Reader
+	stack []tokenizerFrame
+	last  Token
+
+	tagged bool // the previous token was a tag, so its content is pending
+
+	chunkMajorType MajorType
+	chunkLength    uint64
+	chunkRemaining uint64
+	buffer         [lenTokenizerBuffer]byte
+}
+
+func NewTokenizer(in io.Reader) *Tokenizer {
+	return &Tokenizer{in: in}
+}
+
+// Depth returns the number of arrays, maps and indefinite length strings
+// currently open.
+func (t *Tokenizer) Depth() int {
+	return len(t.stack)
+}
+
+// Next returns the next token, or [io.EOF] once the stream is exhausted
+// between top level items.
+func (t *Tokenizer) Next() (Token, error) {
+	token, err := t.next()
+	if err == io.EOF && (len(t.stack) > 0 || t.tagged || t.chunkRemaining > 0) {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return Token{}, err
+	}
+
+	t.last = token
+	return token, nil
+}
+
+func (t *Tokenizer) next() (Token, error) {
+	if t.chunkRemaining > 0 {
+		return t.nextChunk()
+	}
+
+	var top *tokenizerFrame
+	if len(t.stack) > 0 {
+		top = &t.stack[len(t.stack)-1]
+		if !top.indefinite && top.remaining == 0 && !t.tagged {
+			t.stack = t.stack[:len(t.stack)-1]
+			return Token{Kind: TokenEnd}, nil
+		}
+	}
+
+	majorType, arg, value, err := readMajorType(t.in)
+	if err != nil {
+		return Token{}, err
+	}
+
+	if majorType == MajorTypeSimpleFloat && arg == SimpleBreak {
+		if top == nil || !top.indefinite || t.tagged {
+			return Token{}, ErrNotWellFormed
+		}
+		t.stack = t.stack[:len(t.stack)-1]
+		return Token{Kind: TokenEnd, Indefinite: true}, nil
+	}
+
+	if top != nil {
+		if top.majorType == MajorTypeBstr || top.majorType == MajorTypeTstr {
+			if majorType != top.majorType {
+				return Token{}, ErrMismatchedChunk
+			}
+			if arg == ArgIndefinite {
+				return Token{}, ErrNestedIndefinite
+			}
+		} else if !top.indefinite && !t.tagged {
+			top.remaining--
+		}
+	}
+
+	t.tagged = majorType == MajorTypeTagged
+
+	switch majorType {
+	case MajorTypeUInt:
+		return Token{Kind: TokenUint, Value: value}, nil
+
+	case MajorTypeNInt:
+		return Token{Kind: TokenNegInt, Value: value}, nil
+
+	case MajorTypeBstr, MajorTypeTstr:
+		if arg == ArgIndefinite {
+			t.stack = append(t.stack, tokenizerFrame{majorType: majorType, indefinite: true})
+			return Token{Kind: stringTokenKind(majorType), Indefinite: true, More: true}, nil
+		}
+
+		t.chunkMajorType = majorType
+		t.chunkLength = value
+		t.chunkRemaining = value
+		return t.nextChunk()
+
+	case MajorTypeArray:
+		t.stack = append(t.stack, tokenizerFrame{
+			majorType:  majorType,
+			indefinite: arg == ArgIndefinite,
+			remaining:  value,
+		})
+		return Token{Kind: TokenArrayStart, Value: value, Indefinite: arg == ArgIndefinite}, nil
+
+	case MajorTypeMap:
+		if value > math.MaxUint64/2 {
+			return Token{}, ErrOverflow
+		}
+		t.stack = append(t.stack, tokenizerFrame{
+			majorType:  majorType,
+			indefinite: arg == ArgIndefinite,
+			remaining:  value * 2,
+		})
+		return Token{Kind: TokenMapStart, Value: value, Indefinite: arg == ArgIndefinite}, nil
+
+	case MajorTypeTagged:
+		return Token{Kind: TokenTag, Value: value}, nil
+
+	default: // MajorTypeSimpleFloat
+		switch arg {
+		case SimpleFloat16, SimpleFloat32, SimpleFloat64:
+			f, err := readFloat[float64](majorType, arg, value)
+			if err != nil {
+				return Token{}, err
+			}
+			return Token{Kind: TokenFloat, Float: f}, nil
+		case ArgIndefinite:
+			return Token{}, ErrNotWellFormed
+		default:
+			return Token{Kind: TokenSimple, Value: value}, nil
+		}
+	}
+}
+
+// nextChunk reads the next part of the current definite length string.
+func (t *Tokenizer) nextChunk() (Token, error) {
+	l := min(t.chunkRemaining, lenTokenizerBuffer)
+	b := t.buffer[:l]
+	if _, err := io.ReadFull(t.in, b); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return Token{}, err
+	}
+	t.chunkRemaining -= l
+
+	inIndefinite := len(t.stack) > 0 && t.stack[len(t.stack)-1].majorType == t.chunkMajorType
+	return Token{
+		Kind:  stringTokenKind(t.chunkMajorType),
+		Value: t.chunkLength,
+		Chunk: b,
+		More:  t.chunkRemaining > 0 || inIndefinite,
+	}, nil
+}
+
+func stringTokenKind(majorType MajorType) TokenKind {
+	if majorType == MajorTypeTstr {
+		return TokenText
+	}
+	return TokenBytes
+}
+
+// Skip reads over the remainder of the item started by the last token
+// returned from [Tokenizer.Next], including the rest of an indefinite length
+// string when called after one of its chunks. It does nothing if that item is
+// complete.
+func (t *Tokenizer) Skip() error {
+	last := t.last
+	t.last = Token{}
+
+	switch last.Kind {
+	case TokenTag:
+		if _, err := t.Next(); err != nil {
+			return err
+		}
+		return t.Skip()
+
+	case TokenBytes, TokenText:
+		if !last.Indefinite {
+			remaining := t.chunkRemaining
+			t.chunkRemaining = 0
+			if err := skipBytes(t.in, remaining); err != nil {
+				return err
+			}
+			if len(t.stack) == 0 || t.stack[len(t.stack)-1].majorType != t.chunkMajorType {
+				return nil
+			}
+		}
+
+	case TokenArrayStart, TokenMapStart:
+
+	default:
+		return nil
+	}
+
+	depth := len(t.stack) - 1
+	for len(t.stack) > depth {
+		if t.chunkRemaining > 0 {
+			if err := skipBytes(t.in, t.chunkRemaining); err != nil {
+				return err
+			}
+			t.chunkRemaining = 0
+			continue
+		}
+
+		if _, err := t.Next(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// skipBytes reads and discards [length] bytes from [in].
+func skipBytes(in io.Reader, length uint64) error {
+	for length > 0 {
+		n, err := io.ReadFull(in, sharedBuffer[:min(lenSharedBuffer, length)])
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		length -= uint64(n)
+	}
+	return nil
+}
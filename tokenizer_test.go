@@ -0,0 +1,235 @@
+package cbor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_Tokenizer(t *testing.T) {
+	for _, tt := range tests_ExampleEncoded {
+		t.Run(tt.encoded, func(t *testing.T) {
+			in := bytes.NewReader(decodeHex(t, tt.encoded))
+			tokenizer := NewTokenizer(in)
+
+			for {
+				_, err := tokenizer.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if tokenizer.Depth() != 0 {
+				t.Fatalf("depth %d", tokenizer.Depth())
+			}
+		})
+	}
+}
+
+func Test_Tokenizer_Tokens(t *testing.T) {
+	tests := []struct {
+		encoded string
+		want    []string
+	}{
+		{
+			encoded: "9f018202039f0405ffff",
+			want: []string{
+				"array indefinite", "uint 1", "array 2", "uint 2", "uint 3", "end",
+				"array indefinite", "uint 4", "uint 5", "end indefinite", "end indefinite",
+			},
+		},
+		{
+			encoded: "bf61610161629f0203ffff",
+			want: []string{
+				"map indefinite", "text a", "uint 1", "text b",
+				"array indefinite", "uint 2", "uint 3", "end indefinite", "end indefinite",
+			},
+		},
+		{
+			encoded: "5f42010243030405ff",
+			want: []string{
+				"bytes indefinite more", "bytes 0102 more", "bytes 030405 more", "end indefinite",
+			},
+		},
+		{
+			encoded: "a1c11a514b67b03903e7",
+			want: []string{
+				"map 1", "tag 1", "uint 1363896240", "negint 999", "end",
+			},
+		},
+		{
+			encoded: "83f4f6f93e00",
+			want: []string{
+				"array 3", "simple 20", "simple 22", "float 1.5", "end",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			tokenizer := NewTokenizer(bytes.NewReader(decodeHex(t, tt.encoded)))
+
+			var got []string
+			for {
+				token, err := tokenizer.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, formatToken(token))
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func Test_Tokenizer_Chunks(t *testing.T) {
+	value := strings.Repeat("x", lenTokenizerBuffer+1)
+
+	out := bytes.NewBuffer(nil)
+	_, _ = WriteString(out, value)
+
+	tokenizer := NewTokenizer(out)
+
+	token, err := tokenizer.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(token.Chunk) != lenTokenizerBuffer || !token.More || token.Value != uint64(len(value)) {
+		t.Fatalf("unexpected token %+v", token)
+	}
+
+	token, err = tokenizer.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(token.Chunk) != 1 || token.More {
+		t.Fatalf("unexpected token %+v", token)
+	}
+}
+
+func Test_Tokenizer_Skip(t *testing.T) {
+	tests := []struct {
+		encoded string
+		next    int
+		want    TokenKind
+	}{
+		{encoded: "82830102030a", next: 2, want: TokenUint},
+		{encoded: "829f01829f02ff03ff0a", next: 2, want: TokenUint},
+		{encoded: "82c1c2820102f4", next: 2, want: TokenSimple},
+		{encoded: "825f4101420203ff0a", next: 3, want: TokenUint},
+		{encoded: "827818000102030405060708090a0b0c0d0e0f1011121314151617f5", next: 2, want: TokenSimple},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			tokenizer := NewTokenizer(bytes.NewReader(decodeHex(t, tt.encoded)))
+			for range tt.next {
+				if _, err := tokenizer.Next(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := tokenizer.Skip(); err != nil {
+				t.Fatal(err)
+			}
+
+			token, err := tokenizer.Next()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if token.Kind != tt.want {
+				t.Fatalf("want %d, got %+v", tt.want, token)
+			}
+			if tokenizer.Depth() != 1 {
+				t.Fatalf("depth %d", tokenizer.Depth())
+			}
+		})
+	}
+}
+
+func Test_Tokenizer_NotWellFormed(t *testing.T) {
+	tests := []struct {
+		encoded string
+		wantErr error
+	}{
+		{encoded: "ff", wantErr: ErrNotWellFormed},
+		{encoded: "82ff", wantErr: ErrNotWellFormed},
+		{encoded: "5f6161ff", wantErr: ErrMismatchedChunk},
+		{encoded: "5f5fffff", wantErr: ErrNestedIndefinite},
+		{encoded: "8201", wantErr: io.ErrUnexpectedEOF},
+		{encoded: "4401", wantErr: io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			tokenizer := NewTokenizer(bytes.NewReader(decodeHex(t, tt.encoded)))
+
+			var err error
+			for err == nil {
+				_, err = tokenizer.Next()
+			}
+			if err != tt.wantErr {
+				t.Fatalf("want %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func formatToken(token Token) string {
+	var b strings.Builder
+	switch token.Kind {
+	case TokenUint:
+		b.WriteString("uint")
+	case TokenNegInt:
+		b.WriteString("negint")
+	case TokenBytes:
+		b.WriteString("bytes")
+	case TokenText:
+		b.WriteString("text")
+	case TokenArrayStart:
+		b.WriteString("array")
+	case TokenMapStart:
+		b.WriteString("map")
+	case TokenEnd:
+		b.WriteString("end")
+	case TokenTag:
+		b.WriteString("tag")
+	case TokenSimple:
+		b.WriteString("simple")
+	case TokenFloat:
+		b.WriteString("float")
+	}
+
+	switch {
+	case token.Indefinite:
+		b.WriteString(" indefinite")
+	case token.Kind == TokenText:
+		b.WriteString(" " + string(token.Chunk))
+	case token.Kind == TokenBytes:
+		b.WriteString(" " + hex.EncodeToString(token.Chunk))
+	case token.Kind == TokenFloat:
+		b.WriteString(" " + strconv.FormatFloat(token.Float, 'f', -1, 64))
+	case token.Kind != TokenEnd:
+		b.WriteString(" " + strconv.FormatUint(token.Value, 10))
+	}
+
+	if token.More {
+		b.WriteString(" more")
+	}
+
+	return b.String()
+}
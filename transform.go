@@ -0,0 +1,589 @@
+package cbor
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+)
+
+// TransformAction is what [Transform] does with an item.
+type TransformAction byte
+
+const (
+	// TransformKeep writes the item, with any changes made to its header, and
+	// transforms the items within it.
+	TransformKeep TransformAction = iota
+	// TransformDrop leaves the item out. Leaving out a map key or value leaves
+	// out the whole entry.
+	TransformDrop
+	// TransformReplace skips the item and writes its Replacement instead.
+	TransformReplace
+	// TransformUnwrap leaves out a tag, transforming its content in its place.
+	// For anything else it is TransformKeep.
+	TransformUnwrap
+)
+
+// TransformItem is the header of an item, passed to a [TransformFunc] before
+// the rest of the item is read.
+type TransformItem struct {
+	// Path is where the item is in the input, as [Difference.Path]. A map key
+	// has the path of its value.
+	Path string
+
+	// Key is true for a map key, and Raw holds its encoding.
+	Key bool
+	Raw []byte
+
+	MajorType MajorType
+	Value     uint64
+
+	// Arg is the encoding of the header. A TransformFunc rewrites the header
+	// by changing it: to 0 for the shortest encoding, including for floats,
+	// and for a definite length; to ArgIndefinite for an indefinite length
+	// string, array or map; or to a fixed width.
+	Arg Arg
+
+	// SortKeys, set for a map, writes the entries in the bytewise order of
+	// their encoded keys.
+	SortKeys bool
+
+	// Replacement is the encoding written for TransformReplace.
+	Replacement []byte
+}
+
+// TransformFunc decides what [Transform] does with [item], and can rewrite its
+// header. Only Arg, SortKeys and Replacement are read back from [item].
+type TransformFunc func(item *TransformItem) (TransformAction, error)
+
+// TransformOptions configures the optional behaviour of [Transform]. The zero
+// value matches the behaviour of the package level function.
+type TransformOptions struct {
+	// NoDrop promises the TransformFunc never returns TransformDrop, so the
+	// number of items in an array or map cannot change, and those keeping
+	// their definite lengths are streamed too. Transform fails with
+	// ErrUnsupportedValue if an item is dropped.
+	NoDrop bool
+}
+
+// Transform copies the next object from [in] to [out], as [ReadRaw], calling
+// [fn] for each item within it, and for the object itself, to keep, drop,
+// replace or rewrite it.
+//
+// Strings, and arrays and maps written with indefinite lengths, are streamed.
+// The content of arrays and maps written with definite lengths, or with sorted
+// keys, is held in memory until the outermost of them is complete, as a
+// dropped item changes their length; see [TransformOptions.NoDrop].
+func Transform(in io.Reader, out io.Writer, fn TransformFunc) error {
+	return TransformOptions{}.Transform(in, out, fn)
+}
+
+// Transform copies the next object from [in] to [out], as [Transform].
+func (o TransformOptions) Transform(in io.Reader, out io.Writer, fn TransformFunc) error {
+	t := transformer{fn: fn, noDrop: o.NoDrop}
+
+	majorType, arg, value, err := readMajorType(in)
+	if err != nil {
+		return err
+	}
+	if majorType == MajorTypeSimpleFloat && arg == ArgIndefinite {
+		return ErrNotWellFormed
+	}
+
+	_, err = t.transform(in, out, &TransformItem{MajorType: majorType, Arg: arg, Value: value}, nil)
+	return err
+}
+
+// ChainTransforms returns a [TransformFunc] calling each of [fns] in turn,
+// until one returns an action other than TransformKeep.
+func ChainTransforms(fns ...TransformFunc) TransformFunc {
+	return func(item *TransformItem) (TransformAction, error) {
+		for _, fn := range fns {
+			action, err := fn(item)
+			if err != nil || action != TransformKeep {
+				return action, err
+			}
+		}
+		return TransformKeep, nil
+	}
+}
+
+// MakeDefinite is a [TransformFunc] rewriting indefinite length strings,
+// arrays and maps with definite lengths.
+func MakeDefinite(item *TransformItem) (TransformAction, error) {
+	if item.Arg == ArgIndefinite {
+		item.Arg = 0
+	}
+	return TransformKeep, nil
+}
+
+// StripTags is a [TransformFunc] leaving out every tag, keeping its content.
+func StripTags(item *TransformItem) (TransformAction, error) {
+	if item.MajorType == MajorTypeTagged {
+		return TransformUnwrap, nil
+	}
+	return TransformKeep, nil
+}
+
+// Canonicalize is a [TransformFunc] rewriting items with the core
+// deterministic encoding (RFC 8949 section 4.2.1): the shortest arguments and
+// floats, definite lengths, and map entries sorted by their encoded keys.
+func Canonicalize(item *TransformItem) (TransformAction, error) {
+	item.Arg = 0
+	item.SortKeys = item.MajorType == MajorTypeMap
+	return TransformKeep, nil
+}
+
+// RedactKeys returns a [TransformFunc] leaving out the map entries, at any
+// depth, with any of [keys], which may be anything accepted by [Key]. Keys are
+// matched by value, as [Key], however they are encoded.
+func RedactKeys(keys ...any) TransformFunc {
+	var encoded [][]byte
+	var err error
+	for _, key := range keys {
+		var b []byte
+		if b, err = encodeKey(key); err != nil {
+			break
+		}
+		encoded = append(encoded, b)
+	}
+
+	return func(item *TransformItem) (TransformAction, error) {
+		if err != nil {
+			return TransformKeep, err
+		}
+		if !item.Key {
+			return TransformKeep, nil
+		}
+		raw := deterministicKey(item.Raw)
+		if slices.ContainsFunc(encoded, func(key []byte) bool { return bytes.Equal(key, raw) }) {
+			return TransformDrop, nil
+		}
+		return TransformKeep, nil
+	}
+}
+
+type transformer struct {
+	fn     TransformFunc
+	noDrop bool
+}
+
+// next reads the header of the next item from [in], and transforms it as
+// [item], which holds the path.
+func (t *transformer) next(in io.Reader, out io.Writer, item TransformItem, prefix []byte) (bool, error) {
+	majorType, arg, value, err := readMajorType(in)
+	if err != nil {
+		return false, unexpectedEOF(err)
+	}
+	if majorType == MajorTypeSimpleFloat && arg == ArgIndefinite {
+		return false, ErrNotWellFormed
+	}
+
+	item.MajorType, item.Arg, item.Value = majorType, arg, value
+	return t.transform(in, out, &item, prefix)
+}
+
+// transform calls the TransformFunc for [item], whose header has been read
+// from [in], and writes the result to [out] after [prefix], the encoded key
+// of a map value. It returns false if the item was left out.
+func (t *transformer) transform(in io.Reader, out io.Writer, item *TransformItem, prefix []byte) (bool, error) {
+	majorType, arg, value := item.MajorType, item.Arg, item.Value
+
+	action, err := t.fn(item)
+	if err != nil {
+		return false, err
+	}
+
+	switch action {
+	case TransformKeep, TransformUnwrap:
+		if action == TransformUnwrap && majorType == MajorTypeTagged {
+			return t.next(in, out, TransformItem{Path: item.Path}, prefix)
+		}
+
+	case TransformDrop:
+		if t.noDrop {
+			return false, ErrUnsupportedValue
+		}
+		return false, unexpectedEOF(readOver(in, majorType, arg, value, false))
+
+	case TransformReplace:
+		if err = readOver(in, majorType, arg, value, false); err != nil {
+			return false, unexpectedEOF(err)
+		}
+		if _, err = out.Write(prefix); err != nil {
+			return false, err
+		}
+		_, err = out.Write(item.Replacement)
+		return true, err
+
+	default:
+		return false, ErrUnsupportedValue
+	}
+
+	if _, err = out.Write(prefix); err != nil {
+		return false, err
+	}
+
+	switch majorType {
+	case MajorTypeUInt,
+		MajorTypeNInt:
+		_, err = writeHeader(out, majorType, item.Arg, value)
+
+	case MajorTypeBstr,
+		MajorTypeTstr:
+		err = transformString(in, out, majorType, arg, value, item.Arg)
+
+	case MajorTypeArray,
+		MajorTypeMap:
+		err = t.transformContainer(in, out, item.Path, majorType, arg, value, item.Arg, item.SortKeys)
+
+	case MajorTypeTagged:
+		if _, err = writeHeader(out, majorType, item.Arg, value); err != nil {
+			return false, err
+		}
+		_, err = t.next(in, out, TransformItem{Path: item.Path}, nil)
+
+	default: // MajorTypeSimpleFloat
+		switch arg {
+		case SimpleFloat16, SimpleFloat32, SimpleFloat64:
+			if arg, value, err = transformFloat(arg, value, item.Arg); err != nil {
+				return false, err
+			}
+			_, err = writeHeader(out, majorType, arg, value)
+		default:
+			if item.Arg != 0 && item.Arg != Arg8 {
+				return false, ErrUnsupportedValue
+			}
+			_, err = writeHeader(out, majorType, item.Arg, value)
+		}
+	}
+
+	return true, err
+}
+
+// transformString copies a string, whose header has been read from [in],
+// with a header encoded as [to].
+func transformString(in io.Reader, out io.Writer, majorType MajorType, arg Arg, value uint64, to Arg) error {
+	var err error
+	switch {
+	case arg != ArgIndefinite && to != ArgIndefinite:
+		if _, err = writeHeader(out, majorType, to, value); err != nil {
+			return err
+		}
+		return readByteChunks(in, value, out)
+
+	case arg != ArgIndefinite:
+		// a single chunk
+		if _, err = writeHeader(out, majorType, ArgIndefinite, 0); err != nil {
+			return err
+		}
+		if value > 0 {
+			if _, err = writeHeader(out, majorType, 0, value); err != nil {
+				return err
+			}
+			if err = readByteChunks(in, value, out); err != nil {
+				return err
+			}
+		}
+		_, err = out.Write([]byte{valueBreak})
+		return err
+
+	case to != ArgIndefinite:
+		content := bytes.NewBuffer(nil)
+		err = readBytes(in, majorType, arg, value, false,
+			func(indefinite bool, length uint64) error { return nil },
+			content,
+		)
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		if _, err = writeHeader(out, majorType, to, uint64(content.Len())); err != nil {
+			return err
+		}
+		_, err = content.WriteTo(out)
+		return err
+
+	default:
+		// chunk by chunk
+		if _, err = writeHeader(out, majorType, ArgIndefinite, 0); err != nil {
+			return err
+		}
+		for {
+			chunkMajorType, chunkArg, length, err := readMajorType(in)
+			if err != nil {
+				return unexpectedEOF(err)
+			}
+
+			if chunkMajorType == MajorTypeSimpleFloat && chunkArg == SimpleBreak {
+				break
+			}
+
+			if chunkMajorType != majorType {
+				return ErrMismatchedChunk
+			}
+
+			if chunkArg == ArgIndefinite {
+				return ErrNestedIndefinite
+			}
+
+			if _, err = writeHeader(out, majorType, chunkArg, length); err != nil {
+				return err
+			}
+			if err = readByteChunks(in, length, out); err != nil {
+				return err
+			}
+		}
+		_, err = out.Write([]byte{valueBreak})
+		return err
+	}
+}
+
+// transformContainer transforms the items of an array or map, whose header
+// has been read from [in], writing a header encoded as [to].
+//
+// The items are streamed to [out] unless the header holds their number, which
+// is unknown until the end, or the entries are sorted. Then they are held in a
+// transformBuffer, shared with any containers within, until the outermost
+// buffered container is complete.
+func (t *transformer) transformContainer(
+	in io.Reader,
+	out io.Writer,
+	path string,
+	majorType MajorType,
+	arg Arg,
+	value uint64,
+	to Arg,
+	sortKeys bool,
+) error {
+	var pin *peekReader
+	if arg == ArgIndefinite {
+		pin = &peekReader{r: in}
+		in = pin
+	}
+
+	sortKeys = sortKeys && majorType == MajorTypeMap
+
+	var buffer *transformBuffer
+	var owner io.Writer // written to once the buffer is complete, if set
+	var header int
+	switch {
+	case sortKeys || (to != ArgIndefinite && (arg == ArgIndefinite || !t.noDrop)):
+		var shared bool
+		if buffer, shared = out.(*transformBuffer); !shared {
+			buffer = &transformBuffer{}
+			owner = out
+			out = buffer
+		}
+		header = buffer.reserve(majorType, to)
+	case to == ArgIndefinite:
+		if _, err := writeHeader(out, majorType, ArgIndefinite, 0); err != nil {
+			return err
+		}
+	default:
+		if _, err := writeHeader(out, majorType, to, value); err != nil {
+			return err
+		}
+	}
+
+	type entry struct {
+		key                []byte
+		start, end         int // of the entry in buffer.data
+		headers, endHeader int // of the entry in buffer.headers
+	}
+	var entries []entry
+	var count uint64
+	key := bytes.NewBuffer(nil)
+	encodedKey := bytes.NewBuffer(nil)
+	for i := uint64(0); ; i++ {
+		if pin != nil {
+			b, err := pin.PeekByte()
+			if err != nil {
+				return unexpectedEOF(err)
+			}
+			if b == valueBreak {
+				break
+			}
+		} else if i == value {
+			break
+		}
+
+		if majorType == MajorTypeArray {
+			written, err := t.next(in, out, TransformItem{Path: path + "/" + strconv.FormatUint(i, 10)}, nil)
+			if err != nil {
+				return err
+			}
+			if written {
+				count++
+			}
+			continue
+		}
+
+		key.Reset()
+		if err := ReadRaw(in, key); err != nil {
+			return unexpectedEOF(err)
+		}
+		k, err := DecodeOptions{Validity: ValidityNone}.ReadValue(bytes.NewReader(key.Bytes()))
+		if err != nil {
+			return err
+		}
+		item := TransformItem{Path: path + "/" + pathSegment(k)}
+
+		encodedKey.Reset()
+		keyItem := item
+		keyItem.Key = true
+		keyItem.Raw = key.Bytes()
+		written, err := t.next(bytes.NewReader(key.Bytes()), encodedKey, keyItem, nil)
+		if err != nil {
+			return err
+		}
+		if !written {
+			if err = readOverNext(in, false); err != nil {
+				return unexpectedEOF(err)
+			}
+			continue
+		}
+
+		var e entry
+		if sortKeys {
+			e = entry{start: len(buffer.data), headers: len(buffer.headers)}
+		}
+		if written, err = t.next(in, out, item, encodedKey.Bytes()); err != nil {
+			return err
+		}
+		if written {
+			count++
+		}
+		if written && sortKeys {
+			e.key = bytes.Clone(encodedKey.Bytes())
+			e.end, e.endHeader = len(buffer.data), len(buffer.headers)
+			entries = append(entries, e)
+		}
+	}
+
+	if buffer == nil {
+		if to == ArgIndefinite {
+			_, err := out.Write([]byte{valueBreak})
+			return err
+		}
+		return nil
+	}
+
+	if len(entries) > 0 {
+		// entries were written one after the other
+		first := entries[0]
+		slices.SortStableFunc(entries, func(a, b entry) int {
+			return bytes.Compare(a.key, b.key)
+		})
+		buffer.reorder(first.start, first.headers, len(entries), func(i int) (int, int, int, int) {
+			e := entries[i]
+			return e.start, e.end, e.headers, e.endHeader
+		})
+	}
+
+	buffer.headers[header].value = count
+	if to == ArgIndefinite {
+		buffer.data = append(buffer.data, valueBreak)
+	}
+
+	if owner == nil {
+		return nil
+	}
+	return buffer.flush(owner)
+}
+
+// transformBuffer holds buffered containers, and everything written within
+// them, with the header of each container left out until its number of items
+// is known.
+type transformBuffer struct {
+	data    []byte
+	headers []transformHeader // in order of offset
+}
+
+type transformHeader struct {
+	at        int // offset in data
+	majorType MajorType
+	arg       Arg
+	value     uint64
+}
+
+func (b *transformBuffer) Write(p []byte) (int, error) {
+	// doubling, as append grows large slices more slowly
+	if len(b.data)+len(p) > cap(b.data) {
+		b.data = slices.Grow(b.data, max(len(p), cap(b.data)))
+	}
+	b.data = append(b.data, p...)
+	return len(p), nil
+}
+
+// reserve adds a header at the end of the buffer, returning its index.
+func (b *transformBuffer) reserve(majorType MajorType, arg Arg) int {
+	b.headers = append(b.headers, transformHeader{at: len(b.data), majorType: majorType, arg: arg})
+	return len(b.headers) - 1
+}
+
+// reorder rewrites everything from offset [start], and header [headers], as
+// the [n] spans returned by [span]: the start and end offsets of each, and the
+// indexes of its first header and of the header after its last.
+func (b *transformBuffer) reorder(start, headers, n int, span func(i int) (int, int, int, int)) {
+	data := slices.Clone(b.data[start:])
+	moved := slices.Clone(b.headers[headers:])
+	b.data = b.data[:start]
+	b.headers = b.headers[:headers]
+
+	for i := range n {
+		from, to, h, endHeader := span(i)
+		shift := len(b.data) - from
+		b.data = append(b.data, data[from-start:to-start]...)
+		for _, header := range moved[h-headers : endHeader-headers] {
+			header.at += shift
+			b.headers = append(b.headers, header)
+		}
+	}
+}
+
+// flush writes the buffer to [out], with each header in place.
+func (b *transformBuffer) flush(out io.Writer) error {
+	pos := 0
+	for _, h := range b.headers {
+		if _, err := out.Write(b.data[pos:h.at]); err != nil {
+			return err
+		}
+		if _, err := writeHeader(out, h.majorType, h.arg, h.value); err != nil {
+			return err
+		}
+		pos = h.at
+	}
+	_, err := out.Write(b.data[pos:])
+	return err
+}
+
+// transformFloat returns the float [value], of width [arg], at width [to]: 0
+// for the shortest width holding it exactly. It fails with ErrOverflow if [to]
+// is too narrow.
+func transformFloat(arg Arg, value uint64, to Arg) (Arg, uint64, error) {
+	if to == arg {
+		return arg, value, nil
+	}
+
+	f, err := readFloat[float64](MajorTypeSimpleFloat, arg, value)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	shortest := FloatValue(f)
+	switch {
+	case to == 0:
+		return shortest.Arg, shortest.Uint, nil
+	case to == Arg16 && shortest.Arg == Arg16:
+		return Arg16, shortest.Uint, nil
+	case to == Arg32 && shortest.Arg <= Arg32:
+		return Arg32, uint64(math.Float32bits(float32(f))), nil
+	case to == Arg64:
+		return Arg64, math.Float64bits(f), nil
+	case to == Arg16 || to == Arg32:
+		return 0, 0, ErrOverflow
+	default:
+		return 0, 0, ErrUnsupportedValue
+	}
+}
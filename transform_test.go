@@ -0,0 +1,419 @@
+package cbor
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"runtime"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func keep(*TransformItem) (TransformAction, error) {
+	return TransformKeep, nil
+}
+
+func Test_Transform_Keep(t *testing.T) {
+	encoded := []string{
+		"1800",
+		"5f41614162ff",
+		"5fff",
+		"7f780161ff",
+		"9f01820203ff",
+		"bf6161f4ff",
+		"a2616201616101",
+		"d818f6",
+		"f820",
+		"f97c01",
+		"fb3ff0000000000000",
+	}
+	for _, tt := range tests_ExampleEncoded {
+		encoded = append(encoded, tt.encoded)
+	}
+
+	for _, e := range encoded {
+		t.Run(e, func(t *testing.T) {
+			data := decodeHex(t, e)
+			in := bytes.NewReader(data)
+			out := &bytes.Buffer{}
+			if err := Transform(in, out, keep); err != nil {
+				t.Fatal(err)
+			}
+			if in.Len() != 0 {
+				t.Fatalf("trailing data %d", in.Len())
+			}
+			if diff := cmp.Diff(data, out.Bytes()); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func Test_Transform(t *testing.T) {
+	// {"b": [1, 2], "a": 1(5), "secret": "x", "n": {"secret": 1, "k": 2}}
+	document := "a461628201026161c105667365637265746178616ea26673656372657401616b02"
+
+	tests := []struct {
+		name    string
+		fn      TransformFunc
+		encoded string
+		want    string
+	}{
+		{name: "definite bytes", fn: MakeDefinite, encoded: "5f41614162ff", want: "426162"},
+		{name: "definite empty text", fn: MakeDefinite, encoded: "7fff", want: "60"},
+		{name: "definite array", fn: MakeDefinite, encoded: "9f01ff", want: "8101"},
+		{name: "definite empty array", fn: MakeDefinite, encoded: "9fff", want: "80"},
+		{name: "definite nested", fn: MakeDefinite, encoded: "9f9f01ffff", want: "818101"},
+		{name: "definite map", fn: MakeDefinite, encoded: "bf6161f4ff", want: "a16161f4"},
+		{name: "definite keeps widths", fn: MakeDefinite, encoded: "9f1801ff", want: "811801"},
+
+		{name: "strip tag", fn: StripTags, encoded: "c11a514b67b0", want: "1a514b67b0"},
+		{name: "strip nested tags", fn: StripTags, encoded: "d9d9f7c100", want: "00"},
+		{name: "strip tags in array", fn: StripTags, encoded: "82c100c200", want: "820000"},
+		{name: "strip tags in map", fn: StripTags, encoded: document, want: "a46162820102616105667365637265746178616ea26673656372657401616b02"},
+
+		{name: "canonical uint", fn: Canonicalize, encoded: "1800", want: "00"},
+		{name: "canonical uint16", fn: Canonicalize, encoded: "190017", want: "17"},
+		{name: "canonical negative", fn: Canonicalize, encoded: "3b0000000000000000", want: "20"},
+		{name: "canonical float", fn: Canonicalize, encoded: "fb3ff0000000000000", want: "f93c00"},
+		{name: "canonical float32", fn: Canonicalize, encoded: "fa47c35000", want: "fa47c35000"},
+		{name: "canonical infinity", fn: Canonicalize, encoded: "fbfff0000000000000", want: "f9fc00"},
+		{name: "canonical tag", fn: Canonicalize, encoded: "d80100", want: "c100"},
+		{name: "canonical simple", fn: Canonicalize, encoded: "f8ff", want: "f8ff"},
+		{name: "canonical bytes", fn: Canonicalize, encoded: "5f41614162ff", want: "426162"},
+		{name: "canonical text", fn: Canonicalize, encoded: "780161", want: "6161"},
+		{name: "canonical array", fn: Canonicalize, encoded: "9f0102ff", want: "820102"},
+		{name: "canonical map", fn: Canonicalize, encoded: "bf61621801616101ff", want: "a2616101616201"},
+		{name: "canonical nested", fn: Canonicalize, encoded: "bf6162bfff61619f9fffffff", want: "a2616181806162a0"},
+		{name: "canonical nested maps", fn: Canonicalize, encoded: "bf6162bf616201616102ff616100ff", want: "a26161006162a2616102616201"},
+		{name: "canonical document", fn: Canonicalize, encoded: document, want: "a46161c1056162820102616ea2616b026673656372657401667365637265746178"},
+
+		{name: "redact", fn: RedactKeys("secret"), encoded: document, want: "a361628201026161c105616ea1616b02"},
+		{name: "redact integer", fn: RedactKeys(1, 2), encoded: "a3010102020303", want: "a10303"},
+		{name: "redact indefinite", fn: RedactKeys("a"), encoded: "bf616101616202ff", want: "bf616202ff"},
+		{name: "redact none", fn: RedactKeys("c"), encoded: document, want: document},
+		{name: "redact chunked key", fn: RedactKeys("pw"), encoded: "a17f627077ff01", want: "a0"},
+		{name: "redact wide key", fn: RedactKeys("pw"), encoded: "a17802707701", want: "a0"},
+		{name: "redact wide integer", fn: RedactKeys(1), encoded: "a2180101190002f5", want: "a1190002f5"},
+		{name: "redact nested", fn: RedactKeys("a"), encoded: "8282a1616101a16162028100", want: "8282a0a16162028100"},
+		{name: "redact float", fn: RedactKeys(1.5), encoded: "a1fb3ff800000000000001", want: "a0"},
+
+		{name: "chain", fn: ChainTransforms(RedactKeys("secret"), StripTags, Canonicalize), encoded: document, want: "a36161056162820102616ea1616b02"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			if err := Transform(bytes.NewReader(decodeHex(t, tt.encoded)), out, tt.fn); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(decodeHex(t, tt.want), out.Bytes()); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func Test_Transform_Items(t *testing.T) {
+	// {"b": [1, 2], "a": 1(5), "n": {"k": 2}}
+	encoded := decodeHex(t, "a361628201026161c105616ea1616b02")
+
+	var got []string
+	fn := func(item *TransformItem) (TransformAction, error) {
+		s := item.Path
+		if item.Key {
+			key, err := ReadString(bytes.NewReader(item.Raw))
+			if err != nil {
+				return TransformKeep, err
+			}
+			s += " key " + key
+		}
+		got = append(got, s)
+		return TransformKeep, nil
+	}
+	if err := Transform(bytes.NewReader(encoded), io.Discard, fn); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"",
+		"/b key b",
+		"/b",
+		"/b/0",
+		"/b/1",
+		"/a key a",
+		"/a",
+		"/a",
+		"/n key n",
+		"/n",
+		"/n/k key k",
+		"/n/k",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func Test_Transform_Rewrite(t *testing.T) {
+	tests := []struct {
+		name    string
+		fn      TransformFunc
+		encoded string
+		want    string
+	}{
+		{
+			name: "replace",
+			fn: func(item *TransformItem) (TransformAction, error) {
+				if item.Path == "/b" && !item.Key {
+					item.Replacement = []byte{0xf6}
+					return TransformReplace, nil
+				}
+				return TransformKeep, nil
+			},
+			encoded: "a2616282010261610c",
+			want:    "a26162f661610c",
+		},
+		{
+			name: "replace key",
+			fn: func(item *TransformItem) (TransformAction, error) {
+				if item.Key && item.Path == "/b" {
+					item.Replacement = []byte{0x61, 0x63}
+					return TransformReplace, nil
+				}
+				return TransformKeep, nil
+			},
+			encoded: "a2616282010261610c",
+			want:    "a2616382010261610c",
+		},
+		{
+			name: "drop array item",
+			fn: func(item *TransformItem) (TransformAction, error) {
+				if item.Path == "/0" {
+					return TransformDrop, nil
+				}
+				return TransformKeep, nil
+			},
+			encoded: "830182020304",
+			want:    "8282020304",
+		},
+		{
+			name: "drop map value",
+			fn: func(item *TransformItem) (TransformAction, error) {
+				if item.MajorType == MajorTypeArray && item.Path != "" {
+					return TransformDrop, nil
+				}
+				return TransformKeep, nil
+			},
+			encoded: "a2616282010261610c",
+			want:    "a161610c",
+		},
+		{
+			name: "drop root",
+			fn: func(*TransformItem) (TransformAction, error) {
+				return TransformDrop, nil
+			},
+			encoded: "820102",
+			want:    "",
+		},
+		{
+			name: "indefinite",
+			fn: func(item *TransformItem) (TransformAction, error) {
+				if item.MajorType >= MajorTypeBstr && item.MajorType <= MajorTypeMap {
+					item.Arg = ArgIndefinite
+				}
+				return TransformKeep, nil
+			},
+			encoded: "83426162408000",
+			want:    "9f5f426162ff5fff9fffff",
+		},
+		{
+			name: "indefinite sorted",
+			fn: func(item *TransformItem) (TransformAction, error) {
+				item.SortKeys = true
+				return TransformKeep, nil
+			},
+			encoded: "bf616202616101ff",
+			want:    "bf616101616202ff",
+		},
+		{
+			name: "wider",
+			fn: func(item *TransformItem) (TransformAction, error) {
+				if item.MajorType == MajorTypeUInt {
+					item.Arg = Arg16
+				}
+				if item.MajorType == MajorTypeSimpleFloat {
+					item.Arg = Arg64
+				}
+				return TransformKeep, nil
+			},
+			encoded: "8201f93c00",
+			want:    "82190001fb3ff0000000000000",
+		},
+		{
+			name: "float32",
+			fn: func(item *TransformItem) (TransformAction, error) {
+				item.Arg = Arg32
+				return TransformKeep, nil
+			},
+			encoded: "f93c00",
+			want:    "fa3f800000",
+		},
+		{
+			name: "unwrap untagged",
+			fn: func(*TransformItem) (TransformAction, error) {
+				return TransformUnwrap, nil
+			},
+			encoded: "8101",
+			want:    "8101",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			if err := Transform(bytes.NewReader(decodeHex(t, tt.encoded)), out, tt.fn); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(decodeHex(t, tt.want), out.Bytes(), cmp.Comparer(bytes.Equal)); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func Test_Transform_Errors(t *testing.T) {
+	errTest := errors.New("test")
+	withArg := func(arg Arg) TransformFunc {
+		return func(item *TransformItem) (TransformAction, error) {
+			item.Arg = arg
+			return TransformKeep, nil
+		}
+	}
+
+	tests := []struct {
+		name    string
+		fn      TransformFunc
+		encoded string
+		err     error
+	}{
+		{name: "empty", fn: keep, encoded: "", err: io.EOF},
+		{name: "truncated", fn: keep, encoded: "8201", err: io.ErrUnexpectedEOF},
+		{name: "truncated string", fn: keep, encoded: "4301", err: io.ErrUnexpectedEOF},
+		{name: "truncated indefinite", fn: MakeDefinite, encoded: "9f01", err: io.ErrUnexpectedEOF},
+		{name: "truncated key", fn: keep, encoded: "a1", err: io.ErrUnexpectedEOF},
+		{name: "truncated dropped", fn: RedactKeys("a"), encoded: "a16161", err: io.ErrUnexpectedEOF},
+		{name: "break", fn: keep, encoded: "ff", err: ErrNotWellFormed},
+		{name: "break in array", fn: keep, encoded: "82ff", err: ErrNotWellFormed},
+		{name: "mismatched chunk", fn: keep, encoded: "5f6161ff", err: ErrMismatchedChunk},
+		{name: "nested indefinite", fn: keep, encoded: "5f5fffff", err: ErrNestedIndefinite},
+		{name: "too narrow", fn: withArg(Arg8), encoded: "190100", err: ErrOverflow},
+		{name: "too narrow float", fn: withArg(Arg16), encoded: "fa47c35000", err: ErrOverflow},
+		{name: "indefinite integer", fn: withArg(ArgIndefinite), encoded: "01", err: ErrUnsupportedValue},
+		{name: "wide simple", fn: withArg(Arg16), encoded: "f4", err: ErrUnsupportedValue},
+		{name: "float arg", fn: withArg(Arg8), encoded: "f93c00", err: ErrUnsupportedValue},
+		{name: "invalid key", fn: RedactKeys(struct{}{}), encoded: "01", err: ErrUnsupportedValue},
+		{
+			name: "error",
+			fn: func(item *TransformItem) (TransformAction, error) {
+				if item.Path == "/1" {
+					return TransformKeep, errTest
+				}
+				return TransformKeep, nil
+			},
+			encoded: "820102",
+			err:     errTest,
+		},
+		{
+			name: "action",
+			fn: func(*TransformItem) (TransformAction, error) {
+				return TransformUnwrap + 1, nil
+			},
+			encoded: "01",
+			err:     ErrUnsupportedValue,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Transform(bytes.NewReader(decodeHex(t, tt.encoded)), io.Discard, tt.fn)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func Test_TransformOptions_NoDrop(t *testing.T) {
+	// {"b": [1, 2], "a": 1(5), "secret": "x", "n": {"secret": 1, "k": 2}}
+	document := "a461628201026161c105667365637265746178616ea26673656372657401616b02"
+
+	tests := []struct {
+		name    string
+		fn      TransformFunc
+		encoded string
+		want    string
+		err     error
+	}{
+		{name: "keep", fn: keep, encoded: document, want: document},
+		{name: "canonical", fn: Canonicalize, encoded: document, want: "a46161c1056162820102616ea2616b026673656372657401667365637265746178"},
+		{name: "definite", fn: MakeDefinite, encoded: "9f9f01ff82f4f5ff", want: "82810182f4f5"},
+		{name: "strip tags", fn: StripTags, encoded: "82c100c200", want: "820000"},
+		{name: "drop", fn: RedactKeys("secret"), encoded: document, err: ErrUnsupportedValue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			err := TransformOptions{NoDrop: true}.Transform(bytes.NewReader(decodeHex(t, tt.encoded)), out, tt.fn)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(decodeHex(t, tt.want), out.Bytes()); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func Test_Transform_Allocations(t *testing.T) {
+	// arrays nested 100 deep, each holding a 10 KiB string
+	const depth, size = 100, 10 << 10
+	encoded := bytes.NewBuffer(nil)
+	for range depth {
+		encoded.Write([]byte{0x82, 0x59, size >> 8, size & 0xff})
+		encoded.Write(make([]byte, size))
+	}
+	encoded.WriteByte(0x00)
+	data := encoded.Bytes()
+
+	tests := []struct {
+		name     string
+		options  TransformOptions
+		maxAlloc uint64
+	}{
+		// copied once, into a buffer grown by doubling, rather than once for
+		// each level
+		{name: "buffered", maxAlloc: uint64(5 * len(data))},
+		{name: "streamed", options: TransformOptions{NoDrop: true}, maxAlloc: uint64(len(data) / 8)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+
+			out := &countingWriter{}
+			if err := tt.options.Transform(bytes.NewReader(data), out, keep); err != nil {
+				t.Fatal(err)
+			}
+
+			runtime.ReadMemStats(&after)
+
+			if out.n != int64(len(data)) {
+				t.Fatalf("wrote %d, want %d", out.n, len(data))
+			}
+			if alloc := after.TotalAlloc - before.TotalAlloc; alloc > tt.maxAlloc {
+				t.Fatalf("allocated %d bytes", alloc)
+			}
+		})
+	}
+}
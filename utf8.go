@@ -0,0 +1,67 @@
+package cbor
+
+import (
+	"io"
+	"unicode/utf8"
+)
+
+// utf8Writer passes everything written to it on to [w], failing if it is not
+// valid UTF-8. Sequences split across writes are checked once complete.
+type utf8Writer struct {
+	w  io.Writer
+	p  [utf8.UTFMax]byte // incomplete trailing sequence
+	pn int
+}
+
+func (v *utf8Writer) Write(b []byte) (int, error) {
+	if err := v.check(b); err != nil {
+		return 0, err
+	}
+
+	return v.w.Write(b)
+}
+
+func (v *utf8Writer) check(b []byte) error {
+	if v.pn > 0 {
+		for v.pn < utf8.UTFMax && len(b) > 0 && !utf8.FullRune(v.p[:v.pn]) {
+			v.p[v.pn] = b[0]
+			v.pn++
+			b = b[1:]
+		}
+
+		if !utf8.FullRune(v.p[:v.pn]) {
+			return nil
+		}
+
+		if r, size := utf8.DecodeRune(v.p[:v.pn]); r == utf8.RuneError && size <= 1 {
+			return ErrInvalidUTF8
+		}
+		v.pn = 0
+	}
+
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				v.pn = copy(v.p[:], b[i:])
+				b = b[:i]
+			}
+			break
+		}
+	}
+
+	if !utf8.Valid(b) {
+		return ErrInvalidUTF8
+	}
+
+	return nil
+}
+
+// end checks the text written so far did not finish part way through a
+// sequence, and resets for the next string.
+func (v *utf8Writer) end() error {
+	if v.pn > 0 {
+		v.pn = 0
+		return ErrInvalidUTF8
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package cbor
+
+import (
+	"bytes"
+	"testing"
+)
+
+func Test_utf8Writer(t *testing.T) {
+	tests := []struct {
+		name    string
+		writes  []string
+		wantErr error
+	}{
+		{name: "ascii", writes: []string{"abc"}},
+		{name: "whole", writes: []string{"水\U00010151"}},
+		{name: "split", writes: []string{"a\xe6", "\xb0", "\xb4b"}},
+		{name: "split four", writes: []string{"\xf0\x90", "\x85\x91"}},
+		{name: "invalid", writes: []string{"\xc3\x28"}, wantErr: ErrInvalidUTF8},
+		{name: "invalid split", writes: []string{"\xe6\xb0", "a"}, wantErr: ErrInvalidUTF8},
+		{name: "truncated", writes: []string{"\xe6\xb0"}, wantErr: ErrInvalidUTF8},
+		{name: "surrogate", writes: []string{"\xed\xa0\x80"}, wantErr: ErrInvalidUTF8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := bytes.NewBuffer(nil)
+			v := &utf8Writer{w: out}
+
+			var err error
+			for _, w := range tt.writes {
+				if _, err = v.Write([]byte(w)); err != nil {
+					break
+				}
+			}
+			if err == nil {
+				err = v.end()
+			}
+
+			if err != tt.wantErr {
+				t.Fatalf("want %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
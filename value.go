@@ -0,0 +1,773 @@
+package cbor
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/x448/float16"
+)
+
+type ValueKind byte
+
+const (
+	ValueUint ValueKind = iota
+	ValueNegInt
+	ValueBytes
+	ValueText
+	ValueArray
+	ValueMap
+	ValueTag
+	ValueSimple
+	ValueFloat
+)
+
+// Value is a single item of any type, held precisely enough to be written
+// back exactly as it was read. The fields used depend on Kind.
+type Value struct {
+	Kind ValueKind
+
+	// Arg is how the header argument is encoded: 0 for the shortest form,
+	// Arg8 to Arg64 for a wider one, or ArgIndefinite for an indefinite length
+	// string, array or map. Floats use Arg16, Arg32 or Arg64 for their width.
+	Arg Arg
+
+	// Uint is the unsigned integer, the negative integer as -1 - Uint, the tag
+	// number, the simple value, or the bits of a float at the width of Arg.
+	Uint uint64
+
+	// Bytes is the content of a definite length string.
+	Bytes []byte
+
+	// Items are the items of an array, the chunks of an indefinite length
+	// string, or the single content item of a tag.
+	Items []Value
+
+	// Pairs are the entries of a map, in the order they were read.
+	Pairs []Pair
+}
+
+// Pair is a single map entry.
+type Pair struct {
+	Key   Value
+	Value Value
+}
+
+func UintValue(value uint64) Value {
+	return Value{Kind: ValueUint, Uint: value}
+}
+
+func IntValue(value int64) Value {
+	if value >= 0 {
+		return Value{Kind: ValueUint, Uint: uint64(value)}
+	}
+	return Value{Kind: ValueNegInt, Uint: uint64(-value - 1)}
+}
+
+func BytesValue(value []byte) Value {
+	return Value{Kind: ValueBytes, Bytes: value}
+}
+
+func TextValue(value string) Value {
+	return Value{Kind: ValueText, Bytes: []byte(value)}
+}
+
+func ArrayValue(items ...Value) Value {
+	return Value{Kind: ValueArray, Items: items}
+}
+
+func MapValue(pairs ...Pair) Value {
+	return Value{Kind: ValueMap, Pairs: pairs}
+}
+
+func TagValue(number uint64, content Value) Value {
+	return Value{Kind: ValueTag, Uint: number, Items: []Value{content}}
+}
+
+func SimpleValue(value uint8) Value {
+	return Value{Kind: ValueSimple, Uint: uint64(value)}
+}
+
+func BoolValue(value bool) Value {
+	if value {
+		return SimpleValue(SimpleTrue)
+	}
+	return SimpleValue(SimpleFalse)
+}
+
+func NullValue() Value {
+	return SimpleValue(SimpleNull)
+}
+
+func UndefinedValue() Value {
+	return SimpleValue(SimpleUndefined)
+}
+
+// FloatValue returns a float at the shortest width that holds [value]
+// exactly, NaN as a half precision quiet NaN.
+func FloatValue(value float64) Value {
+	if math.IsNaN(value) {
+		return Value{Kind: ValueFloat, Arg: Arg16, Uint: 0x7e00}
+	}
+
+	v32 := float32(value)
+	if float64(v32) != value {
+		return Value{Kind: ValueFloat, Arg: Arg64, Uint: math.Float64bits(value)}
+	}
+	if float16.PrecisionFromfloat32(v32) == float16.PrecisionExact {
+		return Value{Kind: ValueFloat, Arg: Arg16, Uint: uint64(float16.Fromfloat32(v32).Bits())}
+	}
+	return Value{Kind: ValueFloat, Arg: Arg32, Uint: uint64(math.Float32bits(v32))}
+}
+
+// ValueOf converts a Go value to a Value. It accepts the types returned by
+// [ReadAny], other integer types, []any, map[string]any, RawMessage and Value.
+func ValueOf(v any) (Value, error) {
+	switch v := v.(type) {
+	case Value:
+		return v, nil
+	case nil:
+		return NullValue(), nil
+	case bool:
+		return BoolValue(v), nil
+	case int:
+		return IntValue(int64(v)), nil
+	case int8:
+		return IntValue(int64(v)), nil
+	case int16:
+		return IntValue(int64(v)), nil
+	case int32:
+		return IntValue(int64(v)), nil
+	case int64:
+		return IntValue(v), nil
+	case uint:
+		return UintValue(uint64(v)), nil
+	case uint8:
+		return UintValue(uint64(v)), nil
+	case uint16:
+		return UintValue(uint64(v)), nil
+	case uint32:
+		return UintValue(uint64(v)), nil
+	case uint64:
+		return UintValue(v), nil
+	case float32:
+		return FloatValue(float64(v)), nil
+	case float64:
+		return FloatValue(v), nil
+	case string:
+		return TextValue(v), nil
+	case []byte:
+		return BytesValue(v), nil
+	case RawMessage:
+		return readValueData(v)
+	case []any:
+		items := make([]Value, len(v))
+		for i, item := range v {
+			var err error
+			if items[i], err = ValueOf(item); err != nil {
+				return Value{}, err
+			}
+		}
+		return ArrayValue(items...), nil
+	case map[any]any:
+		return mapValueOf(v)
+	case map[string]any:
+		return mapValueOf(v)
+	default:
+		return Value{}, ErrUnsupportedValue
+	}
+}
+
+// mapValueOf converts a map, with its entries in deterministic order.
+func mapValueOf[K comparable](m map[K]any) (Value, error) {
+	pairs := make([]Pair, 0, len(m))
+	for k, v := range m {
+		key, err := ValueOf(k)
+		if err != nil {
+			return Value{}, err
+		}
+		value, err := ValueOf(v)
+		if err != nil {
+			return Value{}, err
+		}
+		pairs = append(pairs, Pair{Key: key, Value: value})
+	}
+	slices.SortFunc(pairs, func(a, b Pair) int {
+		return Compare(a.Key, b.Key)
+	})
+	return MapValue(pairs...), nil
+}
+
+func readValueData(data []byte) (Value, error) {
+	in := bytes.NewReader(data)
+	v, err := ReadValue(in)
+	if err != nil {
+		return Value{}, err
+	}
+	if in.Len() != 0 {
+		return Value{}, ErrTrailingData
+	}
+	return v, nil
+}
+
+// ReadValue reads the next object from [in], keeping everything needed to
+// write it back exactly.
+func ReadValue(in io.Reader) (Value, error) {
+	return DecodeOptions{}.ReadValue(in)
+}
+
+// ReadValue reads the next object from [in], as [ReadValue]. Text strings
+// are checked to be valid UTF-8 unless Validity is ValidityNone.
+func (o DecodeOptions) ReadValue(in io.Reader) (Value, error) {
+	majorType, arg, value, err := readMajorType(in)
+	if err != nil {
+		return Value{}, err
+	}
+
+	return o.readValue(in, majorType, arg, value)
+}
+
+func (o DecodeOptions) readValue(in io.Reader, majorType MajorType, arg Arg, value uint64) (Value, error) {
+	v := Value{Arg: arg, Uint: value}
+
+	switch majorType {
+	case MajorTypeUInt, MajorTypeNInt, MajorTypeTagged:
+		if arg == ArgIndefinite {
+			return Value{}, ErrNotWellFormed
+		}
+		switch majorType {
+		case MajorTypeUInt:
+			v.Kind = ValueUint
+		case MajorTypeNInt:
+			v.Kind = ValueNegInt
+		default:
+			v.Kind = ValueTag
+			content, err := o.ReadValue(in)
+			if err != nil {
+				return Value{}, unexpectedEOF(err)
+			}
+			v.Items = []Value{content}
+		}
+
+	case MajorTypeBstr, MajorTypeTstr:
+		v.Kind, v.Uint = ValueBytes, 0
+		if majorType == MajorTypeTstr {
+			v.Kind = ValueText
+		}
+
+		if arg != ArgIndefinite {
+			b, err := o.readValueChunk(in, majorType, value)
+			if err != nil {
+				return Value{}, err
+			}
+			v.Bytes = b
+			break
+		}
+
+		v.Items = []Value{}
+		for {
+			chunkMajorType, chunkArg, chunkValue, err := readMajorType(in)
+			if err != nil {
+				return Value{}, unexpectedEOF(err)
+			}
+			if chunkMajorType == MajorTypeSimpleFloat && chunkArg == SimpleBreak {
+				break
+			}
+			if chunkMajorType != majorType {
+				return Value{}, ErrMismatchedChunk
+			}
+			if chunkArg == ArgIndefinite {
+				return Value{}, ErrNestedIndefinite
+			}
+
+			b, err := o.readValueChunk(in, majorType, chunkValue)
+			if err != nil {
+				return Value{}, err
+			}
+			v.Items = append(v.Items, Value{Kind: v.Kind, Arg: chunkArg, Bytes: b})
+		}
+
+	case MajorTypeArray:
+		v.Kind, v.Uint = ValueArray, 0
+		v.Items = make([]Value, 0, min(value, maxPrealloc))
+		err := readArray(in, majorType, arg, value,
+			func(bool, uint64) error { return nil },
+			func(in io.Reader) error {
+				item, err := o.ReadValue(in)
+				if err != nil {
+					return unexpectedEOF(err)
+				}
+				v.Items = append(v.Items, item)
+				return nil
+			},
+		)
+		if err != nil {
+			return Value{}, unexpectedEOF(err)
+		}
+
+	case MajorTypeMap:
+		v.Kind, v.Uint = ValueMap, 0
+		v.Pairs = make([]Pair, 0, min(value, maxPrealloc))
+		err := readMap(in, majorType, arg, value,
+			func(bool, uint64) error { return nil },
+			func(in io.Reader) error {
+				key, err := o.ReadValue(in)
+				if err != nil {
+					return unexpectedEOF(err)
+				}
+				value, err := o.ReadValue(in)
+				if err != nil {
+					return unexpectedEOF(err)
+				}
+				v.Pairs = append(v.Pairs, Pair{Key: key, Value: value})
+				return nil
+			},
+		)
+		if err != nil {
+			return Value{}, unexpectedEOF(err)
+		}
+
+	default: // MajorTypeSimpleFloat
+		switch arg {
+		case 0, SimpleUint8:
+			v.Kind = ValueSimple
+		case SimpleFloat16, SimpleFloat32, SimpleFloat64:
+			v.Kind = ValueFloat
+		default: // SimpleBreak
+			return Value{}, ErrNotWellFormed
+		}
+	}
+
+	return v, nil
+}
+
+// readValueChunk reads the content of a definite length string.
+func (o DecodeOptions) readValueChunk(in io.Reader, majorType MajorType, length uint64) ([]byte, error) {
+	w := &sliceWriter{b: make([]byte, 0, min(length, maxPreallocBytes)), max: math.MaxUint64}
+	if err := readByteChunks(in, length, w); err != nil {
+		return nil, err
+	}
+	if majorType == MajorTypeTstr && o.validateTyped() && !utf8.Valid(w.b) {
+		return nil, ErrInvalidUTF8
+	}
+	return w.b, nil
+}
+
+// unexpectedEOF converts io.EOF part way through an object to
+// io.ErrUnexpectedEOF.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// WriteValue writes [value] using the encoding held in it.
+func WriteValue(out io.Writer, value Value) (int, error) {
+	switch value.Kind {
+	case ValueUint, ValueNegInt:
+		return writeHeader(out, majorTypes[value.Kind], value.Arg, value.Uint)
+
+	case ValueBytes, ValueText:
+		majorType := majorTypes[value.Kind]
+		if value.Arg != ArgIndefinite {
+			return writeValueChunk(out, majorType, value.Arg, value.Bytes)
+		}
+
+		tn := 0
+		n, err := writeHeader(out, majorType, ArgIndefinite, 0)
+		tn += n
+		if err != nil {
+			return tn, err
+		}
+		for _, chunk := range value.Items {
+			if chunk.Kind != value.Kind || chunk.Arg == ArgIndefinite {
+				return tn, ErrMismatchedChunk
+			}
+			n, err = writeValueChunk(out, majorType, chunk.Arg, chunk.Bytes)
+			tn += n
+			if err != nil {
+				return tn, err
+			}
+		}
+		n, err = out.Write([]byte{valueBreak})
+		tn += n
+		return tn, err
+
+	case ValueArray:
+		tn := 0
+		n, err := writeHeader(out, MajorTypeArray, value.Arg, uint64(len(value.Items)))
+		tn += n
+		if err != nil {
+			return tn, err
+		}
+		for _, item := range value.Items {
+			n, err = WriteValue(out, item)
+			tn += n
+			if err != nil {
+				return tn, err
+			}
+		}
+		if value.Arg == ArgIndefinite {
+			n, err = out.Write([]byte{valueBreak})
+			tn += n
+		}
+		return tn, err
+
+	case ValueMap:
+		tn := 0
+		n, err := writeHeader(out, MajorTypeMap, value.Arg, uint64(len(value.Pairs)))
+		tn += n
+		if err != nil {
+			return tn, err
+		}
+		for _, pair := range value.Pairs {
+			n, err = WriteValue(out, pair.Key)
+			tn += n
+			if err != nil {
+				return tn, err
+			}
+			n, err = WriteValue(out, pair.Value)
+			tn += n
+			if err != nil {
+				return tn, err
+			}
+		}
+		if value.Arg == ArgIndefinite {
+			n, err = out.Write([]byte{valueBreak})
+			tn += n
+		}
+		return tn, err
+
+	case ValueTag:
+		if len(value.Items) != 1 {
+			return 0, ErrInvalidTagContent
+		}
+		tn := 0
+		n, err := writeHeader(out, MajorTypeTagged, value.Arg, value.Uint)
+		tn += n
+		if err != nil {
+			return tn, err
+		}
+		n, err = WriteValue(out, value.Items[0])
+		tn += n
+		return tn, err
+
+	case ValueSimple:
+		if value.Uint > math.MaxUint8 {
+			return 0, ErrOverflow
+		}
+		return writeHeader(out, MajorTypeSimpleFloat, value.Arg, value.Uint)
+
+	case ValueFloat:
+		switch value.Arg {
+		case Arg16, Arg32, Arg64:
+			return writeHeader(out, MajorTypeSimpleFloat, value.Arg, value.Uint)
+		default:
+			return 0, ErrUnsupportedValue
+		}
+
+	default:
+		return 0, ErrUnsupportedValue
+	}
+}
+
+var majorTypes = [...]MajorType{
+	ValueUint:   MajorTypeUInt,
+	ValueNegInt: MajorTypeNInt,
+	ValueBytes:  MajorTypeBstr,
+	ValueText:   MajorTypeTstr,
+	ValueArray:  MajorTypeArray,
+	ValueMap:    MajorTypeMap,
+	ValueTag:    MajorTypeTagged,
+	ValueSimple: MajorTypeSimpleFloat,
+	ValueFloat:  MajorTypeSimpleFloat,
+}
+
+func writeValueChunk(out io.Writer, majorType MajorType, arg Arg, b []byte) (int, error) {
+	tn := 0
+	n, err := writeHeader(out, majorType, arg, uint64(len(b)))
+	tn += n
+	if err != nil {
+		return tn, err
+	}
+	n, err = out.Write(b)
+	tn += n
+	return tn, err
+}
+
+// writeHeader writes a header with the argument encoded as [arg], or in the
+// shortest form if [arg] is 0.
+func writeHeader(out io.Writer, majorType MajorType, arg Arg, value uint64) (int, error) {
+	n := 0
+	switch arg {
+	case 0:
+		return writeMajorType(out, majorType, value)
+	case Arg8:
+		n = 1
+	case Arg16:
+		n = 2
+	case Arg32:
+		n = 4
+	case Arg64:
+		n = 8
+	case ArgIndefinite:
+		switch majorType {
+		case MajorTypeBstr, MajorTypeTstr, MajorTypeArray, MajorTypeMap:
+			sharedBuffer[0] = byte(majorType) | byte(ArgIndefinite)
+			return out.Write(sharedBuffer[0:1])
+		}
+		return 0, ErrUnsupportedValue
+	default:
+		return 0, ErrUnsupportedValue
+	}
+
+	if n < 8 && value >= 1<<(8*n) {
+		return 0, ErrOverflow
+	}
+
+	sharedBuffer[0] = byte(majorType) | byte(arg)
+	shiftBytesFrom(value, sharedBuffer[1:1+n])
+	return out.Write(sharedBuffer[0 : 1+n])
+}
+
+// Equal reports whether [a] and [b] hold the same data, regardless of how
+// they are encoded: integer widths, string chunks, indefinite lengths, float
+// widths and the order of map entries are ignored.
+func Equal(a, b Value) bool {
+	return Compare(a, b) == 0
+}
+
+// Compare orders values by the bytewise order of their deterministic
+// encodings, as in RFC 8949 section 4.2.1, returning -1, 0 or +1.
+func Compare(a, b Value) int {
+	return bytes.Compare(appendDeterministic(nil, a), appendDeterministic(nil, b))
+}
+
+// appendDeterministic appends the deterministic encoding of [v] to [dst]:
+// shortest arguments, definite lengths, shortest exact floats, and map entries
+// sorted by their encoded keys.
+func appendDeterministic(dst []byte, v Value) []byte {
+	w := &sliceWriter{b: dst, max: math.MaxUint64}
+	switch v.Kind {
+	case ValueBytes, ValueText:
+		_, _ = writeMajorType(w, majorTypes[v.Kind], uint64(v.length()))
+		if v.Arg != ArgIndefinite {
+			return append(w.b, v.Bytes...)
+		}
+		for _, chunk := range v.Items {
+			w.b = append(w.b, chunk.Bytes...)
+		}
+		return w.b
+
+	case ValueArray:
+		_, _ = writeMajorType(w, MajorTypeArray, uint64(len(v.Items)))
+		for _, item := range v.Items {
+			w.b = appendDeterministic(w.b, item)
+		}
+		return w.b
+
+	case ValueMap:
+		_, _ = writeMajorType(w, MajorTypeMap, uint64(len(v.Pairs)))
+		entries := make([][]byte, len(v.Pairs))
+		for i, pair := range v.Pairs {
+			entry := appendDeterministic(nil, pair.Key)
+			entries[i] = appendDeterministic(entry, pair.Value)
+		}
+		// keys are compared first, and a key is never a prefix of another
+		slices.SortFunc(entries, bytes.Compare)
+		for _, entry := range entries {
+			w.b = append(w.b, entry...)
+		}
+		return w.b
+
+	case ValueTag:
+		_, _ = writeMajorType(w, MajorTypeTagged, v.Uint)
+		if len(v.Items) == 1 {
+			w.b = appendDeterministic(w.b, v.Items[0])
+		}
+		return w.b
+
+	case ValueFloat:
+		f, _ := v.AsFloat()
+		_, _ = WriteValue(w, FloatValue(f))
+		return w.b
+
+	default:
+		_, _ = writeMajorType(w, majorTypes[v.Kind], v.Uint)
+		return w.b
+	}
+}
+
+// length returns the length of the content of a string.
+func (v Value) length() int {
+	if v.Arg != ArgIndefinite {
+		return len(v.Bytes)
+	}
+	n := 0
+	for _, chunk := range v.Items {
+		n += len(chunk.Bytes)
+	}
+	return n
+}
+
+// content returns the content of a string, joining the chunks of an
+// indefinite length string.
+func (v Value) content() []byte {
+	if v.Arg != ArgIndefinite {
+		return v.Bytes
+	}
+	b := make([]byte, 0, v.length())
+	for _, chunk := range v.Items {
+		b = append(b, chunk.Bytes...)
+	}
+	return b
+}
+
+// AsUint returns the value of an unsigned integer.
+func (v Value) AsUint() (uint64, error) {
+	if v.Kind != ValueUint {
+		return 0, ErrUnsupportedMajorType
+	}
+	return v.Uint, nil
+}
+
+// AsInt returns the value of an integer, failing with ErrOverflow if it does
+// not fit an int64.
+func (v Value) AsInt() (int64, error) {
+	switch v.Kind {
+	case ValueUint, ValueNegInt:
+		if v.Uint > math.MaxInt64 {
+			return 0, ErrOverflow
+		}
+		if v.Kind == ValueNegInt {
+			return -1 - int64(v.Uint), nil
+		}
+		return int64(v.Uint), nil
+	default:
+		return 0, ErrUnsupportedMajorType
+	}
+}
+
+// AsFloat returns the value of a float, or of an integer.
+func (v Value) AsFloat() (float64, error) {
+	switch v.Kind {
+	case ValueUint:
+		return float64(v.Uint), nil
+	case ValueNegInt:
+		return -1 - float64(v.Uint), nil
+	case ValueFloat:
+		switch v.Arg {
+		case Arg16:
+			return float64(float16.Frombits(uint16(v.Uint)).Float32()), nil
+		case Arg32:
+			return float64(math.Float32frombits(uint32(v.Uint))), nil
+		case Arg64:
+			return math.Float64frombits(v.Uint), nil
+		}
+		return 0, ErrUnsupportedValue
+	default:
+		return 0, ErrUnsupportedMajorType
+	}
+}
+
+// AsBool returns the value of true or false.
+func (v Value) AsBool() (bool, error) {
+	if v.Kind != ValueSimple {
+		return false, ErrUnsupportedMajorType
+	}
+	switch v.Uint {
+	case uint64(SimpleFalse):
+		return false, nil
+	case SimpleTrue:
+		return true, nil
+	default:
+		return false, ErrUnsupportedValue
+	}
+}
+
+// AsBytes returns the content of a byte string.
+func (v Value) AsBytes() ([]byte, error) {
+	if v.Kind != ValueBytes {
+		return nil, ErrUnsupportedMajorType
+	}
+	return v.content(), nil
+}
+
+// AsText returns the content of a text string.
+func (v Value) AsText() (string, error) {
+	if v.Kind != ValueText {
+		return "", ErrUnsupportedMajorType
+	}
+	return string(v.content()), nil
+}
+
+// IsNull reports whether [v] is null.
+func (v Value) IsNull() bool {
+	return v.Kind == ValueSimple && v.Uint == SimpleNull
+}
+
+// IsUndefined reports whether [v] is undefined.
+func (v Value) IsUndefined() bool {
+	return v.Kind == ValueSimple && v.Uint == SimpleUndefined
+}
+
+// AsAny converts [v] to the Go types returned by [ReadAny]: uint64, int64,
+// []byte, string, []any, map[any]any, float64, bool, uint8 for other simple
+// values, and nil. Tags are discarded, keeping their content.
+func (v Value) AsAny() (any, error) {
+	switch v.Kind {
+	case ValueUint:
+		return v.Uint, nil
+	case ValueNegInt:
+		return v.AsInt()
+	case ValueBytes:
+		return v.AsBytes()
+	case ValueText:
+		return v.AsText()
+	case ValueArray:
+		a := make([]any, len(v.Items))
+		for i, item := range v.Items {
+			var err error
+			if a[i], err = item.AsAny(); err != nil {
+				return nil, err
+			}
+		}
+		return a, nil
+	case ValueMap:
+		m := make(map[any]any, len(v.Pairs))
+		for _, pair := range v.Pairs {
+			k, err := pair.Key.AsAny()
+			if err != nil {
+				return nil, err
+			}
+			switch k.(type) {
+			case []byte, []any, map[any]any:
+				return nil, ErrUnsupportedValue
+			}
+			if m[k], err = pair.Value.AsAny(); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case ValueTag:
+		if len(v.Items) != 1 {
+			return nil, ErrInvalidTagContent
+		}
+		return v.Items[0].AsAny()
+	case ValueSimple:
+		switch v.Uint {
+		case uint64(SimpleFalse), SimpleTrue:
+			return v.AsBool()
+		case SimpleNull, SimpleUndefined:
+			return nil, nil
+		}
+		return uint8(v.Uint), nil
+	case ValueFloat:
+		return v.AsFloat()
+	default:
+		return nil, ErrUnsupportedValue
+	}
+}
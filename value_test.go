@@ -0,0 +1,437 @@
+package cbor
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_ReadValue_WriteValue(t *testing.T) {
+	encoded := []string{
+		"1800",               // non-minimal argument
+		"190017",             // non-minimal argument
+		"3b0000000000000000", // non-minimal negative
+		"5f41614162ff",       // indefinite bytes
+		"5fff",               // empty indefinite bytes
+		"7f780161ff",         // indefinite text, non-minimal chunk
+		"9f01820203ff",       // indefinite array
+		"bf6161f4ff",         // indefinite map
+		"a2616201616101",     // unsorted map
+		"d818f6",             // non-minimal tag
+		"f820",               // simple 32
+		"f97c01",             // signalling NaN
+		"fa7fc00001",         // float32 NaN payload
+		"fb3ff0000000000000", // wide float
+		"c11a514b67b0",       // epoch date
+		"f90000",             // zero as float16
+		"80",                 // empty array
+	}
+	for _, tt := range tests_ExampleEncoded {
+		encoded = append(encoded, tt.encoded)
+	}
+
+	for _, e := range encoded {
+		t.Run(e, func(t *testing.T) {
+			data := decodeHex(t, e)
+			in := bytes.NewReader(data)
+
+			v, err := ReadValue(in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if in.Len() != 0 {
+				t.Fatalf("trailing data %d", in.Len())
+			}
+
+			out := &bytes.Buffer{}
+			n, err := WriteValue(out, v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != out.Len() {
+				t.Fatalf("wrote %d, counted %d", out.Len(), n)
+			}
+			if diff := cmp.Diff(data, out.Bytes()); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func Test_ReadValue(t *testing.T) {
+	tests := []struct {
+		encoded string
+		want    Value
+	}{
+		{encoded: "1819", want: Value{Kind: ValueUint, Arg: Arg8, Uint: 25}},
+		{encoded: "20", want: Value{Kind: ValueNegInt, Uint: 0}},
+		{encoded: "4161", want: Value{Kind: ValueBytes, Bytes: []byte("a")}},
+		{encoded: "40", want: Value{Kind: ValueBytes, Bytes: []byte{}}},
+		{
+			encoded: "7f61616162ff",
+			want: Value{Kind: ValueText, Arg: ArgIndefinite, Items: []Value{
+				{Kind: ValueText, Bytes: []byte("a")},
+				{Kind: ValueText, Bytes: []byte("b")},
+			}},
+		},
+		{
+			encoded: "9f01ff",
+			want:    Value{Kind: ValueArray, Arg: ArgIndefinite, Items: []Value{{Kind: ValueUint, Uint: 1}}},
+		},
+		{
+			encoded: "a10102",
+			want: Value{Kind: ValueMap, Pairs: []Pair{
+				{Key: Value{Kind: ValueUint, Uint: 1}, Value: Value{Kind: ValueUint, Uint: 2}},
+			}},
+		},
+		{
+			encoded: "c0f6",
+			want:    Value{Kind: ValueTag, Items: []Value{{Kind: ValueSimple, Uint: uint64(SimpleNull)}}},
+		},
+		{encoded: "f7", want: Value{Kind: ValueSimple, Uint: uint64(SimpleUndefined)}},
+		{encoded: "f8ff", want: Value{Kind: ValueSimple, Arg: Arg8, Uint: 255}},
+		{encoded: "f93c00", want: Value{Kind: ValueFloat, Arg: Arg16, Uint: 0x3c00}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			v, err := ReadValue(bytes.NewReader(decodeHex(t, tt.encoded)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, v); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func Test_ReadValue_Errors(t *testing.T) {
+	tests := []struct {
+		encoded string
+		want    error
+	}{
+		{encoded: "", want: io.EOF},
+		{encoded: "1c", want: ErrNotWellFormed},
+		{encoded: "1f", want: ErrNotWellFormed},
+		{encoded: "ff", want: ErrNotWellFormed},
+		{encoded: "dfff", want: ErrNotWellFormed},
+		{encoded: "5f01ff", want: ErrMismatchedChunk},
+		{encoded: "5f5fffff", want: ErrNestedIndefinite},
+		{encoded: "62c328", want: ErrInvalidUTF8},
+		{encoded: "7f61c3ff", want: ErrInvalidUTF8},
+		{encoded: "8201", want: io.ErrUnexpectedEOF},
+		{encoded: "a101", want: io.ErrUnexpectedEOF},
+		{encoded: "c6", want: io.ErrUnexpectedEOF},
+		{encoded: "5f", want: io.ErrUnexpectedEOF},
+		{encoded: "4201", want: io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			_, err := ReadValue(bytes.NewReader(decodeHex(t, tt.encoded)))
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func Test_ReadValue_ValidityNone(t *testing.T) {
+	v, err := DecodeOptions{Validity: ValidityNone}.ReadValue(bytes.NewReader(decodeHex(t, "62c328")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]byte{0xc3, 0x28}, v.Bytes); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func Test_WriteValue(t *testing.T) {
+	tests := []struct {
+		value Value
+		want  string
+	}{
+		{value: UintValue(0), want: "00"},
+		{value: UintValue(1000), want: "1903e8"},
+		{value: IntValue(-1000), want: "3903e7"},
+		{value: IntValue(math.MinInt64), want: "3b7fffffffffffffff"},
+		{value: BytesValue([]byte{1, 2}), want: "420102"},
+		{value: TextValue("a"), want: "6161"},
+		{value: ArrayValue(), want: "80"},
+		{value: ArrayValue(UintValue(1), TextValue("b")), want: "82016162"},
+		{value: MapValue(Pair{Key: TextValue("a"), Value: BoolValue(true)}), want: "a16161f5"},
+		{value: TagValue(1, UintValue(0)), want: "c100"},
+		{value: BoolValue(false), want: "f4"},
+		{value: NullValue(), want: "f6"},
+		{value: UndefinedValue(), want: "f7"},
+		{value: SimpleValue(16), want: "f0"},
+		{value: SimpleValue(255), want: "f8ff"},
+		{value: FloatValue(1), want: "f93c00"},
+		{value: FloatValue(100000), want: "fa47c35000"},
+		{value: FloatValue(1.1), want: "fb3ff199999999999a"},
+		{value: FloatValue(math.Inf(-1)), want: "f9fc00"},
+		{value: FloatValue(math.NaN()), want: "f97e00"},
+		{value: Value{Kind: ValueUint, Arg: Arg64, Uint: 1}, want: "1b0000000000000001"},
+		{value: Value{Kind: ValueMap, Arg: ArgIndefinite}, want: "bfff"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			n, err := WriteValue(out, tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != out.Len() {
+				t.Fatalf("wrote %d, counted %d", out.Len(), n)
+			}
+			if diff := cmp.Diff(decodeHex(t, tt.want), out.Bytes()); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func Test_WriteValue_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		value Value
+		want  error
+	}{
+		{name: "ArgTooNarrow", value: Value{Kind: ValueUint, Arg: Arg8, Uint: 256}, want: ErrOverflow},
+		{name: "ArgReserved", value: Value{Kind: ValueUint, Arg: 28}, want: ErrUnsupportedValue},
+		{name: "IndefiniteInt", value: Value{Kind: ValueUint, Arg: ArgIndefinite}, want: ErrUnsupportedValue},
+		{name: "FloatWidth", value: Value{Kind: ValueFloat}, want: ErrUnsupportedValue},
+		{name: "SimpleTooLarge", value: Value{Kind: ValueSimple, Uint: 256}, want: ErrOverflow},
+		{name: "TagContent", value: Value{Kind: ValueTag}, want: ErrInvalidTagContent},
+		{
+			name:  "ChunkKind",
+			value: Value{Kind: ValueText, Arg: ArgIndefinite, Items: []Value{BytesValue(nil)}},
+			want:  ErrMismatchedChunk,
+		},
+		{
+			name: "NestedChunk",
+			value: Value{Kind: ValueText, Arg: ArgIndefinite, Items: []Value{
+				{Kind: ValueText, Arg: ArgIndefinite},
+			}},
+			want: ErrMismatchedChunk,
+		},
+		{name: "Kind", value: Value{Kind: 99}, want: ErrUnsupportedValue},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := WriteValue(io.Discard, tt.value)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func Test_Equal(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		equal bool
+	}{
+		{a: "01", b: "1801", equal: true},
+		{a: "01", b: "1b0000000000000001", equal: true},
+		{a: "426162", b: "5f41614162ff", equal: true},
+		{a: "626162", b: "7f6161616260ff", equal: true},
+		{a: "820102", b: "9f0102ff", equal: true},
+		{a: "a201020304", b: "bf03040102ff", equal: true},
+		{a: "c100", b: "d80100", equal: true},
+		{a: "f93c00", b: "fb3ff0000000000000", equal: true},
+		{a: "f97e00", b: "fb7ff8000000000001", equal: true},
+		{a: "f4", b: "f4", equal: true},
+		{a: "01", b: "f93c00"},
+		{a: "01", b: "21"},
+		{a: "4161", b: "6161"},
+		{a: "820102", b: "820201"},
+		{a: "a10102", b: "a10103"},
+		{a: "c100", b: "c200"},
+		{a: "f6", b: "f7"},
+		{a: "f4", b: "00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"_"+tt.b, func(t *testing.T) {
+			a, err := ReadValue(bytes.NewReader(decodeHex(t, tt.a)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := ReadValue(bytes.NewReader(decodeHex(t, tt.b)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := Equal(a, b); got != tt.equal {
+				t.Fatalf("Equal = %v", got)
+			}
+			if got := Equal(b, a); got != tt.equal {
+				t.Fatalf("Equal reversed = %v", got)
+			}
+		})
+	}
+}
+
+func Test_Compare(t *testing.T) {
+	// in deterministic order
+	ordered := []Value{
+		UintValue(10),
+		UintValue(100),
+		IntValue(-1),
+		BytesValue([]byte("z")),
+		TextValue("z"),
+		TextValue("aa"),
+		ArrayValue(IntValue(-1)),
+		MapValue(),
+		TagValue(1, UintValue(0)),
+		BoolValue(false),
+		BoolValue(true),
+		NullValue(),
+		FloatValue(1),
+		FloatValue(1.1),
+	}
+
+	for i, a := range ordered {
+		for j, b := range ordered {
+			want := 0
+			switch {
+			case i < j:
+				want = -1
+			case i > j:
+				want = 1
+			}
+			if got := Compare(a, b); got != want {
+				t.Errorf("Compare(%d, %d) = %d, want %d", i, j, got, want)
+			}
+		}
+	}
+}
+
+func Test_Value_As(t *testing.T) {
+	tests := []struct {
+		encoded string
+		want    any
+	}{
+		{encoded: "1903e8", want: uint64(1000)},
+		{encoded: "3903e7", want: int64(-1000)},
+		{encoded: "5f41614162ff", want: []byte("ab")},
+		{encoded: "7f61616162ff", want: "ab"},
+		{encoded: "82016161", want: []any{uint64(1), "a"}},
+		{encoded: "a1616101", want: map[any]any{"a": uint64(1)}},
+		{encoded: "c11a514b67b0", want: uint64(1363896240)},
+		{encoded: "f5", want: true},
+		{encoded: "f6", want: nil},
+		{encoded: "f7", want: nil},
+		{encoded: "f0", want: uint8(16)},
+		{encoded: "f93e00", want: 1.5},
+		{encoded: "fa47c35000", want: 100000.0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			v, err := ReadValue(bytes.NewReader(decodeHex(t, tt.encoded)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := v.AsAny()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func Test_Value_As_Errors(t *testing.T) {
+	if _, err := TextValue("a").AsUint(); err != ErrUnsupportedMajorType {
+		t.Fatal(err)
+	}
+	if _, err := UintValue(math.MaxUint64).AsInt(); err != ErrOverflow {
+		t.Fatal(err)
+	}
+	if _, err := (Value{Kind: ValueNegInt, Uint: math.MaxUint64}).AsInt(); err != ErrOverflow {
+		t.Fatal(err)
+	}
+	if _, err := NullValue().AsBool(); err != ErrUnsupportedValue {
+		t.Fatal(err)
+	}
+	if _, err := UintValue(1).AsBool(); err != ErrUnsupportedMajorType {
+		t.Fatal(err)
+	}
+	if _, err := TextValue("a").AsBytes(); err != ErrUnsupportedMajorType {
+		t.Fatal(err)
+	}
+	if _, err := BytesValue(nil).AsText(); err != ErrUnsupportedMajorType {
+		t.Fatal(err)
+	}
+	if _, err := TextValue("a").AsFloat(); err != ErrUnsupportedMajorType {
+		t.Fatal(err)
+	}
+	if _, err := MapValue(Pair{Key: ArrayValue(), Value: NullValue()}).AsAny(); err != ErrUnsupportedValue {
+		t.Fatal(err)
+	}
+
+	if f, err := IntValue(-2).AsFloat(); err != nil || f != -2 {
+		t.Fatal(f, err)
+	}
+	if !NullValue().IsNull() || NullValue().IsUndefined() || !UndefinedValue().IsUndefined() {
+		t.Fatal("null / undefined")
+	}
+}
+
+func Test_ValueOf(t *testing.T) {
+	tests := []struct {
+		in   any
+		want string
+	}{
+		{in: nil, want: "f6"},
+		{in: true, want: "f5"},
+		{in: -1, want: "20"},
+		{in: int8(-2), want: "21"},
+		{in: uint16(500), want: "1901f4"},
+		{in: float32(1.5), want: "f93e00"},
+		{in: "a", want: "6161"},
+		{in: []byte{1}, want: "4101"},
+		{in: []any{1, "a"}, want: "82016161"},
+		{in: map[string]any{"b": 1, "a": 2, "aa": 3}, want: "a361610261620162616103"},
+		{in: map[any]any{10: nil, -1: nil}, want: "a20af620f6"},
+		{in: RawMessage{0x9f, 0xff}, want: "9fff"},
+		{in: UintValue(1), want: "01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			v, err := ValueOf(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out := &bytes.Buffer{}
+			if _, err := WriteValue(out, v); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(decodeHex(t, tt.want), out.Bytes()); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+
+	if _, err := ValueOf(struct{}{}); err != ErrUnsupportedValue {
+		t.Fatal(err)
+	}
+	if _, err := ValueOf([]any{struct{}{}}); err != ErrUnsupportedValue {
+		t.Fatal(err)
+	}
+	if _, err := ValueOf(RawMessage{0x01, 0x02}); err != ErrTrailingData {
+		t.Fatal(err)
+	}
+}
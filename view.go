@@ -0,0 +1,429 @@
+package cbor
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"slices"
+)
+
+// View is a read-only view of an encoded object, and of each item within it,
+// for random access without decoding. [NewView] scans the object once to index
+// where each item starts and ends; after that finding an array item is O(1),
+// and a map entry O(log n). Nothing is copied, and nothing is decoded until
+// one of the typed accessors is called.
+//
+// The zero View holds no item.
+type View struct {
+	ix *viewIndex
+	id uint32
+}
+
+type viewIndex struct {
+	data  []byte
+	nodes []viewNode
+
+	// items holds, for each array, the node of each item, and for each map
+	// the nodes of each key and value in turn followed by the entries in
+	// order of the deterministic encodings of their keys.
+	items []uint32
+
+	// keys holds the deterministic encodings of the map keys not encoded
+	// that way, by node.
+	keys map[uint32][]byte
+}
+
+type viewNode struct {
+	start, end uint32 // offsets of the item, including any tags
+	first      uint32 // index in items of the items or entries of an array or map
+	n          uint32 // number of items or entries of an array or map
+}
+
+// maxViewDepth limits the nesting of arrays, maps and tags indexed by a View,
+// as each level is scanned recursively.
+const maxViewDepth = 1024
+
+// NewView indexes the single object in [data], checking it is well formed.
+// Strings are not checked for valid UTF-8. Objects nested more than 1024
+// arrays, maps and tags deep fail with ErrTooLarge.
+func NewView(data []byte) (View, error) {
+	if len(data) == 0 {
+		return View{}, io.EOF
+	}
+	if len(data) > math.MaxUint32 {
+		return View{}, ErrTooLarge
+	}
+
+	ix := &viewIndex{data: data}
+	var stack []uint32
+	end, err := ix.scan(0, 0, &stack)
+	if err != nil {
+		return View{}, err
+	}
+	if end != len(data) {
+		return View{}, ErrTrailingData
+	}
+
+	return View{ix: ix}, nil
+}
+
+// scan indexes the item at [pos], and everything within it, and returns the
+// offset after it. [depth] is the number of enclosing containers and tags,
+// and [stack] holds their items.
+func (ix *viewIndex) scan(pos, depth int, stack *[]uint32) (int, error) {
+	if depth > maxViewDepth {
+		return 0, ErrTooLarge
+	}
+
+	id := len(ix.nodes)
+	ix.nodes = append(ix.nodes, viewNode{start: uint32(pos)})
+
+	majorType, arg, value, n, err := viewHeader(ix.data, pos)
+	if err != nil {
+		return 0, err
+	}
+	pos += n
+
+	if arg == ArgIndefinite && majorType != MajorTypeBstr && majorType != MajorTypeTstr &&
+		majorType != MajorTypeArray && majorType != MajorTypeMap {
+		return 0, ErrNotWellFormed
+	}
+
+	switch majorType {
+	case MajorTypeBstr,
+		MajorTypeTstr:
+		if arg == ArgIndefinite {
+			in := bytes.NewReader(ix.data[pos:])
+			if err = readOver(in, majorType, arg, value, false); err != nil {
+				return 0, unexpectedEOF(err)
+			}
+			pos = len(ix.data) - in.Len()
+		} else {
+			if value > uint64(len(ix.data)-pos) {
+				return 0, io.ErrUnexpectedEOF
+			}
+			pos += int(value)
+		}
+
+	case MajorTypeArray,
+		MajorTypeMap:
+		// each item is at least one byte
+		if arg != ArgIndefinite && value > uint64(len(ix.data)-pos) {
+			return 0, io.ErrUnexpectedEOF
+		}
+		count := value
+		if majorType == MajorTypeMap {
+			count *= 2
+		}
+
+		mark := len(*stack)
+		for i := uint64(0); arg == ArgIndefinite || i < count; i++ {
+			if arg == ArgIndefinite {
+				if pos >= len(ix.data) {
+					return 0, io.ErrUnexpectedEOF
+				}
+				if ix.data[pos] == valueBreak {
+					pos++
+					break
+				}
+			}
+
+			*stack = append(*stack, uint32(len(ix.nodes)))
+			if pos, err = ix.scan(pos, depth+1, stack); err != nil {
+				return 0, err
+			}
+		}
+
+		items := (*stack)[mark:]
+		node := &ix.nodes[id]
+		node.first = uint32(len(ix.items))
+		node.n = uint32(len(items))
+		ix.items = append(ix.items, items...)
+
+		if majorType == MajorTypeMap {
+			if len(items)%2 != 0 {
+				return 0, ErrNotWellFormed
+			}
+			node.n /= 2
+
+			order := make([]uint32, node.n)
+			for i := range order {
+				order[i] = uint32(i)
+
+				raw := ix.bytes(items[2*i])
+				if key := deterministicKey(raw); !bytes.Equal(key, raw) {
+					if ix.keys == nil {
+						ix.keys = make(map[uint32][]byte)
+					}
+					ix.keys[items[2*i]] = key
+				}
+			}
+			slices.SortStableFunc(order, func(a, b uint32) int {
+				return bytes.Compare(ix.key(items[2*a]), ix.key(items[2*b]))
+			})
+			ix.items = append(ix.items, order...)
+		}
+		*stack = (*stack)[:mark]
+
+	case MajorTypeTagged:
+		// the content is the next node
+		if pos, err = ix.scan(pos, depth+1, stack); err != nil {
+			return 0, err
+		}
+	}
+
+	ix.nodes[id].end = uint32(pos)
+	return pos, nil
+}
+
+// bytes returns the encoding of node [id].
+func (ix *viewIndex) bytes(id uint32) []byte {
+	node := ix.nodes[id]
+	return ix.data[node.start:node.end:node.end]
+}
+
+// key returns the deterministic encoding of the map key node [id].
+func (ix *viewIndex) key(id uint32) []byte {
+	if key, ok := ix.keys[id]; ok {
+		return key
+	}
+	return ix.bytes(id)
+}
+
+// viewHeader decodes the header at [pos] in [data], returning its length with
+// the major type, argument and value.
+func viewHeader(data []byte, pos int) (MajorType, Arg, uint64, int, error) {
+	if pos >= len(data) {
+		return 0, 0, 0, 0, io.ErrUnexpectedEOF
+	}
+
+	majorType, arg := decodePrefix(data[pos])
+	arg, l, value, err := decodeArg(arg)
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+
+	if l > 0 {
+		if int(l) > len(data)-pos-1 {
+			return 0, 0, 0, 0, io.ErrUnexpectedEOF
+		}
+		value = shiftBytesInto[uint64](data[pos+1 : pos+1+int(l)])
+	}
+
+	return majorType, arg, value, 1 + int(l), nil
+}
+
+// header returns the header of the item, which is the first tag if it is
+// tagged.
+func (v View) header() (MajorType, Arg, uint64, int) {
+	majorType, arg, value, n, _ := viewHeader(v.ix.data, int(v.ix.nodes[v.id].start))
+	return majorType, arg, value, n
+}
+
+// untagged returns the view of the item within any tags.
+func (v View) untagged() View {
+	for v.ix != nil && v.Type() == MajorTypeTagged {
+		v.id++
+	}
+	return v
+}
+
+// Raw returns the encoding of the item, sharing the underlying bytes.
+func (v View) Raw() RawMessage {
+	if v.ix == nil {
+		return nil
+	}
+	return RawMessage(v.ix.bytes(v.id))
+}
+
+// Reader returns a reader over the item, to pass to any of the Read
+// functions.
+func (v View) Reader() io.Reader {
+	return bytes.NewReader(v.Raw())
+}
+
+// Decode decodes the item into [value], as [Unmarshal].
+func (v View) Decode(value any) error {
+	return Unmarshal(v.Raw(), value)
+}
+
+// Type returns the major type of the item, MajorTypeTagged if it is tagged.
+func (v View) Type() MajorType {
+	if v.ix == nil {
+		return 0
+	}
+	majorType, _, _, _ := v.header()
+	return majorType
+}
+
+// Len returns the number of items in an array, or entries in a map, ignoring
+// any tags, and 0 for anything else.
+func (v View) Len() int {
+	v = v.untagged()
+	if v.ix == nil {
+		return 0
+	}
+	return int(v.ix.nodes[v.id].n)
+}
+
+// Index returns the [i]th item of an array. Tags on the array are ignored,
+// as [Lookup].
+func (v View) Index(i int) (View, error) {
+	v = v.untagged()
+	if v.Type() != MajorTypeArray {
+		return View{}, ErrUnsupportedMajorType
+	}
+
+	node := v.ix.nodes[v.id]
+	if i < 0 || i >= int(node.n) {
+		return View{}, ErrNotFound
+	}
+
+	return View{ix: v.ix, id: v.ix.items[int(node.first)+i]}, nil
+}
+
+// Entry returns the key and value of the [i]th entry of a map, in the order
+// they are encoded. Tags on the map are ignored, as [Lookup].
+func (v View) Entry(i int) (View, View, error) {
+	v = v.untagged()
+	if v.Type() != MajorTypeMap {
+		return View{}, View{}, ErrUnsupportedMajorType
+	}
+
+	node := v.ix.nodes[v.id]
+	if i < 0 || i >= int(node.n) {
+		return View{}, View{}, ErrNotFound
+	}
+
+	pair := v.ix.items[int(node.first)+2*i:]
+	return View{ix: v.ix, id: pair[0]}, View{ix: v.ix, id: pair[1]}, nil
+}
+
+// Get returns the value of the first map entry with [key], which may be
+// anything accepted by [Key], and is matched by value however it is encoded.
+func (v View) Get(key any) (View, error) {
+	return v.Lookup(Key(key))
+}
+
+// Lookup returns the item at [path] within the item, as [Lookup].
+func (v View) Lookup(path ...PathElem) (View, error) {
+	for _, elem := range path {
+		if elem.err != nil {
+			return View{}, elem.err
+		}
+
+		v = v.untagged()
+		switch v.Type() {
+		case MajorTypeArray:
+			if !elem.isIndex || elem.index > math.MaxInt {
+				return View{}, ErrNotFound
+			}
+			var err error
+			if v, err = v.Index(int(elem.index)); err != nil {
+				return View{}, err
+			}
+
+		case MajorTypeMap:
+			value, ok := v.find(elem)
+			if !ok {
+				return View{}, ErrNotFound
+			}
+			v = value
+
+		default:
+			return View{}, ErrUnsupportedMajorType
+		}
+	}
+
+	return v, nil
+}
+
+// find returns the value of the first entry of a map selected by [elem].
+func (v View) find(elem PathElem) (View, bool) {
+	node := v.ix.nodes[v.id]
+	pairs := v.ix.items[node.first : node.first+2*node.n]
+	order := v.ix.items[node.first+2*node.n : node.first+3*node.n]
+
+	if elem.raw {
+		// the order is by value, so match exact encodings one by one
+		for i := 0; i < len(pairs); i += 2 {
+			if elem.matches(v.ix.bytes(pairs[i])) {
+				return View{ix: v.ix, id: pairs[i+1]}, true
+			}
+		}
+		return View{}, false
+	}
+
+	found := -1
+	for _, key := range elem.keys {
+		i, ok := slices.BinarySearchFunc(order, key, func(entry uint32, key []byte) int {
+			return bytes.Compare(v.ix.key(pairs[2*entry]), key)
+		})
+		if ok && (found < 0 || int(order[i]) < found) {
+			found = int(order[i])
+		}
+	}
+	if found < 0 {
+		return View{}, false
+	}
+
+	return View{ix: v.ix, id: pairs[2*found+1]}, true
+}
+
+// Tag returns the number of the outermost tag on the item, and the view of
+// its content.
+func (v View) Tag() (uint64, View, error) {
+	if v.Type() != MajorTypeTagged {
+		return 0, View{}, ErrUnsupportedMajorType
+	}
+
+	_, _, value, _ := v.header()
+	return value, View{ix: v.ix, id: v.id + 1}, nil
+}
+
+// Uint returns the item as an unsigned integer, as [ReadUnsigned].
+func (v View) Uint() (uint64, error) {
+	return ReadUnsigned[uint64](v.Reader())
+}
+
+// Int returns the item as a signed integer, as [ReadSigned].
+func (v View) Int() (int64, error) {
+	return ReadSigned[int64](v.Reader())
+}
+
+// Float returns the item as a float, as [ReadFloat].
+func (v View) Float() (float64, error) {
+	return ReadFloat[float64](v.Reader())
+}
+
+// Bool returns the item as a bool, as [ReadBool].
+func (v View) Bool() (bool, error) {
+	return ReadBool(v.Reader())
+}
+
+// IsNull returns true if the item is null.
+func (v View) IsNull() bool {
+	raw := v.Raw()
+	return len(raw) == 1 && raw[0] == MajorTypeSimpleFloat|SimpleNull
+}
+
+// Bytes returns the content of a byte string. Unless the string is
+// indefinite length the content shares the underlying bytes.
+func (v View) Bytes() ([]byte, error) {
+	if v.Type() != MajorTypeBstr {
+		return nil, ErrUnsupportedMajorType
+	}
+
+	_, arg, value, n := v.header()
+	if arg == ArgIndefinite {
+		return ReadByteString(v.Reader())
+	}
+
+	start := int(v.ix.nodes[v.id].start) + n
+	return v.ix.data[start : start+int(value) : start+int(value)], nil
+}
+
+// Text returns the content of a text string, as [ReadString].
+func (v View) Text() (string, error) {
+	return ReadString(v.Reader())
+}
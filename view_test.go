@@ -0,0 +1,377 @@
+package cbor
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_NewView(t *testing.T) {
+	for _, tt := range tests_ExampleEncoded {
+		t.Run(tt.encoded, func(t *testing.T) {
+			data := decodeHex(t, tt.encoded)
+			v, err := NewView(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(data, []byte(v.Raw())); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func Test_View_Lookup(t *testing.T) {
+	// {"a": [1, 2, {"b": "x"}], "c": 1([5]), 1: h'6279', "e": null}
+	definite := "a46161830102a1616261786163c18105014262796165f6"
+	// {_ "a": [_ 1, 2, {_ "b": "x"}], "c": 1([_ 5]), 1: (_ h'62', h'79'), "e": null}
+	indefinite := "bf61619f0102bf61626178ffff6163c19f05ff015f41624179ff6165f6ff"
+
+	tests := []struct {
+		path           string
+		want           string
+		wantIndefinite string
+	}{
+		{path: "", want: definite, wantIndefinite: indefinite},
+		{path: "/a", want: "830102a161626178", wantIndefinite: "9f0102bf61626178ffff"},
+		{path: "/a/0", want: "01", wantIndefinite: "01"},
+		{path: "/a/2", want: "a161626178", wantIndefinite: "bf61626178ff"},
+		{path: "/a/2/b", want: "6178", wantIndefinite: "6178"},
+		{path: "/c", want: "c18105", wantIndefinite: "c19f05ff"},
+		{path: "/c/0", want: "05", wantIndefinite: "05"},
+		{path: "/1", want: "426279", wantIndefinite: "5f41624179ff"},
+		{path: "/e", want: "f6", wantIndefinite: "f6"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			path, err := ParsePath(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for encoded, want := range map[string]string{definite: tt.want, indefinite: tt.wantIndefinite} {
+				v, err := NewView(decodeHex(t, encoded))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				got, err := v.Lookup(path...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if diff := cmp.Diff(decodeHex(t, want), []byte(got.Raw())); diff != "" {
+					t.Fatal(diff)
+				}
+			}
+		})
+	}
+}
+
+func Test_View_Lookup_Errors(t *testing.T) {
+	v, err := NewView(decodeHex(t, "a46161830102a1616261786163c18105014262796165f6"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path []PathElem
+		err  error
+	}{
+		{name: "missing key", path: []PathElem{Key("x")}, err: ErrNotFound},
+		{name: "index out of range", path: []PathElem{Key("a"), Index(3)}, err: ErrNotFound},
+		{name: "key in array", path: []PathElem{Key("a"), Key("b")}, err: ErrNotFound},
+		{name: "not a container", path: []PathElem{Key("e"), Index(0)}, err: ErrUnsupportedMajorType},
+		{name: "invalid key", path: []PathElem{Key(struct{}{})}, err: ErrUnsupportedValue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := v.Lookup(tt.path...)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func Test_View_Accessors(t *testing.T) {
+	// [1, -2, 1.5, true, "text", h'0102', 1(2), null, {"b": 1, "a": 2}]
+	data := decodeHex(t, "890121f93e00f56474657874420102c102f6a2616201616102")
+	v, err := NewView(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Len() != 9 || v.Type() != MajorTypeArray {
+		t.Fatalf("got %d, %d", v.Len(), v.Type())
+	}
+
+	item := func(i int) View {
+		t.Helper()
+		item, err := v.Index(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return item
+	}
+
+	if u, err := item(0).Uint(); err != nil || u != 1 {
+		t.Fatal(u, err)
+	}
+	if i, err := item(1).Int(); err != nil || i != -2 {
+		t.Fatal(i, err)
+	}
+	if f, err := item(2).Float(); err != nil || f != 1.5 {
+		t.Fatal(f, err)
+	}
+	if b, err := item(3).Bool(); err != nil || !b {
+		t.Fatal(b, err)
+	}
+	if s, err := item(4).Text(); err != nil || s != "text" {
+		t.Fatal(s, err)
+	}
+
+	b, err := item(5).Bytes()
+	if err != nil || !bytes.Equal(b, []byte{1, 2}) {
+		t.Fatal(b, err)
+	}
+	if &b[0] != &data[13] {
+		t.Fatal("bytes copied")
+	}
+
+	tag, content, err := item(6).Tag()
+	if err != nil || tag != 1 {
+		t.Fatal(tag, err)
+	}
+	if u, err := content.Uint(); err != nil || u != 2 {
+		t.Fatal(u, err)
+	}
+
+	if !item(7).IsNull() || item(0).IsNull() {
+		t.Fatal("IsNull")
+	}
+
+	m := item(8)
+	if m.Len() != 2 {
+		t.Fatal(m.Len())
+	}
+	key, value, err := m.Entry(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := key.Text(); s != "a" {
+		t.Fatal(s)
+	}
+	if u, _ := value.Uint(); u != 2 {
+		t.Fatal(u)
+	}
+	if value, err = m.Get("b"); err != nil {
+		t.Fatal(err)
+	}
+	if u, _ := value.Uint(); u != 1 {
+		t.Fatal(u)
+	}
+
+	var decoded string
+	if err = key.Decode(&decoded); err != nil || decoded != "a" {
+		t.Fatal(decoded, err)
+	}
+}
+
+func Test_View_Accessors_Errors(t *testing.T) {
+	v, err := NewView(decodeHex(t, "8201a0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, _ := v.Index(1)
+
+	if _, err = v.Index(2); !errors.Is(err, ErrNotFound) {
+		t.Fatal(err)
+	}
+	if _, err = v.Index(-1); !errors.Is(err, ErrNotFound) {
+		t.Fatal(err)
+	}
+	if _, _, err = v.Entry(0); !errors.Is(err, ErrUnsupportedMajorType) {
+		t.Fatal(err)
+	}
+	if _, err = m.Index(0); !errors.Is(err, ErrUnsupportedMajorType) {
+		t.Fatal(err)
+	}
+	if _, _, err = m.Entry(0); !errors.Is(err, ErrNotFound) {
+		t.Fatal(err)
+	}
+	if _, _, err = v.Tag(); !errors.Is(err, ErrUnsupportedMajorType) {
+		t.Fatal(err)
+	}
+	if _, err = v.Bytes(); !errors.Is(err, ErrUnsupportedMajorType) {
+		t.Fatal(err)
+	}
+	if _, err = v.Uint(); !errors.Is(err, ErrUnsupportedMajorType) {
+		t.Fatal(err)
+	}
+
+	var zero View
+	if zero.Len() != 0 || zero.Raw() != nil {
+		t.Fatal("zero view")
+	}
+	if _, err = zero.Index(0); !errors.Is(err, ErrUnsupportedMajorType) {
+		t.Fatal(err)
+	}
+}
+
+func Test_View_DuplicateKeys(t *testing.T) {
+	// {"a": 1, 1: 2, "a": 3, 1: 4}
+	v, err := NewView(decodeHex(t, "a461610101026161030104"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []any{"a", 1} {
+		value, err := v.Get(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u, _ := value.Uint(); u > 2 {
+			t.Fatalf("%v: got %d", key, u)
+		}
+	}
+
+	// "/1" matches both the text key "1" and the integer key 1, the first wins
+	v, err = NewView(decodeHex(t, "a20102613103"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path, _ := ParsePath("/1")
+	value, err := v.Lookup(path...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u, _ := value.Uint(); u != 2 {
+		t.Fatal(u)
+	}
+}
+
+func Test_View_KeyEncoding(t *testing.T) {
+	// {"pw" chunked: 1, 2 with a one byte argument: 2, 1.5 as a double: 3}
+	v, err := NewView(decodeHex(t, "a37f627077ff01180202fb3ff800000000000003"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, key := range []any{"pw", 2, 1.5} {
+		value, err := v.Get(key)
+		if err != nil {
+			t.Fatalf("%v: %v", key, err)
+		}
+		if u, _ := value.Uint(); u != uint64(i+1) {
+			t.Fatalf("%v: got %d", key, u)
+		}
+	}
+
+	value, err := v.Lookup(RawKey(decodeHex(t, "1802")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u, _ := value.Uint(); u != 2 {
+		t.Fatal(u)
+	}
+	if _, err = v.Lookup(RawKey(decodeHex(t, "02"))); !errors.Is(err, ErrNotFound) {
+		t.Fatal(err)
+	}
+}
+
+func Test_NewView_Errors(t *testing.T) {
+	tests := []struct {
+		encoded string
+		err     error
+	}{
+		{encoded: "", err: io.EOF},
+		{encoded: "8201", err: io.ErrUnexpectedEOF},
+		{encoded: "19", err: io.ErrUnexpectedEOF},
+		{encoded: "4301", err: io.ErrUnexpectedEOF},
+		{encoded: "5f4101", err: io.ErrUnexpectedEOF},
+		{encoded: "9f01", err: io.ErrUnexpectedEOF},
+		{encoded: "9bffffffffffffffff00", err: io.ErrUnexpectedEOF},
+		{encoded: "0000", err: ErrTrailingData},
+		{encoded: "ff", err: ErrNotWellFormed},
+		{encoded: "1f", err: ErrNotWellFormed},
+		{encoded: "1c", err: ErrNotWellFormed},
+		{encoded: "bf01ff", err: ErrNotWellFormed},
+		{encoded: "5f6161ff", err: ErrMismatchedChunk},
+	}
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			_, err := NewView(decodeHex(t, tt.encoded))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func Test_NewView_Depth(t *testing.T) {
+	nested := func(prefix byte, n int) []byte {
+		return append(bytes.Repeat([]byte{prefix}, n), 0x01)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{name: "arrays", data: nested(0x81, maxViewDepth)},
+		{name: "tags", data: nested(0xc1, maxViewDepth)},
+		{name: "arrays too deep", data: nested(0x81, maxViewDepth+1), err: ErrTooLarge},
+		{name: "tags too deep", data: nested(0xc1, maxViewDepth+1), err: ErrTooLarge},
+		{name: "large", data: nested(0x81, 20<<20), err: ErrTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewView(tt.data); err != tt.err {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func Benchmark_View_Get(b *testing.B) {
+	pairs := make([]Pair, 10000)
+	for i := range pairs {
+		pairs[i] = Pair{Key: UintValue(uint64(i)), Value: ArrayValue(TextValue("value"), UintValue(uint64(i)))}
+	}
+	out := bytes.NewBuffer(nil)
+	if _, err := WriteValue(out, MapValue(pairs...)); err != nil {
+		b.Fatal(err)
+	}
+	data := out.Bytes()
+
+	b.Run("NewView", func(b *testing.B) {
+		for range b.N {
+			if _, err := NewView(data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	v, err := NewView(data)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("View", func(b *testing.B) {
+		for i := range b.N {
+			if _, err := v.Get(i % len(pairs)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("Lookup", func(b *testing.B) {
+		for i := range b.N {
+			if _, err := LookupRaw(bytes.NewReader(data), Key(i%len(pairs))); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
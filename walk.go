@@ -0,0 +1,262 @@
+package cbor
+
+import (
+	"errors"
+	"io"
+)
+
+// SkipItem is returned by the start callbacks of a [Visitor] to skip over the
+// contents of the item. The matching end callback is not called. Returned
+// from any other callback it is ignored.
+var SkipItem = errors.New("cbor: skip item")
+
+// Visitor receives callbacks from [Walk] for each item, and for the start and
+// end of each string, array, map, and tag. Negative integers are -1 - value.
+//
+// Embed [BaseVisitor] to only implement some of the callbacks.
+type Visitor interface {
+	Unsigned(value uint64) error
+	Negative(value uint64) error
+	Simple(value uint8) error
+	Float(value float64) error
+
+	StringStart(majorType MajorType, indefinite bool, length uint64) error
+	StringChunk(majorType MajorType, chunk []byte) error
+	StringEnd(majorType MajorType) error
+
+	ArrayStart(indefinite bool, length uint64) error
+	ArrayEnd() error
+
+	MapStart(indefinite bool, length uint64) error
+	MapEnd() error
+
+	TagStart(tag uint64) error
+	TagEnd(tag uint64) error
+}
+
+// BaseVisitor implements every [Visitor] callback by doing nothing.
+type BaseVisitor struct{}
+
+func (BaseVisitor) Unsigned(uint64) error                     { return nil }
+func (BaseVisitor) Negative(uint64) error                     { return nil }
+func (BaseVisitor) Simple(uint8) error                        { return nil }
+func (BaseVisitor) Float(float64) error                       { return nil }
+func (BaseVisitor) StringStart(MajorType, bool, uint64) error { return nil }
+func (BaseVisitor) StringChunk(MajorType, []byte) error       { return nil }
+func (BaseVisitor) StringEnd(MajorType) error                 { return nil }
+func (BaseVisitor) ArrayStart(bool, uint64) error             { return nil }
+func (BaseVisitor) ArrayEnd() error                           { return nil }
+func (BaseVisitor) MapStart(bool, uint64) error               { return nil }
+func (BaseVisitor) MapEnd() error                             { return nil }
+func (BaseVisitor) TagStart(uint64) error                     { return nil }
+func (BaseVisitor) TagEnd(uint64) error                       { return nil }
+
+const lenWalkBuffer = 512
+
+type walker struct {
+	v      Visitor
+	buffer [lenWalkBuffer]byte
+}
+
+// Walk reads the next object from [in], calling [v] for it and everything
+// nested within it. String contents are passed to [v] in chunks, so an object
+// is never held in memory.
+func Walk(in io.Reader, v Visitor) error {
+	w := walker{v: v}
+	return w.walk(in)
+}
+
+func (w *walker) walk(in io.Reader) error {
+	majorType, arg, value, err := readMajorType(in)
+	if err != nil {
+		return err
+	}
+
+	return w.walkItem(in, majorType, arg, value)
+}
+
+func (w *walker) walkItem(in io.Reader, majorType MajorType, arg Arg, value uint64) error {
+	switch majorType {
+	case MajorTypeUInt:
+		return ignoreSkip(w.v.Unsigned(value))
+
+	case MajorTypeNInt:
+		return ignoreSkip(w.v.Negative(value))
+
+	case MajorTypeBstr, MajorTypeTstr:
+		indefinite := arg == ArgIndefinite
+
+		err := w.v.StringStart(majorType, indefinite, value)
+		if err == SkipItem {
+			return readOver(in, majorType, arg, value, false)
+		}
+		if err != nil {
+			return err
+		}
+
+		if indefinite {
+			for {
+				chunkMajorType, arg, value, err := readMajorType(in)
+				if err != nil {
+					return err
+				}
+
+				if chunkMajorType == MajorTypeSimpleFloat && arg == SimpleBreak {
+					break
+				}
+
+				if chunkMajorType != majorType {
+					return ErrMismatchedChunk
+				}
+
+				if arg == ArgIndefinite {
+					return ErrNestedIndefinite
+				}
+
+				if err = w.walkChunk(in, majorType, value); err != nil {
+					return err
+				}
+			}
+		} else if err = w.walkChunk(in, majorType, value); err != nil {
+			return err
+		}
+
+		return ignoreSkip(w.v.StringEnd(majorType))
+
+	case MajorTypeArray:
+		indefinite := arg == ArgIndefinite
+
+		err := w.v.ArrayStart(indefinite, value)
+		if err == SkipItem {
+			return readOver(in, majorType, arg, value, false)
+		}
+		if err != nil {
+			return err
+		}
+
+		if indefinite {
+			for {
+				majorType, arg, value, err := readMajorType(in)
+				if err != nil {
+					return err
+				}
+
+				if majorType == MajorTypeSimpleFloat && arg == SimpleBreak {
+					break
+				}
+
+				if err = w.walkItem(in, majorType, arg, value); err != nil {
+					return err
+				}
+			}
+		} else {
+			for i := uint64(0); i < value; i++ {
+				if err = w.walk(in); err != nil {
+					return err
+				}
+			}
+		}
+
+		return ignoreSkip(w.v.ArrayEnd())
+
+	case MajorTypeMap:
+		indefinite := arg == ArgIndefinite
+
+		err := w.v.MapStart(indefinite, value)
+		if err == SkipItem {
+			return readOver(in, majorType, arg, value, false)
+		}
+		if err != nil {
+			return err
+		}
+
+		if indefinite {
+			for {
+				majorType, arg, value, err := readMajorType(in)
+				if err != nil {
+					return err
+				}
+
+				if majorType == MajorTypeSimpleFloat && arg == SimpleBreak {
+					break
+				}
+
+				if err = w.walkItem(in, majorType, arg, value); err != nil {
+					return err
+				}
+				if err = w.walk(in); err != nil {
+					return err
+				}
+			}
+		} else {
+			for i := uint64(0); i < value; i++ {
+				if err = w.walk(in); err != nil {
+					return err
+				}
+				if err = w.walk(in); err != nil {
+					return err
+				}
+			}
+		}
+
+		return ignoreSkip(w.v.MapEnd())
+
+	case MajorTypeTagged:
+		err := w.v.TagStart(value)
+		if err == SkipItem {
+			return ReadOver(in)
+		}
+		if err != nil {
+			return err
+		}
+
+		if err = w.walk(in); err != nil {
+			return err
+		}
+
+		return ignoreSkip(w.v.TagEnd(value))
+
+	default: // MajorTypeSimpleFloat
+		switch arg {
+		case SimpleFloat16, SimpleFloat32, SimpleFloat64:
+			f, err := readFloat[float64](majorType, arg, value)
+			if err != nil {
+				return err
+			}
+			return ignoreSkip(w.v.Float(f))
+		case SimpleBreak:
+			return ErrNotWellFormed
+		default:
+			return ignoreSkip(w.v.Simple(uint8(value)))
+		}
+	}
+}
+
+// walkChunk passes [length] bytes from [in] to the visitor.
+func (w *walker) walkChunk(in io.Reader, majorType MajorType, length uint64) error {
+	for length > 0 {
+		b := w.buffer[:min(lenWalkBuffer, length)]
+		n, err := io.ReadFull(in, b)
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+
+		if err = ignoreSkip(w.v.StringChunk(majorType, b)); err != nil {
+			return err
+		}
+
+		length -= uint64(n)
+	}
+
+	return nil
+}
+
+func ignoreSkip(err error) error {
+	if err == SkipItem {
+		return nil
+	}
+	return err
+}
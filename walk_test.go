@@ -0,0 +1,218 @@
+package cbor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type recordingVisitor struct {
+	events []string
+	skip   string
+}
+
+func (v *recordingVisitor) record(event string) error {
+	v.events = append(v.events, event)
+	if event == v.skip {
+		return SkipItem
+	}
+	return nil
+}
+
+func (v *recordingVisitor) Unsigned(value uint64) error {
+	return v.record(fmt.Sprintf("%d", value))
+}
+
+func (v *recordingVisitor) Negative(value uint64) error {
+	return v.record(fmt.Sprintf("-1-%d", value))
+}
+
+func (v *recordingVisitor) Simple(value uint8) error {
+	return v.record(fmt.Sprintf("simple(%d)", value))
+}
+
+func (v *recordingVisitor) Float(value float64) error {
+	return v.record(fmt.Sprintf("%g", value))
+}
+
+func (v *recordingVisitor) StringStart(majorType MajorType, indefinite bool, length uint64) error {
+	return v.record(fmt.Sprintf("string(%d) %t %d", majorType>>5, indefinite, length))
+}
+
+func (v *recordingVisitor) StringChunk(majorType MajorType, chunk []byte) error {
+	return v.record(hex.EncodeToString(chunk))
+}
+
+func (v *recordingVisitor) StringEnd(majorType MajorType) error {
+	return v.record(fmt.Sprintf("end string(%d)", majorType>>5))
+}
+
+func (v *recordingVisitor) ArrayStart(indefinite bool, length uint64) error {
+	return v.record(fmt.Sprintf("array %t %d", indefinite, length))
+}
+
+func (v *recordingVisitor) ArrayEnd() error {
+	return v.record("end array")
+}
+
+func (v *recordingVisitor) MapStart(indefinite bool, length uint64) error {
+	return v.record(fmt.Sprintf("map %t %d", indefinite, length))
+}
+
+func (v *recordingVisitor) MapEnd() error {
+	return v.record("end map")
+}
+
+func (v *recordingVisitor) TagStart(tag uint64) error {
+	return v.record(fmt.Sprintf("tag %d", tag))
+}
+
+func (v *recordingVisitor) TagEnd(tag uint64) error {
+	return v.record(fmt.Sprintf("end tag %d", tag))
+}
+
+func Test_Walk(t *testing.T) {
+	for _, tt := range tests_ExampleEncoded {
+		t.Run(tt.encoded, func(t *testing.T) {
+			in := bytes.NewReader(decodeHex(t, tt.encoded))
+
+			if err := Walk(in, &recordingVisitor{}); err != nil {
+				t.Fatal(err)
+			}
+
+			if in.Len() != 0 {
+				t.Fatal("trailing data")
+			}
+		})
+	}
+}
+
+func Test_Walk_Events(t *testing.T) {
+	tests := []struct {
+		encoded string
+		skip    string
+		want    []string
+	}{
+		{
+			encoded: "826161a161626163",
+			want: []string{
+				"array false 2",
+				"string(3) false 1", "61", "end string(3)",
+				"map false 1",
+				"string(3) false 1", "62", "end string(3)",
+				"string(3) false 1", "63", "end string(3)",
+				"end map",
+				"end array",
+			},
+		},
+		{
+			encoded: "9f20c1f97e00f6ff",
+			want: []string{
+				"array true 0",
+				"-1-0",
+				"tag 1", "NaN", "end tag 1",
+				"simple(22)",
+				"end array",
+			},
+		},
+		{
+			encoded: "5f42010243030405ff",
+			want: []string{
+				"string(2) true 0", "0102", "030405", "end string(2)",
+			},
+		},
+		{
+			encoded: "8283010203bf6161f5ff04",
+			skip:    "array false 3",
+			want: []string{
+				"array false 2",
+				"array false 3",
+				"map true 0",
+				"string(3) false 1", "61", "end string(3)",
+				"simple(21)",
+				"end map",
+				"end array",
+			},
+		},
+		{
+			encoded: "82c1c2410001",
+			skip:    "tag 1",
+			want: []string{
+				"array false 2",
+				"tag 1",
+				"1",
+				"end array",
+			},
+		},
+		{
+			encoded: "827f6161ff01",
+			skip:    "string(3) true 0",
+			want: []string{
+				"array false 2",
+				"string(3) true 0",
+				"1",
+				"end array",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			in := bytes.NewReader(decodeHex(t, tt.encoded))
+			v := &recordingVisitor{skip: tt.skip}
+
+			if err := Walk(in, v); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, v.events); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+type stringSizeVisitor struct {
+	BaseVisitor
+	total uint64
+}
+
+func (v *stringSizeVisitor) StringChunk(majorType MajorType, chunk []byte) error {
+	v.total += uint64(len(chunk))
+	return nil
+}
+
+func Test_Walk_BaseVisitor(t *testing.T) {
+	v := &stringSizeVisitor{}
+
+	err := Walk(bytes.NewReader(decodeHex(t, "a56161614161626142616361436164614461656145")), v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.total != 10 {
+		t.Fatalf("want 10, got %d", v.total)
+	}
+}
+
+func Test_Walk_NotWellFormed(t *testing.T) {
+	tests := []struct {
+		encoded string
+		wantErr error
+	}{
+		{encoded: "ff", wantErr: ErrNotWellFormed},
+		{encoded: "5f6161ff", wantErr: ErrMismatchedChunk},
+		{encoded: "5f5fffff", wantErr: ErrNestedIndefinite},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			err := Walk(bytes.NewReader(decodeHex(t, tt.encoded)), BaseVisitor{})
+			if err != tt.wantErr {
+				t.Fatalf("want %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
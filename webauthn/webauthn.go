@@ -0,0 +1,236 @@
+// Package webauthn parses the attestation objects and authenticator data of
+// Web Authentication, as described in the W3C Web Authentication
+// specification, with limits on the size of each part.
+package webauthn
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	cbor "github.com/alex-richards/tiny-cbor"
+	"github.com/alex-richards/tiny-cbor/cose"
+)
+
+var (
+	ErrInvalidAttestationObject = errors.New("webauthn: invalid attestation object")
+	ErrInvalidAuthenticatorData = errors.New("webauthn: invalid authenticator data")
+)
+
+// Limits on the size of the parts of an attestation object.
+const (
+	MaxAttestationObjectLength = 64 * 1024
+	MaxFormatLength            = 32
+	MaxCredentialIDLength      = 1023
+)
+
+// Flags are the flags of authenticator data.
+type Flags byte
+
+const (
+	FlagUserPresent            Flags = 0x01
+	FlagUserVerified           Flags = 0x04
+	FlagBackupEligible         Flags = 0x08
+	FlagBackupState            Flags = 0x10
+	FlagAttestedCredentialData Flags = 0x40
+	FlagExtensionData          Flags = 0x80
+)
+
+// AttestationObject is the attestation returned when a credential is created.
+type AttestationObject struct {
+	// Format is the attestation statement format, such as "none" or "packed".
+	Format string
+
+	// AttStmt is the attestation statement map, whose contents depend on
+	// Format.
+	AttStmt cbor.RawMessage
+
+	AuthData AuthenticatorData
+
+	// RawAuthData is the authenticator data as signed by the attestation
+	// statement.
+	RawAuthData []byte
+}
+
+// AuthenticatorData is the data returned by an authenticator with each
+// attestation or assertion.
+type AuthenticatorData struct {
+	RPIDHash  [32]byte
+	Flags     Flags
+	SignCount uint32
+
+	// AttestedCredential is set if FlagAttestedCredentialData is.
+	AttestedCredential *AttestedCredentialData
+
+	// Extensions is the extension outputs map, set if FlagExtensionData is.
+	Extensions cbor.RawMessage
+}
+
+// AttestedCredentialData describes a newly created credential.
+type AttestedCredentialData struct {
+	AAGUID       [16]byte
+	CredentialID []byte
+
+	// PublicKey is the credential public key, or nil if its key type is not
+	// supported by the cose package, in which case only RawPublicKey is set.
+	PublicKey    *cose.Key
+	RawPublicKey cbor.RawMessage
+}
+
+// strict rejects malformed items, invalid UTF-8 and invalid tag contents while
+// items are copied as RawMessages.
+var strict = cbor.DecodeOptions{Validity: cbor.ValidityStrict}
+
+// ParseAttestationObject parses [data], which must hold exactly one
+// attestation object of at most MaxAttestationObjectLength bytes.
+func ParseAttestationObject(data []byte) (*AttestationObject, error) {
+	if len(data) > MaxAttestationObjectLength {
+		return nil, ErrInvalidAttestationObject
+	}
+
+	var a AttestationObject
+	var seen [3]bool
+	in := bytes.NewReader(data)
+	err := cbor.ReadMap(
+		in,
+		func(bool, uint64) error { return nil },
+		func(in io.Reader) error {
+			key, err := cbor.ReadStringMax(in, MaxFormatLength)
+			if err != nil {
+				return err
+			}
+
+			var i int
+			switch key {
+			case "fmt":
+				i = 0
+				a.Format, err = cbor.ReadStringMax(in, MaxFormatLength)
+			case "attStmt":
+				i = 1
+				a.AttStmt, err = readMap(in)
+			case "authData":
+				i = 2
+				a.RawAuthData, err = cbor.ReadByteString(in)
+			default:
+				return ErrInvalidAttestationObject
+			}
+
+			if seen[i] {
+				return ErrInvalidAttestationObject
+			}
+			seen[i] = true
+			return err
+		},
+	)
+	if err != nil {
+		if err != ErrInvalidAuthenticatorData {
+			err = ErrInvalidAttestationObject
+		}
+		return nil, err
+	}
+
+	if in.Len() != 0 || !seen[0] || !seen[1] || !seen[2] {
+		return nil, ErrInvalidAttestationObject
+	}
+
+	authData, err := ParseAuthenticatorData(a.RawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	a.AuthData = *authData
+
+	return &a, nil
+}
+
+// ParseAuthenticatorData parses [data], which must hold exactly the
+// authenticator data.
+func ParseAuthenticatorData(data []byte) (*AuthenticatorData, error) {
+	var d AuthenticatorData
+	if len(data) < 37 {
+		return nil, ErrInvalidAuthenticatorData
+	}
+
+	copy(d.RPIDHash[:], data[:32])
+	d.Flags = Flags(data[32])
+	d.SignCount = binary.BigEndian.Uint32(data[33:37])
+
+	in := bytes.NewReader(data[37:])
+
+	if d.Flags&FlagAttestedCredentialData != 0 {
+		c, err := readAttestedCredentialData(in)
+		if err != nil {
+			return nil, ErrInvalidAuthenticatorData
+		}
+		d.AttestedCredential = c
+	}
+
+	if d.Flags&FlagExtensionData != 0 {
+		extensions, err := readMap(in)
+		if err != nil {
+			return nil, ErrInvalidAuthenticatorData
+		}
+		d.Extensions = extensions
+	}
+
+	if in.Len() != 0 {
+		return nil, ErrInvalidAuthenticatorData
+	}
+
+	return &d, nil
+}
+
+func readAttestedCredentialData(in *bytes.Reader) (*AttestedCredentialData, error) {
+	var c AttestedCredentialData
+
+	var header [18]byte
+	if _, err := io.ReadFull(in, header[:]); err != nil {
+		return nil, err
+	}
+	copy(c.AAGUID[:], header[:16])
+
+	length := binary.BigEndian.Uint16(header[16:])
+	if length > MaxCredentialIDLength || int(length) > in.Len() {
+		return nil, ErrInvalidAuthenticatorData
+	}
+	c.CredentialID = make([]byte, length)
+	if _, err := io.ReadFull(in, c.CredentialID); err != nil {
+		return nil, err
+	}
+
+	raw, err := readMap(in)
+	if err != nil {
+		return nil, err
+	}
+	c.RawPublicKey = raw
+
+	key, err := cose.ReadKey(raw.Reader())
+	switch err {
+	case nil:
+		c.PublicKey = key
+	case cose.ErrUnsupportedKey:
+	default:
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// readMap reads a well formed map from [in], without decoding it.
+func readMap(in io.Reader) (cbor.RawMessage, error) {
+	d := cbor.NewDecoder(in)
+	majorType, _, _, err := d.PeekType()
+	if err != nil {
+		return nil, err
+	}
+	if majorType != cbor.MajorTypeMap {
+		return nil, cbor.ErrUnsupportedMajorType
+	}
+
+	b := bytes.NewBuffer(nil)
+	if err = strict.ReadRaw(d, b); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
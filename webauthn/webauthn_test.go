@@ -0,0 +1,317 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	cbor "github.com/alex-richards/tiny-cbor"
+	"github.com/alex-richards/tiny-cbor/cose"
+	"github.com/google/go-cmp/cmp"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+func Test_ParseAttestationObject(t *testing.T) {
+	rpIDHash := sha256.Sum256([]byte("example.com"))
+	credentialID := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+	t.Run("none-es256", func(t *testing.T) {
+		a, err := ParseAttestationObject(readFixture(t, "none-es256.cbor"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if a.Format != "none" || !bytes.Equal(a.AttStmt, []byte{0xa0}) {
+			t.Fatalf("unexpected attestation %q %x", a.Format, a.AttStmt)
+		}
+
+		d := a.AuthData
+		if d.RPIDHash != rpIDHash || d.Flags != FlagUserPresent|FlagUserVerified|FlagAttestedCredentialData || d.SignCount != 0 {
+			t.Fatalf("unexpected authenticator data %+v", d)
+		}
+		if d.Extensions != nil {
+			t.Fatalf("unexpected extensions %x", d.Extensions)
+		}
+
+		c := d.AttestedCredential
+		if c == nil || c.AAGUID != [16]byte{} || !bytes.Equal(c.CredentialID, credentialID) {
+			t.Fatalf("unexpected credential %+v", c)
+		}
+		if c.PublicKey.Type != cose.KeyTypeEC2 || c.PublicKey.Algorithm != cose.AlgES256 {
+			t.Fatalf("unexpected key %+v", c.PublicKey)
+		}
+		pub, err := c.PublicKey.PublicKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := pub.(*ecdsa.PublicKey); !ok {
+			t.Fatalf("unexpected key %T", pub)
+		}
+	})
+
+	t.Run("packed-eddsa", func(t *testing.T) {
+		a, err := ParseAttestationObject(readFixture(t, "packed-eddsa.cbor"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if a.Format != "packed" {
+			t.Fatalf("unexpected format %q", a.Format)
+		}
+		var alg int64
+		in, err := cbor.Lookup(a.AttStmt.Reader(), cbor.Key("alg"))
+		if err == nil {
+			alg, err = cbor.ReadSigned[int64](in)
+		}
+		if err != nil || cose.Algorithm(alg) != cose.AlgEdDSA {
+			t.Fatalf("unexpected alg %d, %v", alg, err)
+		}
+
+		d := a.AuthData
+		if d.Flags&FlagExtensionData == 0 || d.SignCount != 7 {
+			t.Fatalf("unexpected authenticator data %+v", d)
+		}
+
+		var credProtect uint64
+		in, err = cbor.Lookup(d.Extensions.Reader(), cbor.Key("credProtect"))
+		if err == nil {
+			credProtect, err = cbor.ReadUnsigned[uint64](in)
+		}
+		if err != nil || credProtect != 2 {
+			t.Fatalf("unexpected credProtect %d, %v", credProtect, err)
+		}
+
+		c := d.AttestedCredential
+		if c.AAGUID == [16]byte{} {
+			t.Fatal("missing AAGUID")
+		}
+		pub, err := c.PublicKey.PublicKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := pub.(ed25519.PublicKey); !ok {
+			t.Fatalf("unexpected key %T", pub)
+		}
+	})
+
+	t.Run("none-rs256", func(t *testing.T) {
+		a, err := ParseAttestationObject(readFixture(t, "none-rs256.cbor"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c := a.AuthData.AttestedCredential
+		if c.PublicKey != nil || len(c.RawPublicKey) == 0 {
+			t.Fatalf("unexpected credential %+v", c)
+		}
+	})
+}
+
+func Test_ParseAttestationObject_Errors(t *testing.T) {
+	valid := readFixture(t, "none-es256.cbor")
+
+	authData := func(a []byte) []byte {
+		return append([]byte{0xa3, 0x63, 'f', 'm', 't', 0x64, 'n', 'o', 'n', 'e', 0x67, 'a', 't', 't', 'S', 't', 'm', 't', 0xa0,
+			0x68, 'a', 'u', 't', 'h', 'D', 'a', 't', 'a', 0x58, byte(len(a))}, a...)
+	}
+	header := make([]byte, 37)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{name: "trailing", data: append(bytes.Clone(valid), 0x00), wantErr: ErrInvalidAttestationObject},
+		{name: "truncated", data: valid[:len(valid)-1], wantErr: ErrInvalidAttestationObject},
+		{name: "too large", data: make([]byte, MaxAttestationObjectLength+1), wantErr: ErrInvalidAttestationObject},
+		{name: "missing authData", data: []byte{0xa1, 0x63, 'f', 'm', 't', 0x64, 'n', 'o', 'n', 'e'}, wantErr: ErrInvalidAttestationObject},
+		{name: "duplicate fmt", data: []byte{0xa2, 0x63, 'f', 'm', 't', 0x64, 'n', 'o', 'n', 'e', 0x63, 'f', 'm', 't', 0x64, 'n', 'o', 'n', 'e'}, wantErr: ErrInvalidAttestationObject},
+		{name: "unknown key", data: []byte{0xa1, 0x63, 'f', 'o', 'o', 0x00}, wantErr: ErrInvalidAttestationObject},
+		{name: "attStmt not a map", data: []byte{0xa3, 0x63, 'f', 'm', 't', 0x64, 'n', 'o', 'n', 'e', 0x67, 'a', 't', 't', 'S', 't', 'm', 't', 0x80,
+			0x68, 'a', 'u', 't', 'h', 'D', 'a', 't', 'a', 0x40}, wantErr: ErrInvalidAttestationObject},
+		{name: "short authData", data: authData(header[:36]), wantErr: ErrInvalidAuthenticatorData},
+		{name: "authData", data: authData(header)},
+		{name: "authData trailing", data: authData(append(bytes.Clone(header), 0)), wantErr: ErrInvalidAuthenticatorData},
+		{name: "missing credential", data: authData(withFlags(header, FlagAttestedCredentialData)), wantErr: ErrInvalidAuthenticatorData},
+		{name: "missing extensions", data: authData(withFlags(header, FlagExtensionData)), wantErr: ErrInvalidAuthenticatorData},
+		{name: "extensions", data: authData(append(withFlags(header, FlagExtensionData), 0xa0))},
+		{name: "extensions not a map", data: authData(append(withFlags(header, FlagExtensionData), 0x80)), wantErr: ErrInvalidAuthenticatorData},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseAttestationObject(tt.data)
+			if err != tt.wantErr {
+				t.Fatalf("want %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func Test_ParseAuthenticatorData_CredentialID(t *testing.T) {
+	data := withFlags(make([]byte, 37), FlagAttestedCredentialData)
+	data = append(data, make([]byte, 16)...)
+	data = binary.BigEndian.AppendUint16(data, MaxCredentialIDLength+1)
+	data = append(data, make([]byte, MaxCredentialIDLength+1)...)
+	data = append(data, 0xa0)
+
+	if _, err := ParseAuthenticatorData(data); err != ErrInvalidAuthenticatorData {
+		t.Fatalf("want %v, got %v", ErrInvalidAuthenticatorData, err)
+	}
+}
+
+func Test_ParseAuthenticatorData(t *testing.T) {
+	a, err := ParseAttestationObject(readFixture(t, "packed-eddsa.cbor"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := ParseAuthenticatorData(a.RawAuthData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&a.AuthData, d); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func withFlags(header []byte, flags Flags) []byte {
+	header = bytes.Clone(header)
+	header[32] = byte(flags)
+	return header
+}
+
+func Test_ParseAttestationObject_Recorded(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		rpID   string
+	}{
+		{name: "packed-self", format: "packed", rpID: "localhost"},
+		{name: "packed-x5c", format: "packed", rpID: "webauthn.firstyear.id.au"},
+		{name: "fido-u2f", format: "fido-u2f", rpID: "webauthn.io"},
+		{name: "none", format: "none", rpID: "webauthn.io"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := ParseAttestationObject(readFixture(t, tt.name+".cbor"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			clientDataHash := sha256.Sum256(readFixture(t, tt.name+".json"))
+
+			if a.Format != tt.format {
+				t.Fatalf("unexpected format %q", a.Format)
+			}
+			if a.AuthData.RPIDHash != sha256.Sum256([]byte(tt.rpID)) {
+				t.Fatalf("unexpected RP ID hash %x", a.AuthData.RPIDHash)
+			}
+			c := a.AuthData.AttestedCredential
+			if c == nil || c.PublicKey == nil {
+				t.Fatalf("unexpected credential %+v", c)
+			}
+
+			if err = verifyAttestation(a, clientDataHash[:]); err != nil {
+				t.Fatal(err)
+			}
+
+			if a.Format != "none" {
+				clientDataHash[0] ^= 1
+				if err = verifyAttestation(a, clientDataHash[:]); err == nil {
+					t.Fatal("verified altered client data")
+				}
+			}
+		})
+	}
+}
+
+// verifyAttestation checks the signature of a packed, fido-u2f or none
+// attestation statement, as described in the Web Authentication
+// specification, without checking the attestation certificate.
+func verifyAttestation(a *AttestationObject, clientDataHash []byte) error {
+	if a.Format == "none" {
+		if !bytes.Equal(a.AttStmt, []byte{0xa0}) {
+			return errors.New("none attestation with a statement")
+		}
+		return nil
+	}
+
+	var sig []byte
+	in, err := cbor.Lookup(a.AttStmt.Reader(), cbor.Key("sig"))
+	if err == nil {
+		sig, err = cbor.ReadByteString(in)
+	}
+	if err != nil {
+		return err
+	}
+
+	// the attestation key is the credential key for packed self attestation,
+	// and otherwise the key of the first certificate
+	c := a.AuthData.AttestedCredential
+	credentialKey, err := c.PublicKey.PublicKey()
+	if err != nil {
+		return err
+	}
+	key := credentialKey
+	in, err = cbor.Lookup(a.AttStmt.Reader(), cbor.Key("x5c"), cbor.Index(0))
+	if err == nil {
+		var der []byte
+		if der, err = cbor.ReadByteString(in); err != nil {
+			return err
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return err
+		}
+		key = cert.PublicKey
+	} else if err != cbor.ErrNotFound || a.Format != "packed" {
+		return err
+	}
+
+	var signed []byte
+	switch a.Format {
+	case "packed":
+		signed = append(bytes.Clone(a.RawAuthData), clientDataHash...)
+
+	case "fido-u2f":
+		point, err := credentialKey.(*ecdsa.PublicKey).ECDH()
+		if err != nil {
+			return err
+		}
+		signed = append([]byte{0x00}, a.AuthData.RPIDHash[:]...)
+		signed = append(signed, clientDataHash...)
+		signed = append(signed, c.CredentialID...)
+		signed = append(signed, point.Bytes()...)
+
+	default:
+		return errors.New("unsupported format " + a.Format)
+	}
+
+	pub, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return errors.New("unsupported attestation key")
+	}
+	hash := sha256.Sum256(signed)
+	if !ecdsa.VerifyASN1(pub, hash[:], sig) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
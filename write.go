@@ -100,6 +100,78 @@ func WriteBytes(out io.Writer, value []byte) (int, error) {
 	return tn, err
 }
 
+// WriteBytesFrom writes a byte string of [size] bytes read from [r], copying
+// them with io.Copy rather than holding them in memory. It fails with
+// io.ErrUnexpectedEOF if [r] ends early, and reads nothing from [r] beyond
+// [size] bytes.
+func WriteBytesFrom(out io.Writer, r io.Reader, size int64) (int64, error) {
+	if size < 0 {
+		return 0, ErrUnsupportedValue
+	}
+
+	var tn int64
+	n, err := writeMajorType(out, MajorTypeBstr, uint64(size))
+	tn += int64(n)
+	if err != nil {
+		return tn, err
+	}
+
+	m, err := io.CopyN(out, r, size)
+	tn += m
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return tn, err
+}
+
+// defaultChunkSize is the chunk size used by WriteBytesChunked if none is
+// given.
+const defaultChunkSize = 64 * 1024
+
+// WriteBytesChunked writes an indefinite length byte string of everything
+// read from [r], for when its size is not known up front. Each chunk is
+// [chunkSize] bytes, except the last, and only one chunk is held in memory at
+// a time. A [chunkSize] of 0 or less uses 64 KiB.
+func WriteBytesChunked(out io.Writer, r io.Reader, chunkSize int) (int64, error) {
+	if chunkSize <= 0 {
+		chunkSize = defaultChunkSize
+	}
+
+	var tn int64
+	n, err := writeHeader(out, MajorTypeBstr, ArgIndefinite, 0)
+	tn += int64(n)
+	if err != nil {
+		return tn, err
+	}
+
+	chunk := make([]byte, chunkSize)
+	for {
+		l, err := io.ReadFull(r, chunk)
+		if l > 0 {
+			n, werr := writeMajorType(out, MajorTypeBstr, uint64(l))
+			tn += int64(n)
+			if werr != nil {
+				return tn, werr
+			}
+			n, werr = out.Write(chunk[:l])
+			tn += int64(n)
+			if werr != nil {
+				return tn, werr
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return tn, err
+		}
+	}
+
+	n, err = out.Write([]byte{valueBreak})
+	tn += int64(n)
+	return tn, err
+}
+
 func WriteString(out io.Writer, value string) (int, error) {
 	tn := 0
 	n, err := writeMajorType(out, MajorTypeTstr, uint64(len(value)))
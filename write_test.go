@@ -2,8 +2,12 @@ package cbor
 
 import (
 	"bytes"
+	"errors"
 	"github.com/x448/float16"
+	"io"
+	"runtime"
 	"testing"
+	"testing/iotest"
 
 	"github.com/google/go-cmp/cmp"
 )
@@ -401,6 +405,203 @@ func Test_WriteBytes(t *testing.T) {
 	}
 }
 
+func Test_WriteBytesFrom(t *testing.T) {
+	tests := []struct {
+		with string
+		size int64
+		want string
+	}{
+		{with: "", size: 0, want: "40"},
+		{with: "01020304", size: 4, want: "4401020304"},
+		{with: "0102030405", size: 4, want: "4401020304"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			r := bytes.NewReader(decodeHex(t, tt.with))
+			out := bytes.NewBuffer(nil)
+			n, err := WriteBytesFrom(out, r, tt.size)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(decodeHex(t, tt.want), out.Bytes()); diff != "" {
+				t.Fatal(diff)
+			}
+			if n != int64(out.Len()) {
+				t.Fatalf("wrote %d, counted %d", out.Len(), n)
+			}
+			if r.Len() != len(tt.with)/2-int(tt.size) {
+				t.Fatalf("read %d too many", len(tt.with)/2-int(tt.size)-r.Len())
+			}
+		})
+	}
+}
+
+func Test_WriteBytesFrom_Errors(t *testing.T) {
+	errTest := errors.New("test")
+
+	if _, err := WriteBytesFrom(io.Discard, bytes.NewReader(nil), -1); !errors.Is(err, ErrUnsupportedValue) {
+		t.Fatal(err)
+	}
+	if _, err := WriteBytesFrom(io.Discard, bytes.NewReader([]byte{1}), 2); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatal(err)
+	}
+	if _, err := WriteBytesFrom(io.Discard, iotest.ErrReader(errTest), 2); !errors.Is(err, errTest) {
+		t.Fatal(err)
+	}
+}
+
+func Test_WriteBytesChunked(t *testing.T) {
+	tests := []struct {
+		with      string
+		chunkSize int
+		want      string
+	}{
+		{with: "", chunkSize: 2, want: "5fff"},
+		{with: "01", chunkSize: 2, want: "5f4101ff"},
+		{with: "0102", chunkSize: 2, want: "5f420102ff"},
+		{with: "0102030405", chunkSize: 2, want: "5f4201024203044105ff"},
+		{with: "0102030405", chunkSize: 0, want: "5f450102030405ff"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			for name, wrap := range map[string]func(io.Reader) io.Reader{
+				"whole":    func(r io.Reader) io.Reader { return r },
+				"one byte": iotest.OneByteReader,
+			} {
+				t.Run(name, func(t *testing.T) {
+					out := bytes.NewBuffer(nil)
+					n, err := WriteBytesChunked(out, wrap(bytes.NewReader(decodeHex(t, tt.with))), tt.chunkSize)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if diff := cmp.Diff(decodeHex(t, tt.want), out.Bytes()); diff != "" {
+						t.Fatal(diff)
+					}
+					if n != int64(out.Len()) {
+						t.Fatalf("wrote %d, counted %d", out.Len(), n)
+					}
+				})
+			}
+		})
+	}
+
+	errTest := errors.New("test")
+	if _, err := WriteBytesChunked(io.Discard, iotest.ErrReader(errTest), 2); !errors.Is(err, errTest) {
+		t.Fatal(err)
+	}
+}
+
+func Test_WriteBytes_RoundTrip(t *testing.T) {
+	content := make([]byte, 1<<20+3)
+	for i := range content {
+		content[i] = byte(i * 7)
+	}
+
+	for name, write := range map[string]func(io.Writer, io.Reader) (int64, error){
+		"from": func(out io.Writer, r io.Reader) (int64, error) {
+			return WriteBytesFrom(out, r, int64(len(content)))
+		},
+		"chunked": func(out io.Writer, r io.Reader) (int64, error) {
+			return WriteBytesChunked(out, r, 4096)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			encoded := bytes.NewBuffer(nil)
+			if _, err := write(encoded, bytes.NewReader(content)); err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := ReadBytesStream(encoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := bytes.NewBuffer(nil)
+			if _, err = io.Copy(got, r); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(content, got.Bytes()) {
+				t.Fatal("content differs")
+			}
+			if encoded.Len() != 0 {
+				t.Fatalf("trailing data %d", encoded.Len())
+			}
+		})
+	}
+}
+
+// zeroReader reads an endless stream of zero bytes.
+type zeroReader struct{}
+
+func (zeroReader) Read(b []byte) (int, error) {
+	clear(b)
+	return len(b), nil
+}
+
+// countingWriter counts, and discards, everything written to it.
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	w.n += int64(len(b))
+	return len(b), nil
+}
+
+func Test_WriteBytes_Large(t *testing.T) {
+	if testing.Short() {
+		t.Skip("writes gigabytes")
+	}
+
+	const size = 3 << 30
+	const maxAlloc = 1 << 20
+
+	tests := []struct {
+		name  string
+		write func(io.Writer, io.Reader) (int64, error)
+		want  int64
+	}{
+		{
+			name: "from",
+			write: func(out io.Writer, r io.Reader) (int64, error) {
+				return WriteBytesFrom(out, r, size)
+			},
+			want: 5 + size,
+		},
+		{
+			name: "chunked",
+			write: func(out io.Writer, r io.Reader) (int64, error) {
+				return WriteBytesChunked(out, io.LimitReader(r, size), 0)
+			},
+			// a 64 KiB chunk takes a 5 byte header
+			want: 1 + size/defaultChunkSize*(5+defaultChunkSize) + 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+
+			out := &countingWriter{}
+			n, err := tt.write(out, zeroReader{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			runtime.ReadMemStats(&after)
+
+			if n != tt.want || out.n != tt.want {
+				t.Fatalf("wrote %d, counted %d, want %d", out.n, n, tt.want)
+			}
+			if alloc := after.TotalAlloc - before.TotalAlloc; alloc > maxAlloc {
+				t.Fatalf("allocated %d bytes", alloc)
+			}
+		})
+	}
+}
+
 func Test_WriteString(t *testing.T) {
 	tests := []struct {
 		with string